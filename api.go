@@ -31,7 +31,7 @@
 //
 //	invoice := &schema.Invoice{
 //	    Version:      "6.0.2",
-//	    DocumentType: 1, // Invoice
+//	    DocumentType: types.DocumentTypeInvoice,
 //	    ID:           "FV-2025-001",
 //	    UUID:         types.UUID("12345678-1234-1234-1234-123456789012"),
 //	    IssueDate:    types.MustParseDate("2025-01-20"),
@@ -68,6 +68,16 @@
 //	errs := isdoc.ValidateCommonDocument(doc)
 //	xmlOut, err := isdoc.EncodeCommonDocumentBytes(doc)
 //
+// # Lossless Round Trip
+//
+// By default, XML content not modeled by the schema structs is dropped. To
+// edit a document without losing signatures, vendor elements or comments,
+// decode it with Lossless set; the encoder re-emits the preserved content
+// in its original position:
+//
+//	invoice, err := isdoc.DecodeBytesWithOptions(data, isdoc.DecodeOptions{Lossless: true})
+//	xmlOut, err := isdoc.EncodeBytes(invoice)
+//
 // # Streaming API
 //
 // For large files, use the streaming API with io.Reader/io.Writer:
@@ -77,6 +87,14 @@
 //
 //	encoder := isdoc.NewEncoder(writer)
 //	err = encoder.Encode(invoice)
+//
+// For invoices with many lines, StreamEncoder writes lines one at a time
+// without holding the whole document in memory:
+//
+//	enc := isdoc.NewStreamEncoder(writer)
+//	err = enc.WriteHeader(invoice)
+//	err = enc.WriteLine(&line) // repeat for each line
+//	err = enc.WriteFooter(invoice)
 package isdoc
 
 import (
@@ -0,0 +1,98 @@
+// Package cii converts ISDOC invoices to and from UN/CEFACT Cross Industry
+// Invoice (CII D16B) documents following EN 16931, the XML carried in
+// Factur-X and ZUGFeRD 2 PDF files.
+//
+// Fields that cannot be carried over are reported as warnings with code
+// isdoc.ErrCodeLossyConversion:
+//
+//	data, warnings, err := cii.Marshal(invoice)
+//
+//	invoice, warnings, err := cii.Unmarshal(data)
+//
+// Codes are mapped as in the ubl package: DocumentType to UNCL1001 type
+// codes, VAT rates to UNCL5305 categories and payment means as UNCL4461
+// codes. ISDOC credit notes carry negative amounts, CII credit notes (type
+// code 381) positive ones; signs are flipped when converting between them.
+// An invoice in a foreign currency becomes a CII document in that currency
+// with the VAT total in the local currency as TaxCurrencyCode.
+//
+// Factur-X and ZUGFeRD PDF files are read with the pdf package, which
+// returns the embedded CII XML with ReadResult.Format set to pdf.FormatCII.
+package cii
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/schema"
+)
+
+// ErrNotCII is returned by Unmarshal for documents that are not a
+// CrossIndustryInvoice.
+var ErrNotCII = errors.New("not a CII CrossIndustryInvoice")
+
+// Marshal converts inv to CII and encodes it as indented XML. The warnings
+// list the ISDOC fields that could not be converted.
+func Marshal(inv *schema.Invoice) ([]byte, isdoc.ValidationErrors, error) {
+	doc, warnings := FromISDOC(inv)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, warnings, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), warnings, nil
+}
+
+// Unmarshal decodes a CrossIndustryInvoice and converts it to ISDOC. The
+// warnings list the CII fields that could not be converted.
+func Unmarshal(data []byte) (*schema.Invoice, isdoc.ValidationErrors, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	inv, warnings := ToISDOC(doc)
+	return inv, warnings, nil
+}
+
+// Decode decodes a CrossIndustryInvoice.
+func Decode(data []byte) (*Document, error) {
+	dec := xml.NewTokenDecoder(prefixReader{xml.NewDecoder(bytes.NewReader(data))})
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, ErrNotCII) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("decoding CII: %w", err)
+	}
+	return &doc, nil
+}
+
+// IsCII reports whether data looks like a CrossIndustryInvoice.
+func IsCII(data []byte) bool {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if el, ok := t.(xml.StartElement); ok {
+			return el.Name.Space == NamespaceRSM && el.Name.Local == "CrossIndustryInvoice"
+		}
+	}
+}
+
+func lossy(field, msg string) *isdoc.ValidationError {
+	return &isdoc.ValidationError{
+		Field:    field,
+		Code:     isdoc.ErrCodeLossyConversion,
+		Severity: isdoc.SeverityWarning,
+		Msg:      msg,
+	}
+}
@@ -0,0 +1,308 @@
+package cii
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func loadFixture(t *testing.T, name string) *schema.Invoice {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	inv, err := isdoc.DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	return inv
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"test001.isdoc", "test002.isdoc", "sample.isdoc", "no-vat-applicable.isdoc"} {
+		t.Run(name, func(t *testing.T) {
+			want := loadFixture(t, name)
+
+			data, _, err := Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if !IsCII(data) {
+				t.Fatal("IsCII = false for marshaled document")
+			}
+			got, _, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			// The fixtures use "Ks" and other units outside UN/ECE Rec 20
+			for _, e := range isdoc.ValidateInvoice(got) {
+				if e.Code == isdoc.ErrCodeInvalidEnum && strings.HasSuffix(e.Field, ".@unitCode") {
+					continue
+				}
+				t.Errorf("converted invoice is invalid: %v", e)
+			}
+			for field, pair := range map[string][2]string{
+				"ID":                  {got.ID, want.ID},
+				"DocumentType":        {got.DocumentType.String(), want.DocumentType.String()},
+				"IssueDate":           {got.IssueDate.String(), want.IssueDate.String()},
+				"TaxPointDate":        {got.TaxPointDate.String(), want.TaxPointDate.String()},
+				"LocalCurrencyCode":   {got.LocalCurrencyCode, want.LocalCurrencyCode},
+				"ForeignCurrencyCode": {got.ForeignCurrencyCode, want.ForeignCurrencyCode},
+				"Seller":              {got.AccountingSupplierParty.Party.PartyIdentification.ID, want.AccountingSupplierParty.Party.PartyIdentification.ID},
+				"SellerStreet":        {got.AccountingSupplierParty.Party.PostalAddress.StreetName, want.AccountingSupplierParty.Party.PostalAddress.StreetName},
+				"SellerBuilding":      {got.AccountingSupplierParty.Party.PostalAddress.BuildingNumber, want.AccountingSupplierParty.Party.PostalAddress.BuildingNumber},
+			} {
+				if pair[0] != pair[1] {
+					t.Errorf("%s = %q, want %q", field, pair[0], pair[1])
+				}
+			}
+			for field, pair := range map[string][2]types.Decimal{
+				"TaxExclusiveAmount": {got.LegalMonetaryTotal.TaxExclusiveAmount, want.LegalMonetaryTotal.TaxExclusiveAmount},
+				"TaxInclusiveAmount": {got.LegalMonetaryTotal.TaxInclusiveAmount, want.LegalMonetaryTotal.TaxInclusiveAmount},
+				"PayableAmount":      {got.LegalMonetaryTotal.PayableAmount, want.LegalMonetaryTotal.PayableAmount},
+				"TaxAmount":          {got.TaxTotal.TaxAmount, want.TaxTotal.TaxAmount},
+			} {
+				if en16931.ParseRat(pair[0]).Cmp(en16931.ParseRat(pair[1])) != 0 {
+					t.Errorf("%s = %s, want %s", field, pair[0], pair[1])
+				}
+			}
+			if len(got.InvoiceLines.InvoiceLine) != len(want.InvoiceLines.InvoiceLine) {
+				t.Errorf("got %d lines, want %d", len(got.InvoiceLines.InvoiceLine), len(want.InvoiceLines.InvoiceLine))
+			}
+		})
+	}
+}
+
+func TestFromISDOCForeignCurrency(t *testing.T) {
+	inv := loadFixture(t, "sample.isdoc")
+	if inv.ForeignCurrencyCode == "" {
+		t.Fatal("fixture has no foreign currency")
+	}
+	doc, _ := FromISDOC(inv)
+	s := doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+
+	if s.InvoiceCurrencyCode != inv.ForeignCurrencyCode {
+		t.Errorf("InvoiceCurrencyCode = %s, want %s", s.InvoiceCurrencyCode, inv.ForeignCurrencyCode)
+	}
+	if s.TaxCurrencyCode != inv.LocalCurrencyCode {
+		t.Errorf("TaxCurrencyCode = %s, want %s", s.TaxCurrencyCode, inv.LocalCurrencyCode)
+	}
+	totals := s.SpecifiedTradeSettlementHeaderMonetarySummation.TaxTotalAmount
+	if len(totals) != 2 {
+		t.Fatalf("got %d tax totals, want 2", len(totals))
+	}
+	if local := totals[1]; local.CurrencyID != inv.LocalCurrencyCode || !local.Value.Equal(inv.TaxTotal.TaxAmount) {
+		t.Errorf("local tax total = %s %s, want %s %s", local.Value, local.CurrencyID, inv.TaxTotal.TaxAmount, inv.LocalCurrencyCode)
+	}
+}
+
+func TestFromISDOCWarnings(t *testing.T) {
+	inv := loadFixture(t, "test001.isdoc")
+	_, warnings := FromISDOC(inv)
+	var uuid bool
+	for _, w := range warnings {
+		if w.Code != isdoc.ErrCodeLossyConversion || w.Severity != isdoc.SeverityWarning {
+			t.Errorf("unexpected warning %v", w)
+		}
+		if w.Field == "Invoice.UUID" {
+			uuid = true
+		}
+	}
+	if !uuid {
+		t.Error("missing warning for UUID")
+	}
+}
+
+const creditNote = `<?xml version="1.0" encoding="UTF-8"?>
+<x:CrossIndustryInvoice xmlns:x="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+    xmlns:a="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+    xmlns:q="urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+    xmlns:u="urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100">
+  <x:ExchangedDocumentContext>
+    <a:GuidelineSpecifiedDocumentContextParameter><a:ID>urn:cen.eu:en16931:2017</a:ID></a:GuidelineSpecifiedDocumentContextParameter>
+  </x:ExchangedDocumentContext>
+  <x:ExchangedDocument>
+    <a:ID>GS-2024-7</a:ID>
+    <a:TypeCode>381</a:TypeCode>
+    <a:IssueDateTime><u:DateTimeString format="102">20240301</u:DateTimeString></a:IssueDateTime>
+    <a:IncludedNote><a:Content>Gutschrift</a:Content></a:IncludedNote>
+  </x:ExchangedDocument>
+  <x:SupplyChainTradeTransaction>
+    <a:IncludedSupplyChainTradeLineItem>
+      <a:AssociatedDocumentLineDocument><a:LineID>1</a:LineID></a:AssociatedDocumentLineDocument>
+      <a:SpecifiedTradeProduct>
+        <a:GlobalID schemeID="0160">4012345000016</a:GlobalID>
+        <a:Name>Returned goods</a:Name>
+      </a:SpecifiedTradeProduct>
+      <a:SpecifiedLineTradeAgreement>
+        <a:NetPriceProductTradePrice><a:ChargeAmount>50.00</a:ChargeAmount></a:NetPriceProductTradePrice>
+      </a:SpecifiedLineTradeAgreement>
+      <a:SpecifiedLineTradeDelivery><a:BilledQuantity unitCode="C62">2</a:BilledQuantity></a:SpecifiedLineTradeDelivery>
+      <a:SpecifiedLineTradeSettlement>
+        <a:ApplicableTradeTax>
+          <a:TypeCode>VAT</a:TypeCode>
+          <a:CategoryCode>S</a:CategoryCode>
+          <a:RateApplicablePercent>19</a:RateApplicablePercent>
+        </a:ApplicableTradeTax>
+        <a:SpecifiedTradeSettlementLineMonetarySummation><a:LineTotalAmount>100.00</a:LineTotalAmount></a:SpecifiedTradeSettlementLineMonetarySummation>
+      </a:SpecifiedLineTradeSettlement>
+    </a:IncludedSupplyChainTradeLineItem>
+    <a:ApplicableHeaderTradeAgreement>
+      <a:SellerTradeParty>
+        <a:Name>Lieferant GmbH</a:Name>
+        <a:SpecifiedLegalOrganization><a:ID>HRB 12345</a:ID></a:SpecifiedLegalOrganization>
+        <a:PostalTradeAddress>
+          <a:PostcodeCode>80333</a:PostcodeCode>
+          <a:LineOne>Lieferantenstraße 20</a:LineOne>
+          <a:CityName>München</a:CityName>
+          <a:CountryID>DE</a:CountryID>
+        </a:PostalTradeAddress>
+        <a:SpecifiedTaxRegistration><a:ID schemeID="VA">DE123456789</a:ID></a:SpecifiedTaxRegistration>
+      </a:SellerTradeParty>
+      <a:BuyerTradeParty>
+        <a:ID>12345678</a:ID>
+        <a:Name>Kunde s.r.o.</a:Name>
+        <a:PostalTradeAddress>
+          <a:LineOne>Národní 1</a:LineOne>
+          <a:CityName>Praha</a:CityName>
+          <a:CountryID>CZ</a:CountryID>
+        </a:PostalTradeAddress>
+      </a:BuyerTradeParty>
+    </a:ApplicableHeaderTradeAgreement>
+    <a:ApplicableHeaderTradeDelivery/>
+    <a:ApplicableHeaderTradeSettlement>
+      <a:InvoiceCurrencyCode>EUR</a:InvoiceCurrencyCode>
+      <a:SpecifiedTradeSettlementPaymentMeans>
+        <a:TypeCode>58</a:TypeCode>
+        <a:PayeePartyCreditorFinancialAccount><a:IBANID>DE02120300000000202051</a:IBANID></a:PayeePartyCreditorFinancialAccount>
+      </a:SpecifiedTradeSettlementPaymentMeans>
+      <a:ApplicableTradeTax>
+        <a:CalculatedAmount>19.00</a:CalculatedAmount>
+        <a:TypeCode>VAT</a:TypeCode>
+        <a:BasisAmount>100.00</a:BasisAmount>
+        <a:CategoryCode>S</a:CategoryCode>
+        <a:TaxPointDate><u:DateString format="102">20240228</u:DateString></a:TaxPointDate>
+        <a:RateApplicablePercent>19</a:RateApplicablePercent>
+      </a:ApplicableTradeTax>
+      <a:SpecifiedTradeSettlementHeaderMonetarySummation>
+        <a:LineTotalAmount>100.00</a:LineTotalAmount>
+        <a:TaxBasisTotalAmount>100.00</a:TaxBasisTotalAmount>
+        <a:TaxTotalAmount currencyID="EUR">19.00</a:TaxTotalAmount>
+        <a:GrandTotalAmount>119.00</a:GrandTotalAmount>
+        <a:DuePayableAmount>119.00</a:DuePayableAmount>
+      </a:SpecifiedTradeSettlementHeaderMonetarySummation>
+      <a:InvoiceReferencedDocument>
+        <a:IssuerAssignedID>RE-2024-1</a:IssuerAssignedID>
+        <a:FormattedIssueDateTime><q:DateTimeString format="102">20240201</q:DateTimeString></a:FormattedIssueDateTime>
+      </a:InvoiceReferencedDocument>
+    </a:ApplicableHeaderTradeSettlement>
+  </x:SupplyChainTradeTransaction>
+</x:CrossIndustryInvoice>
+`
+
+func TestCreditNote(t *testing.T) {
+	data := []byte(creditNote)
+	if !IsCII(data) {
+		t.Fatal("IsCII = false")
+	}
+	inv, _, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if inv.DocumentType != 2 {
+		t.Errorf("DocumentType = %d, want 2", inv.DocumentType)
+	}
+	refs := inv.OriginalDocumentReferences
+	if refs == nil || len(refs.OriginalDocumentReference) != 1 ||
+		refs.OriginalDocumentReference[0].OriginalDocumentID != "RE-2024-1" ||
+		refs.OriginalDocumentReference[0].IssueDate.String() != "2024-02-01" {
+		t.Errorf("OriginalDocumentReferences = %+v", refs)
+	}
+	if got := inv.TaxPointDate.String(); got != "2024-02-28" {
+		t.Errorf("TaxPointDate = %s, want 2024-02-28", got)
+	}
+	seller := inv.AccountingSupplierParty.Party
+	if seller.PartyIdentification.ID != "HRB 12345" {
+		t.Errorf("seller ID = %q", seller.PartyIdentification.ID)
+	}
+	if a := seller.PostalAddress; a.StreetName != "Lieferantenstraße" || a.BuildingNumber != "20" {
+		t.Errorf("seller address = %q %q", a.StreetName, a.BuildingNumber)
+	}
+	if got := inv.LegalMonetaryTotal.TaxInclusiveAmount; en16931.ParseRat(got).Cmp(en16931.ParseRat("-119")) != 0 {
+		t.Errorf("TaxInclusiveAmount = %s, want -119", got)
+	}
+	line := inv.InvoiceLines.InvoiceLine[0]
+	if got := line.LineExtensionAmount; en16931.ParseRat(got).Cmp(en16931.ParseRat("-100")) != 0 {
+		t.Errorf("line LineExtensionAmount = %s, want -100", got)
+	}
+	if id := line.Item.CatalogueItemIdentification; id == nil || id.ID != "4012345000016" {
+		t.Errorf("CatalogueItemIdentification = %+v", id)
+	}
+	if a := inv.PaymentMeans.Payment[0].Details.BankAccount; a == nil || a.IBAN != "DE02120300000000202051" {
+		t.Errorf("BankAccount = %+v", a)
+	}
+	if errs := isdoc.ValidateInvoice(inv); len(errs) > 0 {
+		t.Errorf("converted credit note is invalid: %v", errs)
+	}
+
+	out, _, err := Marshal(inv)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{
+		"<ram:TypeCode>381</ram:TypeCode>",
+		"<ram:DuePayableAmount>119.00</ram:DuePayableAmount>",
+		`<udt:DateTimeString format="102">20240301</udt:DateTimeString>`,
+		`<qdt:DateTimeString format="102">20240201</qdt:DateTimeString>`,
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output lacks %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestUnmarshalNotCII(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", "test001.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if IsCII(data) {
+		t.Error("IsCII = true for an ISDOC document")
+	}
+	if _, _, err := Unmarshal(data); !errors.Is(err, ErrNotCII) {
+		t.Errorf("Unmarshal error = %v, want ErrNotCII", err)
+	}
+}
+
+func TestDateFormat(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+		err      bool
+	}{
+		{`<d><DateTimeString format="102">20240229</DateTimeString></d>`, "2024-02-29", false},
+		{`<d><DateTimeString format="203">202402291530</DateTimeString></d>`, "2024-02-29", false},
+		{`<d><DateTimeString format="102">2024-02-29</DateTimeString></d>`, "", true},
+	} {
+		var d DateTime
+		err := xml.Unmarshal([]byte(tt.in), &d)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error = %v", tt.in, err)
+			continue
+		}
+		if got := d.String(); !tt.err && got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
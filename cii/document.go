@@ -0,0 +1,482 @@
+package cii
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xseman/isdoc/types"
+)
+
+// UN/CEFACT CII D16B namespaces.
+const (
+	NamespaceRSM = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	NamespaceRAM = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	NamespaceQDT = "urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+	NamespaceUDT = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+)
+
+// GuidelineID is the EN 16931 specification identifier written by
+// FromISDOC. It is also the identifier of the Factur-X and ZUGFeRD 2
+// EN 16931 profile.
+const GuidelineID = "urn:cen.eu:en16931:2017"
+
+// Document is a CrossIndustryInvoice restricted to the elements used by
+// EN 16931. Elements are written with the "rsm", "ram", "qdt" and "udt"
+// prefixes; any prefixes are accepted when reading.
+type Document struct {
+	ExchangedDocumentContext    DocumentContext   `xml:"rsm:ExchangedDocumentContext"`
+	ExchangedDocument           ExchangedDocument `xml:"rsm:ExchangedDocument"`
+	SupplyChainTradeTransaction Transaction       `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+// DocumentContext identifies the specification and profile the document
+// follows.
+type DocumentContext struct {
+	BusinessProcessSpecifiedDocumentContextParameter *ContextParameter `xml:"ram:BusinessProcessSpecifiedDocumentContextParameter,omitempty"`
+	GuidelineSpecifiedDocumentContextParameter       ContextParameter  `xml:"ram:GuidelineSpecifiedDocumentContextParameter"`
+}
+
+// ContextParameter is a specification or process identifier.
+type ContextParameter struct {
+	ID string `xml:"ram:ID"`
+}
+
+// ExchangedDocument holds the document number, type and date.
+type ExchangedDocument struct {
+	ID string `xml:"ram:ID"`
+
+	// TypeCode is the UNCL1001 document type code.
+	TypeCode      string   `xml:"ram:TypeCode"`
+	IssueDateTime DateTime `xml:"ram:IssueDateTime"`
+	IncludedNote  []Note   `xml:"ram:IncludedNote,omitempty"`
+}
+
+// Note is a free text note.
+type Note struct {
+	Content     string `xml:"ram:Content"`
+	SubjectCode string `xml:"ram:SubjectCode,omitempty"`
+}
+
+// Transaction holds the lines and the header agreement, delivery and
+// settlement.
+type Transaction struct {
+	IncludedSupplyChainTradeLineItem []LineItem       `xml:"ram:IncludedSupplyChainTradeLineItem"`
+	ApplicableHeaderTradeAgreement   HeaderAgreement  `xml:"ram:ApplicableHeaderTradeAgreement"`
+	ApplicableHeaderTradeDelivery    HeaderDelivery   `xml:"ram:ApplicableHeaderTradeDelivery"`
+	ApplicableHeaderTradeSettlement  HeaderSettlement `xml:"ram:ApplicableHeaderTradeSettlement"`
+}
+
+// ID is an identifier with an optional scheme.
+type ID struct {
+	Value    string `xml:",chardata"`
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+}
+
+// Amount is an amount with a currency, used for the VAT totals.
+type Amount struct {
+	Value      types.Decimal `xml:",chardata"`
+	CurrencyID string        `xml:"currencyID,attr,omitempty"`
+}
+
+// Quantity is a quantity with a UN/ECE Rec 20 unit code.
+type Quantity struct {
+	Value    types.Decimal `xml:",chardata"`
+	UnitCode string        `xml:"unitCode,attr,omitempty"`
+}
+
+// HeaderAgreement holds the parties and the order and contract references.
+type HeaderAgreement struct {
+	BuyerReference                string              `xml:"ram:BuyerReference,omitempty"`
+	SellerTradeParty              TradeParty          `xml:"ram:SellerTradeParty"`
+	BuyerTradeParty               TradeParty          `xml:"ram:BuyerTradeParty"`
+	SellerOrderReferencedDocument *ReferencedDocument `xml:"ram:SellerOrderReferencedDocument,omitempty"`
+	BuyerOrderReferencedDocument  *ReferencedDocument `xml:"ram:BuyerOrderReferencedDocument,omitempty"`
+	ContractReferencedDocument    *ReferencedDocument `xml:"ram:ContractReferencedDocument,omitempty"`
+}
+
+// TradeParty is a seller, buyer or ship-to party.
+type TradeParty struct {
+	ID                         []ID               `xml:"ram:ID,omitempty"`
+	GlobalID                   []ID               `xml:"ram:GlobalID,omitempty"`
+	Name                       string             `xml:"ram:Name,omitempty"`
+	Description                string             `xml:"ram:Description,omitempty"`
+	SpecifiedLegalOrganization *LegalOrganization `xml:"ram:SpecifiedLegalOrganization,omitempty"`
+	DefinedTradeContact        *TradeContact      `xml:"ram:DefinedTradeContact,omitempty"`
+	PostalTradeAddress         *TradeAddress      `xml:"ram:PostalTradeAddress,omitempty"`
+	URIUniversalCommunication  *URICommunication  `xml:"ram:URIUniversalCommunication,omitempty"`
+	SpecifiedTaxRegistration   []TaxRegistration  `xml:"ram:SpecifiedTaxRegistration,omitempty"`
+}
+
+// LegalOrganization is the legal registration of a party.
+type LegalOrganization struct {
+	ID                  *ID    `xml:"ram:ID,omitempty"`
+	TradingBusinessName string `xml:"ram:TradingBusinessName,omitempty"`
+}
+
+// TradeContact is a contact person.
+type TradeContact struct {
+	PersonName                      string              `xml:"ram:PersonName,omitempty"`
+	DepartmentName                  string              `xml:"ram:DepartmentName,omitempty"`
+	TelephoneUniversalCommunication *PhoneCommunication `xml:"ram:TelephoneUniversalCommunication,omitempty"`
+	EmailURIUniversalCommunication  *URICommunication   `xml:"ram:EmailURIUniversalCommunication,omitempty"`
+}
+
+// PhoneCommunication is a telephone number.
+type PhoneCommunication struct {
+	CompleteNumber string `xml:"ram:CompleteNumber"`
+}
+
+// URICommunication is an e-mail or electronic address.
+type URICommunication struct {
+	URIID ID `xml:"ram:URIID"`
+}
+
+// TradeAddress is a postal address.
+type TradeAddress struct {
+	PostcodeCode string `xml:"ram:PostcodeCode,omitempty"`
+	LineOne      string `xml:"ram:LineOne,omitempty"`
+	LineTwo      string `xml:"ram:LineTwo,omitempty"`
+	LineThree    string `xml:"ram:LineThree,omitempty"`
+	CityName     string `xml:"ram:CityName,omitempty"`
+	CountryID    string `xml:"ram:CountryID"`
+}
+
+// TaxRegistration is a VAT ("VA") or tax ("FC") number.
+type TaxRegistration struct {
+	ID ID `xml:"ram:ID"`
+}
+
+// ReferencedDocument references an order, contract, despatch advice or
+// preceding invoice, or a line of one.
+type ReferencedDocument struct {
+	IssuerAssignedID       string             `xml:"ram:IssuerAssignedID,omitempty"`
+	LineID                 string             `xml:"ram:LineID,omitempty"`
+	FormattedIssueDateTime *FormattedDateTime `xml:"ram:FormattedIssueDateTime,omitempty"`
+}
+
+// HeaderDelivery holds the ship-to party, the delivery date and the
+// despatch advice.
+type HeaderDelivery struct {
+	ShipToTradeParty                 *TradeParty         `xml:"ram:ShipToTradeParty,omitempty"`
+	ActualDeliverySupplyChainEvent   *SupplyChainEvent   `xml:"ram:ActualDeliverySupplyChainEvent,omitempty"`
+	DespatchAdviceReferencedDocument *ReferencedDocument `xml:"ram:DespatchAdviceReferencedDocument,omitempty"`
+}
+
+// SupplyChainEvent is the date of delivery.
+type SupplyChainEvent struct {
+	OccurrenceDateTime DateTime `xml:"ram:OccurrenceDateTime"`
+}
+
+// HeaderSettlement holds the currencies, payment, VAT breakdown and totals.
+type HeaderSettlement struct {
+	CreditorReferenceID                             string               `xml:"ram:CreditorReferenceID,omitempty"`
+	PaymentReference                                string               `xml:"ram:PaymentReference,omitempty"`
+	TaxCurrencyCode                                 string               `xml:"ram:TaxCurrencyCode,omitempty"`
+	InvoiceCurrencyCode                             string               `xml:"ram:InvoiceCurrencyCode"`
+	SpecifiedTradeSettlementPaymentMeans            []PaymentMeans       `xml:"ram:SpecifiedTradeSettlementPaymentMeans,omitempty"`
+	ApplicableTradeTax                              []TradeTax           `xml:"ram:ApplicableTradeTax"`
+	SpecifiedTradeAllowanceCharge                   []AllowanceCharge    `xml:"ram:SpecifiedTradeAllowanceCharge,omitempty"`
+	SpecifiedTradePaymentTerms                      []PaymentTerms       `xml:"ram:SpecifiedTradePaymentTerms,omitempty"`
+	SpecifiedTradeSettlementHeaderMonetarySummation Summation            `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+	InvoiceReferencedDocument                       []ReferencedDocument `xml:"ram:InvoiceReferencedDocument,omitempty"`
+}
+
+// PaymentMeans is a UNCL4461 means of payment with the payee's account.
+type PaymentMeans struct {
+	TypeCode                                   string                `xml:"ram:TypeCode"`
+	Information                                string                `xml:"ram:Information,omitempty"`
+	PayeePartyCreditorFinancialAccount         *FinancialAccount     `xml:"ram:PayeePartyCreditorFinancialAccount,omitempty"`
+	PayeeSpecifiedCreditorFinancialInstitution *FinancialInstitution `xml:"ram:PayeeSpecifiedCreditorFinancialInstitution,omitempty"`
+}
+
+// FinancialAccount is a bank account given by IBAN or a proprietary number.
+type FinancialAccount struct {
+	IBANID        string `xml:"ram:IBANID,omitempty"`
+	AccountName   string `xml:"ram:AccountName,omitempty"`
+	ProprietaryID string `xml:"ram:ProprietaryID,omitempty"`
+}
+
+// FinancialInstitution is the payee's bank.
+type FinancialInstitution struct {
+	BICID string `xml:"ram:BICID"`
+}
+
+// TradeTax is a VAT breakdown at the header or the VAT category of a line
+// or an allowance or charge. Amounts are only set at the header.
+type TradeTax struct {
+	CalculatedAmount      types.Decimal `xml:"ram:CalculatedAmount,omitempty"`
+	TypeCode              string        `xml:"ram:TypeCode"`
+	ExemptionReason       string        `xml:"ram:ExemptionReason,omitempty"`
+	BasisAmount           types.Decimal `xml:"ram:BasisAmount,omitempty"`
+	CategoryCode          string        `xml:"ram:CategoryCode"`
+	ExemptionReasonCode   string        `xml:"ram:ExemptionReasonCode,omitempty"`
+	TaxPointDate          *DateString   `xml:"ram:TaxPointDate,omitempty"`
+	RateApplicablePercent types.Decimal `xml:"ram:RateApplicablePercent,omitempty"`
+}
+
+// AllowanceCharge is a document or line level allowance or charge.
+type AllowanceCharge struct {
+	ChargeIndicator  Indicator     `xml:"ram:ChargeIndicator"`
+	ActualAmount     types.Decimal `xml:"ram:ActualAmount"`
+	ReasonCode       string        `xml:"ram:ReasonCode,omitempty"`
+	Reason           string        `xml:"ram:Reason,omitempty"`
+	CategoryTradeTax *TradeTax     `xml:"ram:CategoryTradeTax,omitempty"`
+}
+
+// Indicator is a udt:IndicatorType.
+type Indicator struct {
+	Indicator bool `xml:"udt:Indicator"`
+}
+
+// PaymentTerms holds the payment terms and the due date.
+type PaymentTerms struct {
+	Description     string    `xml:"ram:Description,omitempty"`
+	DueDateDateTime *DateTime `xml:"ram:DueDateDateTime,omitempty"`
+}
+
+// Summation holds the document totals. TaxTotalAmount is given in the
+// invoice currency and, if it differs, the tax currency.
+type Summation struct {
+	LineTotalAmount      types.Decimal `xml:"ram:LineTotalAmount"`
+	ChargeTotalAmount    types.Decimal `xml:"ram:ChargeTotalAmount,omitempty"`
+	AllowanceTotalAmount types.Decimal `xml:"ram:AllowanceTotalAmount,omitempty"`
+	TaxBasisTotalAmount  types.Decimal `xml:"ram:TaxBasisTotalAmount"`
+	TaxTotalAmount       []Amount      `xml:"ram:TaxTotalAmount,omitempty"`
+	RoundingAmount       types.Decimal `xml:"ram:RoundingAmount,omitempty"`
+	GrandTotalAmount     types.Decimal `xml:"ram:GrandTotalAmount"`
+	TotalPrepaidAmount   types.Decimal `xml:"ram:TotalPrepaidAmount,omitempty"`
+	DuePayableAmount     types.Decimal `xml:"ram:DuePayableAmount"`
+}
+
+// LineItem is an invoice line.
+type LineItem struct {
+	AssociatedDocumentLineDocument LineDocument   `xml:"ram:AssociatedDocumentLineDocument"`
+	SpecifiedTradeProduct          Product        `xml:"ram:SpecifiedTradeProduct"`
+	SpecifiedLineTradeAgreement    LineAgreement  `xml:"ram:SpecifiedLineTradeAgreement"`
+	SpecifiedLineTradeDelivery     LineDelivery   `xml:"ram:SpecifiedLineTradeDelivery"`
+	SpecifiedLineTradeSettlement   LineSettlement `xml:"ram:SpecifiedLineTradeSettlement"`
+}
+
+// LineDocument holds the line number and note.
+type LineDocument struct {
+	LineID       string `xml:"ram:LineID"`
+	IncludedNote []Note `xml:"ram:IncludedNote,omitempty"`
+}
+
+// Product describes the goods or services of a line.
+type Product struct {
+	GlobalID         *ID    `xml:"ram:GlobalID,omitempty"`
+	SellerAssignedID string `xml:"ram:SellerAssignedID,omitempty"`
+	BuyerAssignedID  string `xml:"ram:BuyerAssignedID,omitempty"`
+	Name             string `xml:"ram:Name"`
+	Description      string `xml:"ram:Description,omitempty"`
+}
+
+// LineAgreement holds the order line reference and the prices.
+type LineAgreement struct {
+	BuyerOrderReferencedDocument *ReferencedDocument `xml:"ram:BuyerOrderReferencedDocument,omitempty"`
+	GrossPriceProductTradePrice  *TradePrice         `xml:"ram:GrossPriceProductTradePrice,omitempty"`
+	NetPriceProductTradePrice    TradePrice          `xml:"ram:NetPriceProductTradePrice"`
+}
+
+// TradePrice is a unit price, optionally per a base quantity.
+type TradePrice struct {
+	ChargeAmount  types.Decimal `xml:"ram:ChargeAmount"`
+	BasisQuantity *Quantity     `xml:"ram:BasisQuantity,omitempty"`
+}
+
+// LineDelivery holds the invoiced quantity.
+type LineDelivery struct {
+	BilledQuantity Quantity `xml:"ram:BilledQuantity"`
+}
+
+// LineSettlement holds the VAT category, allowances and charges and the
+// net amount of a line.
+type LineSettlement struct {
+	ApplicableTradeTax                            TradeTax          `xml:"ram:ApplicableTradeTax"`
+	SpecifiedTradeAllowanceCharge                 []AllowanceCharge `xml:"ram:SpecifiedTradeAllowanceCharge,omitempty"`
+	SpecifiedTradeSettlementLineMonetarySummation LineSummation     `xml:"ram:SpecifiedTradeSettlementLineMonetarySummation"`
+}
+
+// LineSummation holds the net amount of a line.
+type LineSummation struct {
+	LineTotalAmount types.Decimal `xml:"ram:LineTotalAmount"`
+}
+
+// dateFormat is the UNTDID 2379 code 102 date format, CCYYMMDD.
+const dateFormat = "20060102"
+
+// DateTime is a udt:DateTimeString date in format 102.
+type DateTime struct {
+	types.Date
+}
+
+// MarshalXML writes the date as a udt:DateTimeString element.
+func (d DateTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalDate(e, start, "udt:DateTimeString", d.Date)
+}
+
+// UnmarshalXML reads a DateTimeString element in format 102.
+func (d *DateTime) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalDate(dec, &d.Date)
+}
+
+// FormattedDateTime is a qdt:DateTimeString date in format 102, used for
+// the dates of referenced documents.
+type FormattedDateTime struct {
+	types.Date
+}
+
+// MarshalXML writes the date as a qdt:DateTimeString element.
+func (d FormattedDateTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalDate(e, start, "qdt:DateTimeString", d.Date)
+}
+
+// UnmarshalXML reads a DateTimeString element in format 102.
+func (d *FormattedDateTime) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalDate(dec, &d.Date)
+}
+
+// DateString is a udt:DateString date in format 102, used for the tax
+// point date.
+type DateString struct {
+	types.Date
+}
+
+// MarshalXML writes the date as a udt:DateString element.
+func (d DateString) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalDate(e, start, "udt:DateString", d.Date)
+}
+
+// UnmarshalXML reads a DateString element in format 102.
+func (d *DateString) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	return unmarshalDate(dec, &d.Date)
+}
+
+func marshalDate(e *xml.Encoder, start xml.StartElement, name string, d types.Date) error {
+	inner := xml.StartElement{
+		Name: xml.Name{Local: name},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "format"}, Value: "102"}},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(d.Time.Format(dateFormat), inner); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// unmarshalDate reads the DateTimeString or DateString child of the
+// current element.
+// Only the date part of formats other than 102 is kept.
+func unmarshalDate(dec *xml.Decoder, d *types.Date) error {
+	var s string
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		switch el := t.(type) {
+		case xml.StartElement:
+			if err := dec.DecodeElement(&s, &el); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			s = strings.TrimSpace(s)
+			if s == "" {
+				*d = types.Date{}
+				return nil
+			}
+			if len(s) > 8 {
+				s = s[:8]
+			}
+			parsed, err := time.Parse(dateFormat, s)
+			if err != nil {
+				return fmt.Errorf("invalid date %q: expected CCYYMMDD", s)
+			}
+			*d = types.NewDate(parsed)
+			return nil
+		}
+	}
+}
+
+// documentFields has the fields of Document without its XML methods.
+type documentFields Document
+
+// documentXML adds the root element and namespace declarations.
+type documentXML struct {
+	XMLName  xml.Name `xml:"rsm:CrossIndustryInvoice"`
+	XmlnsRSM string   `xml:"xmlns:rsm,attr"`
+	XmlnsQDT string   `xml:"xmlns:qdt,attr"`
+	XmlnsRAM string   `xml:"xmlns:ram,attr"`
+	XmlnsUDT string   `xml:"xmlns:udt,attr"`
+	documentFields
+}
+
+// MarshalXML writes the document as a CrossIndustryInvoice element.
+func (d *Document) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	return e.Encode(documentXML{
+		XmlnsRSM:       NamespaceRSM,
+		XmlnsQDT:       NamespaceQDT,
+		XmlnsRAM:       NamespaceRAM,
+		XmlnsUDT:       NamespaceUDT,
+		documentFields: documentFields(*d),
+	})
+}
+
+// UnmarshalXML reads a CrossIndustryInvoice element. Names are expected in
+// the form produced by prefixReader.
+func (d *Document) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	if start.Name.Local != "rsm:CrossIndustryInvoice" {
+		return fmt.Errorf("%w: root element %s", ErrNotCII, start.Name.Local)
+	}
+	return dec.DecodeElement((*documentFields)(d), &start)
+}
+
+// prefixReader rewrites element names from the CII namespaces to the
+// prefixed names used in struct tags, so documents decode whatever
+// prefixes they use.
+type prefixReader struct {
+	d *xml.Decoder
+}
+
+func (r prefixReader) Token() (xml.Token, error) {
+	t, err := r.d.Token()
+	switch el := t.(type) {
+	case xml.StartElement:
+		el.Name = prefixedName(el.Name)
+		attrs := el.Attr[:0]
+		for _, a := range el.Attr {
+			if a.Name.Space == "" && a.Name.Local != "xmlns" {
+				attrs = append(attrs, a)
+			}
+		}
+		el.Attr = attrs
+		return el, err
+	case xml.EndElement:
+		el.Name = prefixedName(el.Name)
+		return el, err
+	}
+	return t, err
+}
+
+func prefixedName(n xml.Name) xml.Name {
+	switch n.Space {
+	case NamespaceRSM:
+		return xml.Name{Local: "rsm:" + n.Local}
+	case NamespaceRAM:
+		return xml.Name{Local: "ram:" + n.Local}
+	case NamespaceQDT:
+		return xml.Name{Local: "qdt:" + n.Local}
+	case NamespaceUDT:
+		return xml.Name{Local: "udt:" + n.Local}
+	}
+	return xml.Name{Local: "other:" + n.Local}
+}
@@ -0,0 +1,542 @@
+package cii
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// FromISDOC converts an ISDOC invoice to a CII document. DocumentType 2 and
+// 6 give a credit note with type code 381. The warnings list ISDOC fields,
+// by their validation path, that have no CII counterpart.
+func FromISDOC(inv *schema.Invoice) (*Document, isdoc.ValidationErrors) {
+	c := &fromISDOC{inv: inv, currency: inv.LocalCurrencyCode}
+	if inv.ForeignCurrencyCode != "" {
+		c.currency = inv.ForeignCurrencyCode
+		// CurrRate local units are worth RefCurrRate foreign units
+		if rate := en16931.ParseRat(inv.CurrRate); rate.Sign() != 0 {
+			c.rate = new(big.Rat).Quo(en16931.ParseRat(inv.RefCurrRate), rate)
+		}
+	}
+	if en16931.IsCreditNote(inv.DocumentType) {
+		total := inv.LegalMonetaryTotal.TaxExclusiveAmount
+		if en16931.ParseRat(total).Sign() == 0 {
+			total = inv.LegalMonetaryTotal.TaxInclusiveAmount
+		}
+		c.negate = en16931.ParseRat(total).Sign() < 0
+	}
+
+	doc := &Document{
+		ExchangedDocumentContext: DocumentContext{
+			GuidelineSpecifiedDocumentContextParameter: ContextParameter{ID: GuidelineID},
+		},
+		ExchangedDocument: ExchangedDocument{
+			ID:            inv.ID,
+			TypeCode:      en16931.TypeCode(inv.DocumentType),
+			IssueDateTime: DateTime{inv.IssueDate},
+		},
+	}
+	switch inv.DocumentType {
+	case types.DocumentTypeAdvanceInvoice, types.DocumentTypeAdvanceCreditNote, types.DocumentTypeSimplified:
+		c.warn("Invoice.DocumentType", "DocumentType %d is written as CII type %s", inv.DocumentType, doc.ExchangedDocument.TypeCode)
+	}
+	if inv.Note != nil && inv.Note.Value != "" {
+		doc.ExchangedDocument.IncludedNote = []Note{{Content: inv.Note.Value}}
+	}
+
+	settlement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+	settlement.InvoiceCurrencyCode = c.currency
+	if inv.ForeignCurrencyCode != "" {
+		settlement.TaxCurrencyCode = inv.LocalCurrencyCode
+		c.warn("Invoice.CurrRate", "only the VAT total is given in the local currency")
+	}
+
+	c.header()
+	c.references(doc)
+	agreement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeAgreement
+	agreement.SellerTradeParty = c.party("Invoice.AccountingSupplierParty.Party", &inv.AccountingSupplierParty.Party)
+	if inv.AccountingCustomerParty != nil {
+		agreement.BuyerTradeParty = c.party("Invoice.AccountingCustomerParty.Party", &inv.AccountingCustomerParty.Party)
+	}
+	c.delivery(doc)
+	c.paymentMeans(doc)
+	c.lines(doc)
+	c.taxes(doc)
+	c.summation(doc)
+
+	return doc, c.warnings
+}
+
+type fromISDOC struct {
+	inv      *schema.Invoice
+	currency string
+	// rate converts local amounts to the foreign currency; it is nil for
+	// invoices in the local currency
+	rate     *big.Rat
+	negate   bool
+	warnings isdoc.ValidationErrors
+}
+
+func (c *fromISDOC) warn(field, format string, args ...any) {
+	c.warnings = append(c.warnings, lossy(field, fmt.Sprintf(format, args...)))
+}
+
+// amount returns an amount in the document currency, taken from curr for
+// foreign currency invoices.
+func (c *fromISDOC) amount(local, curr types.Decimal) types.Decimal {
+	v := local
+	if c.rate != nil {
+		switch {
+		case !curr.IsZero():
+			v = curr
+		case !local.IsZero():
+			v = en16931.FormatAmount(new(big.Rat).Mul(en16931.ParseRat(local), c.rate))
+		}
+	}
+	if c.negate {
+		v = en16931.Negate(v)
+	}
+	if v == "" {
+		v = "0.00"
+	}
+	return v
+}
+
+// header reports header fields without a CII counterpart.
+func (c *fromISDOC) header() {
+	inv := c.inv
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"UUID", !inv.UUID.IsZero()},
+		{"SubDocumentType", inv.SubDocumentType != ""},
+		{"TargetConsolidator", inv.TargetConsolidator != ""},
+		{"ClientOnTargetConsolidator", inv.ClientOnTargetConsolidator != ""},
+		{"ClientBankAccount", inv.ClientBankAccount != ""},
+		{"EgovFlag", inv.EgovFlag.Bool()},
+		{"ISDS_ID", inv.ISDS_ID != ""},
+		{"FileReference", inv.FileReference != ""},
+		{"ReferenceNumber", inv.ReferenceNumber != ""},
+		{"EgovClassifiers", inv.EgovClassifiers != nil},
+		{"IssuingSystem", inv.IssuingSystem != ""},
+		{"ElectronicPossibilityAgreementReference", inv.ElectronicPossibilityAgreementReference.Value != ""},
+		{"Extensions", inv.Extensions != nil},
+		{"SellerSupplierParty", inv.SellerSupplierParty != nil},
+		{"AnonymousCustomerParty", inv.AnonymousCustomerParty != nil},
+		{"BuyerCustomerParty", inv.BuyerCustomerParty != nil},
+		{"NonTaxedDeposits", inv.NonTaxedDeposits != nil},
+		{"TaxedDeposits", inv.TaxedDeposits != nil},
+		{"SupplementsList", inv.SupplementsList != nil},
+	} {
+		if f.set {
+			c.warn("Invoice."+f.name, "%s has no CII counterpart", f.name)
+		}
+	}
+}
+
+func (c *fromISDOC) references(doc *Document) {
+	inv := c.inv
+	agreement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeAgreement
+	delivery := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeDelivery
+	settlement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+
+	if refs := inv.OrderReferences; refs != nil && len(refs.OrderReference) > 0 {
+		ref := refs.OrderReference[0]
+		if ref.ExternalOrderID != "" {
+			agreement.BuyerOrderReferencedDocument = &ReferencedDocument{IssuerAssignedID: ref.ExternalOrderID}
+		}
+		if ref.SalesOrderID != "" {
+			agreement.SellerOrderReferencedDocument = &ReferencedDocument{IssuerAssignedID: ref.SalesOrderID}
+		}
+		if !ref.IssueDate.IsZero() || !ref.ExternalOrderIssueDate.IsZero() || !ref.UUID.IsZero() ||
+			ref.ISDS_ID != "" || ref.FileReference != "" || ref.ReferenceNumber != "" {
+			c.warn("Invoice.OrderReferences.OrderReference[0]", "only the order numbers are converted")
+		}
+		if len(refs.OrderReference) > 1 {
+			c.warn("Invoice.OrderReferences", "only the first of %d order references is converted", len(refs.OrderReference))
+		}
+	}
+
+	if refs := inv.OriginalDocumentReferences; refs != nil {
+		for i, ref := range refs.OriginalDocumentReference {
+			settlement.InvoiceReferencedDocument = append(settlement.InvoiceReferencedDocument, ReferencedDocument{
+				IssuerAssignedID:       ref.OriginalDocumentID,
+				FormattedIssueDateTime: formattedDate(ref.IssueDate),
+			})
+			if !ref.UUID.IsZero() {
+				c.warn(fmt.Sprintf("Invoice.OriginalDocumentReferences.OriginalDocumentReference[%d].UUID", i),
+					"UUID has no CII counterpart")
+			}
+		}
+	}
+
+	if refs := inv.DeliveryNoteReferences; refs != nil && len(refs.DeliveryNoteReference) > 0 {
+		ref := refs.DeliveryNoteReference[0]
+		delivery.DespatchAdviceReferencedDocument = &ReferencedDocument{
+			IssuerAssignedID:       ref.DeliveryNoteID,
+			FormattedIssueDateTime: formattedDate(ref.IssueDate),
+		}
+		if len(refs.DeliveryNoteReference) > 1 {
+			c.warn("Invoice.DeliveryNoteReferences", "only the first of %d delivery notes is converted", len(refs.DeliveryNoteReference))
+		}
+	}
+
+	if refs := inv.ContractReferences; refs != nil && len(refs.ContractReference) > 0 {
+		ref := refs.ContractReference[0]
+		agreement.ContractReferencedDocument = &ReferencedDocument{
+			IssuerAssignedID:       ref.ContractID,
+			FormattedIssueDateTime: formattedDate(ref.IssueDate),
+		}
+		if !ref.LastValidDate.IsZero() || ref.LastValidDateUnbounded.Bool() || ref.ISDS_ID != "" ||
+			ref.FileReference != "" || ref.ReferenceNumber != "" || !ref.UUID.IsZero() {
+			c.warn("Invoice.ContractReferences.ContractReference[0]", "only the contract number and date are converted")
+		}
+		if len(refs.ContractReference) > 1 {
+			c.warn("Invoice.ContractReferences", "only the first of %d contracts is converted", len(refs.ContractReference))
+		}
+	}
+}
+
+func formattedDate(d types.Date) *FormattedDateTime {
+	if d.IsZero() {
+		return nil
+	}
+	return &FormattedDateTime{d}
+}
+
+func (c *fromISDOC) party(path string, p *schema.Party) TradeParty {
+	out := TradeParty{
+		Name:               p.PartyName.Name,
+		PostalTradeAddress: address(&p.PostalAddress),
+	}
+
+	id := p.PartyIdentification
+	if id.ID != "" {
+		out.ID = append(out.ID, ID{Value: id.ID})
+		out.SpecifiedLegalOrganization = &LegalOrganization{ID: &ID{Value: id.ID}}
+	}
+	switch gln := id.CatalogFirmIdentification; {
+	case en16931.IsGLN(gln):
+		out.GlobalID = append(out.GlobalID, ID{Value: gln, SchemeID: en16931.SchemeGLN})
+		out.URIUniversalCommunication = &URICommunication{URIID: ID{Value: gln, SchemeID: en16931.SchemeGLN}}
+	case gln != "" && gln != "0":
+		c.warn(path+".PartyIdentification.CatalogFirmIdentification", "%q is not a GLN", gln)
+	}
+	if id.UserID != "" {
+		c.warn(path+".PartyIdentification.UserID", "UserID has no CII counterpart")
+	}
+
+	for _, ts := range p.PartyTaxScheme {
+		scheme := "FC"
+		if ts.TaxScheme == "VAT" {
+			scheme = "VA"
+			if eas, ok := en16931.EndpointSchemes[en16931.Prefix(ts.CompanyID)]; ok && out.URIUniversalCommunication == nil {
+				out.URIUniversalCommunication = &URICommunication{URIID: ID{Value: ts.CompanyID, SchemeID: eas}}
+			}
+		}
+		out.SpecifiedTaxRegistration = append(out.SpecifiedTaxRegistration, TaxRegistration{
+			ID: ID{Value: ts.CompanyID, SchemeID: scheme},
+		})
+	}
+
+	if reg := p.RegisterIdentification; reg != nil {
+		out.Description = reg.Preformatted
+		if reg.Preformatted == "" {
+			out.Description = en16931.JoinNonEmpty(", ", reg.RegisterKeptAt, reg.RegisterFileRef)
+		}
+		if reg.RegisterKeptAt != "" || reg.RegisterFileRef != "" || !reg.RegisterDate.IsZero() {
+			c.warn(path+".RegisterIdentification", "the register entry is written as text")
+		}
+	}
+
+	if ct := p.Contact; ct != nil {
+		out.DefinedTradeContact = &TradeContact{PersonName: ct.Name}
+		if ct.Telephone != "" {
+			out.DefinedTradeContact.TelephoneUniversalCommunication = &PhoneCommunication{CompleteNumber: ct.Telephone}
+		}
+		if ct.ElectronicMail != "" {
+			out.DefinedTradeContact.EmailURIUniversalCommunication = &URICommunication{URIID: ID{Value: ct.ElectronicMail}}
+		}
+	}
+	return out
+}
+
+// address writes the street and building number as the first address line.
+func address(a *schema.PostalAddress) *TradeAddress {
+	return &TradeAddress{
+		PostcodeCode: a.PostalZone,
+		LineOne:      en16931.JoinNonEmpty(" ", a.StreetName, a.BuildingNumber),
+		CityName:     a.CityName,
+		CountryID:    a.Country.IdentificationCode,
+	}
+}
+
+func (c *fromISDOC) delivery(doc *Document) {
+	d := c.inv.Delivery
+	if d == nil {
+		return
+	}
+	doc.SupplyChainTradeTransaction.ApplicableHeaderTradeDelivery.ShipToTradeParty = &TradeParty{
+		Name:               d.Party.PartyName.Name,
+		PostalTradeAddress: address(&d.Party.PostalAddress),
+	}
+	p := d.Party
+	if p.PartyIdentification.ID != "" || len(p.PartyTaxScheme) > 0 || p.RegisterIdentification != nil || p.Contact != nil {
+		c.warn("Invoice.Delivery.Party", "only the name and address of the delivery party are converted")
+	}
+}
+
+func (c *fromISDOC) paymentMeans(doc *Document) {
+	pm := c.inv.PaymentMeans
+	if pm == nil {
+		return
+	}
+	settlement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+
+	payable := c.inv.LegalMonetaryTotal.PayableAmount
+	var dueDate types.Date
+	for i, p := range pm.Payment {
+		path := fmt.Sprintf("Invoice.PaymentMeans.Payment[%d]", i)
+		m := PaymentMeans{TypeCode: strconv.Itoa(int(p.PaymentMeansCode))}
+		if d := p.Details; d != nil {
+			switch {
+			case dueDate.IsZero():
+				dueDate = d.PaymentDueDate
+			case !d.PaymentDueDate.IsZero() && d.PaymentDueDate != dueDate:
+				c.warn(path+".Details.PaymentDueDate", "only the first due date is converted")
+			}
+			switch {
+			case settlement.PaymentReference == "":
+				settlement.PaymentReference = d.VariableSymbol
+			case d.VariableSymbol != "" && d.VariableSymbol != settlement.PaymentReference:
+				c.warn(path+".Details.VariableSymbol", "only the first variable symbol is converted")
+			}
+			if d.BankAccount != nil {
+				c.financialAccount(path+".Details.BankAccount", &m, d.BankAccount)
+			}
+			if d.DocumentID != "" || !d.IssueDate.IsZero() || d.ConstantSymbol != "" || d.SpecificSymbol != "" {
+				c.warn(path+".Details", "only the due date, variable symbol and bank account are converted")
+			}
+		}
+		if !p.PaidAmount.Equal(payable) {
+			c.warn(path+".PaidAmount", "payment amounts have no CII counterpart")
+		}
+		settlement.SpecifiedTradeSettlementPaymentMeans = append(settlement.SpecifiedTradeSettlementPaymentMeans, m)
+	}
+	if !dueDate.IsZero() {
+		settlement.SpecifiedTradePaymentTerms = []PaymentTerms{{DueDateDateTime: &DateTime{dueDate}}}
+	}
+
+	means := settlement.SpecifiedTradeSettlementPaymentMeans
+	if alt := pm.AlternateBankAccounts; alt != nil && len(means) > 0 {
+		for i := range alt.AlternateBankAccount {
+			path := fmt.Sprintf("Invoice.PaymentMeans.AlternateBankAccounts.AlternateBankAccount[%d]", i)
+			m := PaymentMeans{TypeCode: means[0].TypeCode}
+			c.financialAccount(path, &m, &alt.AlternateBankAccount[i])
+			settlement.SpecifiedTradeSettlementPaymentMeans = append(settlement.SpecifiedTradeSettlementPaymentMeans, m)
+		}
+	}
+}
+
+// financialAccount sets the payee account of m, preferring the IBAN over
+// the domestic account number.
+func (c *fromISDOC) financialAccount(path string, m *PaymentMeans, a *schema.BankAccount) {
+	fa := &FinancialAccount{IBANID: a.IBAN, AccountName: a.Name}
+	if fa.IBANID == "" {
+		fa.ProprietaryID = a.ID
+		if a.BankCode != "" {
+			fa.ProprietaryID += "/" + a.BankCode
+		}
+	} else if a.ID != "" {
+		if id, code, ok := en16931.CzechAccount(a.IBAN); !ok || id != a.ID || code != a.BankCode {
+			c.warn(path+".ID", "the domestic account number is replaced by the IBAN")
+		}
+	}
+	m.PayeePartyCreditorFinancialAccount = fa
+	if a.BIC != "" {
+		m.PayeeSpecifiedCreditorFinancialInstitution = &FinancialInstitution{BICID: a.BIC}
+	}
+}
+
+func (c *fromISDOC) lines(doc *Document) {
+	for i := range c.inv.InvoiceLines.InvoiceLine {
+		l := &c.inv.InvoiceLines.InvoiceLine[i]
+		path := fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i)
+
+		qty := l.InvoicedQuantity.Value
+		if qty.IsZero() {
+			qty = "1"
+		}
+		if c.negate {
+			qty = en16931.Negate(qty)
+		}
+
+		price := en16931.ParseRat(l.UnitPrice)
+		if c.rate != nil {
+			if q := en16931.ParseRat(qty); !l.LineExtensionAmountCurr.IsZero() && q.Sign() != 0 {
+				price = new(big.Rat).Quo(en16931.ParseRat(c.amount(l.LineExtensionAmount, l.LineExtensionAmountCurr)), q)
+			} else {
+				price.Mul(price, c.rate)
+			}
+		}
+		// EN 16931 prices are never negative
+		if price.Sign() < 0 {
+			price.Neg(price)
+			qty = en16931.Negate(qty)
+		}
+
+		category := en16931.TaxCategory(c.inv.VATApplicable.Bool(),
+			l.ClassifiedTaxCategory.Percent, l.ClassifiedTaxCategory.LocalReverseCharge != nil)
+		out := LineItem{
+			AssociatedDocumentLineDocument: LineDocument{LineID: l.ID},
+			SpecifiedTradeProduct:          Product{Name: l.Item.Description},
+			SpecifiedLineTradeAgreement: LineAgreement{
+				NetPriceProductTradePrice: TradePrice{ChargeAmount: en16931.FormatPrice(price)},
+			},
+			SpecifiedLineTradeDelivery: LineDelivery{
+				BilledQuantity: Quantity{Value: qty, UnitCode: l.InvoicedQuantity.UnitCode},
+			},
+			SpecifiedLineTradeSettlement: LineSettlement{
+				ApplicableTradeTax: TradeTax{
+					TypeCode:              "VAT",
+					CategoryCode:          category.ID,
+					RateApplicablePercent: category.Percent,
+				},
+				SpecifiedTradeSettlementLineMonetarySummation: LineSummation{
+					LineTotalAmount: c.amount(l.LineExtensionAmount, l.LineExtensionAmountCurr),
+				},
+			},
+		}
+		if l.Note != "" {
+			out.AssociatedDocumentLineDocument.IncludedNote = []Note{{Content: l.Note}}
+		}
+		if ref := l.OrderReference; ref != nil && ref.LineID != "" {
+			out.SpecifiedLineTradeAgreement.BuyerOrderReferencedDocument = &ReferencedDocument{LineID: ref.LineID}
+		}
+		if l.DeliveryNoteReference != nil || l.OriginalDocumentReference != nil || l.ContractReference != nil {
+			c.warn(path, "line references other than the order line have no CII counterpart")
+		}
+
+		item := &l.Item
+		product := &out.SpecifiedTradeProduct
+		if id := item.SellersItemIdentification; id != nil {
+			product.SellerAssignedID = id.ID
+		}
+		if id := item.BuyersItemIdentification; id != nil {
+			product.BuyerAssignedID = id.ID
+		}
+		if id := item.CatalogueItemIdentification; id != nil && id.ID != "" {
+			product.GlobalID = &ID{Value: id.ID}
+			if en16931.IsGTIN(id.ID) {
+				product.GlobalID.SchemeID = en16931.SchemeGTIN
+			}
+		}
+
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"Item.SecondarySellersItemIdentification", hasID(item.SecondarySellersItemIdentification)},
+			{"Item.TertiarySellersItemIdentification", hasID(item.TertiarySellersItemIdentification)},
+			{"Item.StoreBatches", item.StoreBatches != nil},
+			{"EgovClassifier", l.EgovClassifier != ""},
+			{"VATNote", l.VATNote != ""},
+			{"Extensions", l.Extensions != nil},
+			{"LineExtensionAmountBeforeDiscount", !l.LineExtensionAmountBeforeDiscount.IsZero()},
+		} {
+			if f.set {
+				c.warn(path+"."+f.name, "%s has no CII counterpart", f.name)
+			}
+		}
+		if l.ClassifiedTaxCategory.VATCalculationMethod == types.VATCalculationFromTop {
+			c.warn(path+".ClassifiedTaxCategory.VATCalculationMethod",
+				"VAT calculated from the tax-inclusive price is written as net amounts")
+		}
+		if rc := l.ClassifiedTaxCategory.LocalReverseCharge; rc != nil && (rc.LocalReverseChargeCode != "" || !rc.LocalReverseChargeQuantity.IsZero()) {
+			c.warn(path+".ClassifiedTaxCategory.LocalReverseCharge", "the reverse charge code has no CII counterpart")
+		}
+
+		doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem = append(
+			doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem, out)
+	}
+}
+
+func hasID(id *schema.ItemIdentification) bool {
+	return id != nil && id.ID != ""
+}
+
+// taxes writes the VAT breakdown, with the tax point date on each rate.
+func (c *fromISDOC) taxes(doc *Document) {
+	vat := c.inv.VATApplicable.Bool()
+	settlement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+
+	var taxPoint *DateString
+	if !c.inv.TaxPointDate.IsZero() {
+		taxPoint = &DateString{c.inv.TaxPointDate}
+	}
+	for i, st := range c.inv.TaxTotal.TaxSubTotal {
+		reverseCharge := st.TaxCategory.LocalReverseChargeFlag.Bool()
+		category := en16931.TaxCategory(vat, st.TaxCategory.Percent, reverseCharge)
+		settlement.ApplicableTradeTax = append(settlement.ApplicableTradeTax, TradeTax{
+			CalculatedAmount:      c.amount(st.TaxAmount, st.TaxAmountCurr),
+			TypeCode:              "VAT",
+			ExemptionReason:       category.Reason,
+			BasisAmount:           c.amount(st.TaxableAmount, st.TaxableAmountCurr),
+			CategoryCode:          category.ID,
+			ExemptionReasonCode:   category.ReasonCode,
+			TaxPointDate:          taxPoint,
+			RateApplicablePercent: category.Percent,
+		})
+		if reverseCharge && en16931.ParseRat(st.TaxCategory.Percent).Sign() != 0 {
+			c.warn(fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d].TaxCategory.Percent", i),
+				"the reverse charge rate %s is written as 0", st.TaxCategory.Percent)
+		}
+	}
+}
+
+func (c *fromISDOC) summation(doc *Document) {
+	lmt := &c.inv.LegalMonetaryTotal
+	tt := &c.inv.TaxTotal
+	settlement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+
+	lines := new(big.Rat)
+	for _, l := range doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem {
+		lines.Add(lines, en16931.ParseRat(l.SpecifiedLineTradeSettlement.SpecifiedTradeSettlementLineMonetarySummation.LineTotalAmount))
+	}
+
+	sum := Summation{
+		LineTotalAmount:     en16931.FormatAmount(lines),
+		TaxBasisTotalAmount: c.amount(lmt.TaxExclusiveAmount, lmt.TaxExclusiveAmountCurr),
+		TaxTotalAmount:      []Amount{{Value: c.amount(tt.TaxAmount, tt.TaxAmountCurr), CurrencyID: c.currency}},
+		GrandTotalAmount:    c.amount(lmt.TaxInclusiveAmount, lmt.TaxInclusiveAmountCurr),
+		DuePayableAmount:    c.amount(lmt.PayableAmount, lmt.PayableAmountCurr),
+	}
+	if c.rate != nil {
+		tax := tt.TaxAmount
+		if c.negate {
+			tax = en16931.Negate(tax)
+		}
+		sum.TaxTotalAmount = append(sum.TaxTotalAmount, Amount{Value: tax, CurrencyID: c.inv.LocalCurrencyCode})
+	}
+
+	// Deposits and amounts claimed by earlier documents are prepaid
+	claimed := en16931.ParseRat(c.amount(lmt.AlreadyClaimedTaxInclusiveAmount, lmt.AlreadyClaimedTaxInclusiveAmountCurr))
+	// ISDOC deducts paid deposits whatever their sign
+	deposits := new(big.Rat).Abs(en16931.ParseRat(c.amount(lmt.PaidDepositsAmount, lmt.PaidDepositsAmountCurr)))
+	if c.negate {
+		deposits.Neg(deposits)
+	}
+	if prepaid := claimed.Add(claimed, deposits); prepaid.Sign() != 0 {
+		sum.TotalPrepaidAmount = en16931.FormatAmount(prepaid)
+	}
+	if !lmt.PayableRoundingAmount.IsZero() {
+		sum.RoundingAmount = c.amount(lmt.PayableRoundingAmount, lmt.PayableRoundingAmountCurr)
+	}
+	settlement.SpecifiedTradeSettlementHeaderMonetarySummation = sum
+}
@@ -0,0 +1,618 @@
+package cii
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// Element paths used in warnings.
+const (
+	pathDocument    = "CrossIndustryInvoice.ExchangedDocument"
+	pathTransaction = "CrossIndustryInvoice.SupplyChainTradeTransaction"
+	pathAgreement   = pathTransaction + ".ApplicableHeaderTradeAgreement"
+	pathDelivery    = pathTransaction + ".ApplicableHeaderTradeDelivery"
+	pathSettlement  = pathTransaction + ".ApplicableHeaderTradeSettlement"
+)
+
+// ToISDOC converts a CII document to an ISDOC invoice. A new UUID is
+// generated. Line tax amounts and tax-inclusive prices, which CII does not
+// carry, are computed from the VAT rates; document totals are taken as
+// given. Document level allowances and charges become invoice lines. The
+// warnings list CII fields, by their element path, that have no ISDOC
+// counterpart.
+func ToISDOC(doc *Document) (*schema.Invoice, isdoc.ValidationErrors) {
+	c := &toISDOC{doc: doc}
+	ed := &doc.ExchangedDocument
+
+	docType, ok := en16931.DocumentType(ed.TypeCode, false)
+	if !ok {
+		c.warn(pathDocument+".TypeCode", "type code %q is written as DocumentType %d", ed.TypeCode, docType)
+	}
+	c.negate = en16931.IsCreditNote(docType)
+
+	inv := &schema.Invoice{
+		Version:       schema.Version,
+		DocumentType:  docType,
+		ID:            ed.ID,
+		UUID:          types.NewRandomUUID(),
+		IssueDate:     ed.IssueDateTime.Date,
+		VATApplicable: types.Bool(c.vatApplicable()),
+	}
+	c.inv = inv
+
+	if len(ed.IncludedNote) > 0 {
+		texts := make([]string, len(ed.IncludedNote))
+		for i, n := range ed.IncludedNote {
+			texts[i] = n.Content
+		}
+		inv.Note = &schema.Note{Value: strings.Join(texts, "\n")}
+	}
+	agreement := &doc.SupplyChainTradeTransaction.ApplicableHeaderTradeAgreement
+	if agreement.BuyerReference != "" {
+		c.warn(pathAgreement+".BuyerReference", "BuyerReference has no ISDOC counterpart")
+	}
+
+	c.currency()
+	c.references()
+
+	inv.AccountingSupplierParty.Party = c.party(pathAgreement+".SellerTradeParty", &agreement.SellerTradeParty)
+	inv.AccountingCustomerParty = &schema.AccountingCustomerParty{
+		Party: c.party(pathAgreement+".BuyerTradeParty", &agreement.BuyerTradeParty),
+	}
+	c.delivery()
+	c.lines()
+	c.allowanceCharges()
+	c.taxes()
+	c.summation()
+	c.paymentMeans()
+
+	return inv, c.warnings
+}
+
+type toISDOC struct {
+	doc *Document
+	inv *schema.Invoice
+	// rate converts document amounts to the local currency; it is nil for
+	// documents in the local currency
+	rate     *big.Rat
+	negate   bool
+	warnings isdoc.ValidationErrors
+}
+
+func (c *toISDOC) warn(field, format string, args ...any) {
+	c.warnings = append(c.warnings, lossy(field, fmt.Sprintf(format, args...)))
+}
+
+func (c *toISDOC) settlement() *HeaderSettlement {
+	return &c.doc.SupplyChainTradeTransaction.ApplicableHeaderTradeSettlement
+}
+
+// vatApplicable reports whether any VAT category is subject to VAT.
+func (c *toISDOC) vatApplicable() bool {
+	for _, t := range c.settlement().ApplicableTradeTax {
+		if t.CategoryCode != en16931.CategoryOutOfScope {
+			return true
+		}
+	}
+	for _, l := range c.doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem {
+		if l.SpecifiedLineTradeSettlement.ApplicableTradeTax.CategoryCode != en16931.CategoryOutOfScope {
+			return true
+		}
+	}
+	return false
+}
+
+// currency sets the ISDOC currencies. A TaxCurrencyCode other than the
+// invoice currency makes the invoice currency foreign; the exchange rate is
+// derived from the two VAT totals.
+func (c *toISDOC) currency() {
+	s, inv := c.settlement(), c.inv
+	inv.LocalCurrencyCode = s.InvoiceCurrencyCode
+	inv.CurrRate = "1"
+	inv.RefCurrRate = "1"
+	if s.TaxCurrencyCode == "" || s.TaxCurrencyCode == s.InvoiceCurrencyCode {
+		return
+	}
+
+	amounts := s.SpecifiedTradeSettlementHeaderMonetarySummation.TaxTotalAmount
+	totals := make([]en16931.TaxTotal, len(amounts))
+	for i, a := range amounts {
+		totals[i] = en16931.TaxTotal{CurrencyID: a.CurrencyID, Amount: a.Value}
+	}
+	rate, ok := en16931.ExchangeRate(totals, s.InvoiceCurrencyCode, s.TaxCurrencyCode)
+	if !ok {
+		c.warn(pathSettlement+".TaxCurrencyCode", "the exchange rate to %s cannot be derived from the VAT totals", s.TaxCurrencyCode)
+		return
+	}
+	c.rate = rate
+	inv.LocalCurrencyCode = s.TaxCurrencyCode
+	inv.ForeignCurrencyCode = s.InvoiceCurrencyCode
+	inv.CurrRate = en16931.FormatRate(rate)
+	c.warn(pathSettlement+".TaxCurrencyCode", "local currency amounts are computed with the rate %s derived from the VAT totals", inv.CurrRate)
+}
+
+// amounts sets the local and foreign currency amounts of a document amount.
+func (c *toISDOC) amounts(v types.Decimal, local, curr *types.Decimal) {
+	if v == "" {
+		v = "0.00"
+	}
+	if c.negate {
+		v = en16931.Negate(v)
+	}
+	en16931.SetAmounts(v, c.rate, local, curr)
+}
+
+func (c *toISDOC) references() {
+	inv := c.inv
+	tx := &c.doc.SupplyChainTradeTransaction
+	agreement := &tx.ApplicableHeaderTradeAgreement
+
+	buyer, seller := agreement.BuyerOrderReferencedDocument, agreement.SellerOrderReferencedDocument
+	if buyer != nil || seller != nil {
+		order := schema.OrderReference{ID: "order-1"}
+		if buyer != nil {
+			order.ExternalOrderID = buyer.IssuerAssignedID
+		}
+		if seller != nil {
+			order.SalesOrderID = seller.IssuerAssignedID
+		}
+		inv.OrderReferences = &schema.OrderReferences{OrderReference: []schema.OrderReference{order}}
+	}
+
+	for _, ref := range c.settlement().InvoiceReferencedDocument {
+		if inv.OriginalDocumentReferences == nil {
+			inv.OriginalDocumentReferences = &schema.OriginalDocumentReferences{}
+		}
+		inv.OriginalDocumentReferences.OriginalDocumentReference = append(
+			inv.OriginalDocumentReferences.OriginalDocumentReference, schema.OriginalDocumentReference{
+				OriginalDocumentID: ref.IssuerAssignedID,
+				IssueDate:          ref.issueDate(),
+			})
+	}
+
+	if ref := tx.ApplicableHeaderTradeDelivery.DespatchAdviceReferencedDocument; ref != nil {
+		inv.DeliveryNoteReferences = &schema.DeliveryNoteReferences{
+			DeliveryNoteReference: []schema.DeliveryNoteReference{{
+				DeliveryNoteID: ref.IssuerAssignedID,
+				IssueDate:      ref.issueDate(),
+			}},
+		}
+	}
+
+	if ref := agreement.ContractReferencedDocument; ref != nil {
+		inv.ContractReferences = &schema.ContractReferences{
+			ContractReference: []schema.ContractReference{{
+				ContractID: ref.IssuerAssignedID,
+				IssueDate:  ref.issueDate(),
+			}},
+		}
+	}
+}
+
+func (r *ReferencedDocument) issueDate() types.Date {
+	if r.FormattedIssueDateTime == nil {
+		return types.Date{}
+	}
+	return r.FormattedIssueDateTime.Date
+}
+
+func (c *toISDOC) party(path string, p *TradeParty) schema.Party {
+	out := schema.Party{PartyName: schema.PartyName{Name: p.Name}}
+
+	for _, id := range p.GlobalID {
+		if id.SchemeID == en16931.SchemeGLN && out.PartyIdentification.CatalogFirmIdentification == "" {
+			out.PartyIdentification.CatalogFirmIdentification = id.Value
+		} else {
+			c.warn(path+".GlobalID", "the global identifier %s:%s has no ISDOC counterpart", id.SchemeID, id.Value)
+		}
+	}
+	for i, id := range p.ID {
+		if out.PartyIdentification.ID != "" {
+			c.warn(fmt.Sprintf("%s.ID[%d]", path, i), "only one party identifier is converted")
+			continue
+		}
+		out.PartyIdentification.ID = id.Value
+	}
+	if lo := p.SpecifiedLegalOrganization; lo != nil {
+		if lo.ID != nil {
+			switch out.PartyIdentification.ID {
+			case "":
+				out.PartyIdentification.ID = lo.ID.Value
+			case lo.ID.Value:
+			default:
+				c.warn(path+".SpecifiedLegalOrganization.ID", "the legal registration differs from the party identifier")
+			}
+		}
+		if lo.TradingBusinessName != "" && lo.TradingBusinessName != p.Name {
+			c.warn(path+".SpecifiedLegalOrganization.TradingBusinessName", "the trading name differs from the registration name")
+		}
+	}
+	if p.Description != "" {
+		out.RegisterIdentification = &schema.RegisterIdentification{Preformatted: p.Description}
+	}
+
+	if p.PostalTradeAddress != nil {
+		out.PostalAddress = c.postalAddress(path+".PostalTradeAddress", p.PostalTradeAddress)
+	}
+
+	for _, tr := range p.SpecifiedTaxRegistration {
+		scheme := "VAT"
+		if tr.ID.SchemeID == "FC" {
+			scheme = "TIN"
+		}
+		out.PartyTaxScheme = append(out.PartyTaxScheme, schema.PartyTaxScheme{
+			CompanyID: tr.ID.Value,
+			TaxScheme: scheme,
+		})
+	}
+
+	if ct := p.DefinedTradeContact; ct != nil {
+		out.Contact = &schema.Contact{Name: ct.PersonName}
+		if ct.TelephoneUniversalCommunication != nil {
+			out.Contact.Telephone = ct.TelephoneUniversalCommunication.CompleteNumber
+		}
+		if ct.EmailURIUniversalCommunication != nil {
+			out.Contact.ElectronicMail = ct.EmailURIUniversalCommunication.URIID.Value
+		}
+		if ct.DepartmentName != "" {
+			c.warn(path+".DefinedTradeContact.DepartmentName", "DepartmentName has no ISDOC counterpart")
+		}
+	}
+
+	if u := p.URIUniversalCommunication; u != nil && !derivedEndpoint(&u.URIID, &out) {
+		e := u.URIID
+		switch {
+		case e.SchemeID == en16931.SchemeGLN && out.PartyIdentification.CatalogFirmIdentification == "":
+			out.PartyIdentification.CatalogFirmIdentification = e.Value
+		case e.SchemeID == "EM" && (out.Contact == nil || out.Contact.ElectronicMail == ""):
+			if out.Contact == nil {
+				out.Contact = &schema.Contact{}
+			}
+			out.Contact.ElectronicMail = e.Value
+		default:
+			c.warn(path+".URIUniversalCommunication", "the electronic address %s:%s has no ISDOC counterpart", e.SchemeID, e.Value)
+		}
+	}
+	return out
+}
+
+// derivedEndpoint reports whether e is the electronic address FromISDOC
+// writes for the party.
+func derivedEndpoint(e *ID, p *schema.Party) bool {
+	if e.SchemeID == en16931.SchemeGLN {
+		return e.Value == p.PartyIdentification.CatalogFirmIdentification
+	}
+	for _, ts := range p.PartyTaxScheme {
+		if ts.CompanyID == e.Value && en16931.EndpointSchemes[en16931.Prefix(ts.CompanyID)] == e.SchemeID {
+			return true
+		}
+	}
+	return false
+}
+
+// postalAddress splits a trailing building number off the first address
+// line.
+func (c *toISDOC) postalAddress(path string, a *TradeAddress) schema.PostalAddress {
+	out := schema.PostalAddress{
+		StreetName: a.LineOne,
+		CityName:   a.CityName,
+		PostalZone: a.PostcodeCode,
+		Country:    schema.Country{IdentificationCode: a.CountryID},
+	}
+	if i := strings.LastIndexByte(a.LineOne, ' '); i > 0 && i+1 < len(a.LineOne) {
+		if b := a.LineOne[i+1]; b >= '0' && b <= '9' {
+			out.StreetName, out.BuildingNumber = a.LineOne[:i], a.LineOne[i+1:]
+		}
+	}
+	if a.LineTwo != "" || a.LineThree != "" {
+		c.warn(path, "only the first address line is converted")
+	}
+	return out
+}
+
+func (c *toISDOC) delivery() {
+	d := &c.doc.SupplyChainTradeTransaction.ApplicableHeaderTradeDelivery
+	if p := d.ShipToTradeParty; p != nil {
+		party := schema.Party{PartyName: schema.PartyName{Name: p.Name}}
+		if p.PostalTradeAddress != nil {
+			party.PostalAddress = c.postalAddress(pathDelivery+".ShipToTradeParty.PostalTradeAddress", p.PostalTradeAddress)
+		}
+		c.inv.Delivery = &schema.Delivery{Party: party}
+	}
+	if d.ActualDeliverySupplyChainEvent != nil {
+		c.warn(pathDelivery+".ActualDeliverySupplyChainEvent", "the delivery date has no ISDOC counterpart")
+	}
+}
+
+// category returns the ISDOC tax category of a CII tax category.
+func (c *toISDOC) category(path string, t *TradeTax) schema.ClassifiedTaxCategory {
+	out := schema.ClassifiedTaxCategory{Percent: t.RateApplicablePercent}
+	if out.Percent == "" {
+		out.Percent = "0"
+	}
+	switch t.CategoryCode {
+	case en16931.CategoryStandard, en16931.CategoryZero, en16931.CategoryExempt:
+	case en16931.CategoryReverseCharge:
+		out.LocalReverseCharge = &schema.LocalReverseCharge{}
+		c.warn(path, "the reverse charge code is not known")
+	case en16931.CategoryOutOfScope:
+		out.Percent = "0"
+	default:
+		c.warn(path, "VAT category %s is written as a %s%% rate", t.CategoryCode, out.Percent)
+	}
+	if c.inv.VATApplicable.Bool() && out.LocalReverseCharge == nil && t.CategoryCode != en16931.CategoryOutOfScope {
+		out.VATApplicable = true
+	}
+	return out
+}
+
+func (c *toISDOC) lines() {
+	for i := range c.doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem {
+		l := &c.doc.SupplyChainTradeTransaction.IncludedSupplyChainTradeLineItem[i]
+		path := fmt.Sprintf("%s.IncludedSupplyChainTradeLineItem[%d]", pathTransaction, i)
+		ld := &l.AssociatedDocumentLineDocument
+		agreement := &l.SpecifiedLineTradeAgreement
+		settlement := &l.SpecifiedLineTradeSettlement
+
+		line := schema.InvoiceLine{ID: ld.LineID}
+		if len(ld.IncludedNote) > 0 {
+			texts := make([]string, len(ld.IncludedNote))
+			for i, n := range ld.IncludedNote {
+				texts[i] = n.Content
+			}
+			line.Note = strings.Join(texts, "\n")
+		}
+
+		q := l.SpecifiedLineTradeDelivery.BilledQuantity
+		line.InvoicedQuantity = schema.Quantity{Value: q.Value, UnitCode: q.UnitCode}
+		if c.negate {
+			line.InvoicedQuantity.Value = en16931.Negate(q.Value)
+		}
+		c.amounts(settlement.SpecifiedTradeSettlementLineMonetarySummation.LineTotalAmount,
+			&line.LineExtensionAmount, &line.LineExtensionAmountCurr)
+
+		net := &agreement.NetPriceProductTradePrice
+		price := en16931.ParseRat(net.ChargeAmount)
+		if base := net.BasisQuantity; base != nil {
+			if b := en16931.ParseRat(base.Value); b.Sign() != 0 {
+				price.Quo(price, b)
+			}
+		}
+		if c.rate != nil {
+			price.Mul(price, c.rate)
+		}
+		line.UnitPrice = en16931.FormatPrice(price)
+
+		line.ClassifiedTaxCategory = c.category(path+".SpecifiedLineTradeSettlement.ApplicableTradeTax", &settlement.ApplicableTradeTax)
+		en16931.ComputeLine(&line, c.rate != nil)
+
+		product := &l.SpecifiedTradeProduct
+		line.Item.Description = product.Name
+		if product.Description != "" && product.Description != product.Name {
+			c.warn(path+".SpecifiedTradeProduct.Description", "only the product name is converted")
+		}
+		if product.SellerAssignedID != "" {
+			line.Item.SellersItemIdentification = &schema.ItemIdentification{ID: product.SellerAssignedID}
+		}
+		if product.BuyerAssignedID != "" {
+			line.Item.BuyersItemIdentification = &schema.ItemIdentification{ID: product.BuyerAssignedID}
+		}
+		if product.GlobalID != nil {
+			line.Item.CatalogueItemIdentification = &schema.ItemIdentification{ID: product.GlobalID.Value}
+		}
+
+		if ref := agreement.BuyerOrderReferencedDocument; ref != nil && ref.LineID != "" {
+			line.OrderReference = &schema.OrderLineReference{LineID: ref.LineID}
+			if c.inv.OrderReferences != nil {
+				line.OrderReference.Ref = c.inv.OrderReferences.OrderReference[0].ID
+			}
+		}
+		if agreement.GrossPriceProductTradePrice != nil {
+			c.warn(path+".SpecifiedLineTradeAgreement.GrossPriceProductTradePrice", "only the net price is converted")
+		}
+		if len(settlement.SpecifiedTradeAllowanceCharge) > 0 {
+			c.warn(path+".SpecifiedLineTradeSettlement.SpecifiedTradeAllowanceCharge",
+				"line allowances and charges are only kept in the line amount")
+		}
+
+		c.inv.InvoiceLines.InvoiceLine = append(c.inv.InvoiceLines.InvoiceLine, line)
+	}
+}
+
+// allowanceCharges adds document level allowances and charges as lines.
+func (c *toISDOC) allowanceCharges() {
+	for i, ac := range c.settlement().SpecifiedTradeAllowanceCharge {
+		path := fmt.Sprintf("%s.SpecifiedTradeAllowanceCharge[%d]", pathSettlement, i)
+
+		amount := ac.ActualAmount
+		if !ac.ChargeIndicator.Indicator {
+			amount = en16931.Negate(amount)
+		}
+		line := schema.InvoiceLine{
+			ID:               fmt.Sprintf("AC%d", i+1),
+			InvoicedQuantity: schema.Quantity{Value: "1"},
+		}
+		c.amounts(amount, &line.LineExtensionAmount, &line.LineExtensionAmountCurr)
+		line.UnitPrice = line.LineExtensionAmount
+
+		line.Item.Description = en16931.JoinNonEmpty(" ", ac.ReasonCode, ac.Reason)
+		if line.Item.Description == "" {
+			line.Item.Description = "Allowance"
+			if ac.ChargeIndicator.Indicator {
+				line.Item.Description = "Charge"
+			}
+		}
+		if ac.CategoryTradeTax != nil {
+			line.ClassifiedTaxCategory = c.category(path+".CategoryTradeTax", ac.CategoryTradeTax)
+		}
+		en16931.ComputeLine(&line, c.rate != nil)
+
+		c.inv.InvoiceLines.InvoiceLine = append(c.inv.InvoiceLines.InvoiceLine, line)
+		c.warn(path, "the allowance or charge is written as line %s", line.ID)
+	}
+}
+
+func (c *toISDOC) taxes() {
+	s := c.settlement()
+	vat := c.inv.VATApplicable
+	tt := &c.inv.TaxTotal
+
+	for _, t := range s.SpecifiedTradeSettlementHeaderMonetarySummation.TaxTotalAmount {
+		if t.CurrencyID == "" || t.CurrencyID == s.InvoiceCurrencyCode {
+			c.amounts(t.Value, &tt.TaxAmount, &tt.TaxAmountCurr)
+			break
+		}
+	}
+
+	for i, t := range s.ApplicableTradeTax {
+		var out schema.TaxSubTotal
+		c.amounts(t.BasisAmount, &out.TaxableAmount, &out.TaxableAmountCurr)
+		c.amounts(t.CalculatedAmount, &out.TaxAmount, &out.TaxAmountCurr)
+		out.TaxInclusiveAmount = en16931.Sum(out.TaxableAmount, out.TaxAmount)
+		out.AlreadyClaimedTaxableAmount = "0.00"
+		out.AlreadyClaimedTaxAmount = "0.00"
+		out.AlreadyClaimedTaxInclusiveAmount = "0.00"
+		out.DifferenceTaxableAmount = out.TaxableAmount
+		out.DifferenceTaxAmount = out.TaxAmount
+		out.DifferenceTaxInclusiveAmount = out.TaxInclusiveAmount
+		if c.rate != nil {
+			out.TaxInclusiveAmountCurr = en16931.Sum(out.TaxableAmountCurr, out.TaxAmountCurr)
+			out.AlreadyClaimedTaxableAmountCurr = "0.00"
+			out.AlreadyClaimedTaxAmountCurr = "0.00"
+			out.AlreadyClaimedTaxInclusiveAmountCurr = "0.00"
+			out.DifferenceTaxableAmountCurr = out.TaxableAmountCurr
+			out.DifferenceTaxAmountCurr = out.TaxAmountCurr
+			out.DifferenceTaxInclusiveAmountCurr = out.TaxInclusiveAmountCurr
+		}
+		out.TaxCategory = schema.TaxCategory{
+			Percent:                t.RateApplicablePercent,
+			VATApplicable:          vat,
+			LocalReverseChargeFlag: t.CategoryCode == en16931.CategoryReverseCharge,
+		}
+		if out.TaxCategory.Percent == "" {
+			out.TaxCategory.Percent = "0"
+		}
+		tt.TaxSubTotal = append(tt.TaxSubTotal, out)
+
+		if t.TaxPointDate != nil {
+			switch {
+			case c.inv.TaxPointDate.IsZero():
+				c.inv.TaxPointDate = t.TaxPointDate.Date
+			case t.TaxPointDate.Date != c.inv.TaxPointDate:
+				c.warn(fmt.Sprintf("%s.ApplicableTradeTax[%d].TaxPointDate", pathSettlement, i), "only the first tax point date is converted")
+			}
+		}
+	}
+}
+
+func (c *toISDOC) summation() {
+	src := &c.settlement().SpecifiedTradeSettlementHeaderMonetarySummation
+	lmt := &c.inv.LegalMonetaryTotal
+
+	c.amounts(src.TaxBasisTotalAmount, &lmt.TaxExclusiveAmount, &lmt.TaxExclusiveAmountCurr)
+	c.amounts(src.GrandTotalAmount, &lmt.TaxInclusiveAmount, &lmt.TaxInclusiveAmountCurr)
+	lmt.AlreadyClaimedTaxExclusiveAmount = "0.00"
+	lmt.AlreadyClaimedTaxInclusiveAmount = "0.00"
+	lmt.DifferenceTaxExclusiveAmount = lmt.TaxExclusiveAmount
+	lmt.DifferenceTaxInclusiveAmount = lmt.TaxInclusiveAmount
+	if c.rate != nil {
+		lmt.AlreadyClaimedTaxExclusiveAmountCurr = "0.00"
+		lmt.AlreadyClaimedTaxInclusiveAmountCurr = "0.00"
+		lmt.DifferenceTaxExclusiveAmountCurr = lmt.TaxExclusiveAmountCurr
+		lmt.DifferenceTaxInclusiveAmountCurr = lmt.TaxInclusiveAmountCurr
+	}
+	if !src.TotalPrepaidAmount.IsZero() {
+		c.amounts(src.TotalPrepaidAmount, &lmt.PaidDepositsAmount, &lmt.PaidDepositsAmountCurr)
+	}
+	if !src.RoundingAmount.IsZero() {
+		c.amounts(src.RoundingAmount, &lmt.PayableRoundingAmount, &lmt.PayableRoundingAmountCurr)
+	}
+	c.amounts(src.DuePayableAmount, &lmt.PayableAmount, &lmt.PayableAmountCurr)
+}
+
+func (c *toISDOC) paymentMeans() {
+	s := c.settlement()
+	var dueDate types.Date
+	for i, t := range s.SpecifiedTradePaymentTerms {
+		if t.DueDateDateTime != nil && dueDate.IsZero() {
+			dueDate = t.DueDateDateTime.Date
+		}
+		if t.Description != "" {
+			c.warn(fmt.Sprintf("%s.SpecifiedTradePaymentTerms[%d].Description", pathSettlement, i),
+				"payment terms have no ISDOC counterpart")
+		}
+	}
+
+	if len(s.SpecifiedTradeSettlementPaymentMeans) == 0 {
+		if !dueDate.IsZero() {
+			c.warn(pathSettlement+".SpecifiedTradePaymentTerms", "a due date without payment means has no ISDOC counterpart")
+		}
+		return
+	}
+
+	first := s.SpecifiedTradeSettlementPaymentMeans[0]
+	code, ok := en16931.PaymentMeansCode(first.TypeCode)
+	if !ok {
+		c.warn(pathSettlement+".SpecifiedTradeSettlementPaymentMeans[0].TypeCode", "UNCL4461 code %s is written as %d", first.TypeCode, code)
+	}
+	details := &schema.PaymentDetails{
+		PaymentDueDate: dueDate,
+		VariableSymbol: s.PaymentReference,
+	}
+	details.BankAccount = bankAccount(&first)
+	payment := schema.Payment{
+		PaidAmount:       c.inv.LegalMonetaryTotal.PayableAmount,
+		PaymentMeansCode: code,
+		Details:          details,
+	}
+	c.inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{payment}}
+
+	// Further means of payment are alternative bank accounts
+	for i, m := range s.SpecifiedTradeSettlementPaymentMeans[1:] {
+		a := bankAccount(&m)
+		if a == nil {
+			c.warn(fmt.Sprintf("%s.SpecifiedTradeSettlementPaymentMeans[%d]", pathSettlement, i+1),
+				"only the bank accounts of further payment means are converted")
+			continue
+		}
+		pm := c.inv.PaymentMeans
+		if pm.AlternateBankAccounts == nil {
+			pm.AlternateBankAccounts = &schema.AlternateBankAccounts{}
+		}
+		pm.AlternateBankAccounts.AlternateBankAccount = append(pm.AlternateBankAccounts.AlternateBankAccount, *a)
+	}
+}
+
+// bankAccount returns the payee account of m, splitting a Czech IBAN or an
+// "account/bank code" number, or nil if m has none.
+func bankAccount(m *PaymentMeans) *schema.BankAccount {
+	fa := m.PayeePartyCreditorFinancialAccount
+	if fa == nil {
+		return nil
+	}
+	a := &schema.BankAccount{ID: fa.ProprietaryID, Name: fa.AccountName}
+	if m.PayeeSpecifiedCreditorFinancialInstitution != nil {
+		a.BIC = m.PayeeSpecifiedCreditorFinancialInstitution.BICID
+	}
+	if iban := strings.ReplaceAll(fa.IBANID, " ", ""); en16931.IsIBAN(iban) {
+		a.IBAN = iban
+		if a.ID == "" {
+			a.ID = iban
+			if id, code, ok := en16931.CzechAccount(iban); ok {
+				a.ID, a.BankCode = id, code
+			}
+		}
+	} else if a.ID == "" {
+		a.ID = fa.IBANID
+	}
+	if id, code, ok := strings.Cut(a.ID, "/"); ok && a.BankCode == "" {
+		a.ID, a.BankCode = id, code
+	}
+	return a
+}
@@ -49,22 +49,76 @@ type Group struct {
 	Sequence Sequence `xml:"sequence"`
 }
 
+// Sequence and Choice keep their particles in document order, so nested
+// sequences and choices are emitted where they appear in the schema.
 type Sequence struct {
-	Elements  []Element  `xml:"element"`
-	Sequences []Sequence `xml:"sequence"`
-	Choices   []Choice   `xml:"choice"`
-	Groups    []GroupRef `xml:"group"`
+	Particles []Particle
 }
 
 type Choice struct {
-	Elements  []Element  `xml:"element"`
-	Sequences []Sequence `xml:"sequence"`
+	Particles []Particle
+}
+
+// Particle is a single child of a sequence or choice.
+type Particle struct {
+	Element  *Element
+	Sequence *Sequence
+	Choice   *Choice
 }
 
 type All struct {
 	Elements []Element `xml:"element"`
 }
 
+func (s *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	particles, err := decodeParticles(d)
+	s.Particles = particles
+	return err
+}
+
+func (c *Choice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	particles, err := decodeParticles(d)
+	c.Particles = particles
+	return err
+}
+
+// decodeParticles reads the children of a sequence or choice in document order.
+func decodeParticles(d *xml.Decoder) ([]Particle, error) {
+	var particles []Particle
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var p Particle
+			switch t.Name.Local {
+			case "element":
+				p.Element = &Element{}
+				err = d.DecodeElement(p.Element, &t)
+			case "sequence":
+				p.Sequence = &Sequence{}
+				err = d.DecodeElement(p.Sequence, &t)
+			case "choice":
+				p.Choice = &Choice{}
+				err = d.DecodeElement(p.Choice, &t)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return nil, err
+			}
+			if p.Element != nil || p.Sequence != nil || p.Choice != nil {
+				particles = append(particles, p)
+			}
+		case xml.EndElement:
+			return particles, nil
+		}
+	}
+}
+
 type Element struct {
 	Name        string      `xml:"name,attr"`
 	Type        string      `xml:"type,attr"`
@@ -73,10 +127,6 @@ type Element struct {
 	ComplexType ComplexType `xml:"complexType"`
 }
 
-type GroupRef struct {
-	Ref string `xml:"ref,attr"`
-}
-
 func main() {
 	outputPath := flag.String("out", "", "Output Go file path")
 	flag.Parse()
@@ -134,7 +184,7 @@ func main() {
 		if g.Name == "" {
 			continue
 		}
-		elements := extractSequenceElements(g.Sequence)
+		elements := dedupeKeepLast(extractSequenceElements(g.Sequence))
 		if len(elements) > 0 {
 			sequences[g.Name] = elements
 		}
@@ -213,45 +263,51 @@ func extractElements(seq Sequence, choice Choice, all All) []string {
 		}
 	}
 
-	return elements
+	return dedupeKeepLast(elements)
 }
 
 func extractSequenceElements(seq Sequence) []string {
-	var elements []string
+	return extractParticles(seq.Particles)
+}
 
-	for _, e := range seq.Elements {
-		if e.Name != "" {
-			elements = append(elements, e.Name)
-		}
-	}
+func extractChoiceElements(choice Choice) []string {
+	return extractParticles(choice.Particles)
+}
 
-	// Nested sequences
-	for _, nested := range seq.Sequences {
-		elements = append(elements, extractSequenceElements(nested)...)
-	}
+func extractParticles(particles []Particle) []string {
+	var elements []string
 
-	// Choices within sequence
-	for _, c := range seq.Choices {
-		elements = append(elements, extractChoiceElements(c)...)
+	for _, p := range particles {
+		switch {
+		case p.Element != nil && p.Element.Name != "":
+			elements = append(elements, p.Element.Name)
+		case p.Sequence != nil:
+			elements = append(elements, extractSequenceElements(*p.Sequence)...)
+		case p.Choice != nil:
+			elements = append(elements, extractChoiceElements(*p.Choice)...)
+		}
 	}
 
 	return elements
 }
 
-func extractChoiceElements(choice Choice) []string {
-	var elements []string
-
-	for _, e := range choice.Elements {
-		if e.Name != "" {
-			elements = append(elements, e.Name)
-		}
+// dedupeKeepLast removes repeated element names, keeping the last occurrence.
+// An element can appear in several branches of a choice, e.g.
+// (AccountingCustomerParty | (AnonymousCustomerParty, AccountingCustomerParty?));
+// its last position is the one valid for every branch.
+func dedupeKeepLast(elements []string) []string {
+	last := make(map[string]int, len(elements))
+	for i, e := range elements {
+		last[e] = i
 	}
 
-	for _, seq := range choice.Sequences {
-		elements = append(elements, extractSequenceElements(seq)...)
+	result := make([]string, 0, len(last))
+	for i, e := range elements {
+		if last[e] == i {
+			result = append(result, e)
+		}
 	}
-
-	return elements
+	return result
 }
 
 func generateGoCode(sequences map[string][]string) string {
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/cii"
+	"github.com/xseman/isdoc/pdf"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/ubl"
+)
+
+// Document formats handled by convert.
+const (
+	formatXML  = "xml"
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatUBL  = "ubl"
+	formatCII  = "cii"
+)
+
+func cmdConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	indent := fs.Bool("indent", true, "Indent JSON output")
+	to := fs.String("to", "", "Output format: xml, json, yaml, ubl or cii")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc convert [options] <input> [output]
+
+Convert between ISDOC XML, its JSON representation, YAML, UBL 2.1 and
+UN/CEFACT CII. Formats are taken from the file extensions (.isdoc/.xml,
+.json, .yaml/.yml) or, for stdin, from the content. XML is converted to
+JSON and JSON, YAML, UBL or CII to XML unless -to or the output file says
+otherwise.
+
+UBL Invoice and CreditNote documents are recognized by their namespace and
+are written with "-to ubl" following EN 16931 and Peppol BIS Billing 3.0;
+the result is checked against their business rules and broken rules are
+reported with their identifiers, e.g. [BR-CO-15] or [PEPPOL-EN16931-R003].
+CII CrossIndustryInvoice documents are recognized the same way and are
+written with "-to cii"; PDF inputs are read from their embedded ISDOC or
+Factur-X/ZUGFeRD XML. Fields without a counterpart in the other format are
+reported as warnings.
+
+Invoices read from YAML are completed before conversion: a UUID is
+generated, the version defaults to 6.0.2 and line amounts, tax
+recapitulation and totals are computed. Use "isdoc new -template" for a
+commented starting point and "isdoc schema" for the JSON Schema.
+
+Examples:
+  isdoc convert invoice.isdoc invoice.json
+  isdoc convert invoice.json invoice.isdoc
+  isdoc convert invoice.yaml invoice.isdoc
+  isdoc convert -to yaml invoice.isdoc
+  isdoc convert -to ubl invoice.isdoc peppol.xml
+  isdoc convert peppol.xml invoice.isdoc
+  isdoc convert -to cii invoice.isdoc factur-x.xml
+  isdoc convert zugferd.pdf invoice.isdoc
+
+Options:
+  -indent     Indent JSON output (default: true)
+  -to string  Output format: xml, json, yaml, ubl or cii`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(stderr, "error: missing input file")
+		fs.Usage()
+		return exitError
+	}
+
+	inputPath := fs.Arg(0)
+	outputPath := fs.Arg(1)
+
+	var data []byte
+	var err error
+	var inFormat string
+	switch {
+	case inputPath == "-":
+		data, err = io.ReadAll(stdin)
+	case strings.EqualFold(filepath.Ext(inputPath), ".pdf"):
+		var result *pdf.ReadResult
+		if result, err = pdf.NewReader().ReadFile(inputPath); err == nil {
+			data = result.XML
+			inFormat = detectFormat("-", data)
+		}
+	default:
+		data, err = os.ReadFile(inputPath)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: reading input: %v\n", err)
+		return exitError
+	}
+
+	if inFormat == "" {
+		inFormat = detectFormat(inputPath, data)
+	}
+	outFormat := *to
+	if outFormat == "" {
+		outFormat = formatFromExt(outputPath)
+	}
+	if outFormat == "" {
+		outFormat = formatXML
+		if inFormat == formatXML {
+			outFormat = formatJSON
+		}
+	}
+	switch outFormat {
+	case formatXML, formatJSON, formatYAML, formatUBL, formatCII:
+	default:
+		fmt.Fprintf(stderr, "error: unknown output format %q\n", outFormat)
+		return exitError
+	}
+
+	var doc any
+	switch inFormat {
+	case formatUBL, formatCII:
+		unmarshal := ubl.Unmarshal
+		if inFormat == formatCII {
+			unmarshal = cii.Unmarshal
+		}
+		inv, warnings, err := unmarshal(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		printWarnings(stderr, warnings)
+		doc = inv
+	default:
+		if doc, err = decodeDocument(inFormat, data); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+
+	var out []byte
+	if outFormat == formatUBL || outFormat == formatCII {
+		inv, ok := doc.(*schema.Invoice)
+		if !ok {
+			fmt.Fprintf(stderr, "error: only invoices can be converted to %s\n", strings.ToUpper(outFormat))
+			return exitError
+		}
+		var warnings isdoc.ValidationErrors
+		if outFormat == formatCII {
+			out, warnings, err = cii.Marshal(inv)
+		} else {
+			d, w := ubl.FromISDOC(inv)
+			warnings = append(w, ubl.Validate(d)...)
+			out, err = ubl.Encode(d)
+		}
+		printWarnings(stderr, warnings)
+	} else {
+		out, err = encodeDocument(outFormat, doc, *indent)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		stdout.Write(out)
+		if !bytes.HasSuffix(out, []byte("\n")) {
+			fmt.Fprintln(stdout)
+		}
+	} else {
+		if err := os.WriteFile(outputPath, out, 0644); err != nil {
+			fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
+			return exitError
+		}
+		baseName := filepath.Base(inputPath)
+		if inputPath == "-" {
+			baseName = "stdin"
+		}
+		fmt.Fprintf(stderr, "converted %s to %s\n", baseName, outFormat)
+	}
+
+	return exitSuccess
+}
+
+// formatFromExt returns the format of a file name, or "" if unknown.
+func formatFromExt(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".isdoc", ".xml":
+		return formatXML
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	return ""
+}
+
+// detectFormat returns the format of an input by file extension or, for
+// stdin and unknown extensions, by its first non-space byte. XML inputs are
+// told apart from UBL and CII by their root element.
+func detectFormat(path string, data []byte) string {
+	if f := formatFromExt(path); f != "" && path != "-" {
+		if f == formatXML {
+			return detectXML(data)
+		}
+		return f
+	}
+	trimmed := bytes.TrimLeft(data, " \t\r\n\ufeff")
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return detectXML(data)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return formatJSON
+	}
+	return formatYAML
+}
+
+// detectXML returns formatUBL or formatCII for UBL and CII documents and
+// formatXML otherwise.
+func detectXML(data []byte) string {
+	switch {
+	case ubl.IsUBL(data):
+		return formatUBL
+	case cii.IsCII(data):
+		return formatCII
+	}
+	return formatXML
+}
+
+// decodeDocument decodes a *schema.Invoice or *schema.CommonDocument.
+// Invoices read from YAML are completed with isdoc.FillDefaults.
+func decodeDocument(format string, data []byte) (any, error) {
+	switch format {
+	case formatYAML:
+		jsonData, err := yamlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing YAML: %w", err)
+		}
+		doc, err := decodeJSONDocument(jsonData)
+		if err != nil {
+			return nil, err
+		}
+		if inv, ok := doc.(*schema.Invoice); ok {
+			if err := isdoc.FillDefaults(inv); err != nil {
+				return nil, fmt.Errorf("computing totals: %w", err)
+			}
+		}
+		return doc, nil
+
+	case formatJSON:
+		return decodeJSONDocument(data)
+
+	case formatUBL:
+		inv, _, err := ubl.Unmarshal(data)
+		return inv, err
+
+	case formatCII:
+		inv, _, err := cii.Unmarshal(data)
+		return inv, err
+	}
+
+	root, err := isdoc.RootElement(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ISDOC: %w", err)
+	}
+	var doc any
+	if root == "CommonDocument" {
+		doc, err = isdoc.DecodeCommonDocumentBytes(data)
+	} else {
+		doc, err = isdoc.DecodeBytes(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing ISDOC: %w", err)
+	}
+	return doc, nil
+}
+
+func decodeJSONDocument(data []byte) (any, error) {
+	root, err := isdoc.JSONRoot(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+	var doc any
+	if root == "commonDocument" {
+		doc, err = isdoc.DecodeCommonDocumentJSON(data)
+	} else {
+		doc, err = isdoc.DecodeJSON(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+	return doc, nil
+}
+
+// encodeDocument encodes doc, a *schema.Invoice or *schema.CommonDocument.
+func encodeDocument(format string, doc any, indent bool) ([]byte, error) {
+	if format == formatXML {
+		switch d := doc.(type) {
+		case *schema.CommonDocument:
+			return isdoc.EncodeCommonDocumentBytes(d)
+		default:
+			return isdoc.EncodeBytes(d.(*schema.Invoice))
+		}
+	}
+
+	var out []byte
+	var err error
+	switch d := doc.(type) {
+	case *schema.CommonDocument:
+		out, err = isdoc.EncodeCommonDocumentJSON(d)
+	default:
+		out, err = isdoc.EncodeJSON(d.(*schema.Invoice))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("converting to JSON: %w", err)
+	}
+
+	if format == formatYAML {
+		return jsonToYAML(out)
+	}
+	if !indent {
+		return out, nil
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, out, "", "  "); err != nil {
+		return nil, fmt.Errorf("converting to JSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// printWarnings writes conversion warnings and broken business rules to w,
+// one per line.
+func printWarnings(w io.Writer, warnings isdoc.ValidationErrors) {
+	for _, warning := range warnings {
+		if warning.Rule != "" {
+			fmt.Fprintf(w, "%s [%s]\n", warning, warning.Rule)
+			continue
+		}
+		fmt.Fprintln(w, warning)
+	}
+}
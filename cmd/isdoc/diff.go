@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/xseman/isdoc"
+)
+
+func cmdDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "Output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc diff [options] <old> <new>
+
+Show the fields that differ between two invoices, e.g. an invoice and its
+corrected version. Inputs may be in any format handled by convert; "-" reads
+standard input. Invoice lines are matched by ID and indexed as in the new
+invoice; removed lines are selected by ID, as in InvoiceLine[?ID=='3'].
+Exits with status 1 when the invoices differ.
+
+Options:
+  -format string  Output format: text or json (default "text")
+
+Text output marks added fields with +, removed with - and modified with ~.`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(stderr, "error: expected two input files")
+		fs.Usage()
+		return exitError
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "error: unknown format %q\n", *format)
+		return exitError
+	}
+
+	a, err := readInvoice(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	b, err := readInvoice(fs.Arg(1), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	changes := isdoc.Diff(a, b)
+	if *format == "json" {
+		if changes == nil {
+			changes = []isdoc.Change{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	} else {
+		for _, c := range changes {
+			fmt.Fprintln(stdout, c)
+		}
+	}
+
+	if len(changes) > 0 {
+		return exitError
+	}
+	return exitSuccess
+}
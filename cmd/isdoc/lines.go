@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/lines"
+	"github.com/xseman/isdoc/schema"
+)
+
+func cmdLines(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printLinesUsage(stderr)
+		return exitError
+	}
+
+	switch args[0] {
+	case "export":
+		return cmdLinesExport(args[1:], stdin, stdout, stderr)
+	case "import":
+		return cmdLinesImport(args[1:], stdin, stdout, stderr)
+	case "-h", "--help", "help":
+		printLinesUsage(stdout)
+		return exitSuccess
+	default:
+		fmt.Fprintf(stderr, "unknown lines command: %s\n\n", args[0])
+		printLinesUsage(stderr)
+		return exitError
+	}
+}
+
+func printLinesUsage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: isdoc lines <command> [arguments]
+
+Commands:
+  export  Write the invoice lines of a document as CSV
+  import  Replace the invoice lines of a document with lines from CSV
+
+CSV columns: `+strings.Join(lines.Columns, ", "))
+}
+
+// linesFlags registers the options shared by export and import.
+func linesFlags(fs *flag.FlagSet) (delimiter, mapping *string) {
+	delimiter = fs.String("d", ",", "Field delimiter, e.g. \";\" or \"tab\"")
+	mapping = fs.String("map", "", "Column mapping, e.g. \"description=Název,quantity=Množství\"")
+	return delimiter, mapping
+}
+
+func linesOptions(delimiter, mapping string) (lines.Options, error) {
+	var opts lines.Options
+
+	if delimiter == "tab" || delimiter == `\t` {
+		delimiter = "\t"
+	}
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return opts, fmt.Errorf("delimiter must be a single character: %q", delimiter)
+	}
+	opts.Comma, _ = utf8.DecodeRuneInString(delimiter)
+
+	if mapping != "" {
+		opts.Mapping = make(map[string]string)
+		for _, pair := range strings.Split(mapping, ",") {
+			column, header, ok := strings.Cut(pair, "=")
+			if !ok {
+				return opts, fmt.Errorf("invalid mapping %q: want column=header", pair)
+			}
+			opts.Mapping[strings.TrimSpace(column)] = strings.TrimSpace(header)
+		}
+	}
+	return opts, nil
+}
+
+// readInvoice reads an invoice in any format handled by convert.
+func readInvoice(path string, stdin io.Reader) (*schema.Invoice, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading input: %w", err)
+	}
+
+	doc, err := decodeDocument(detectFormat(path, data), data)
+	if err != nil {
+		return nil, err
+	}
+	inv, ok := doc.(*schema.Invoice)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an invoice", path)
+	}
+	return inv, nil
+}
+
+func cmdLinesExport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lines export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	delimiter, mapping := linesFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc lines export [options] <invoice> [lines.csv]
+
+Write the invoice lines as CSV with one row per line.
+
+Options:
+  -d string    Field delimiter, e.g. ";" or "tab" (default ",")
+  -map string  Column mapping, e.g. "description=Název,quantity=Množství"`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(stderr, "error: missing input file")
+		fs.Usage()
+		return exitError
+	}
+
+	opts, err := linesOptions(*delimiter, *mapping)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	inv, err := readInvoice(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	var buf bytes.Buffer
+	if err := lines.WriteCSV(&buf, inv.InvoiceLines.InvoiceLine, opts); err != nil {
+		fmt.Fprintf(stderr, "error: writing CSV: %v\n", err)
+		return exitError
+	}
+
+	outputPath := fs.Arg(1)
+	if outputPath == "" || outputPath == "-" {
+		stdout.Write(buf.Bytes())
+		return exitSuccess
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "exported %d lines to %s\n", len(inv.InvoiceLines.InvoiceLine), outputPath)
+	return exitSuccess
+}
+
+func cmdLinesImport(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lines import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	delimiter, mapping := linesFlags(fs)
+	appendLines := fs.Bool("append", false, "Append to the existing lines instead of replacing them")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc lines import [options] <invoice> <lines.csv> [output]
+
+Build invoice lines from CSV and write the invoice with recomputed line
+amounts, tax recapitulation and totals. The invoice supplies everything
+but the lines and may be ISDOC XML, JSON or YAML. Lines without an id are
+numbered and a single payment of the whole amount is updated. The result
+is validated and only written if it is valid.
+
+Options:
+  -append      Append to the existing lines instead of replacing them
+  -d string    Field delimiter, e.g. ";" or "tab" (default ",")
+  -map string  Column mapping, e.g. "description=Název,quantity=Množství"`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintln(stderr, "error: missing input files")
+		fs.Usage()
+		return exitError
+	}
+
+	opts, err := linesOptions(*delimiter, *mapping)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	inv, err := readInvoice(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	var csvData io.Reader
+	if fs.Arg(1) == "-" {
+		csvData = stdin
+	} else {
+		f, err := os.Open(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(stderr, "error: reading CSV: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		csvData = f
+	}
+
+	imported, err := lines.ReadCSV(csvData, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s: %v\n", fs.Arg(1), err)
+		return exitError
+	}
+
+	if err := importLines(inv, imported, *appendLines); err != nil {
+		fmt.Fprintf(stderr, "error: computing totals: %v\n", err)
+		return exitError
+	}
+
+	if errs := isdoc.ValidateInvoice(inv); errs.HasErrors() {
+		for _, e := range errs.Errors() {
+			fmt.Fprintln(stderr, e.Error())
+		}
+		fmt.Fprintln(stderr, "error: invoice with imported lines is not valid")
+		return exitError
+	}
+
+	outputPath := fs.Arg(2)
+	format := formatFromExt(outputPath)
+	if format == "" {
+		format = formatXML
+	}
+	out, err := encodeDocument(format, inv, true)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		stdout.Write(out)
+		return exitSuccess
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "imported %d lines into %s\n", len(imported), outputPath)
+	return exitSuccess
+}
+
+// importLines replaces the lines of inv with imported, or appends them, and
+// recomputes the totals. The foreign currency amounts of a foreign currency
+// invoice are derived from the local ones at the invoice rate.
+func importLines(inv *schema.Invoice, imported []schema.InvoiceLine, appendLines bool) error {
+	if appendLines {
+		inv.InvoiceLines.InvoiceLine = append(inv.InvoiceLines.InvoiceLine, imported...)
+	} else {
+		inv.InvoiceLines.InvoiceLine = imported
+	}
+
+	// A payment of the whole old amount pays the new amount instead
+	if pm := inv.PaymentMeans; pm != nil && len(pm.Payment) == 1 &&
+		pm.Payment[0].PaidAmount.Equal(inv.LegalMonetaryTotal.PayableAmount) {
+		pm.Payment[0].PaidAmount = ""
+	}
+	if err := isdoc.FillDefaults(inv); err != nil {
+		return err
+	}
+	if inv.ForeignCurrencyCode != "" {
+		return isdoc.FillForeignAmounts(inv, nil)
+	}
+	return nil
+}
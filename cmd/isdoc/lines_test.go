@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestImportLinesForeignCurrency(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	inv, err := isdoc.DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+
+	// Double the price of the first line, dropping its computed amounts
+	imported := inv.InvoiceLines.InvoiceLine
+	imported[0].UnitPrice = "2.1586"
+	imported[0].UnitPriceTaxInclusive = ""
+	imported[0].LineExtensionAmount = ""
+	imported[0].LineExtensionTaxAmount = ""
+	imported[0].LineExtensionAmountTaxInclusive = ""
+	if err := importLines(inv, imported, false); err != nil {
+		t.Fatalf("importLines failed: %v", err)
+	}
+
+	// 215.86 CZK at 25.10 CZK/EUR
+	if got := inv.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr; got != types.Decimal("8.60") {
+		t.Errorf("LineExtensionAmountCurr = %s, want 8.60", got)
+	}
+	for _, e := range isdoc.ValidateInvoice(inv) {
+		if e.Code == isdoc.ErrCodeTotalMismatch {
+			t.Errorf("unexpected %v", e)
+		}
+	}
+}
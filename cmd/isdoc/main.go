@@ -5,17 +5,26 @@
 //	isdoc extract input.pdf [output.isdoc]  - Extract ISDOC XML from PDF
 //	isdoc embed input.pdf invoice.isdoc [output.pdf] - Embed ISDOC into PDF
 //	isdoc validate input.isdoc              - Validate ISDOC XML
+//	isdoc validate -r dir/                  - Validate all documents in a directory
 //	isdoc convert input.isdoc output.json   - Convert ISDOC to JSON
+//	isdoc convert input.json output.isdoc   - Convert JSON to ISDOC
+//	isdoc convert input.yaml output.isdoc   - Convert YAML to ISDOC
+//	isdoc convert -to ubl in.isdoc out.xml  - Convert ISDOC to UBL 2.1
+//	isdoc convert -to cii in.isdoc out.xml  - Convert ISDOC to UN/CEFACT CII
+//	isdoc new -template invoice.yaml        - Write a commented YAML invoice
+//	isdoc lines export invoice.isdoc lines.csv - Export invoice lines as CSV
+//	isdoc lines import invoice.isdoc lines.csv out.isdoc - Import lines from CSV
+//	isdoc diff old.isdoc new.isdoc          - Show what changed between two invoices
+//	isdoc redact -key k in.isdoc out.isdoc  - Replace personal data for sharing
+//	isdoc query 'Invoice.ID' *.isdoc        - Print values selected by a path
+//	isdoc schema                            - Print the JSON Schema
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/xseman/isdoc"
 	"github.com/xseman/isdoc/pdf"
@@ -52,6 +61,18 @@ func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
 		return cmdValidate(args[1:], stdin, stdout, stderr)
 	case "convert":
 		return cmdConvert(args[1:], stdin, stdout, stderr)
+	case "lines":
+		return cmdLines(args[1:], stdin, stdout, stderr)
+	case "new":
+		return cmdNew(args[1:], stdout, stderr)
+	case "diff":
+		return cmdDiff(args[1:], stdin, stdout, stderr)
+	case "redact":
+		return cmdRedact(args[1:], stdin, stdout, stderr)
+	case "query":
+		return cmdQuery(args[1:], stdin, stdout, stderr)
+	case "schema":
+		return cmdSchema(args[1:], stdout, stderr)
 	default:
 		fmt.Fprintf(stderr, "unknown command: %s\n\n", cmd)
 		printUsage(stderr)
@@ -66,7 +87,13 @@ Commands:
   extract   Extract ISDOC XML from a PDF file
   embed     Embed ISDOC XML into a PDF file
   validate  Validate an ISDOC XML document
-  convert   Convert ISDOC XML to JSON format
+  convert   Convert between ISDOC XML, JSON, YAML, UBL and CII
+  lines     Export or import invoice lines as CSV
+  new       Create a sample invoice or a commented YAML template
+  diff      Show the differences between two invoices
+  redact    Replace personal data of a document for sharing
+  query     Print values selected by a path expression
+  schema    Print the JSON Schema of the JSON format
 
 Use "isdoc <command> -h" for more information about a command.`)
 }
@@ -172,146 +199,37 @@ Arguments:
 	return exitSuccess
 }
 
-func cmdValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
-	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+func cmdSchema(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
 	fs.SetOutput(stderr)
-	strict := fs.Bool("strict", false, "Enable strict validation mode")
+	output := fs.String("o", "", "Write the schema to `file` instead of stdout")
 	fs.Usage = func() {
-		fmt.Fprintln(stderr, `Usage: isdoc validate [options] <input.isdoc>
+		fmt.Fprintln(stderr, `Usage: isdoc schema [-o file]
 
-Validate an ISDOC XML document.
+Print the JSON Schema of the ISDOC JSON representation used by
+"isdoc convert".
 
 Options:
-  -strict  Enable strict validation mode`)
+  -o file  Write the schema to file instead of stdout`)
 	}
 
 	if err := fs.Parse(args); err != nil {
 		return exitError
 	}
 
-	if fs.NArg() < 1 {
-		fmt.Fprintln(stderr, "error: missing input file")
-		fs.Usage()
-		return exitError
-	}
-
-	inputPath := fs.Arg(0)
-
-	var data []byte
-	var err error
-	if inputPath == "-" {
-		data, err = io.ReadAll(stdin)
-	} else {
-		data, err = os.ReadFile(inputPath)
-	}
-	if err != nil {
-		fmt.Fprintf(stderr, "error: reading input: %v\n", err)
-		return exitError
-	}
-
-	invoice, err := isdoc.DecodeBytes(data)
+	data, err := isdoc.JSONSchema()
 	if err != nil {
-		fmt.Fprintf(stderr, "error: parsing ISDOC: %v\n", err)
+		fmt.Fprintf(stderr, "error: generating schema: %v\n", err)
 		return exitError
 	}
 
-	opts := isdoc.DefaultValidateOptions()
-	opts.Strict = *strict
-
-	errs := isdoc.ValidateInvoiceWithOptions(invoice, opts)
-
-	if len(errs) == 0 {
-		fmt.Fprintln(stdout, "validation passed")
+	if *output == "" || *output == "-" {
+		stdout.Write(data)
 		return exitSuccess
 	}
-
-	hasErrors := false
-	for _, e := range errs {
-		if e.Severity == isdoc.SeverityError {
-			hasErrors = true
-		}
-		fmt.Fprintf(stdout, "[%s] %s: %s\n", e.Severity, e.Field, e.Msg)
-	}
-
-	if hasErrors {
-		fmt.Fprintf(stdout, "\nvalidation failed with %d error(s)\n", len(errs.Errors()))
-		return exitError
-	}
-
-	fmt.Fprintf(stdout, "\nvalidation passed with %d warning(s)\n", len(errs))
-	return exitSuccess
-}
-
-func cmdConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
-	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
-	fs.SetOutput(stderr)
-	indent := fs.Bool("indent", true, "Indent JSON output")
-	fs.Usage = func() {
-		fmt.Fprintln(stderr, `Usage: isdoc convert [options] <input.isdoc> [output.json]
-
-Convert ISDOC XML to JSON format.
-
-Options:
-  -indent  Indent JSON output (default: true)`)
-	}
-
-	if err := fs.Parse(args); err != nil {
-		return exitError
-	}
-
-	if fs.NArg() < 1 {
-		fmt.Fprintln(stderr, "error: missing input file")
-		fs.Usage()
-		return exitError
-	}
-
-	inputPath := fs.Arg(0)
-	outputPath := fs.Arg(1)
-
-	var data []byte
-	var err error
-	if inputPath == "-" {
-		data, err = io.ReadAll(stdin)
-	} else {
-		data, err = os.ReadFile(inputPath)
-	}
-	if err != nil {
-		fmt.Fprintf(stderr, "error: reading input: %v\n", err)
-		return exitError
-	}
-
-	invoice, err := isdoc.DecodeBytes(data)
-	if err != nil {
-		fmt.Fprintf(stderr, "error: parsing ISDOC: %v\n", err)
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
 		return exitError
 	}
-
-	var jsonData []byte
-	if *indent {
-		jsonData, err = json.MarshalIndent(invoice, "", "  ")
-	} else {
-		jsonData, err = json.Marshal(invoice)
-	}
-	if err != nil {
-		fmt.Fprintf(stderr, "error: converting to JSON: %v\n", err)
-		return exitError
-	}
-
-	if outputPath == "" || outputPath == "-" {
-		stdout.Write(jsonData)
-		fmt.Fprintln(stdout)
-	} else {
-		if err := os.WriteFile(outputPath, jsonData, 0644); err != nil {
-			fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
-			return exitError
-		}
-		baseName := filepath.Base(inputPath)
-		if inputPath == "-" {
-			baseName = "stdin"
-		}
-		ext := strings.TrimPrefix(filepath.Ext(outputPath), ".")
-		fmt.Fprintf(stderr, "converted %s to %s\n", baseName, ext)
-	}
-
 	return exitSuccess
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	_ "embed"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// invoiceTemplate is the commented YAML invoice written by "isdoc new".
+//
+//go:embed template.yaml
+var invoiceTemplate []byte
+
+func cmdNew(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	template := fs.Bool("template", false, "Write the commented YAML template")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc new [-template] [output]
+
+Create a sample invoice covering parties, lines and payment. With -template
+the commented YAML source is written, ready to be edited and converted with
+"isdoc convert invoice.yaml invoice.isdoc". Otherwise the sample is written
+as a complete document in the format of the output file extension (ISDOC
+XML by default).
+
+Examples:
+  isdoc new -template invoice.yaml
+  isdoc new invoice.isdoc
+
+Options:
+  -template  Write the commented YAML template`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	outputPath := fs.Arg(0)
+
+	out := invoiceTemplate
+	if !*template {
+		format := formatFromExt(outputPath)
+		if format == "" {
+			format = formatXML
+		}
+		doc, err := decodeDocument(formatYAML, invoiceTemplate)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		if out, err = encodeDocument(format, doc, true); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+
+	if outputPath == "" || outputPath == "-" {
+		stdout.Write(out)
+		return exitSuccess
+	}
+	if err := os.WriteFile(outputPath, out, 0644); err != nil {
+		fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stderr, "created %s\n", outputPath)
+	return exitSuccess
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xseman/isdoc"
+)
+
+// queryRow is a value selected from a file.
+type queryRow struct {
+	File  string `json:"file,omitempty"`
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func cmdQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "text", "Output format: text, json or csv")
+	recursive := fs.Bool("r", false, "Query directories recursively")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc query [options] <expression> <input>...
+
+Print the values selected by a path expression from Invoice and
+CommonDocument documents. Inputs may be files, glob patterns or, with -r,
+directories of .isdoc, .isdocx and .pdf files; "-" reads standard input.
+
+Paths use the field notation of validation issues; repeated elements take
+[n], [*] or a filter [?path op value] with ==, !=, <, <=, >, >= and &&.
+sum(path) and count(path) aggregate the values of all inputs.
+
+  isdoc query 'Invoice.AccountingSupplierParty.Party.PartyTaxScheme[?TaxScheme==VAT].CompanyID' *.isdoc
+  isdoc query 'Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.Percent==12].Item.Description' inbox/*.pdf
+  isdoc query -r 'sum(Invoice.LegalMonetaryTotal.PayableAmount)' inbox/
+
+Options:
+  -format string  Output format: text, json or csv (default "text")
+  -r              Query .isdoc, .isdocx and .pdf files in directories recursively`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintln(stderr, "error: expected an expression and input files")
+		fs.Usage()
+		return exitError
+	}
+	if *format != "text" && *format != "json" && *format != "csv" {
+		fmt.Fprintf(stderr, "error: unknown format %q\n", *format)
+		return exitError
+	}
+
+	q, err := isdoc.ParseQuery(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	paths := fs.Args()[1:]
+	if len(paths) != 1 || paths[0] != "-" {
+		if paths, err = expandInputs(paths, *recursive); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+
+	status := exitSuccess
+	agg := q.Aggregator()
+	var rows []queryRow
+	for _, path := range paths {
+		doc, err := readQueryDocument(path, stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %s: %v\n", path, err)
+			status = exitError
+			continue
+		}
+		if q.Aggregates() {
+			if err := agg.Add(doc); err != nil {
+				fmt.Fprintf(stderr, "error: %s: %v\n", path, err)
+				return exitError
+			}
+			continue
+		}
+		for _, r := range q.Select(doc) {
+			rows = append(rows, queryRow{File: path, Field: r.Field, Value: r.Value})
+		}
+	}
+
+	if q.Aggregates() {
+		r := agg.Result()
+		rows = []queryRow{{Field: r.Field, Value: r.Value}}
+	}
+
+	if err := writeQueryRows(stdout, *format, rows, len(paths) > 1); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return status
+}
+
+// readQueryDocument reads an Invoice or CommonDocument in any format
+// handled by convert, extracting it from ISDOCX archives and PDF files.
+func readQueryDocument(path string, stdin io.Reader) (any, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		return decodeDocument(detectFormat(path, data), data)
+	}
+	in, err := readInput(path)
+	if err != nil {
+		return nil, err
+	}
+	format := formatXML
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".isdocx" && ext != ".pdf" {
+		format = detectFormat(path, in.data)
+	}
+	return decodeDocument(format, in.data)
+}
+
+// writeQueryRows writes the selected values. Text output prefixes values
+// with their file when several files were queried.
+func writeQueryRows(w io.Writer, format string, rows []queryRow, multiple bool) error {
+	switch format {
+	case "json":
+		if rows == nil {
+			rows = []queryRow{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"file", "field", "value"})
+		for _, r := range rows {
+			cw.Write([]string{r.File, r.Field, r.Value})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	for _, r := range rows {
+		switch {
+		case r.File == "":
+			fmt.Fprintln(w, r.Value)
+		case multiple:
+			fmt.Fprintf(w, "%s: %s: %s\n", r.File, r.Field, r.Value)
+		default:
+			fmt.Fprintf(w, "%s: %s\n", r.Field, r.Value)
+		}
+	}
+	return nil
+}
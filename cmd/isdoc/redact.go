@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/archive"
+	"github.com/xseman/isdoc/schema"
+)
+
+// redactedAttachment replaces the content of attachments with -blank.
+var redactedAttachment = []byte("redacted\n")
+
+func cmdRedact(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("redact", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	key := fs.String("key", "", "Secret key deriving the replacements")
+	blank := fs.Bool("blank", false, "Replace the attachments of an ISDOCX archive")
+	sign := fs.Bool("sign", false, "Recompute the supplement digests")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc redact [options] <input> [output]
+
+Replace party names, addresses, IČO and DIČ, bank accounts, contacts, notes
+and item descriptions so that a document can be shared in bug reports.
+Amounts, dates and structure are kept, so validation reports the same
+issues. Inputs are ISDOC XML, JSON, YAML or ISDOCX archives; the output
+has the format of the input and defaults to stdout.
+
+Documents redacted with the same key get the same replacements for the
+same values. Keep the key secret: with it, short values such as an IČO
+can be recovered by trying every candidate. Without -key a random key is
+generated and printed to stderr, so later documents can reuse it.
+
+Options:
+  -key string  Secret key deriving the replacements (default: random)
+  -blank       Replace the attachments of an ISDOCX archive with a placeholder
+  -sign        Recompute the supplement digests from the attachments, or
+               from the files next to an ISDOC input`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() < 1 {
+		fmt.Fprintln(stderr, "error: missing input file")
+		fs.Usage()
+		return exitError
+	}
+	inputPath, outputPath := fs.Arg(0), fs.Arg(1)
+
+	if *key == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		*key = hex.EncodeToString(b)
+		fmt.Fprintf(stderr, "key: %s\n", *key)
+	}
+
+	var data []byte
+	var err error
+	if inputPath == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(inputPath)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: reading input: %v\n", err)
+		return exitError
+	}
+
+	if strings.EqualFold(filepath.Ext(inputPath), ".isdocx") {
+		return redactArchive(data, outputPath, []byte(*key), *blank, *sign, stdout, stderr)
+	}
+
+	format := detectFormat(inputPath, data)
+	if format == formatUBL || format == formatCII {
+		fmt.Fprintln(stderr, "error: redact reads ISDOC XML, JSON, YAML and ISDOCX; convert UBL and CII first")
+		return exitError
+	}
+	doc, err := decodeDocument(format, data)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if err := isdoc.Redact(doc, []byte(*key)); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if *sign {
+		signSupplements(doc, supplementFiles(doc, filepath.Dir(inputPath), stderr), stderr)
+	}
+
+	out, err := encodeDocument(format, doc, true)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return writeOutput(out, outputPath, stdout, stderr)
+}
+
+// redactArchive redacts the main document of an ISDOCX archive.
+func redactArchive(data []byte, outputPath string, key []byte, blank, sign bool, stdout, stderr io.Writer) int {
+	a, err := archive.ReadBytes(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: reading archive: %v\n", err)
+		return exitError
+	}
+	doc, err := decodeDocument(formatXML, a.MainDocumentData)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if err := isdoc.Redact(doc, key); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	if blank {
+		for name := range a.Attachments {
+			a.Attachments[name] = redactedAttachment
+		}
+	} else if len(a.Attachments) > 0 {
+		fmt.Fprintln(stderr, "warning: attachments are copied unchanged; use -blank to replace them")
+	}
+	if sign {
+		signSupplements(doc, a.Attachments, stderr)
+	}
+
+	if a.MainDocumentData, err = encodeDocument(formatXML, doc, true); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	out, err := a.WriteBytes()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: writing archive: %v\n", err)
+		return exitError
+	}
+	return writeOutput(out, outputPath, stdout, stderr)
+}
+
+// supplementFiles reads the supplements of doc from dir. Filenames come
+// from the document, so names reaching outside dir are skipped.
+func supplementFiles(doc any, dir string, stderr io.Writer) map[string][]byte {
+	var list *schema.SupplementsList
+	switch d := doc.(type) {
+	case *schema.Invoice:
+		list = d.SupplementsList
+	case *schema.CommonDocument:
+		list = d.SupplementsList
+	}
+	files := make(map[string][]byte)
+	if list == nil {
+		return files
+	}
+	for _, s := range list.Supplement {
+		name := filepath.FromSlash(strings.ReplaceAll(s.Filename, "\\", "/"))
+		if !filepath.IsLocal(name) {
+			fmt.Fprintf(stderr, "warning: skipping supplement %q outside the input directory\n", s.Filename)
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			files[s.Filename] = data
+		}
+	}
+	return files
+}
+
+func signSupplements(doc any, files map[string][]byte, stderr io.Writer) {
+	if err := isdoc.SignSupplements(doc, files); err != nil {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+}
+
+// writeOutput writes data to path, or to stdout when path is empty or "-".
+func writeOutput(data []byte, path string, stdout, stderr io.Writer) int {
+	if path == "" || path == "-" {
+		stdout.Write(data)
+		return exitSuccess
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fmt.Fprintf(stderr, "error: writing output file: %v\n", err)
+		return exitError
+	}
+	return exitSuccess
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+func TestRedactGeneratesKey(t *testing.T) {
+	input := filepath.Join("..", "..", "testdata", "fixtures", "sample.isdoc")
+	var stdout, stderr bytes.Buffer
+	if code := cmdRedact([]string{input}, nil, &stdout, &stderr); code != exitSuccess {
+		t.Fatalf("cmdRedact exited with %d: %s", code, stderr.String())
+	}
+	key, ok := strings.CutPrefix(strings.TrimSpace(stderr.String()), "key: ")
+	if !ok || len(key) != 32 {
+		t.Fatalf("stderr = %q, want the generated key", stderr.String())
+	}
+
+	// The printed key reproduces the output
+	var again bytes.Buffer
+	if code := cmdRedact([]string{"-key", key, input}, nil, &again, &stderr); code != exitSuccess {
+		t.Fatalf("cmdRedact -key exited with %d: %s", code, stderr.String())
+	}
+	if !bytes.Equal(stdout.Bytes(), again.Bytes()) {
+		t.Error("redacting with the printed key gives another output")
+	}
+}
+
+func TestSupplementFilesStayInDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "input")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{
+		filepath.Join(dir, "faktura.pdf"): "pdf",
+		filepath.Join(root, "secret.txt"): "secret",
+	} {
+		if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	doc := &schema.Invoice{SupplementsList: &schema.SupplementsList{Supplement: []schema.Supplement{
+		{Filename: "faktura.pdf"},
+		{Filename: "../secret.txt"},
+		{Filename: `..\secret.txt`},
+		{Filename: filepath.Join(root, "secret.txt")},
+	}}}
+	var stderr bytes.Buffer
+	files := supplementFiles(doc, dir, &stderr)
+	if len(files) != 1 || string(files["faktura.pdf"]) != "pdf" {
+		t.Errorf("supplementFiles = %v, want only faktura.pdf", files)
+	}
+	if got := strings.Count(stderr.String(), "warning:"); got != 3 {
+		t.Errorf("stderr = %q, want 3 warnings", stderr.String())
+	}
+}
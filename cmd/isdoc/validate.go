@@ -0,0 +1,442 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/archive"
+	"github.com/xseman/isdoc/cii"
+	"github.com/xseman/isdoc/pdf"
+	"github.com/xseman/isdoc/report"
+	"github.com/xseman/isdoc/schema"
+)
+
+// codeInputError is the report code of files that could not be read or
+// whose ISDOC XML could not be extracted.
+const codeInputError = "INPUT_ERROR"
+
+// validateExtensions are the file types collected from directories.
+var validateExtensions = []string{".isdoc", ".isdocx", ".pdf"}
+
+// validated is the result of validating a single input.
+type validated struct {
+	file report.File
+	sm   *isdoc.SourceMap
+
+	// readErr and decodeErr are set when the input could not be read or
+	// parsed; file then holds the corresponding issues.
+	readErr   error
+	decodeErr error
+}
+
+func cmdValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	strict := fs.Bool("strict", false, "Enable strict validation mode")
+	formatName := fs.String("format", "text", "Output format: text, json, sarif or junit")
+	recursive := fs.Bool("r", false, "Validate directories recursively")
+	jobs := fs.Int("j", runtime.NumCPU(), "Number of parallel workers")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, `Usage: isdoc validate [options] <input>...
+
+Validate ISDOC Invoice and CommonDocument documents. Inputs may be files,
+glob patterns or, with -r, directories. ISDOC XML is extracted from .isdocx
+and .pdf files. ISDOCX manifests are checked too, and supplements must be
+present, with matching digests, in the ISDOCX archive or among the PDF
+attachments.
+
+Options:
+  -strict          Enable strict validation mode
+  -format string   Output format: text, json, sarif or junit (default "text")
+  -r               Validate .isdoc, .isdocx and .pdf files in directories recursively
+  -j int           Number of parallel workers (default: number of CPUs)`)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	format, err := report.ParseFormat(*formatName)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+
+	if fs.NArg() < 1 {
+		fmt.Fprintln(stderr, "error: missing input file")
+		fs.Usage()
+		return exitError
+	}
+
+	if *jobs < 1 {
+		fmt.Fprintln(stderr, "error: -j must be at least 1")
+		return exitError
+	}
+
+	opts := isdoc.DefaultValidateOptions()
+	opts.Strict = *strict
+
+	if fs.NArg() == 1 && fs.Arg(0) == "-" {
+		data, err := io.ReadAll(stdin)
+		v := validateInput("<stdin>", input{data: data}, err, opts)
+		return printSingle(stdout, stderr, v, format)
+	}
+
+	paths, err := expandInputs(fs.Args(), *recursive)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(stderr, "error: no input files found")
+		return exitError
+	}
+
+	if len(paths) == 1 && !*recursive {
+		return printSingle(stdout, stderr, validatePath(paths[0], opts), format)
+	}
+
+	var r report.Report
+	validateAll(paths, *jobs, opts, format == report.FormatText, func(b batchResult) {
+		r.Add(b.file)
+		io.WriteString(stdout, b.text)
+	})
+
+	if format == report.FormatText {
+		printSummary(stdout, r.Summary())
+	} else if err := report.Write(stdout, &r, format); err != nil {
+		fmt.Fprintf(stderr, "error: writing report: %v\n", err)
+		return exitError
+	}
+
+	if r.Failed() {
+		return exitError
+	}
+	return exitSuccess
+}
+
+// expandInputs resolves command line arguments to the list of files to
+// validate. Glob patterns are expanded, and directories are walked when
+// recursive is set.
+func expandInputs(args []string, recursive bool) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			matches, err = filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				paths = append(paths, path)
+				continue
+			}
+			if !recursive {
+				return nil, fmt.Errorf("%s is a directory (use -r)", path)
+			}
+			err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && isValidateInput(p) {
+					paths = append(paths, p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return paths, nil
+}
+
+func isValidateInput(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range validateExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// batchResult is the result of validating one file of a batch. The text
+// output is rendered by the worker, so the source map holding the file is
+// released before the result waits for its turn to be printed.
+type batchResult struct {
+	file report.File
+	text string
+}
+
+// validateAll validates paths using jobs parallel workers and calls fn with
+// each result in the order of paths. With text set, results carry the text
+// output of printFileResult.
+func validateAll(paths []string, jobs int, opts isdoc.ValidateOptions, text bool, fn func(batchResult)) {
+	results := make([]chan batchResult, len(paths))
+	for i := range results {
+		results[i] = make(chan batchResult, 1)
+	}
+
+	work := make(chan int)
+	for range min(jobs, len(paths)) {
+		go func() {
+			for i := range work {
+				v := validatePath(paths[i], opts)
+				b := batchResult{file: v.file}
+				if text {
+					var out strings.Builder
+					printFileResult(&out, v)
+					b.text = out.String()
+				}
+				results[i] <- b
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			work <- i
+		}
+		close(work)
+	}()
+
+	for _, ch := range results {
+		fn(<-ch)
+	}
+}
+
+// input is the ISDOC XML of a file and the container it was read from.
+type input struct {
+	data []byte
+
+	// archive is set for ISDOCX inputs.
+	archive *archive.Archive
+
+	// attachments holds the other files embedded in ISDOC PDF inputs.
+	attachments map[string][]byte
+}
+
+// validatePath reads and validates a single file.
+func validatePath(path string, opts isdoc.ValidateOptions) validated {
+	in, err := readInput(path)
+	return validateInput(path, in, err, opts)
+}
+
+// readInput returns the ISDOC XML of a file, extracting it from ISDOCX
+// archives and PDF attachments. The CII XML of Factur-X and ZUGFeRD PDFs is
+// converted to ISDOC.
+func readInput(path string) (input, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".isdocx":
+		a, err := archive.ReadFile(path)
+		if err != nil {
+			return input{}, fmt.Errorf("reading ISDOCX: %w", err)
+		}
+		return input{data: a.MainDocumentData, archive: a}, nil
+	case ".pdf":
+		result, err := pdf.NewReader().ReadFile(path)
+		if err != nil {
+			return input{}, fmt.Errorf("extracting ISDOC from PDF: %w", err)
+		}
+		if result.Format == pdf.FormatCII {
+			inv, _, err := cii.Unmarshal(result.XML)
+			if err != nil {
+				return input{}, fmt.Errorf("converting Factur-X XML: %w", err)
+			}
+			data, err := isdoc.EncodeBytes(inv)
+			return input{data: data}, err
+		}
+		attachments := make(map[string][]byte, len(result.Supplements))
+		for _, s := range result.Supplements {
+			attachments[s.Name] = s.Data
+		}
+		return input{data: result.XML, attachments: attachments}, nil
+	default:
+		data, err := os.ReadFile(path)
+		return input{data: data}, err
+	}
+}
+
+// validateInput decodes and validates an Invoice or CommonDocument read
+// from name, including its container. readErr is the error returned while
+// reading the input, if any.
+func validateInput(name string, in input, readErr error, opts isdoc.ValidateOptions) validated {
+	if readErr != nil {
+		return validated{
+			file: report.File{Path: name, Issues: []report.Issue{{
+				Code:     codeInputError,
+				Severity: report.SeverityError,
+				Message:  readErr.Error(),
+			}}},
+			readErr: readErr,
+		}
+	}
+
+	sm := isdoc.NewSourceMap()
+	decodeOpts := isdoc.DecodeOptions{SourceMap: sm}
+	opts.SourceMap = sm
+
+	root, err := isdoc.RootElement(in.data)
+	var doc any
+	var errs isdoc.ValidationErrors
+	switch {
+	case err != nil:
+	case root == "CommonDocument":
+		var cd *schema.CommonDocument
+		cd, err = isdoc.DecodeCommonDocumentBytesWithOptions(in.data, decodeOpts)
+		if err == nil {
+			doc, errs = cd, isdoc.ValidateCommonDocumentWithOptions(cd, opts)
+		}
+	default:
+		var invoice *schema.Invoice
+		invoice, err = isdoc.DecodeBytesWithOptions(in.data, decodeOpts)
+		if err == nil {
+			doc, errs = invoice, isdoc.ValidateInvoiceWithOptions(invoice, opts)
+		}
+	}
+	if err != nil {
+		return validated{file: report.FromError(name, err), sm: sm, decodeErr: err}
+	}
+
+	switch {
+	case in.archive != nil:
+		errs = append(errs, isdoc.ValidateArchive(in.archive, doc, opts)...)
+	case in.attachments != nil:
+		errs = append(errs, isdoc.ValidateSupplements(doc, in.attachments, opts)...)
+	}
+	return validated{file: report.FromValidation(name, errs), sm: sm}
+}
+
+// printSingle writes the result of validating a single document.
+func printSingle(stdout, stderr io.Writer, v validated, format report.Format) int {
+	if format != report.FormatText {
+		return writeReport(stdout, stderr, v.file, format)
+	}
+
+	switch {
+	case v.readErr != nil:
+		fmt.Fprintf(stderr, "error: reading input: %v\n", v.readErr)
+		return exitError
+	case v.decodeErr != nil:
+		printDecodeError(stderr, v.file.Path, v.sm, v.decodeErr)
+		return exitError
+	}
+
+	if len(v.file.Issues) == 0 {
+		fmt.Fprintln(stdout, "validation passed")
+		return exitSuccess
+	}
+
+	printIssues(stdout, v)
+
+	if !v.file.Valid {
+		fmt.Fprintf(stdout, "\nvalidation failed with %d error(s)\n", v.file.Errors())
+		return exitError
+	}
+
+	fmt.Fprintf(stdout, "\nvalidation passed with %d warning(s)\n", len(v.file.Issues))
+	return exitSuccess
+}
+
+// printFileResult writes the status line of a document in a batch,
+// followed by its issues.
+func printFileResult(w io.Writer, v validated) {
+	status := "ok  "
+	switch {
+	case !v.file.Valid:
+		status = "FAIL"
+	case len(v.file.Issues) > 0:
+		status = "WARN"
+	}
+	fmt.Fprintf(w, "%s %s\n", status, v.file.Path)
+	printIssues(w, v)
+}
+
+func printIssues(w io.Writer, v validated) {
+	for _, issue := range v.file.Issues {
+		pos := isdoc.Position{Line: issue.Line, Column: issue.Column}
+		printPositioned(w, v.file.Path, v.sm, pos, issue.Text(""))
+	}
+}
+
+// printSummary writes the totals of a batch and the number of issues per code.
+func printSummary(w io.Writer, s report.Summary) {
+	fmt.Fprintf(w, "\n%d file(s): %d passed, %d passed with warnings, %d failed\n",
+		s.Files, s.Passed, s.Warnings, s.Failed)
+	for _, code := range s.Codes() {
+		fmt.Fprintf(w, "  %-24s %d\n", code, s.ByCode[code])
+	}
+}
+
+// writeReport writes the validation result of a single document in a
+// machine-readable format. The exit code reflects its validity.
+func writeReport(stdout, stderr io.Writer, f report.File, format report.Format) int {
+	var r report.Report
+	r.Add(f)
+	if err := report.Write(stdout, &r, format); err != nil {
+		fmt.Fprintf(stderr, "error: writing report: %v\n", err)
+		return exitError
+	}
+	if r.Failed() {
+		return exitError
+	}
+	return exitSuccess
+}
+
+// printDecodeError reports a decode failure, with source positions if known.
+func printDecodeError(w io.Writer, name string, sm *isdoc.SourceMap, err error) {
+	var decodeErrs isdoc.DecodeErrors
+	var decodeErr *isdoc.DecodeError
+	switch {
+	case errors.As(err, &decodeErrs):
+		for _, e := range decodeErrs {
+			printPositioned(w, name, sm, e.Position(), "error: parsing ISDOC: "+e.Error())
+		}
+	case errors.As(err, &decodeErr):
+		printPositioned(w, name, sm, decodeErr.Position(), "error: parsing ISDOC: "+decodeErr.Error())
+	default:
+		fmt.Fprintf(w, "error: parsing ISDOC: %v\n", err)
+	}
+}
+
+// printPositioned prints msg prefixed with "file:line:col:", or just "file:"
+// when the position is unknown, followed by a caret excerpt of the source line.
+func printPositioned(w io.Writer, name string, sm *isdoc.SourceMap, pos isdoc.Position, msg string) {
+	switch {
+	case pos.Column > 0:
+		fmt.Fprintf(w, "%s:%d:%d: %s\n", name, pos.Line, pos.Column, msg)
+	case pos.Line > 0:
+		fmt.Fprintf(w, "%s:%d: %s\n", name, pos.Line, msg)
+	default:
+		fmt.Fprintf(w, "%s: %s\n", name, msg)
+		return
+	}
+
+	if pos.Column == 0 {
+		return
+	}
+	if excerpt := sm.Excerpt(pos); excerpt != "" {
+		for _, line := range strings.Split(excerpt, "\n") {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xseman/isdoc"
+)
+
+// The YAML format mirrors the JSON representation of the isdoc package:
+// the same keys and structure, with jsonVersion optional. Scalars are read
+// by their position in the JSON Schema, so decimals, dates and IDs may be
+// written without quotes and keep their exact text.
+
+// yamlToJSON converts a YAML document to the JSON representation.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("empty YAML document")
+	}
+
+	raw, err := isdoc.JSONSchema()
+	if err != nil {
+		return nil, err
+	}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, err
+	}
+	c := &yamlConverter{defs: root["$defs"].(map[string]any)}
+
+	v, err := c.value(doc.Content[0], root, "")
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("line %d: document must be a mapping", doc.Content[0].Line)
+	}
+	if _, ok := obj["jsonVersion"]; !ok {
+		obj["jsonVersion"] = isdoc.JSONVersion
+	}
+	return json.Marshal(obj)
+}
+
+type yamlConverter struct {
+	defs map[string]any
+}
+
+// value converts n to a JSON value of the type given by schema s.
+func (c *yamlConverter) value(n *yaml.Node, s map[string]any, path string) (any, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if ref, ok := s["$ref"].(string); ok {
+		s = c.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+
+	typ := s["type"]
+	if _, ok := s["const"].(string); ok {
+		typ = "string"
+	}
+
+	switch typ {
+	case "object":
+		if n.Kind != yaml.MappingNode {
+			return nil, yamlTypeError(n, path, "mapping")
+		}
+		props, _ := s["properties"].(map[string]any)
+		obj := make(map[string]any, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			ps, _ := props[key].(map[string]any)
+			v, err := c.value(n.Content[i+1], ps, joinYAMLPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = v
+		}
+		return obj, nil
+
+	case "array":
+		if n.Kind != yaml.SequenceNode {
+			return nil, yamlTypeError(n, path, "sequence")
+		}
+		items, _ := s["items"].(map[string]any)
+		arr := make([]any, 0, len(n.Content))
+		for i, item := range n.Content {
+			v, err := c.value(item, items, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+
+	case "string":
+		if n.Kind != yaml.ScalarNode {
+			return nil, yamlTypeError(n, path, "scalar")
+		}
+		if n.Tag == "!!null" {
+			return "", nil
+		}
+		return n.Value, nil
+
+	case "integer":
+		if n.Kind != yaml.ScalarNode {
+			return nil, yamlTypeError(n, path, "integer")
+		}
+		i, err := strconv.Atoi(n.Value)
+		if err != nil {
+			return nil, yamlTypeError(n, path, "integer")
+		}
+		return i, nil
+
+	case "boolean":
+		var b bool
+		if n.Kind != yaml.ScalarNode || n.Decode(&b) != nil {
+			return nil, yamlTypeError(n, path, "true or false")
+		}
+		return b, nil
+	}
+
+	// Unknown keys are passed through for DecodeJSON to report
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil, fmt.Errorf("line %d: %s: %w", n.Line, path, err)
+	}
+	return v, nil
+}
+
+func joinYAMLPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func yamlTypeError(n *yaml.Node, path, want string) error {
+	return fmt.Errorf("line %d: %s: expected %s", n.Line, path, want)
+}
+
+// jsonToYAML converts JSON to YAML, keeping the key order.
+func jsonToYAML(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := yamlNodeFromJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlNodeFromJSON(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		if t == '[' {
+			n.Kind = yaml.SequenceNode
+		}
+		for dec.More() {
+			if n.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key.(string)})
+			}
+			child, err := yamlNodeFromJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, child)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		// Plain style unless quoting is needed; the schema gives the type
+		// back on input
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: t}, nil
+	case json.Number:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
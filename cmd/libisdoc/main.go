@@ -10,6 +10,7 @@ import (
 	"unsafe"
 
 	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/report"
 	"github.com/xseman/isdoc/schema"
 )
 
@@ -58,26 +59,15 @@ func isdoc_parse_common_document(xmlData *C.char) *C.char {
 func isdoc_validate(xmlData *C.char) *C.char {
 	input := C.GoString(xmlData)
 
-	invoice, err := isdoc.DecodeBytes([]byte(input))
+	var result report.File
+	sm := isdoc.NewSourceMap()
+	invoice, err := isdoc.DecodeBytesWithOptions([]byte(input), isdoc.DecodeOptions{SourceMap: sm})
 	if err != nil {
-		errJSON := fmt.Sprintf(`{"error": "DecodeBytes error: %s"}`, err.Error())
-		return C.CString(errJSON)
-	}
-
-	errs := isdoc.ValidateInvoice(invoice)
-
-	type ValidationResult struct {
-		Valid  bool     `json:"valid"`
-		Errors []string `json:"errors,omitempty"`
-	}
-
-	result := ValidationResult{
-		Valid:  !errs.HasErrors(),
-		Errors: make([]string, 0),
-	}
-
-	for _, e := range errs.Errors() {
-		result.Errors = append(result.Errors, e.Error())
+		result = report.FromError("", err)
+	} else {
+		opts := isdoc.DefaultValidateOptions()
+		opts.SourceMap = sm
+		result = report.FromValidation("", isdoc.ValidateInvoiceWithOptions(invoice, opts))
 	}
 
 	output, err := json.Marshal(result)
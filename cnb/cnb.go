@@ -0,0 +1,184 @@
+// Package cnb reads the daily exchange rates of the Czech National Bank
+// (kurzy devizového trhu) in the text format of denni_kurz.txt:
+//
+//	17.10.2025 #201
+//	země|měna|množství|kód|kurz
+//	EMU|euro|1|EUR|24,320
+//	Japonsko|jen|100|JPY|13,846
+//
+// The file of a given day is published at
+// https://www.cnb.cz/cs/financni-trhy/devizovy-trh/kurzy-devizoveho-trhu/kurzy-devizoveho-trhu/denni_kurz.txt?date=DD.MM.YYYY.
+// A Table or a set of Tables provides CurrRate and RefCurrRate for
+// invoices in a foreign currency through isdoc.RateSource:
+//
+//	table, err := cnb.ParseFile("denni_kurz.txt")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	err = isdoc.FillForeignAmounts(inv, table)
+package cnb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/types"
+)
+
+// Local is the currency all rates are quoted in.
+const Local = "CZK"
+
+// ErrNoRate is returned when no rate is known for a currency and date.
+var ErrNoRate = errors.New("cnb: no exchange rate")
+
+var (
+	_ isdoc.RateSource = (*Table)(nil)
+	_ isdoc.RateSource = Tables(nil)
+)
+
+// Rate is the exchange rate of one currency.
+type Rate struct {
+	// Country is the Czech name of the country, e.g. "EMU".
+	Country string
+	// Currency is the Czech name of the currency, e.g. "euro".
+	Currency string
+	// Amount is the number of currency units the rate is quoted for,
+	// e.g. 100 for JPY.
+	Amount int
+	// Code is the ISO 4217 code of the currency.
+	Code string
+	// Rate is the price of Amount units in CZK.
+	Rate types.Decimal
+}
+
+// Table is the exchange rate table of one day.
+type Table struct {
+	// Date is the day the table was declared for.
+	Date types.Date
+	// Number is the serial number of the table in its year.
+	Number int
+	// Rates lists the rates in the order of the file.
+	Rates []Rate
+}
+
+// Parse reads a table in the format of denni_kurz.txt.
+func Parse(r io.Reader) (*Table, error) {
+	sc := bufio.NewScanner(r)
+	line := 0
+	next := func() (string, bool) {
+		for sc.Scan() {
+			line++
+			if s := strings.TrimSpace(sc.Text()); s != "" {
+				return s, true
+			}
+		}
+		return "", false
+	}
+
+	header, ok := next()
+	if !ok {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("cnb: empty table")
+	}
+	date, number, _ := strings.Cut(header, " #")
+	d, err := time.Parse("02.01.2006", date)
+	if err != nil {
+		return nil, fmt.Errorf("cnb: line %d: invalid date %q", line, date)
+	}
+	t := &Table{Date: types.NewDate(d)}
+	if t.Number, err = strconv.Atoi(number); err != nil {
+		return nil, fmt.Errorf("cnb: line %d: invalid table number %q", line, number)
+	}
+	if _, ok := next(); !ok {
+		return nil, fmt.Errorf("cnb: line %d: missing column names", line+1)
+	}
+
+	for {
+		s, ok := next()
+		if !ok {
+			break
+		}
+		f := strings.Split(s, "|")
+		if len(f) != 5 {
+			return nil, fmt.Errorf("cnb: line %d: expected 5 fields, got %d", line, len(f))
+		}
+		amount, err := strconv.Atoi(f[2])
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("cnb: line %d: invalid amount %q", line, f[2])
+		}
+		rate, err := types.NewDecimal(strings.Replace(f[4], ",", ".", 1))
+		if err != nil {
+			return nil, fmt.Errorf("cnb: line %d: invalid rate %q", line, f[4])
+		}
+		t.Rates = append(t.Rates, Rate{Country: f[0], Currency: f[1], Amount: amount, Code: f[3], Rate: rate})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseFile reads a table from a denni_kurz.txt file.
+func ParseFile(name string) (*Table, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Lookup returns the rate of the currency with the ISO 4217 code.
+func (t *Table) Lookup(code string) (Rate, bool) {
+	i := slices.IndexFunc(t.Rates, func(r Rate) bool { return r.Code == code })
+	if i < 0 {
+		return Rate{}, false
+	}
+	return t.Rates[i], true
+}
+
+// Rate implements isdoc.RateSource: Amount units of currency are worth
+// Rate CZK. The table applies to its own day and to later days until the
+// next table is declared; dates before it are rejected. The caller is
+// responsible for passing the latest table, or use Tables.
+func (t *Table) Rate(currency, local string, date types.Date) (rate, amount types.Decimal, err error) {
+	if local != Local {
+		return "", "", fmt.Errorf("%w: rates are quoted in %s, not %s", ErrNoRate, Local, local)
+	}
+	if !date.IsZero() && date.Before(t.Date.Time) {
+		return "", "", fmt.Errorf("%w: table of %s does not apply to %s", ErrNoRate, t.Date, date)
+	}
+	r, ok := t.Lookup(currency)
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s not in table of %s", ErrNoRate, currency, t.Date)
+	}
+	return r.Rate, types.Decimal(strconv.Itoa(r.Amount)), nil
+}
+
+// Tables is a RateSource over the tables of several days: a date takes its
+// rate from the latest table declared on or before it.
+type Tables []*Table
+
+// Rate implements isdoc.RateSource.
+func (ts Tables) Rate(currency, local string, date types.Date) (rate, amount types.Decimal, err error) {
+	var latest *Table
+	for _, t := range ts {
+		if (date.IsZero() || !date.Before(t.Date.Time)) && (latest == nil || t.Date.After(latest.Date.Time)) {
+			latest = t
+		}
+	}
+	if latest == nil {
+		return "", "", fmt.Errorf("%w: no table on or before %s", ErrNoRate, date)
+	}
+	return latest.Rate(currency, local, date)
+}
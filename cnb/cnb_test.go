@@ -0,0 +1,100 @@
+package cnb
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestParseFile(t *testing.T) {
+	table, err := ParseFile("testdata/denni_kurz.txt")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if table.Date.String() != "2025-10-17" || table.Number != 201 || len(table.Rates) != 31 {
+		t.Errorf("table = %s #%d with %d rates", table.Date, table.Number, len(table.Rates))
+	}
+	r, ok := table.Lookup("JPY")
+	if !ok || r != (Rate{Country: "Japonsko", Currency: "jen", Amount: 100, Code: "JPY", Rate: "13.846"}) {
+		t.Errorf("Lookup(JPY) = %+v, %v", r, ok)
+	}
+	if _, ok := table.Lookup("CZK"); ok {
+		t.Error("Lookup(CZK) found")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, data, want string
+	}{
+		{"empty", "\n", "empty table"},
+		{"date", "2025-10-17 #201\n", "line 1: invalid date"},
+		{"number", "17.10.2025\n", "line 1: invalid table number"},
+		{"header", "17.10.2025 #201\n", "missing column names"},
+		{"fields", "17.10.2025 #201\nzemě|měna|množství|kód|kurz\nEMU|euro|1|EUR\n", "line 3: expected 5 fields"},
+		{"amount", "17.10.2025 #201\nzemě|měna|množství|kód|kurz\nEMU|euro|0|EUR|24,320\n", "line 3: invalid amount"},
+		{"rate", "17.10.2025 #201\nzemě|měna|množství|kód|kurz\nEMU|euro|1|EUR|24.320,5\n", "line 3: invalid rate"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRate(t *testing.T) {
+	table, err := ParseFile("testdata/denni_kurz.txt")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	rate, amount, err := table.Rate("JPY", "CZK", types.MustParseDate("2025-10-18"))
+	if err != nil || rate != "13.846" || amount != "100" {
+		t.Errorf("Rate(JPY) = %s/%s, %v", rate, amount, err)
+	}
+	for _, tc := range []struct{ currency, local, date string }{
+		{"EUR", "EUR", "2025-10-17"},
+		{"EUR", "CZK", "2025-10-16"},
+		{"XYZ", "CZK", "2025-10-17"},
+	} {
+		if _, _, err := table.Rate(tc.currency, tc.local, types.MustParseDate(tc.date)); !errors.Is(err, ErrNoRate) {
+			t.Errorf("Rate(%s, %s, %s) error = %v, want ErrNoRate", tc.currency, tc.local, tc.date, err)
+		}
+	}
+
+	older := &Table{Date: types.MustParseDate("2025-10-16"), Rates: []Rate{{Amount: 1, Code: "EUR", Rate: "24.300"}}}
+	tables := Tables{table, older}
+	for date, want := range map[string]types.Decimal{"2025-10-16": "24.300", "2025-10-17": "24.320", "2025-10-20": "24.320"} {
+		if rate, _, err := tables.Rate("EUR", "CZK", types.MustParseDate(date)); err != nil || rate != want {
+			t.Errorf("Tables.Rate(EUR, %s) = %s, %v, want %s", date, rate, err, want)
+		}
+	}
+	if _, _, err := tables.Rate("EUR", "CZK", types.MustParseDate("2025-10-15")); !errors.Is(err, ErrNoRate) {
+		t.Errorf("Tables.Rate() before the first table: %v", err)
+	}
+}
+
+func TestFillForeignAmounts(t *testing.T) {
+	table, err := ParseFile("testdata/denni_kurz.txt")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	inv := &schema.Invoice{
+		LocalCurrencyCode:   "CZK",
+		ForeignCurrencyCode: "EUR",
+		TaxPointDate:        types.MustParseDate("2025-10-17"),
+		LegalMonetaryTotal:  schema.LegalMonetaryTotal{PayableAmount: "2432.00"},
+	}
+	if err := isdoc.FillForeignAmounts(inv, table); err != nil {
+		t.Fatalf("FillForeignAmounts() error = %v", err)
+	}
+	if inv.CurrRate != "24.320" || inv.RefCurrRate != "1" || inv.LegalMonetaryTotal.PayableAmountCurr != "100.00" {
+		t.Errorf("rate %s/%s, PayableAmountCurr %s", inv.CurrRate, inv.RefCurrRate, inv.LegalMonetaryTotal.PayableAmountCurr)
+	}
+}
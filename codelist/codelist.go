@@ -0,0 +1,228 @@
+// Package codelist provides the code lists ISDOC documents refer to:
+// ISO 4217 currencies with their minor units, ISO 3166-1 alpha-2 countries,
+// ISO 639-1 languages and UN/ECE Recommendation 20 and 21 units of measure.
+//
+// The tables are embedded CSV files under data/; the *Version constants
+// name the edition each one was taken from. Lookups are case-sensitive, as
+// the codes are defined in upper case, and languages in lower case.
+//
+//	if c, ok := codelist.LookupCurrency("CZK"); ok {
+//	    fmt.Println(c.Name, c.MinorUnits) // Czech Koruna 2
+//	}
+//
+// Recommendation 21 package codes are looked up with an X prefix, as in
+// the EN 16931 unit code list: "XBX" is a box. The Recommendation 20 table
+// is not complete: it holds the codes in common use in trade, and codes
+// outside it are reported as unknown.
+package codelist
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Editions of the embedded code lists.
+const (
+	CurrencyVersion = "ISO 4217, iso-codes 4.15.0"
+	CountryVersion  = "ISO 3166-1, iso-codes 4.15.0"
+	LanguageVersion = "ISO 639-1, iso-codes 4.15.0"
+	UnitVersion     = "UN/ECE Recommendation 20 (common codes), Recommendation 21 revision 12"
+)
+
+//go:embed data/*.csv
+var data embed.FS
+
+// Currency is an ISO 4217 currency.
+type Currency struct {
+	// Code is the alphabetic code, e.g. "CZK".
+	Code string
+	// Numeric is the three-digit numeric code, e.g. "203".
+	Numeric string
+	// MinorUnits is the number of decimal places of the minor unit, or -1
+	// for funds and precious metals without one.
+	MinorUnits int
+	// Name is the English name of the currency.
+	Name string
+}
+
+// Country is an ISO 3166-1 country.
+type Country struct {
+	// Alpha2 is the two-letter code used in Country.IdentificationCode.
+	Alpha2 string
+	// Alpha3 is the three-letter code.
+	Alpha3 string
+	// Numeric is the three-digit numeric code.
+	Numeric string
+	// Name is the English short name.
+	Name string
+}
+
+// Language is an ISO 639-1 language.
+type Language struct {
+	// Alpha2 is the two-letter code used in languageID, e.g. "cs".
+	Alpha2 string
+	// Alpha3 is the ISO 639-2 terminology code, e.g. "ces".
+	Alpha3 string
+	// Name is the English name.
+	Name string
+}
+
+// Unit is a unit of measure from UN/ECE Recommendation 20 or a package
+// type from Recommendation 21.
+type Unit struct {
+	// Code is the unit code, e.g. "KGM", or "XBX" for a Recommendation 21
+	// package.
+	Code string
+	// Name is the English name of the unit.
+	Name string
+	// Package is true for Recommendation 21 package codes.
+	Package bool
+}
+
+// table is a code list indexed by code.
+type table[T any] struct {
+	list  []T
+	index map[string]int
+}
+
+func (t *table[T]) lookup(code string) (T, bool) {
+	i, ok := t.index[code]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return t.list[i], true
+}
+
+func (t *table[T]) all() []T {
+	return append([]T(nil), t.list...)
+}
+
+// load parses an embedded CSV file, skipping its header, into a table.
+func load[T any](name string, columns int, parse func(rec []string) (string, T, error)) *table[T] {
+	f, err := data.Open("data/" + name)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = columns
+	records, err := r.ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("codelist: %s: %v", name, err))
+	}
+
+	t := &table[T]{index: make(map[string]int, len(records))}
+	for _, rec := range records[1:] {
+		code, v, err := parse(rec)
+		if err != nil {
+			panic(fmt.Sprintf("codelist: %s: %s: %v", name, rec[0], err))
+		}
+		t.index[code] = len(t.list)
+		t.list = append(t.list, v)
+	}
+	return t
+}
+
+var currencies = sync.OnceValue(func() *table[Currency] {
+	return load("iso4217.csv", 4, func(rec []string) (string, Currency, error) {
+		minor, err := strconv.Atoi(rec[2])
+		return rec[0], Currency{Code: rec[0], Numeric: rec[1], MinorUnits: minor, Name: rec[3]}, err
+	})
+})
+
+var countries = sync.OnceValue(func() *table[Country] {
+	return load("iso3166.csv", 4, func(rec []string) (string, Country, error) {
+		return rec[0], Country{Alpha2: rec[0], Alpha3: rec[1], Numeric: rec[2], Name: rec[3]}, nil
+	})
+})
+
+var languages = sync.OnceValue(func() *table[Language] {
+	return load("iso639.csv", 3, func(rec []string) (string, Language, error) {
+		return rec[0], Language{Alpha2: rec[0], Alpha3: rec[1], Name: rec[2]}, nil
+	})
+})
+
+var units = sync.OnceValue(func() *table[Unit] {
+	t := load("rec20.csv", 2, func(rec []string) (string, Unit, error) {
+		return rec[0], Unit{Code: rec[0], Name: rec[1]}, nil
+	})
+	packages := load("rec21.csv", 2, func(rec []string) (string, Unit, error) {
+		return "X" + rec[0], Unit{Code: "X" + rec[0], Name: rec[1], Package: true}, nil
+	})
+	for _, u := range packages.list {
+		t.index[u.Code] = len(t.list)
+		t.list = append(t.list, u)
+	}
+	return t
+})
+
+// LookupCurrency returns the ISO 4217 currency with the alphabetic code.
+func LookupCurrency(code string) (Currency, bool) {
+	return currencies().lookup(code)
+}
+
+// CurrencyName returns the name of a currency, or "" for unknown codes.
+func CurrencyName(code string) string {
+	c, _ := LookupCurrency(code)
+	return c.Name
+}
+
+// Currencies returns all currencies in code order.
+func Currencies() []Currency {
+	return currencies().all()
+}
+
+// LookupCountry returns the ISO 3166-1 country with the alpha-2 code.
+func LookupCountry(code string) (Country, bool) {
+	return countries().lookup(code)
+}
+
+// CountryName returns the name of a country, or "" for unknown codes.
+func CountryName(code string) string {
+	c, _ := LookupCountry(code)
+	return c.Name
+}
+
+// Countries returns all countries in alpha-2 code order.
+func Countries() []Country {
+	return countries().all()
+}
+
+// LookupLanguage returns the ISO 639-1 language with the alpha-2 code.
+func LookupLanguage(code string) (Language, bool) {
+	return languages().lookup(code)
+}
+
+// LanguageName returns the name of a language, or "" for unknown codes.
+func LanguageName(code string) string {
+	l, _ := LookupLanguage(code)
+	return l.Name
+}
+
+// Languages returns all languages in alpha-2 code order.
+func Languages() []Language {
+	return languages().all()
+}
+
+// LookupUnit returns the unit with the Recommendation 20 code, or the
+// package with the X-prefixed Recommendation 21 code.
+func LookupUnit(code string) (Unit, bool) {
+	return units().lookup(code)
+}
+
+// UnitName returns the name of a unit, or "" for unknown codes.
+func UnitName(code string) string {
+	u, _ := LookupUnit(code)
+	return u.Name
+}
+
+// Units returns the Recommendation 20 units followed by the
+// Recommendation 21 packages.
+func Units() []Unit {
+	return units().all()
+}
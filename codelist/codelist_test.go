@@ -0,0 +1,123 @@
+package codelist
+
+import "testing"
+
+func TestLookupCurrency(t *testing.T) {
+	tests := []struct {
+		code    string
+		numeric string
+		minor   int
+	}{
+		{"CZK", "203", 2},
+		{"EUR", "978", 2},
+		{"JPY", "392", 0},
+		{"KWD", "414", 3},
+		{"CLF", "990", 4},
+		{"XAU", "959", -1},
+	}
+	for _, tc := range tests {
+		c, ok := LookupCurrency(tc.code)
+		if !ok {
+			t.Errorf("LookupCurrency(%s) not found", tc.code)
+			continue
+		}
+		if c.Numeric != tc.numeric || c.MinorUnits != tc.minor {
+			t.Errorf("LookupCurrency(%s) = %+v, want numeric %s and %d minor units", tc.code, c, tc.numeric, tc.minor)
+		}
+	}
+	for _, code := range []string{"", "czk", "CZ", "XYZ", "KČS"} {
+		if _, ok := LookupCurrency(code); ok {
+			t.Errorf("LookupCurrency(%q) found", code)
+		}
+	}
+	if got := CurrencyName("CZK"); got != "Czech Koruna" {
+		t.Errorf("CurrencyName(CZK) = %q", got)
+	}
+}
+
+func TestLookupCountry(t *testing.T) {
+	c, ok := LookupCountry("CZ")
+	if !ok || c.Alpha3 != "CZE" || c.Numeric != "203" || c.Name != "Czechia" {
+		t.Errorf("LookupCountry(CZ) = %+v, %v", c, ok)
+	}
+	for _, code := range []string{"", "cz", "CZE", "EL", "UK"} {
+		if _, ok := LookupCountry(code); ok {
+			t.Errorf("LookupCountry(%q) found", code)
+		}
+	}
+	if got := CountryName("SK"); got != "Slovakia" {
+		t.Errorf("CountryName(SK) = %q", got)
+	}
+}
+
+func TestLookupLanguage(t *testing.T) {
+	l, ok := LookupLanguage("cs")
+	if !ok || l.Alpha3 != "ces" || l.Name != "Czech" {
+		t.Errorf("LookupLanguage(cs) = %+v, %v", l, ok)
+	}
+	for _, code := range []string{"", "CS", "cz", "ces", "cs-CZ"} {
+		if _, ok := LookupLanguage(code); ok {
+			t.Errorf("LookupLanguage(%q) found", code)
+		}
+	}
+	if got := LanguageName("sk"); got != "Slovak" {
+		t.Errorf("LanguageName(sk) = %q", got)
+	}
+}
+
+func TestLookupUnit(t *testing.T) {
+	tests := []struct {
+		code string
+		name string
+		pkg  bool
+	}{
+		{"C62", "one", false},
+		{"H87", "piece", false},
+		{"KGM", "kilogram", false},
+		{"HUR", "hour", false},
+		{"X1", "Gunter's chain", false},
+		{"XBX", "Box", true},
+		{"XPX", "Pallet", true},
+	}
+	for _, tc := range tests {
+		u, ok := LookupUnit(tc.code)
+		if !ok || u.Name != tc.name || u.Package != tc.pkg {
+			t.Errorf("LookupUnit(%s) = %+v, %v", tc.code, u, ok)
+		}
+	}
+	// package codes need the X prefix; Czech abbreviations are not codes
+	for _, code := range []string{"", "BX", "ks", "kgm", "XXX"} {
+		if _, ok := LookupUnit(code); ok {
+			t.Errorf("LookupUnit(%q) found", code)
+		}
+	}
+	if UnitName("LTR") != "litre" {
+		t.Errorf("UnitName(LTR) = %q", UnitName("LTR"))
+	}
+}
+
+func TestTables(t *testing.T) {
+	if n := len(Currencies()); n < 150 {
+		t.Errorf("Currencies() has %d entries", n)
+	}
+	if n := len(Countries()); n != 249 {
+		t.Errorf("Countries() has %d entries, want 249", n)
+	}
+	if n := len(Languages()); n != 184 {
+		t.Errorf("Languages() has %d entries, want 184", n)
+	}
+
+	seen := make(map[string]bool)
+	for _, u := range Units() {
+		if seen[u.Code] {
+			t.Errorf("duplicate unit %s", u.Code)
+		}
+		seen[u.Code] = true
+	}
+
+	list := Currencies()
+	list[0].Code = "changed"
+	if Currencies()[0].Code == "changed" {
+		t.Error("Currencies() returned the table itself")
+	}
+}
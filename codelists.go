@@ -0,0 +1,178 @@
+package isdoc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/xseman/isdoc/codelist"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// validateCodeLists checks currency, country and unit codes against the
+// embedded code lists, and that document totals have no more decimal places
+// than the minor unit of their currency allows. Line amounts may be unrounded.
+func validateCodeLists(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	// LocalCurrencyCode of the wrong length is reported by validateStructural
+	local := -1
+	if len(inv.LocalCurrencyCode) == 3 {
+		if c, ok := codelist.LookupCurrency(inv.LocalCurrencyCode); ok {
+			local = c.MinorUnits
+		} else {
+			errs = append(errs, unknownCode("Invoice.LocalCurrencyCode", "ISO 4217 currency", inv.LocalCurrencyCode, severity))
+		}
+	}
+	foreign := -1
+	if inv.ForeignCurrencyCode != "" {
+		if c, ok := codelist.LookupCurrency(inv.ForeignCurrencyCode); ok {
+			foreign = c.MinorUnits
+		} else {
+			errs = append(errs, unknownCode("Invoice.ForeignCurrencyCode", "ISO 4217 currency", inv.ForeignCurrencyCode, severity))
+		}
+	}
+
+	// The accounting parties are checked by validateParty
+	if p := inv.SellerSupplierParty; p != nil {
+		errs = append(errs, validateCountry("Invoice.SellerSupplierParty.Party", &p.Party, severity)...)
+	}
+	if p := inv.BuyerCustomerParty; p != nil {
+		errs = append(errs, validateCountry("Invoice.BuyerCustomerParty.Party", &p.Party, severity)...)
+	}
+	if p := inv.Delivery; p != nil {
+		errs = append(errs, validateCountry("Invoice.Delivery.Party", &p.Party, severity)...)
+	}
+
+	errs = append(errs, validatePrecision("Invoice.LegalMonetaryTotal", &inv.LegalMonetaryTotal, local, foreign, severity)...)
+	errs = append(errs, validatePrecision("Invoice.TaxTotal", &inv.TaxTotal, local, foreign, severity)...)
+	for i := range inv.TaxTotal.TaxSubTotal {
+		errs = append(errs, validatePrecision(fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d]", i),
+			&inv.TaxTotal.TaxSubTotal[i], local, foreign, severity)...)
+	}
+
+	for i := range inv.InvoiceLines.InvoiceLine {
+		line := &inv.InvoiceLines.InvoiceLine[i]
+		path := fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i)
+
+		errs = append(errs, validateUnit(path+".InvoicedQuantity", line.InvoicedQuantity.UnitCode, severity)...)
+		if line.Item.StoreBatches != nil {
+			for j, batch := range line.Item.StoreBatches.StoreBatch {
+				errs = append(errs, validateUnit(fmt.Sprintf("%s.Item.StoreBatches.StoreBatch[%d].Quantity", path, j),
+					batch.Quantity.UnitCode, severity)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+func unknownCode(field, list, code string, severity Severity) *ValidationError {
+	return &ValidationError{
+		Field:    field,
+		Code:     ErrCodeInvalidEnum,
+		Severity: severity,
+		Msg:      fmt.Sprintf("%q is not a known %s code", code, list),
+	}
+}
+
+// validateCountry checks the country code of a party's address, if any.
+func validateCountry(path string, party *schema.Party, severity Severity) ValidationErrors {
+	code := party.PostalAddress.Country.IdentificationCode
+	if code == "" {
+		return nil
+	}
+	if _, ok := codelist.LookupCountry(code); !ok {
+		return ValidationErrors{unknownCode(path+".PostalAddress.Country.IdentificationCode", "ISO 3166-1 country", code, severity)}
+	}
+	return nil
+}
+
+// unitSuggestions maps Czech unit abbreviations, compared in lower case,
+// to their Recommendation 20 codes.
+var unitSuggestions = map[string]string{
+	"ks": "H87", "kus": "H87", "bal": "XPK",
+	"g": "GRM", "kg": "KGM", "t": "TNE",
+	"l": "LTR", "m": "MTR", "m2": "MTK", "m3": "MTQ",
+	"h": "HUR", "hod": "HUR", "den": "DAY", "kwh": "KWH",
+}
+
+// validateUnit checks a unitCode against UN/ECE Recommendations 20 and 21.
+// The embedded Recommendation 20 table holds the units in common use, so
+// well-formed codes missing from it are accepted.
+func validateUnit(path, code string, severity Severity) ValidationErrors {
+	if code == "" || wellFormedUnit(code) {
+		return nil
+	}
+	e := unknownCode(path+".@unitCode", "UN/ECE Recommendation 20 unit", code, severity)
+	if s, ok := unitSuggestions[strings.ToLower(code)]; ok {
+		e.Msg += fmt.Sprintf(", use %q", s)
+	}
+	return ValidationErrors{e}
+}
+
+// wellFormedUnit reports whether code has the form of a Recommendation 20
+// or 21 code: two or three upper case letters or digits.
+func wellFormedUnit(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, c := range code {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validatePrecision checks the amount fields of the struct v points to:
+// *Curr amounts against the minor units of the foreign currency, the others
+// against those of the local currency. Negative minor units skip the check.
+func validatePrecision(path string, v any, local, foreign int, severity Severity) ValidationErrors {
+	var errs ValidationErrors
+	rv := reflect.ValueOf(v).Elem()
+	for i := range rv.NumField() {
+		f := rv.Type().Field(i)
+		if f.Type != decimalType || !strings.Contains(f.Name, "Amount") {
+			continue
+		}
+		minor := local
+		if strings.HasSuffix(f.Name, "Curr") {
+			minor = foreign
+		}
+		d := rv.Field(i).Interface().(types.Decimal)
+		if minor < 0 || decimalPlaces(d) <= minor {
+			continue
+		}
+		errs = append(errs, &ValidationError{
+			Field:    path + "." + f.Name,
+			Code:     ErrCodeInvalidDecimal,
+			Severity: severity,
+			Msg:      fmt.Sprintf("amount %s has more than the %d decimal places of its currency", d, minor),
+		})
+	}
+	return errs
+}
+
+// minorUnits returns the decimal places of amounts in a currency: its ISO
+// 4217 minor unit, or 2 for unknown codes and currencies without one.
+func minorUnits(code string) int {
+	if c, ok := codelist.LookupCurrency(code); ok && c.MinorUnits >= 0 {
+		return c.MinorUnits
+	}
+	return 2
+}
+
+// decimalPlaces returns the number of significant decimal places of d.
+func decimalPlaces(d types.Decimal) int {
+	_, frac, ok := strings.Cut(string(d), ".")
+	if !ok {
+		return 0
+	}
+	return len(strings.TrimRight(frac, "0"))
+}
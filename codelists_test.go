@@ -0,0 +1,126 @@
+package isdoc
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestValidateCodeLists(t *testing.T) {
+	if errs := validateCodeLists(createValidInvoice(), DefaultValidateOptions()); len(errs) > 0 {
+		t.Fatalf("valid invoice: %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(inv *schema.Invoice)
+		field  string
+		code   string
+	}{
+		{
+			name:   "unknown local currency",
+			mutate: func(inv *schema.Invoice) { inv.LocalCurrencyCode = "CZE" },
+			field:  "Invoice.LocalCurrencyCode",
+			code:   ErrCodeInvalidEnum,
+		},
+		{
+			name:   "unknown foreign currency",
+			mutate: func(inv *schema.Invoice) { inv.ForeignCurrencyCode = "EURO" },
+			field:  "Invoice.ForeignCurrencyCode",
+			code:   ErrCodeInvalidEnum,
+		},
+		{
+			name: "unknown delivery country",
+			mutate: func(inv *schema.Invoice) {
+				inv.Delivery = &schema.Delivery{Party: schema.Party{
+					PostalAddress: schema.PostalAddress{Country: schema.Country{IdentificationCode: "CZE"}},
+				}}
+			},
+			field: "Invoice.Delivery.Party.PostalAddress.Country.IdentificationCode",
+			code:  ErrCodeInvalidEnum,
+		},
+		{
+			name:   "unknown unit",
+			mutate: func(inv *schema.Invoice) { inv.InvoiceLines.InvoiceLine[0].InvoicedQuantity.UnitCode = "pcs" },
+			field:  "Invoice.InvoiceLines.InvoiceLine[0].InvoicedQuantity.@unitCode",
+			code:   ErrCodeInvalidEnum,
+		},
+		{
+			name: "amount precision",
+			mutate: func(inv *schema.Invoice) {
+				inv.LegalMonetaryTotal.PayableAmount = types.MustDecimal("1210.125")
+			},
+			field: "Invoice.LegalMonetaryTotal.PayableAmount",
+			code:  ErrCodeInvalidDecimal,
+		},
+		{
+			name: "foreign amount precision",
+			mutate: func(inv *schema.Invoice) {
+				inv.ForeignCurrencyCode = "JPY"
+				inv.TaxTotal.TaxSubTotal[0].TaxAmountCurr = types.MustDecimal("6500.50")
+			},
+			field: "Invoice.TaxTotal.TaxSubTotal[0].TaxAmountCurr",
+			code:  ErrCodeInvalidDecimal,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := createValidInvoice()
+			tc.mutate(inv)
+			errs := validateCodeLists(inv, DefaultValidateOptions())
+			i := slices.IndexFunc(errs, func(e *ValidationError) bool { return e.Field == tc.field })
+			if i < 0 {
+				t.Fatalf("no issue on %s, got %v", tc.field, errs)
+			}
+			if errs[i].Code != tc.code || errs[i].Severity != SeverityWarning {
+				t.Errorf("got %v, want warning %s", errs[i], tc.code)
+			}
+		})
+	}
+}
+
+func TestValidateCodeListsAccepted(t *testing.T) {
+	inv := createValidInvoice()
+	inv.ForeignCurrencyCode = "EUR"
+	inv.InvoiceLines.InvoiceLine[0].InvoicedQuantity.UnitCode = "XBX"
+	inv.InvoiceLines.InvoiceLine[0].LineExtensionAmount = types.MustDecimal("1000.125")
+	inv.LegalMonetaryTotal.PayableAmount = types.MustDecimal("1210.000")
+	if errs := validateCodeLists(inv, DefaultValidateOptions()); len(errs) > 0 {
+		t.Errorf("validateCodeLists() = %v", errs)
+	}
+}
+
+func TestValidateUnitSuggestion(t *testing.T) {
+	errs := validateUnit("Quantity", "Ks", SeverityError)
+	if len(errs) != 1 || !strings.HasSuffix(errs[0].Msg, `use "H87"`) || errs[0].Severity != SeverityError {
+		t.Errorf("validateUnit(Ks) = %v", errs)
+	}
+}
+
+func TestValidateUnitOutsideTable(t *testing.T) {
+	// HAR (hectare) is a Recommendation 20 code missing from the table
+	if errs := validateUnit("Quantity", "HAR", SeverityError); len(errs) != 0 {
+		t.Errorf("validateUnit(HAR) = %v", errs)
+	}
+	for _, code := range []string{"H", "kg", "KGMS", "K-G"} {
+		if errs := validateUnit("Quantity", code, SeverityError); len(errs) != 1 {
+			t.Errorf("validateUnit(%s) = %v", code, errs)
+		}
+	}
+}
+
+func TestValidatePartyCountry(t *testing.T) {
+	inv := createValidInvoice()
+	inv.AccountingSupplierParty.Party.PostalAddress.Country.IdentificationCode = "XX"
+	errs := ValidateInvoiceWithOptions(inv, ValidateOptions{Strict: true})
+	i := slices.IndexFunc(errs, func(e *ValidationError) bool {
+		return e.Field == "Invoice.AccountingSupplierParty.Party.PostalAddress.Country.IdentificationCode"
+	})
+	if i < 0 || errs[i].Code != ErrCodeInvalidEnum || errs[i].Severity != SeverityError {
+		t.Errorf("unknown supplier country not reported as error in strict mode: %v", errs)
+	}
+}
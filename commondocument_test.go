@@ -180,6 +180,75 @@ func TestValidateCommonDocumentMissingFields(t *testing.T) {
 	}
 }
 
+func TestValidateCommonDocumentSemantic(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*schema.CommonDocument)
+		field  string
+		code   string
+	}{
+		{
+			name:   "blank SubDocumentTypeOrigin",
+			modify: func(d *schema.CommonDocument) { d.SubDocumentTypeOrigin = " " },
+			field:  "CommonDocument.SubDocumentTypeOrigin",
+			code:   ErrCodeRequiredField,
+		},
+		{
+			name:   "malformed UUID",
+			modify: func(d *schema.CommonDocument) { d.UUID = "f47ac10b58cc4372a5670e02b2c3d479" },
+			field:  "CommonDocument.UUID",
+			code:   ErrCodeInvalidUUID,
+		},
+		{
+			name:   "valid before issue",
+			modify: func(d *schema.CommonDocument) { d.LastValidDate = types.MustParseDate("2025-01-14") },
+			field:  "CommonDocument.LastValidDate",
+			code:   ErrCodeInvalidDate,
+		},
+		{
+			name:   "IČO check digit",
+			modify: func(d *schema.CommonDocument) { d.AccountingSupplierParty.Party.PartyIdentification.ID = "12345678" },
+			field:  "CommonDocument.AccountingSupplierParty.Party.PartyIdentification.ID",
+			code:   ErrCodeInvalidPattern,
+		},
+		{
+			name: "DIČ check digit",
+			modify: func(d *schema.CommonDocument) {
+				d.AccountingCustomerParty.Party.PartyTaxScheme = []schema.PartyTaxScheme{{CompanyID: "CZ87654321", TaxScheme: "VAT"}}
+			},
+			field: "CommonDocument.AccountingCustomerParty.Party.PartyTaxScheme[0].CompanyID",
+			code:  ErrCodeInvalidPattern,
+		},
+		{
+			name:   "note language",
+			modify: func(d *schema.CommonDocument) { d.Note = &schema.Note{Value: "Smlouva", LanguageID: "cz"} },
+			field:  "CommonDocument.Note.@languageID",
+			code:   ErrCodeInvalidEnum,
+		},
+		{
+			name: "duplicate supplement",
+			modify: func(d *schema.CommonDocument) {
+				d.SupplementsList = &schema.SupplementsList{Supplement: []schema.Supplement{
+					{Filename: "smlouva.pdf"}, {Filename: "smlouva.pdf"},
+				}}
+			},
+			field: "CommonDocument.SupplementsList.Supplement[1].Filename",
+			code:  ErrCodeDuplicateID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := validCommonDocument()
+			tt.modify(doc)
+			errs := ValidateCommonDocumentWithOptions(doc, ValidateOptions{Strict: true})
+			if !hasIssue(errs.Errors(), tt.field, tt.code) {
+				t.Errorf("no %s error at %s: %v", tt.code, tt.field, errs)
+			}
+		})
+	}
+}
+
 func TestCommonDocumentValidation(t *testing.T) {
 	data, err := os.ReadFile("testdata/fixtures/sample-commondocument.isdoc")
 	if err != nil {
@@ -218,7 +287,7 @@ func validCommonDocument() *schema.CommonDocument {
 		IssueDate:             types.MustParseDate("2025-01-15"),
 		AccountingSupplierParty: schema.AccountingSupplierParty{
 			Party: schema.Party{
-				PartyIdentification: schema.PartyIdentification{ID: "12345678"},
+				PartyIdentification: schema.PartyIdentification{ID: "12345679"},
 				PartyName:           schema.PartyName{Name: "Supplier Corp"},
 				PostalAddress: schema.PostalAddress{
 					StreetName:     "Main St",
@@ -231,7 +300,7 @@ func validCommonDocument() *schema.CommonDocument {
 		},
 		AccountingCustomerParty: schema.AccountingCustomerParty{
 			Party: schema.Party{
-				PartyIdentification: schema.PartyIdentification{ID: "87654321"},
+				PartyIdentification: schema.PartyIdentification{ID: "87654326"},
 				PartyName:           schema.PartyName{Name: "Customer Corp"},
 				PostalAddress: schema.PostalAddress{
 					StreetName:     "Side St",
@@ -0,0 +1,208 @@
+package isdoc
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"path"
+	"strings"
+
+	"github.com/xseman/isdoc/archive"
+	"github.com/xseman/isdoc/schema"
+)
+
+// Digest algorithm URIs for Supplement.DigestMethod.
+const (
+	DigestSHA1   = "http://www.w3.org/2000/09/xmldsig#sha1"
+	DigestSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	DigestSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+// ErrUnsupportedDigest is returned by Digest for unknown algorithms.
+var ErrUnsupportedDigest = errors.New("unsupported digest algorithm")
+
+// Digest returns the base64-encoded digest of data, as stored in
+// Supplement.DigestValue. algorithm is one of the Digest* URIs.
+func Digest(algorithm string, data []byte) (string, error) {
+	sum, err := digestSum(algorithm, data)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+func digestSum(algorithm string, data []byte) ([]byte, error) {
+	var h hash.Hash
+	switch algorithm {
+	case DigestSHA1:
+		h = sha1.New()
+	case DigestSHA256:
+		h = sha256.New()
+	case DigestSHA512:
+		h = sha512.New()
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedDigest, algorithm)
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// ValidateArchive validates the container of an ISDOCX archive. doc is its
+// decoded main document, a *schema.Invoice or *schema.CommonDocument.
+//
+// A missing manifest.xml is reported as a warning, or an error in strict
+// mode. Supplements are checked with ValidateSupplements against the files
+// in the archive.
+func ValidateArchive(a *archive.Archive, doc any, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	if a.Manifest == nil {
+		errs = append(errs, &ValidationError{
+			Field:    archive.ManifestFilename,
+			Code:     ErrCodeMissingManifest,
+			Severity: severity,
+			Msg:      "archive has no manifest.xml",
+		})
+	}
+
+	errs = append(errs, ValidateSupplements(doc, a.Attachments, opts)...)
+	return errs
+}
+
+// ValidateSupplements checks that each Supplement of doc, a *schema.Invoice
+// or *schema.CommonDocument, is present in files and matches its digest.
+// files maps file names, as used in Supplement.Filename, to their content.
+func ValidateSupplements(doc any, files map[string][]byte, opts ValidateOptions) ValidationErrors {
+	root, list := supplements(doc)
+	if list == nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	for i, s := range list.Supplement {
+		p := fmt.Sprintf("%s.SupplementsList.Supplement[%d]", root, i)
+
+		data, ok := lookupSupplement(files, s.Filename)
+		if !ok {
+			errs = append(errs, &ValidationError{
+				Field:    p + ".Filename",
+				Code:     ErrCodeMissingSupplement,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("supplement %q not found in container", s.Filename),
+			})
+			continue
+		}
+
+		if s.DigestMethod == nil || s.DigestValue == "" {
+			continue
+		}
+
+		want, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s.DigestValue))
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field:    p + ".DigestValue",
+				Code:     ErrCodeInvalidPattern,
+				Severity: SeverityError,
+				Msg:      "DigestValue is not valid base64",
+			})
+			continue
+		}
+
+		got, err := digestSum(s.DigestMethod.Algorithm, data)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Field:    p + ".DigestMethod.@Algorithm",
+				Code:     ErrCodeInvalidEnum,
+				Severity: severity,
+				Msg:      fmt.Sprintf("unsupported digest algorithm %q", s.DigestMethod.Algorithm),
+			})
+			continue
+		}
+
+		if !bytes.Equal(got, want) {
+			errs = append(errs, &ValidationError{
+				Field:    p + ".DigestValue",
+				Code:     ErrCodeDigestMismatch,
+				Severity: SeverityError,
+				Msg: fmt.Sprintf("digest of %q does not match: got %s, want %s",
+					s.Filename, base64.StdEncoding.EncodeToString(got), s.DigestValue),
+			})
+		}
+	}
+
+	if opts.SourceMap != nil {
+		opts.SourceMap.Annotate(errs)
+	}
+
+	return errs
+}
+
+// SignSupplements sets the DigestValue of each Supplement of doc, a
+// *schema.Invoice or *schema.CommonDocument, to the digest of its file in
+// files, e.g. after the files were replaced. Supplements without a
+// DigestMethod get SHA-256. It returns an error naming the supplements not
+// found in files, which are left unchanged.
+func SignSupplements(doc any, files map[string][]byte) error {
+	_, list := supplements(doc)
+	if list == nil {
+		return nil
+	}
+	var missing []string
+	for i := range list.Supplement {
+		s := &list.Supplement[i]
+		data, ok := lookupSupplement(files, s.Filename)
+		if !ok {
+			missing = append(missing, s.Filename)
+			continue
+		}
+		if s.DigestMethod == nil {
+			s.DigestMethod = &schema.DigestMethod{Algorithm: DigestSHA256}
+		}
+		digest, err := Digest(s.DigestMethod.Algorithm, data)
+		if err != nil {
+			return fmt.Errorf("supplement %q: %w", s.Filename, err)
+		}
+		s.DigestValue = digest
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("supplements not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// supplements returns the root element name and the supplements of doc.
+func supplements(doc any) (string, *schema.SupplementsList) {
+	switch d := doc.(type) {
+	case *schema.Invoice:
+		return "Invoice", d.SupplementsList
+	case *schema.CommonDocument:
+		return "CommonDocument", d.SupplementsList
+	}
+	return "", nil
+}
+
+// lookupSupplement finds a supplement by file name. Windows path separators
+// and "./" prefixes in the name are normalized.
+func lookupSupplement(files map[string][]byte, name string) ([]byte, bool) {
+	if data, ok := files[name]; ok {
+		return data, true
+	}
+	data, ok := files[path.Clean(strings.ReplaceAll(name, "\\", "/"))]
+	return data, ok
+}
@@ -0,0 +1,131 @@
+package isdoc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/archive"
+	"github.com/xseman/isdoc/schema"
+)
+
+func TestDigest(t *testing.T) {
+	got, err := Digest(DigestSHA1, []byte("abc"))
+	if err != nil {
+		t.Fatalf("Digest failed: %v", err)
+	}
+	if want := "qZk+NkcGgWq6PiVxeFDCbJzQ2J0="; got != want {
+		t.Errorf("Digest = %s, want %s", got, want)
+	}
+
+	if _, err := Digest("urn:md5", nil); !errors.Is(err, ErrUnsupportedDigest) {
+		t.Errorf("Digest(md5): got %v, want ErrUnsupportedDigest", err)
+	}
+}
+
+func TestRootElement(t *testing.T) {
+	for _, name := range []string{"sample.isdoc", "sample-commondocument.isdoc"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "fixtures", name))
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+		root, err := RootElement(data)
+		if err != nil {
+			t.Fatalf("RootElement(%s) failed: %v", name, err)
+		}
+		want := "Invoice"
+		if name == "sample-commondocument.isdoc" {
+			want = "CommonDocument"
+		}
+		if root != want {
+			t.Errorf("RootElement(%s) = %q, want %q", name, root, want)
+		}
+	}
+
+	if _, err := RootElement([]byte("<!-- empty -->")); err == nil {
+		t.Error("RootElement without element should fail")
+	}
+}
+
+func TestValidateArchive(t *testing.T) {
+	logo := []byte("logo")
+	terms := []byte("terms")
+	logoDigest, _ := Digest(DigestSHA1, logo)
+	method := &schema.DigestMethod{Algorithm: DigestSHA1}
+
+	invoice := createValidInvoice()
+	invoice.SupplementsList = &schema.SupplementsList{Supplement: []schema.Supplement{
+		{Filename: "Logo.bmp", DigestMethod: method, DigestValue: logoDigest},
+		{Filename: `docs\terms.txt`, DigestMethod: method, DigestValue: logoDigest},
+		{Filename: "missing.pdf", DigestMethod: method, DigestValue: logoDigest},
+	}}
+
+	arc := archive.NewArchive([]byte("<Invoice/>"), "invoice.isdoc")
+	arc.AddAttachment("Logo.bmp", logo)
+	arc.AddAttachment("docs/terms.txt", terms)
+
+	codes := func(errs ValidationErrors) map[string]string {
+		m := make(map[string]string)
+		for _, e := range errs {
+			m[e.Field] = e.Code
+		}
+		return m
+	}
+
+	got := codes(ValidateArchive(arc, invoice, DefaultValidateOptions()))
+	want := map[string]string{
+		"manifest.xml": ErrCodeMissingManifest,
+		"Invoice.SupplementsList.Supplement[1].DigestValue": ErrCodeDigestMismatch,
+		"Invoice.SupplementsList.Supplement[2].Filename":    ErrCodeMissingSupplement,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got errors %v, want %v", got, want)
+	}
+	for field, code := range want {
+		if got[field] != code {
+			t.Errorf("%s: code = %q, want %q", field, got[field], code)
+		}
+	}
+
+	// Archives written by this package have a manifest
+	data, err := arc.WriteBytes()
+	if err != nil {
+		t.Fatalf("WriteBytes failed: %v", err)
+	}
+	arc, err = archive.ReadBytes(data)
+	if err != nil {
+		t.Fatalf("ReadBytes failed: %v", err)
+	}
+	if _, ok := codes(ValidateArchive(arc, invoice, DefaultValidateOptions()))["manifest.xml"]; ok {
+		t.Error("unexpected manifest error")
+	}
+}
+
+func TestSignSupplements(t *testing.T) {
+	doc := validCommonDocument()
+	doc.SupplementsList = &schema.SupplementsList{Supplement: []schema.Supplement{
+		{Filename: "smlouva.pdf", DigestMethod: &schema.DigestMethod{Algorithm: DigestSHA1}, DigestValue: "stale"},
+		{Filename: "priloha.txt"},
+		{Filename: "chybi.pdf", DigestValue: "kept"},
+	}}
+	files := map[string][]byte{"smlouva.pdf": []byte("redacted"), "priloha.txt": []byte("redacted")}
+
+	err := SignSupplements(doc, files)
+	if err == nil || !strings.Contains(err.Error(), "chybi.pdf") {
+		t.Errorf("SignSupplements() error = %v, want missing chybi.pdf", err)
+	}
+	list := doc.SupplementsList.Supplement
+	if list[1].DigestMethod == nil || list[1].DigestMethod.Algorithm != DigestSHA256 {
+		t.Errorf("DigestMethod = %+v, want SHA-256", list[1].DigestMethod)
+	}
+	if list[2].DigestValue != "kept" {
+		t.Errorf("DigestValue of a missing file = %q", list[2].DigestValue)
+	}
+	delete(files, "chybi.pdf")
+	doc.SupplementsList.Supplement = list[:2]
+	if errs := ValidateSupplements(doc, files, ValidateOptions{Strict: true}); len(errs) > 0 {
+		t.Errorf("ValidateSupplements() after signing = %v", errs)
+	}
+}
@@ -0,0 +1,247 @@
+package isdoc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// ErrNotCorrectable is returned by NewCreditNote and NewDebitNote for
+// originals that are not tax documents: advance invoices and corrective
+// documents themselves.
+var ErrNotCorrectable = errors.New("document type cannot be corrected")
+
+// originalRef is the id linking corrective lines to the original document.
+const originalRef = "original"
+
+// LineCorrection selects a line of the original invoice for NewCreditNote
+// or NewDebitNote.
+//
+// Without UnitPrice the line is returned (credit note) or delivered again
+// (debit note) at its original price. With UnitPrice, the price of Quantity
+// units is lowered (credit note) or raised (debit note) by UnitPrice. For
+// lines with VATCalculationMethod 1 the prices are tax-inclusive.
+type LineCorrection struct {
+	// LineID is the ID of the original invoice line.
+	LineID string
+	// Quantity is the quantity corrected, without sign. Empty means the
+	// whole invoiced quantity.
+	Quantity types.Decimal
+	// UnitPrice is the change of the unit price, without sign. Empty means
+	// the original unit price.
+	UnitPrice types.Decimal
+}
+
+// NewCreditNote returns a credit note correcting original: DocumentType 2,
+// or 6 for a tax document for a received payment. Without corrections all
+// lines are returned in full.
+//
+// The credit note copies the parties, currency and VAT regime of original,
+// references it in OriginalDocumentReferences and each line, and carries
+// negative amounts computed by ComputeTotals. Its UUID is new, IssueDate
+// and TaxPointDate are today; ID is left for the caller to assign.
+// Foreign currency amounts are derived from the local ones through
+// CurrRate and RefCurrRate.
+func NewCreditNote(original *schema.Invoice, corrections []LineCorrection) (*schema.Invoice, error) {
+	docType := types.DocumentTypeCreditNote
+	if original.DocumentType == types.DocumentTypeAdvanceTaxDocument {
+		docType = types.DocumentTypeAdvanceCreditNote
+	}
+	return newCorrective(original, corrections, docType, -1)
+}
+
+// NewDebitNote returns a debit note (DocumentType 3) correcting original,
+// as NewCreditNote does, with positive amounts. Without corrections all
+// lines are charged again in full.
+func NewDebitNote(original *schema.Invoice, corrections []LineCorrection) (*schema.Invoice, error) {
+	if original.DocumentType == types.DocumentTypeAdvanceTaxDocument {
+		return nil, fmt.Errorf("%w: no debit note exists for DocumentType %d", ErrNotCorrectable, original.DocumentType)
+	}
+	return newCorrective(original, corrections, types.DocumentTypeDebitNote, 1)
+}
+
+func newCorrective(original *schema.Invoice, corrections []LineCorrection, docType types.DocumentType, sign int) (*schema.Invoice, error) {
+	switch original.DocumentType {
+	case types.DocumentTypeInvoice, types.DocumentTypeAdvanceTaxDocument, types.DocumentTypeSimplified:
+	default:
+		return nil, fmt.Errorf("%w: DocumentType %d", ErrNotCorrectable, original.DocumentType)
+	}
+
+	today := types.NewDate(time.Now())
+	inv := &schema.Invoice{
+		Version:                                 schema.Version,
+		DocumentType:                            docType,
+		UUID:                                    types.NewRandomUUID(),
+		IssueDate:                               today,
+		TaxPointDate:                            today,
+		VATApplicable:                           original.VATApplicable,
+		ElectronicPossibilityAgreementReference: original.ElectronicPossibilityAgreementReference,
+		LocalCurrencyCode:                       original.LocalCurrencyCode,
+		ForeignCurrencyCode:                     original.ForeignCurrencyCode,
+		CurrRate:                                original.CurrRate,
+		RefCurrRate:                             original.RefCurrRate,
+		AccountingSupplierParty:                 clone(original.AccountingSupplierParty),
+		SellerSupplierParty:                     clone(original.SellerSupplierParty),
+		AnonymousCustomerParty:                  clone(original.AnonymousCustomerParty),
+		AccountingCustomerParty:                 clone(original.AccountingCustomerParty),
+		BuyerCustomerParty:                      clone(original.BuyerCustomerParty),
+		OriginalDocumentReferences: &schema.OriginalDocumentReferences{
+			OriginalDocumentReference: []schema.OriginalDocumentReference{{
+				ID:                 originalRef,
+				OriginalDocumentID: original.ID,
+				IssueDate:          original.IssueDate,
+				UUID:               original.UUID,
+			}},
+		},
+	}
+
+	if len(corrections) == 0 {
+		for _, line := range original.InvoiceLines.InvoiceLine {
+			corrections = append(corrections, LineCorrection{LineID: line.ID})
+		}
+	}
+	for i, c := range corrections {
+		line, err := correctLine(original, c, sign)
+		if err != nil {
+			return nil, fmt.Errorf("correction %d: %w", i, err)
+		}
+		line.ID = fmt.Sprint(i + 1)
+		inv.InvoiceLines.InvoiceLine = append(inv.InvoiceLines.InvoiceLine, line)
+	}
+
+	if err := ComputeTotals(inv); err != nil {
+		return nil, err
+	}
+	if inv.ForeignCurrencyCode != "" {
+		if err := FillForeignAmounts(inv, nil); err != nil {
+			return nil, err
+		}
+	}
+	return inv, nil
+}
+
+// correctLine returns the corrective line for c, with a quantity or a unit
+// price of the given sign and without amounts.
+func correctLine(original *schema.Invoice, c LineCorrection, sign int) (schema.InvoiceLine, error) {
+	var orig *schema.InvoiceLine
+	for i := range original.InvoiceLines.InvoiceLine {
+		if original.InvoiceLines.InvoiceLine[i].ID == c.LineID {
+			orig = &original.InvoiceLines.InvoiceLine[i]
+			break
+		}
+	}
+	if orig == nil {
+		return schema.InvoiceLine{}, fmt.Errorf("original invoice has no line %q", c.LineID)
+	}
+
+	// A missing quantity counts as 1, as in ComputeTotals
+	origQty := big.NewRat(1, 1)
+	if !orig.InvoicedQuantity.Value.IsZero() {
+		origQty = parseAmount(orig.InvoicedQuantity.Value)
+	}
+	if origQty.Sign() == 0 {
+		return schema.InvoiceLine{}, fmt.Errorf("line %q has a zero quantity", c.LineID)
+	}
+	qty := new(big.Rat).Abs(origQty)
+	places := decimalPlaces(orig.InvoicedQuantity.Value)
+	if !c.Quantity.IsZero() {
+		q, ok := parseDecimal(c.Quantity)
+		if !ok || q.Sign() <= 0 {
+			return schema.InvoiceLine{}, fmt.Errorf("line %q: invalid quantity %q", c.LineID, c.Quantity)
+		}
+		if q.Cmp(qty) > 0 {
+			return schema.InvoiceLine{}, fmt.Errorf("line %q: quantity %s exceeds the invoiced %s", c.LineID, c.Quantity, orig.InvoicedQuantity.Value)
+		}
+		qty, places = q, max(places, decimalPlaces(c.Quantity))
+	}
+	if origQty.Sign() < 0 {
+		qty.Neg(qty)
+	}
+
+	fromTop := orig.ClassifiedTaxCategory.VATCalculationMethod == types.VATCalculationFromTop
+	origPrice, origAmount := orig.UnitPrice, orig.LineExtensionAmount
+	if fromTop {
+		origPrice, origAmount = orig.UnitPriceTaxInclusive, orig.LineExtensionAmountTaxInclusive
+	}
+	var price *big.Rat
+	if c.UnitPrice.IsZero() {
+		// A return undoes part of the original line, so the quantity takes
+		// the sign
+		price = parseAmount(origPrice)
+		if price.Sign() == 0 {
+			price = new(big.Rat).Quo(parseAmount(origAmount), origQty)
+		}
+		qty.Mul(qty, big.NewRat(int64(sign), 1))
+	} else {
+		p, ok := parseDecimal(c.UnitPrice)
+		if !ok || p.Sign() <= 0 {
+			return schema.InvoiceLine{}, fmt.Errorf("line %q: invalid unit price %q", c.LineID, c.UnitPrice)
+		}
+		price = p.Mul(p, big.NewRat(int64(sign), 1))
+	}
+
+	line := schema.InvoiceLine{
+		OriginalDocumentReference: &schema.OriginalDocumentLineReference{Ref: originalRef, LineID: orig.ID},
+		InvoicedQuantity: schema.Quantity{
+			Value:    types.Decimal(qty.FloatString(places)),
+			UnitCode: orig.InvoicedQuantity.UnitCode,
+		},
+		ClassifiedTaxCategory: clone(orig.ClassifiedTaxCategory),
+		Item:                  clone(orig.Item),
+	}
+	if fromTop {
+		line.UnitPriceTaxInclusive = formatPrice(price)
+	} else {
+		line.UnitPrice = formatPrice(price)
+	}
+
+	// Batch quantities would no longer add up to the corrected quantity
+	line.Item.StoreBatches = nil
+	if rc := line.ClassifiedTaxCategory.LocalReverseCharge; rc != nil && !rc.LocalReverseChargeQuantity.IsZero() {
+		ratio := new(big.Rat).Quo(qty, origQty)
+		scaled := ratio.Mul(ratio, parseAmount(rc.LocalReverseChargeQuantity))
+		rc.LocalReverseChargeQuantity = types.Decimal(scaled.FloatString(max(places, decimalPlaces(rc.LocalReverseChargeQuantity))))
+	}
+	return line, nil
+}
+
+// clone returns a deep copy of v.
+func clone[T any](v T) T {
+	return deepCopy(reflect.ValueOf(v)).Interface().(T)
+}
+
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := range v.NumField() {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,212 @@
+package isdoc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// correctableInvoice returns an invoice with three pieces at 100 and a line
+// priced from top at 1210, both at 21 %.
+func correctableInvoice(t *testing.T) *schema.Invoice {
+	t.Helper()
+	inv := createValidInvoice()
+	inv.InvoiceLines.InvoiceLine[0].InvoicedQuantity = schema.Quantity{Value: "3", UnitCode: "H87"}
+	inv.InvoiceLines.InvoiceLine[0].UnitPrice = "100.00"
+	top := inv.InvoiceLines.InvoiceLine[0]
+	top.ID = "2"
+	top.InvoicedQuantity = schema.Quantity{Value: "1"}
+	top.UnitPrice = ""
+	top.UnitPriceTaxInclusive = "1210.00"
+	top.ClassifiedTaxCategory.VATCalculationMethod = types.VATCalculationFromTop
+	inv.InvoiceLines.InvoiceLine = append(inv.InvoiceLines.InvoiceLine, top)
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	return inv
+}
+
+func TestNewCreditNote(t *testing.T) {
+	original := correctableInvoice(t)
+	cn, err := NewCreditNote(original, nil)
+	if err != nil {
+		t.Fatalf("NewCreditNote() error = %v", err)
+	}
+	cn.ID = "CN-001"
+
+	if cn.DocumentType != types.DocumentTypeCreditNote || cn.UUID == original.UUID {
+		t.Errorf("DocumentType = %d, UUID = %s", cn.DocumentType, cn.UUID)
+	}
+	ref := cn.OriginalDocumentReferences.OriginalDocumentReference[0]
+	if ref.OriginalDocumentID != original.ID || ref.UUID != original.UUID || ref.IssueDate != original.IssueDate {
+		t.Errorf("OriginalDocumentReference = %+v", ref)
+	}
+	for i, line := range cn.InvoiceLines.InvoiceLine {
+		want := original.InvoiceLines.InvoiceLine[i]
+		if r := line.OriginalDocumentReference; r == nil || r.Ref != ref.ID || r.LineID != want.ID {
+			t.Errorf("line %d: OriginalDocumentReference = %+v", i, r)
+		}
+		if got := parseAmount(line.LineExtensionAmount); got.Cmp(parseAmount(want.LineExtensionAmount).Neg(parseAmount(want.LineExtensionAmount))) != 0 {
+			t.Errorf("line %d: LineExtensionAmount = %s, want -%s", i, line.LineExtensionAmount, want.LineExtensionAmount)
+		}
+	}
+	if cn.InvoiceLines.InvoiceLine[0].InvoicedQuantity.Value != "-3" {
+		t.Errorf("InvoicedQuantity = %s, want -3", cn.InvoiceLines.InvoiceLine[0].InvoicedQuantity.Value)
+	}
+	if got, want := cn.LegalMonetaryTotal.PayableAmount, "-"+original.LegalMonetaryTotal.PayableAmount; got != want {
+		t.Errorf("PayableAmount = %s, want %s", got, want)
+	}
+
+	if errs := ValidateInvoice(cn); errs.HasErrors() {
+		t.Errorf("ValidateInvoice() = %v", errs)
+	}
+	data, err := EncodeBytes(cn)
+	if err != nil {
+		t.Fatalf("EncodeBytes() error = %v", err)
+	}
+	if _, err := DecodeBytes(data); err != nil {
+		t.Errorf("DecodeBytes() error = %v", err)
+	}
+
+	cn.AccountingSupplierParty.Party.PartyName.Name = "Changed"
+	if original.AccountingSupplierParty.Party.PartyName.Name == "Changed" {
+		t.Error("credit note shares the supplier party with the original")
+	}
+}
+
+func TestLineCorrections(t *testing.T) {
+	tests := []struct {
+		name       string
+		debit      bool
+		correction LineCorrection
+		quantity   types.Decimal
+		amount     types.Decimal
+		inclusive  types.Decimal
+	}{
+		{
+			name:       "partial return",
+			correction: LineCorrection{LineID: "1", Quantity: "1"},
+			quantity:   "-1",
+			amount:     "-100.00",
+			inclusive:  "-121.00",
+		},
+		{
+			name:       "fractional return",
+			correction: LineCorrection{LineID: "1", Quantity: "0.5"},
+			quantity:   "-0.5",
+			amount:     "-50.00",
+			inclusive:  "-60.50",
+		},
+		{
+			name:       "discount",
+			correction: LineCorrection{LineID: "1", UnitPrice: "10"},
+			quantity:   "3",
+			amount:     "-30.00",
+			inclusive:  "-36.30",
+		},
+		{
+			name:       "discount from top",
+			correction: LineCorrection{LineID: "2", UnitPrice: "121"},
+			quantity:   "1",
+			amount:     "-100.00",
+			inclusive:  "-121.00",
+		},
+		{
+			name:       "surcharge",
+			debit:      true,
+			correction: LineCorrection{LineID: "1", Quantity: "2", UnitPrice: "5"},
+			quantity:   "2",
+			amount:     "10.00",
+			inclusive:  "12.10",
+		},
+		{
+			name:       "additional delivery",
+			debit:      true,
+			correction: LineCorrection{LineID: "2"},
+			quantity:   "1",
+			amount:     "1000.00",
+			inclusive:  "1210.00",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			newDoc := NewCreditNote
+			if tc.debit {
+				newDoc = NewDebitNote
+			}
+			doc, err := newDoc(correctableInvoice(t), []LineCorrection{tc.correction})
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			line := doc.InvoiceLines.InvoiceLine[0]
+			if line.InvoicedQuantity.Value != tc.quantity || line.LineExtensionAmount != tc.amount || line.LineExtensionAmountTaxInclusive != tc.inclusive {
+				t.Errorf("line = %s × %s, %s / %s, want %s, %s / %s", line.InvoicedQuantity.Value, line.UnitPrice,
+					line.LineExtensionAmount, line.LineExtensionAmountTaxInclusive, tc.quantity, tc.amount, tc.inclusive)
+			}
+			if doc.LegalMonetaryTotal.TaxInclusiveAmount != tc.inclusive {
+				t.Errorf("TaxInclusiveAmount = %s, want %s", doc.LegalMonetaryTotal.TaxInclusiveAmount, tc.inclusive)
+			}
+			doc.ID = "DOC-1"
+			if errs := validateOriginalDocumentLink(doc, DefaultValidateOptions()); len(errs) > 0 {
+				t.Errorf("validateOriginalDocumentLink() = %v", errs)
+			}
+		})
+	}
+}
+
+func TestCorrectiveErrors(t *testing.T) {
+	original := correctableInvoice(t)
+	if _, err := NewCreditNote(original, []LineCorrection{{LineID: "9"}}); err == nil {
+		t.Error("unknown line accepted")
+	}
+	if _, err := NewCreditNote(original, []LineCorrection{{LineID: "1", Quantity: "4"}}); err == nil {
+		t.Error("quantity above the invoiced one accepted")
+	}
+	if _, err := NewDebitNote(original, []LineCorrection{{LineID: "1", UnitPrice: "-1"}}); err == nil {
+		t.Error("negative unit price accepted")
+	}
+	if _, err := NewCreditNote(original, []LineCorrection{{LineID: "1", Quantity: "1/2"}}); err == nil {
+		t.Error("fractional quantity syntax accepted")
+	}
+	if _, err := NewDebitNote(original, []LineCorrection{{LineID: "1", UnitPrice: "1e2"}}); err == nil {
+		t.Error("exponent unit price accepted")
+	}
+
+	original.DocumentType = types.DocumentTypeCreditNote
+	if _, err := NewCreditNote(original, nil); !errors.Is(err, ErrNotCorrectable) {
+		t.Errorf("credit note of a credit note: %v", err)
+	}
+	original.DocumentType = types.DocumentTypeAdvanceTaxDocument
+	if _, err := NewDebitNote(original, nil); !errors.Is(err, ErrNotCorrectable) {
+		t.Errorf("debit note of an advance tax document: %v", err)
+	}
+	cn, err := NewCreditNote(original, nil)
+	if err != nil || cn.DocumentType != types.DocumentTypeAdvanceCreditNote {
+		t.Errorf("credit note of an advance tax document: %v, %v", cn, err)
+	}
+}
+
+func TestCreditNoteForeignCurrency(t *testing.T) {
+	original := correctableInvoice(t)
+	original.ForeignCurrencyCode = "EUR"
+	original.CurrRate = "25"
+	original.RefCurrRate = "1"
+
+	cn, err := NewCreditNote(original, []LineCorrection{{LineID: "1", Quantity: "1"}})
+	if err != nil {
+		t.Fatalf("NewCreditNote() error = %v", err)
+	}
+	if got := cn.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr; got != "-4.00" {
+		t.Errorf("LineExtensionAmountCurr = %s, want -4.00", got)
+	}
+	if got := cn.LegalMonetaryTotal.PayableAmountCurr; got != "-4.84" {
+		t.Errorf("PayableAmountCurr = %s, want -4.84", got)
+	}
+	cn.ID = "CN-002"
+	if errs := validateCurrencyConsistency(cn, DefaultValidateOptions()); len(errs) > 0 {
+		t.Errorf("validateCurrencyConsistency() = %v", errs)
+	}
+}
@@ -0,0 +1,210 @@
+package isdoc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// RateSource provides exchange rates for invoices in a foreign currency,
+// e.g. the daily table of the Czech National Bank read by package cnb.
+type RateSource interface {
+	// Rate returns the rate of currency valid on date as CurrRate and
+	// RefCurrRate: amount units of currency are worth rate units of local.
+	Rate(currency, local string, date types.Date) (rate, amount types.Decimal, err error)
+}
+
+// ErrNoForeignCurrency is returned by the currency conversions for
+// invoices without ForeignCurrencyCode.
+var ErrNoForeignCurrency = errors.New("invoice has no ForeignCurrencyCode")
+
+// ApplyRate sets CurrRate and RefCurrRate of an invoice in a foreign
+// currency from src, at TaxPointDate or, without one, at IssueDate.
+func ApplyRate(inv *schema.Invoice, src RateSource) error {
+	if inv.ForeignCurrencyCode == "" {
+		return ErrNoForeignCurrency
+	}
+	date := inv.TaxPointDate
+	if date.IsZero() {
+		date = inv.IssueDate
+	}
+	rate, amount, err := src.Rate(inv.ForeignCurrencyCode, inv.LocalCurrencyCode, date)
+	if err != nil {
+		return err
+	}
+	inv.CurrRate, inv.RefCurrRate = rate, amount
+	return nil
+}
+
+// FillForeignAmounts sets every *Curr amount of lines, tax totals, deposits
+// and LegalMonetaryTotal from its local counterpart: CurrRate local units
+// are worth RefCurrRate foreign units. With src, the rates are first set
+// by ApplyRate. Amounts are rounded to the minor unit of the currency, two
+// decimal places for unknown ones; empty local amounts are skipped.
+func FillForeignAmounts(inv *schema.Invoice, src RateSource) error {
+	rate, err := conversionRate(inv, src)
+	if err != nil {
+		return err
+	}
+	rate.Inv(rate)
+	prec := minorUnits(inv.ForeignCurrencyCode)
+	for _, h := range currAmounts(inv) {
+		convertAmounts(h.v, rate, prec, true)
+	}
+	return nil
+}
+
+// FillLocalAmounts sets every local amount that has a *Curr counterpart
+// from it, the reverse of FillForeignAmounts.
+func FillLocalAmounts(inv *schema.Invoice, src RateSource) error {
+	rate, err := conversionRate(inv, src)
+	if err != nil {
+		return err
+	}
+	prec := minorUnits(inv.LocalCurrencyCode)
+	for _, h := range currAmounts(inv) {
+		convertAmounts(h.v, rate, prec, false)
+	}
+	return nil
+}
+
+// conversionRate returns the local value of one foreign currency unit.
+func conversionRate(inv *schema.Invoice, src RateSource) (*big.Rat, error) {
+	if inv.ForeignCurrencyCode == "" {
+		return nil, ErrNoForeignCurrency
+	}
+	if src != nil {
+		if err := ApplyRate(inv, src); err != nil {
+			return nil, err
+		}
+	}
+	rate, err := ratFromDecimal(inv.CurrRate, "Invoice.CurrRate")
+	if err != nil {
+		return nil, err
+	}
+	ref, err := ratFromDecimal(inv.RefCurrRate, "Invoice.RefCurrRate")
+	if err != nil {
+		return nil, err
+	}
+	if rate.Sign() <= 0 || ref.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid exchange rate %s/%s", inv.CurrRate, inv.RefCurrRate)
+	}
+	return rate.Quo(rate, ref), nil
+}
+
+// amountHolder is a struct with amount pairs in local and foreign currency.
+type amountHolder struct {
+	path string
+	v    any
+}
+
+// currAmounts returns the parts of inv with *Curr amounts.
+func currAmounts(inv *schema.Invoice) []amountHolder {
+	var hs []amountHolder
+	for i := range inv.InvoiceLines.InvoiceLine {
+		hs = append(hs, amountHolder{fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i), &inv.InvoiceLines.InvoiceLine[i]})
+	}
+	if d := inv.NonTaxedDeposits; d != nil {
+		for i := range d.NonTaxedDeposit {
+			hs = append(hs, amountHolder{fmt.Sprintf("Invoice.NonTaxedDeposits.NonTaxedDeposit[%d]", i), &d.NonTaxedDeposit[i]})
+		}
+	}
+	if d := inv.TaxedDeposits; d != nil {
+		for i := range d.TaxedDeposit {
+			hs = append(hs, amountHolder{fmt.Sprintf("Invoice.TaxedDeposits.TaxedDeposit[%d]", i), &d.TaxedDeposit[i]})
+		}
+	}
+	for i := range inv.TaxTotal.TaxSubTotal {
+		hs = append(hs, amountHolder{fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d]", i), &inv.TaxTotal.TaxSubTotal[i]})
+	}
+	hs = append(hs, amountHolder{"Invoice.TaxTotal", &inv.TaxTotal})
+	hs = append(hs, amountHolder{"Invoice.LegalMonetaryTotal", &inv.LegalMonetaryTotal})
+	return hs
+}
+
+// currPairs calls fn for each *Curr amount of the struct v points to and
+// the amount of the same name without the suffix.
+func currPairs(v any, fn func(name string, local, foreign reflect.Value)) {
+	rv := reflect.ValueOf(v).Elem()
+	for i := range rv.NumField() {
+		f := rv.Type().Field(i)
+		name, ok := strings.CutSuffix(f.Name, "Curr")
+		if !ok || f.Type != decimalType {
+			continue
+		}
+		if local := rv.FieldByName(name); local.IsValid() {
+			fn(name, local, rv.Field(i))
+		}
+	}
+}
+
+// convertAmounts sets the *Curr amounts of the struct v points to from
+// the local ones multiplied by rate, or with toForeign false the reverse,
+// rounded to prec decimal places.
+func convertAmounts(v any, rate *big.Rat, prec int, toForeign bool) {
+	currPairs(v, func(_ string, local, foreign reflect.Value) {
+		from, to := foreign, local
+		if toForeign {
+			from, to = local, foreign
+		}
+		if from.String() == "" {
+			return
+		}
+		amount := new(big.Rat).Mul(parseAmount(types.Decimal(from.String())), rate)
+		to.SetString(string(formatAmount(roundAmount(amount, prec), prec)))
+	})
+}
+
+// validateCurrencyAmounts checks that each *Curr amount matches its local
+// counterpart through CurrRate and RefCurrRate. As either amount may be
+// the rounded conversion of the other, a pair matches when either amount
+// is within the tolerance of the other one converted and rounded.
+func validateCurrencyAmounts(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
+	if inv.ForeignCurrencyCode == "" {
+		return nil
+	}
+	rate, ref := parseAmount(inv.CurrRate), parseAmount(inv.RefCurrRate)
+	if rate.Sign() <= 0 || ref.Sign() <= 0 {
+		return nil
+	}
+	rate.Quo(rate, ref)
+
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+	tol := opts.tolerance()
+	localPrec, foreignPrec := minorUnits(inv.LocalCurrencyCode), minorUnits(inv.ForeignCurrencyCode)
+	within := func(got, want *big.Rat, prec int) bool {
+		d := new(big.Rat).Sub(got, roundAmount(want, prec))
+		return d.Abs(d).Cmp(tol) <= 0
+	}
+
+	for _, h := range currAmounts(inv) {
+		currPairs(h.v, func(name string, local, foreign reflect.Value) {
+			if local.String() == "" || foreign.String() == "" {
+				return
+			}
+			l, f := parseAmount(types.Decimal(local.String())), parseAmount(types.Decimal(foreign.String()))
+			want := new(big.Rat).Mul(f, rate)
+			if within(l, want, localPrec) || within(f, new(big.Rat).Quo(l, rate), foreignPrec) {
+				return
+			}
+			errs = append(errs, &ValidationError{
+				Field:    h.path + "." + name + "Curr",
+				Code:     ErrCodeTotalMismatch,
+				Severity: severity,
+				Msg: fmt.Sprintf("%s %s at rate %s/%s is %s %s, but %s is %s",
+					foreign, inv.ForeignCurrencyCode, inv.CurrRate, inv.RefCurrRate,
+					formatAmount(roundAmount(want, localPrec), localPrec), inv.LocalCurrencyCode, name, local),
+			})
+		})
+	}
+	return errs
+}
@@ -0,0 +1,163 @@
+package isdoc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// fixedRates is a RateSource with rates per currency, valid on any date.
+type fixedRates map[string][2]types.Decimal
+
+func (r fixedRates) Rate(currency, local string, date types.Date) (rate, amount types.Decimal, err error) {
+	q, ok := r[currency]
+	if !ok || local != "CZK" {
+		return "", "", errors.New("no rate")
+	}
+	return q[0], q[1], nil
+}
+
+var testRates = fixedRates{"EUR": {"24.320", "1"}, "JPY": {"13.846", "100"}}
+
+// foreignInvoice returns the valid invoice of 1000 CZK at 21 % in currency.
+func foreignInvoice(t *testing.T, currency string) *schema.Invoice {
+	t.Helper()
+	inv := createValidInvoice()
+	inv.ForeignCurrencyCode = currency
+	inv.TaxPointDate = types.MustParseDate("2025-10-17")
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	return inv
+}
+
+func TestFillForeignAmounts(t *testing.T) {
+	tests := []struct {
+		currency            string
+		rate, ref           types.Decimal
+		net, tax, inclusive types.Decimal
+	}{
+		{"EUR", "24.320", "1", "41.12", "8.63", "49.75"},
+		{"JPY", "13.846", "100", "7222", "1517", "8739"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.currency, func(t *testing.T) {
+			inv := foreignInvoice(t, tc.currency)
+			if err := FillForeignAmounts(inv, testRates); err != nil {
+				t.Fatalf("FillForeignAmounts() error = %v", err)
+			}
+			if inv.CurrRate != tc.rate || inv.RefCurrRate != tc.ref {
+				t.Errorf("rate = %s/%s, want %s/%s", inv.CurrRate, inv.RefCurrRate, tc.rate, tc.ref)
+			}
+			st := inv.TaxTotal.TaxSubTotal[0]
+			if st.TaxableAmountCurr != tc.net || st.TaxAmountCurr != tc.tax || st.TaxInclusiveAmountCurr != tc.inclusive {
+				t.Errorf("TaxSubTotal = %s / %s / %s, want %s / %s / %s", st.TaxableAmountCurr, st.TaxAmountCurr,
+					st.TaxInclusiveAmountCurr, tc.net, tc.tax, tc.inclusive)
+			}
+			if got := inv.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr; got != tc.net {
+				t.Errorf("LineExtensionAmountCurr = %s, want %s", got, tc.net)
+			}
+			if got := inv.LegalMonetaryTotal.PayableAmountCurr; got != tc.inclusive {
+				t.Errorf("PayableAmountCurr = %s, want %s", got, tc.inclusive)
+			}
+			if errs := validateCurrencyAmounts(inv, ValidateOptions{Strict: true}); len(errs) > 0 {
+				t.Errorf("validateCurrencyAmounts() = %v", errs)
+			}
+			if errs := validateCodeLists(inv, ValidateOptions{Strict: true}); len(errs) > 0 {
+				t.Errorf("validateCodeLists() = %v", errs)
+			}
+		})
+	}
+}
+
+func TestFillLocalAmounts(t *testing.T) {
+	inv := foreignInvoice(t, "EUR")
+	inv.CurrRate, inv.RefCurrRate = "25", "1"
+	inv.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr = "40.00"
+	inv.LegalMonetaryTotal.PayableAmountCurr = "48.40"
+	inv.LegalMonetaryTotal.TaxExclusiveAmount = ""
+
+	if err := FillLocalAmounts(inv, nil); err != nil {
+		t.Fatalf("FillLocalAmounts() error = %v", err)
+	}
+	if got := inv.InvoiceLines.InvoiceLine[0].LineExtensionAmount; got != "1000.00" {
+		t.Errorf("LineExtensionAmount = %s, want 1000.00", got)
+	}
+	if got := inv.LegalMonetaryTotal.PayableAmount; got != "1210.00" {
+		t.Errorf("PayableAmount = %s, want 1210.00", got)
+	}
+	if got := inv.LegalMonetaryTotal.TaxExclusiveAmount; got != "" {
+		t.Errorf("TaxExclusiveAmount without foreign amount = %q, want empty", got)
+	}
+}
+
+func TestCurrencyConversionErrors(t *testing.T) {
+	inv := createValidInvoice()
+	if err := FillForeignAmounts(inv, testRates); !errors.Is(err, ErrNoForeignCurrency) {
+		t.Errorf("domestic invoice: %v", err)
+	}
+	inv.ForeignCurrencyCode = "USD"
+	if err := ApplyRate(inv, testRates); err == nil {
+		t.Error("rate of an unknown currency applied")
+	}
+	inv.CurrRate = "0"
+	if err := FillLocalAmounts(inv, nil); err == nil {
+		t.Error("zero CurrRate accepted")
+	}
+}
+
+func TestValidateCurrencyAmounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *schema.Invoice)
+		field  string
+	}{
+		{
+			name:   "consistent",
+			modify: func(inv *schema.Invoice) {},
+		},
+		{
+			name:   "rounded local amount",
+			modify: func(inv *schema.Invoice) { inv.TaxTotal.TaxAmountCurr = "8.64" },
+		},
+		{
+			name:   "payable",
+			modify: func(inv *schema.Invoice) { inv.LegalMonetaryTotal.PayableAmountCurr = "48.75" },
+			field:  "Invoice.LegalMonetaryTotal.PayableAmountCurr",
+		},
+		{
+			name:   "line",
+			modify: func(inv *schema.Invoice) { inv.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr = "40.00" },
+			field:  "Invoice.InvoiceLines.InvoiceLine[0].LineExtensionAmountCurr",
+		},
+		{
+			name:   "rate",
+			modify: func(inv *schema.Invoice) { inv.CurrRate = "25" },
+			field:  "Invoice.TaxTotal.TaxSubTotal[0].TaxableAmountCurr",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := foreignInvoice(t, "EUR")
+			if err := FillForeignAmounts(inv, testRates); err != nil {
+				t.Fatalf("FillForeignAmounts() error = %v", err)
+			}
+			tc.modify(inv)
+			errs := ValidateInvoice(inv)
+			if tc.field == "" {
+				for _, e := range errs {
+					if e.Code == ErrCodeTotalMismatch {
+						t.Errorf("unexpected issue %v", e)
+					}
+				}
+				return
+			}
+			if !hasIssue(errs, tc.field, ErrCodeTotalMismatch) {
+				t.Errorf("no %s issue at %s in %v", ErrCodeTotalMismatch, tc.field, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package isdoc
+
+import (
+	"fmt"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// DefaultTaxPointWindow is the number of days a tax document may be issued
+// after its tax point (§ 28 of the Czech VAT Act).
+const DefaultTaxPointWindow = 15
+
+// validateDates checks that the dates of an invoice are consistent:
+//   - a tax document has TaxPointDate and is issued within the tax point
+//     window after it
+//   - no PaymentDueDate is before IssueDate
+//   - a contract ends after it was signed and is either limited by
+//     LastValidDate or unbounded
+//   - no batch expired before IssueDate
+func validateDates(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	if inv.VATApplicable.Bool() && inv.DocumentType != types.DocumentTypeAdvanceInvoice && inv.TaxPointDate.IsZero() {
+		errs = append(errs, &ValidationError{
+			Field:    "Invoice.TaxPointDate",
+			Code:     ErrCodeRequiredField,
+			Severity: severity,
+			Msg:      "TaxPointDate is required for documents with VATApplicable",
+		})
+	}
+	window := opts.TaxPointWindow
+	if window == 0 {
+		window = DefaultTaxPointWindow
+	}
+	if window > 0 && !inv.TaxPointDate.IsZero() && !inv.IssueDate.IsZero() {
+		if days := daysBetween(inv.TaxPointDate, inv.IssueDate); days > window {
+			errs = append(errs, &ValidationError{
+				Field:    "Invoice.IssueDate",
+				Code:     ErrCodeInvalidDate,
+				Severity: severity,
+				Msg: fmt.Sprintf("IssueDate %s is %d days after TaxPointDate %s, more than the %d days allowed",
+					inv.IssueDate, days, inv.TaxPointDate, window),
+			})
+		}
+	}
+
+	if inv.PaymentMeans != nil && !inv.IssueDate.IsZero() {
+		for i, p := range inv.PaymentMeans.Payment {
+			if p.Details == nil || p.Details.PaymentDueDate.IsZero() || !p.Details.PaymentDueDate.Before(inv.IssueDate.Time) {
+				continue
+			}
+			errs = append(errs, &ValidationError{
+				Field:    fmt.Sprintf("Invoice.PaymentMeans.Payment[%d].Details.PaymentDueDate", i),
+				Code:     ErrCodeInvalidDate,
+				Severity: severity,
+				Msg:      fmt.Sprintf("PaymentDueDate %s is before IssueDate %s", p.Details.PaymentDueDate, inv.IssueDate),
+			})
+		}
+	}
+
+	if inv.ContractReferences != nil {
+		for i, c := range inv.ContractReferences.ContractReference {
+			path := fmt.Sprintf("Invoice.ContractReferences.ContractReference[%d]", i)
+			if c.LastValidDate.IsZero() {
+				continue
+			}
+			if c.LastValidDateUnbounded.Bool() {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".LastValidDateUnbounded",
+					Code:     ErrCodeSchemaViolation,
+					Severity: severity,
+					Msg:      fmt.Sprintf("contract %q has both LastValidDate and LastValidDateUnbounded", c.ContractID),
+				})
+			}
+			if !c.IssueDate.IsZero() && c.LastValidDate.Before(c.IssueDate.Time) {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".LastValidDate",
+					Code:     ErrCodeInvalidDate,
+					Severity: severity,
+					Msg: fmt.Sprintf("contract %q ends on %s, before it was signed on %s",
+						c.ContractID, c.LastValidDate, c.IssueDate),
+				})
+			}
+		}
+	}
+
+	if !inv.IssueDate.IsZero() {
+		for i, line := range inv.InvoiceLines.InvoiceLine {
+			if line.Item.StoreBatches == nil {
+				continue
+			}
+			for j, batch := range line.Item.StoreBatches.StoreBatch {
+				if batch.ExpirationDate.IsZero() || !batch.ExpirationDate.Before(inv.IssueDate.Time) {
+					continue
+				}
+				errs = append(errs, &ValidationError{
+					Field:    fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d].Item.StoreBatches.StoreBatch[%d].ExpirationDate", i, j),
+					Code:     ErrCodeInvalidDate,
+					Severity: severity,
+					Msg: fmt.Sprintf("batch %q expired on %s, before IssueDate %s",
+						batch.Name, batch.ExpirationDate, inv.IssueDate),
+				})
+			}
+		}
+	}
+
+	return errs
+}
+
+// daysBetween returns the number of days from a to b.
+func daysBetween(a, b types.Date) int {
+	return int(b.Sub(a.Time).Hours() / 24)
+}
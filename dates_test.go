@@ -0,0 +1,131 @@
+package isdoc
+
+import (
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestValidateDates(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *schema.Invoice)
+		field  string
+		code   string
+	}{
+		{
+			name:   "missing tax point",
+			modify: func(inv *schema.Invoice) { inv.TaxPointDate = types.Date{} },
+			field:  "Invoice.TaxPointDate",
+			code:   ErrCodeRequiredField,
+		},
+		{
+			name:   "issued late",
+			modify: func(inv *schema.Invoice) { inv.TaxPointDate = types.MustParseDate("2023-12-30") },
+			field:  "Invoice.IssueDate",
+			code:   ErrCodeInvalidDate,
+		},
+		{
+			name: "due before issue",
+			modify: func(inv *schema.Invoice) {
+				inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{{
+					PaymentMeansCode: types.PaymentMeansBankTransfer,
+					Details:          &schema.PaymentDetails{PaymentDueDate: types.MustParseDate("2024-01-14")},
+				}}}
+			},
+			field: "Invoice.PaymentMeans.Payment[0].Details.PaymentDueDate",
+			code:  ErrCodeInvalidDate,
+		},
+		{
+			name: "contract ends before signature",
+			modify: func(inv *schema.Invoice) {
+				inv.ContractReferences.ContractReference[0].LastValidDate = types.MustParseDate("2022-12-31")
+			},
+			field: "Invoice.ContractReferences.ContractReference[0].LastValidDate",
+			code:  ErrCodeInvalidDate,
+		},
+		{
+			name: "bounded and unbounded contract",
+			modify: func(inv *schema.Invoice) {
+				inv.ContractReferences.ContractReference[0].LastValidDateUnbounded = true
+			},
+			field: "Invoice.ContractReferences.ContractReference[0].LastValidDateUnbounded",
+			code:  ErrCodeSchemaViolation,
+		},
+		{
+			name: "expired batch",
+			modify: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[0].Item.StoreBatches = &schema.StoreBatches{StoreBatch: []schema.StoreBatch{{
+					Name:           "L-7",
+					ExpirationDate: types.MustParseDate("2024-01-01"),
+				}}}
+			},
+			field: "Invoice.InvoiceLines.InvoiceLine[0].Item.StoreBatches.StoreBatch[0].ExpirationDate",
+			code:  ErrCodeInvalidDate,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := datedInvoice()
+			if errs := validateDates(inv, ValidateOptions{Strict: true}); len(errs) > 0 {
+				t.Fatalf("validateDates() before change = %v", errs)
+			}
+			tc.modify(inv)
+			if !hasIssue(ValidateInvoice(inv), tc.field, tc.code) {
+				t.Errorf("no %s issue at %s", tc.code, tc.field)
+			}
+		})
+	}
+}
+
+// datedInvoice returns a valid invoice issued on its tax point, due in two
+// weeks, under a contract valid for 2023 and 2024.
+func datedInvoice() *schema.Invoice {
+	inv := createValidInvoice()
+	inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{{
+		PaymentMeansCode: types.PaymentMeansBankTransfer,
+		Details:          &schema.PaymentDetails{PaymentDueDate: types.MustParseDate("2024-01-29")},
+	}}}
+	inv.ContractReferences = &schema.ContractReferences{ContractReference: []schema.ContractReference{{
+		ContractID:    "SML-1",
+		IssueDate:     types.MustParseDate("2023-01-01"),
+		LastValidDate: types.MustParseDate("2024-12-31"),
+	}}}
+	return inv
+}
+
+func TestValidateDatesAdvanceInvoice(t *testing.T) {
+	inv := createValidInvoice()
+	inv.DocumentType = types.DocumentTypeAdvanceInvoice
+	inv.TaxPointDate = types.Date{}
+	if errs := validateDates(inv, ValidateOptions{Strict: true}); len(errs) > 0 {
+		t.Errorf("validateDates() = %v", errs)
+	}
+}
+
+func TestValidateDatesTaxPointWindow(t *testing.T) {
+	inv := createValidInvoice()
+	inv.TaxPointDate = types.MustParseDate("2023-12-30")
+
+	errs := validateDates(inv, ValidateOptions{})
+	if len(errs) != 1 || errs[0].Severity != SeverityWarning {
+		t.Errorf("validateDates() after 16 days = %v", errs)
+	}
+	errs = validateDates(inv, ValidateOptions{Strict: true})
+	if len(errs) != 1 || errs[0].Severity != SeverityError {
+		t.Errorf("validateDates() in strict mode = %v", errs)
+	}
+	if errs := validateDates(inv, ValidateOptions{TaxPointWindow: 30}); len(errs) != 0 {
+		t.Errorf("validateDates() within 30 days = %v", errs)
+	}
+	if errs := validateDates(inv, ValidateOptions{TaxPointWindow: -1}); len(errs) != 0 {
+		t.Errorf("validateDates() with the window disabled = %v", errs)
+	}
+
+	inv.TaxPointDate = types.NewDate(inv.IssueDate.AddDate(0, 0, -DefaultTaxPointWindow))
+	if errs := validateDates(inv, ValidateOptions{}); len(errs) != 0 {
+		t.Errorf("validateDates() on the last day = %v", errs)
+	}
+}
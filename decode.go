@@ -1,7 +1,9 @@
 package isdoc
 
 import (
+	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 
@@ -11,6 +13,20 @@ import (
 // Decoder decodes ISDOC XML documents.
 type Decoder struct {
 	reader io.Reader
+	opts   DecodeOptions
+}
+
+// DecodeOptions configures decoding behavior.
+type DecodeOptions struct {
+	// Lossless preserves XML content not modeled by the schema structs:
+	// unknown elements and attributes, comments, processing instructions and
+	// namespace declarations. The content is stored in the Unknown fields and
+	// re-emitted by the encoder in its original position.
+	Lossless bool
+
+	// SourceMap, if set, receives the source position of each decoded
+	// element. Pass it in ValidateOptions to annotate validation errors.
+	SourceMap *SourceMap
 }
 
 // NewDecoder creates a new Decoder that reads from r.
@@ -18,6 +34,17 @@ func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{reader: r}
 }
 
+// SetLossless controls whether unmodeled XML content is preserved for
+// re-encoding. Default is false.
+func (d *Decoder) SetLossless(lossless bool) {
+	d.opts.Lossless = lossless
+}
+
+// SetSourceMap sets the SourceMap receiving element positions. Default is nil.
+func (d *Decoder) SetSourceMap(sm *SourceMap) {
+	d.opts.SourceMap = sm
+}
+
 // Decode decodes an ISDOC XML document and returns the Invoice.
 // It performs two passes:
 // 1. XML unmarshaling to populate struct fields
@@ -29,7 +56,7 @@ func (d *Decoder) Decode() (*schema.Invoice, error) {
 		return nil, NewDecodeError("", fmt.Errorf("reading input: %w", err))
 	}
 
-	return DecodeBytes(data)
+	return DecodeBytesWithOptions(data, d.opts)
 }
 
 // DecodeBytes decodes an ISDOC Invoice XML document from bytes.
@@ -49,15 +76,35 @@ func (d *Decoder) Decode() (*schema.Invoice, error) {
 //	    log.Fatal(err)
 //	}
 func DecodeBytes(data []byte) (*schema.Invoice, error) {
+	return DecodeBytesWithOptions(data, DecodeOptions{})
+}
+
+// DecodeBytesWithOptions decodes an ISDOC Invoice XML document from bytes
+// with custom options.
+//
+// With Lossless set, decoding and re-encoding keeps signatures, vendor
+// extensions and comments:
+//
+//	invoice, err := isdoc.DecodeBytesWithOptions(data, isdoc.DecodeOptions{Lossless: true})
+//	invoice.Note = &schema.Note{Value: "Updated"}
+//	out, err := isdoc.EncodeBytes(invoice)
+func DecodeBytesWithOptions(data []byte, opts DecodeOptions) (*schema.Invoice, error) {
 	var invoice schema.Invoice
 
 	// Pass 1: Unmarshal XML
-	if err := xml.Unmarshal(data, &invoice); err != nil {
-		return nil, NewDecodeError("", fmt.Errorf("XML parsing: %w", err))
+	if err := xml.Unmarshal(withoutForeignElements(data), &invoice); err != nil {
+		return nil, newXMLDecodeError(err)
+	}
+
+	if needsSourceWalk(data, opts) {
+		if err := walkSource(data, &invoice, opts); err != nil {
+			return nil, newXMLDecodeError(err)
+		}
 	}
 
 	// Pass 2: Resolve references
 	if errs := resolveReferences(&invoice); len(errs) > 0 {
+		opts.SourceMap.annotateDecodeErrors(errs)
 		// Return invoice with errors - caller can decide whether to use it
 		return &invoice, errs
 	}
@@ -65,6 +112,35 @@ func DecodeBytes(data []byte) (*schema.Invoice, error) {
 	return &invoice, nil
 }
 
+// RootElement returns the local name of the document element, "Invoice" or
+// "CommonDocument" for ISDOC documents. Use it to pick the decode function
+// for input of unknown type.
+func RootElement(data []byte) (string, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", NewDecodeError("", errors.New("XML parsing: no root element")).withCode(ErrCodeInvalidXML)
+		}
+		if err != nil {
+			return "", newXMLDecodeError(err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// newXMLDecodeError wraps an XML parsing error, keeping its line number.
+func newXMLDecodeError(err error) *DecodeError {
+	de := NewDecodeError("", fmt.Errorf("XML parsing: %w", err)).withCode(ErrCodeInvalidXML)
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		de.Line = syntaxErr.Line
+	}
+	return de
+}
+
 // resolveReferences validates id/ref attribute linkages.
 // Returns slice of errors for any unresolved references.
 func resolveReferences(inv *schema.Invoice) DecodeErrors {
@@ -84,7 +160,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 					errs = append(errs, NewDecodeError(
 						fmt.Sprintf("Invoice.OrderReferences.OrderReference[%d]", i),
 						fmt.Errorf("duplicate id %q", ref.ID),
-					))
+					).withCode(ErrCodeDuplicateID))
 				}
 				orderRefs[ref.ID] = true
 			}
@@ -98,7 +174,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 					errs = append(errs, NewDecodeError(
 						fmt.Sprintf("Invoice.DeliveryNoteReferences.DeliveryNoteReference[%d]", i),
 						fmt.Errorf("duplicate id %q", ref.ID),
-					))
+					).withCode(ErrCodeDuplicateID))
 				}
 				deliveryNoteRefs[ref.ID] = true
 			}
@@ -112,7 +188,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 					errs = append(errs, NewDecodeError(
 						fmt.Sprintf("Invoice.OriginalDocumentReferences.OriginalDocumentReference[%d]", i),
 						fmt.Errorf("duplicate id %q", ref.ID),
-					))
+					).withCode(ErrCodeDuplicateID))
 				}
 				originalDocRefs[ref.ID] = true
 			}
@@ -126,7 +202,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 					errs = append(errs, NewDecodeError(
 						fmt.Sprintf("Invoice.ContractReferences.ContractReference[%d]", i),
 						fmt.Errorf("duplicate id %q", ref.ID),
-					))
+					).withCode(ErrCodeDuplicateID))
 				}
 				contractRefs[ref.ID] = true
 			}
@@ -142,7 +218,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 				errs = append(errs, NewDecodeError(
 					path+".OrderReference",
 					fmt.Errorf("ref %q not found in header OrderReferences", line.OrderReference.Ref),
-				))
+				).withCode(ErrCodeReferenceNotFound))
 			}
 		}
 
@@ -151,7 +227,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 				errs = append(errs, NewDecodeError(
 					path+".DeliveryNoteReference",
 					fmt.Errorf("ref %q not found in header DeliveryNoteReferences", line.DeliveryNoteReference.Ref),
-				))
+				).withCode(ErrCodeReferenceNotFound))
 			}
 		}
 
@@ -160,7 +236,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 				errs = append(errs, NewDecodeError(
 					path+".OriginalDocumentReference",
 					fmt.Errorf("ref %q not found in header OriginalDocumentReferences", line.OriginalDocumentReference.Ref),
-				))
+				).withCode(ErrCodeReferenceNotFound))
 			}
 		}
 
@@ -169,7 +245,7 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 				errs = append(errs, NewDecodeError(
 					path+".ContractReference",
 					fmt.Errorf("ref %q not found in header ContractReferences", line.ContractReference.Ref),
-				))
+				).withCode(ErrCodeReferenceNotFound))
 			}
 		}
 	}
@@ -190,10 +266,22 @@ func resolveReferences(inv *schema.Invoice) DecodeErrors {
 //	    log.Fatal(err)
 //	}
 func DecodeCommonDocumentBytes(data []byte) (*schema.CommonDocument, error) {
+	return DecodeCommonDocumentBytesWithOptions(data, DecodeOptions{})
+}
+
+// DecodeCommonDocumentBytesWithOptions decodes an ISDOC CommonDocument XML
+// from bytes with custom options.
+func DecodeCommonDocumentBytesWithOptions(data []byte, opts DecodeOptions) (*schema.CommonDocument, error) {
 	var doc schema.CommonDocument
 
-	if err := xml.Unmarshal(data, &doc); err != nil {
-		return nil, NewDecodeError("", fmt.Errorf("XML parsing: %w", err))
+	if err := xml.Unmarshal(withoutForeignElements(data), &doc); err != nil {
+		return nil, newXMLDecodeError(err)
+	}
+
+	if needsSourceWalk(data, opts) {
+		if err := walkSource(data, &doc, opts); err != nil {
+			return nil, newXMLDecodeError(err)
+		}
 	}
 
 	return &doc, nil
@@ -0,0 +1,350 @@
+package isdoc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// ErrNotAdvance is returned by SettleDeposits for documents that are not
+// advance invoices or tax documents for received payments.
+var ErrNotAdvance = errors.New("document is not an advance payment")
+
+// SettleDeposits deducts advance payments from a final invoice (DocumentType
+// 1 or 7) whose totals are computed, e.g. by ComputeTotals.
+//
+// Advance invoices (DocumentType 4) become NonTaxedDeposits of their
+// PayableAmount, reported in PaidDepositsAmount as a negative amount, as in
+// the ISDOC sample. Tax documents for received payments (5) become one
+// TaxedDeposit per VAT rate and are recorded in the AlreadyClaimed* amounts
+// of the TaxSubTotal of that rate and of LegalMonetaryTotal; credit notes
+// for received payments (6) reduce them. A payment covered by a tax
+// document must be passed only as that document, not as its advance
+// invoice as well.
+//
+// Deposits and claimed amounts present on inv are replaced. The Difference*
+// amounts and PayableAmount are recomputed, for an invoice in a foreign
+// currency also in that currency.
+func SettleDeposits(inv *schema.Invoice, advances ...*schema.Invoice) error {
+	if inv.DocumentType != types.DocumentTypeInvoice && inv.DocumentType != types.DocumentTypeSimplified {
+		return fmt.Errorf("deposits cannot be settled on DocumentType %d", inv.DocumentType)
+	}
+	foreign := inv.ForeignCurrencyCode != ""
+
+	var nonTaxed []schema.NonTaxedDeposit
+	var taxed []schema.TaxedDeposit
+	for i, adv := range advances {
+		if adv.LocalCurrencyCode != inv.LocalCurrencyCode || adv.ForeignCurrencyCode != inv.ForeignCurrencyCode {
+			return fmt.Errorf("advance %d (%s): currency %s/%s differs from the invoice's %s/%s", i, adv.ID,
+				adv.LocalCurrencyCode, adv.ForeignCurrencyCode, inv.LocalCurrencyCode, inv.ForeignCurrencyCode)
+		}
+		switch adv.DocumentType {
+		case types.DocumentTypeAdvanceInvoice:
+			d := schema.NonTaxedDeposit{
+				ID:             adv.ID,
+				VariableSymbol: variableSymbol(adv),
+				DepositAmount:  adv.LegalMonetaryTotal.PayableAmount,
+			}
+			if foreign {
+				d.DepositAmountCurr = adv.LegalMonetaryTotal.PayableAmountCurr
+			}
+			nonTaxed = append(nonTaxed, d)
+		case types.DocumentTypeAdvanceTaxDocument, types.DocumentTypeAdvanceCreditNote:
+			taxed = append(taxed, taxedDeposits(adv, foreign)...)
+		default:
+			return fmt.Errorf("advance %d (%s): %w: DocumentType %d", i, adv.ID, ErrNotAdvance, adv.DocumentType)
+		}
+	}
+
+	inv.NonTaxedDeposits, inv.TaxedDeposits = nil, nil
+	if len(nonTaxed) > 0 {
+		inv.NonTaxedDeposits = &schema.NonTaxedDeposits{NonTaxedDeposit: nonTaxed}
+	}
+	if len(taxed) > 0 {
+		inv.TaxedDeposits = &schema.TaxedDeposits{TaxedDeposit: taxed}
+	}
+
+	claims := depositClaims(inv)
+	for i := range inv.TaxTotal.TaxSubTotal {
+		st := &inv.TaxTotal.TaxSubTotal[i]
+		key := taxRateKey(st.TaxCategory.Percent, st.TaxCategory.LocalReverseChargeFlag.Bool())
+		c, ok := claims.byRate[key]
+		if !ok {
+			c = newDepositClaim()
+		}
+		c.apply(st, foreign)
+		delete(claims.byRate, key)
+	}
+	for _, key := range claims.rates {
+		c, ok := claims.byRate[key]
+		if !ok {
+			continue
+		}
+		zero := formatAmount(new(big.Rat), 2)
+		st := schema.TaxSubTotal{
+			TaxableAmount:      zero,
+			TaxAmount:          zero,
+			TaxInclusiveAmount: zero,
+			TaxCategory:        c.category,
+		}
+		if foreign {
+			st.TaxableAmountCurr, st.TaxAmountCurr, st.TaxInclusiveAmountCurr = zero, zero, zero
+		}
+		c.apply(&st, foreign)
+		inv.TaxTotal.TaxSubTotal = append(inv.TaxTotal.TaxSubTotal, st)
+	}
+
+	total := &inv.LegalMonetaryTotal
+	total.AlreadyClaimedTaxExclusiveAmount = formatAmount(claims.total.taxable, 2)
+	total.AlreadyClaimedTaxInclusiveAmount = formatAmount(claims.total.inclusive, 2)
+	total.PaidDepositsAmount = ""
+	if paid := nonTaxedSum(inv, false); paid.Sign() != 0 {
+		total.PaidDepositsAmount = formatAmount(paid.Neg(paid), 2)
+	}
+	if foreign {
+		total.AlreadyClaimedTaxExclusiveAmountCurr = formatAmount(claims.total.taxableCurr, 2)
+		total.AlreadyClaimedTaxInclusiveAmountCurr = formatAmount(claims.total.inclusiveCurr, 2)
+		total.PaidDepositsAmountCurr = ""
+		if paid := nonTaxedSum(inv, true); paid.Sign() != 0 {
+			total.PaidDepositsAmountCurr = formatAmount(paid.Neg(paid), 2)
+		}
+	}
+	computeDifferences(inv, foreign)
+	return nil
+}
+
+// variableSymbol returns the variable symbol the advance was paid under,
+// if any.
+func variableSymbol(adv *schema.Invoice) string {
+	if adv.PaymentMeans == nil {
+		return ""
+	}
+	for _, p := range adv.PaymentMeans.Payment {
+		if p.Details != nil && p.Details.VariableSymbol != "" {
+			return p.Details.VariableSymbol
+		}
+	}
+	return ""
+}
+
+// taxedDeposits returns a TaxedDeposit for each non-empty TaxSubTotal of a
+// tax document for a received payment.
+func taxedDeposits(adv *schema.Invoice, foreign bool) []schema.TaxedDeposit {
+	var deposits []schema.TaxedDeposit
+	for _, st := range adv.TaxTotal.TaxSubTotal {
+		if parseAmount(st.TaxableAmount).Sign() == 0 && parseAmount(st.TaxInclusiveAmount).Sign() == 0 {
+			continue
+		}
+		d := schema.TaxedDeposit{
+			ID:                        adv.ID,
+			VariableSymbol:            variableSymbol(adv),
+			TaxableDepositAmount:      st.TaxableAmount,
+			TaxInclusiveDepositAmount: st.TaxInclusiveAmount,
+			ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
+				Percent:       st.TaxCategory.Percent,
+				VATApplicable: st.TaxCategory.VATApplicable,
+			},
+		}
+		if foreign {
+			d.TaxableDepositAmountCurr = st.TaxableAmountCurr
+			d.TaxInclusiveDepositAmountCurr = st.TaxInclusiveAmountCurr
+		}
+		// The reverse charge of the rate is taken from the advance's lines
+		if st.TaxCategory.LocalReverseChargeFlag.Bool() {
+			key := taxRateKey(st.TaxCategory.Percent, true)
+			for _, line := range adv.InvoiceLines.InvoiceLine {
+				rc := line.ClassifiedTaxCategory.LocalReverseCharge
+				if rc != nil && taxRateKey(line.ClassifiedTaxCategory.Percent, true) == key {
+					d.ClassifiedTaxCategory.LocalReverseCharge = &schema.LocalReverseCharge{LocalReverseChargeCode: rc.LocalReverseChargeCode}
+					break
+				}
+			}
+		}
+		deposits = append(deposits, d)
+	}
+	return deposits
+}
+
+// depositClaim holds the amounts claimed at one VAT rate.
+type depositClaim struct {
+	category                                       schema.TaxCategory
+	taxable, inclusive, taxableCurr, inclusiveCurr *big.Rat
+}
+
+func newDepositClaim() *depositClaim {
+	return &depositClaim{taxable: new(big.Rat), inclusive: new(big.Rat), taxableCurr: new(big.Rat), inclusiveCurr: new(big.Rat)}
+}
+
+func (c *depositClaim) add(d *schema.TaxedDeposit) {
+	c.taxable.Add(c.taxable, parseAmount(d.TaxableDepositAmount))
+	c.inclusive.Add(c.inclusive, parseAmount(d.TaxInclusiveDepositAmount))
+	c.taxableCurr.Add(c.taxableCurr, parseAmount(d.TaxableDepositAmountCurr))
+	c.inclusiveCurr.Add(c.inclusiveCurr, parseAmount(d.TaxInclusiveDepositAmountCurr))
+}
+
+// apply sets the AlreadyClaimed* amounts of st.
+func (c *depositClaim) apply(st *schema.TaxSubTotal, curr bool) {
+	st.AlreadyClaimedTaxableAmount = formatAmount(c.taxable, 2)
+	st.AlreadyClaimedTaxAmount = formatAmount(new(big.Rat).Sub(c.inclusive, c.taxable), 2)
+	st.AlreadyClaimedTaxInclusiveAmount = formatAmount(c.inclusive, 2)
+	if curr {
+		st.AlreadyClaimedTaxableAmountCurr = formatAmount(c.taxableCurr, 2)
+		st.AlreadyClaimedTaxAmountCurr = formatAmount(new(big.Rat).Sub(c.inclusiveCurr, c.taxableCurr), 2)
+		st.AlreadyClaimedTaxInclusiveAmountCurr = formatAmount(c.inclusiveCurr, 2)
+	}
+}
+
+// depositSums holds the TaxedDeposits of an invoice summed per rate, in
+// order of first appearance, and in total.
+type depositSums struct {
+	rates  []string
+	byRate map[string]*depositClaim
+	total  *depositClaim
+}
+
+func depositClaims(inv *schema.Invoice) depositSums {
+	sums := depositSums{byRate: make(map[string]*depositClaim), total: newDepositClaim()}
+	if inv.TaxedDeposits == nil {
+		return sums
+	}
+	for i := range inv.TaxedDeposits.TaxedDeposit {
+		d := &inv.TaxedDeposits.TaxedDeposit[i]
+		category := d.ClassifiedTaxCategory
+		key := taxRateKey(category.Percent, category.LocalReverseCharge != nil)
+		c, ok := sums.byRate[key]
+		if !ok {
+			c = newDepositClaim()
+			c.category = schema.TaxCategory{
+				Percent:                category.Percent,
+				VATApplicable:          inv.VATApplicable,
+				LocalReverseChargeFlag: types.Bool(category.LocalReverseCharge != nil),
+			}
+			sums.byRate[key] = c
+			sums.rates = append(sums.rates, key)
+		}
+		c.add(d)
+		sums.total.add(d)
+	}
+	return sums
+}
+
+// nonTaxedSum returns the sum of the NonTaxedDeposits of inv, in the
+// foreign currency with curr set.
+func nonTaxedSum(inv *schema.Invoice, curr bool) *big.Rat {
+	sum := new(big.Rat)
+	if inv.NonTaxedDeposits == nil {
+		return sum
+	}
+	for _, d := range inv.NonTaxedDeposits.NonTaxedDeposit {
+		amount := d.DepositAmount
+		if curr {
+			amount = d.DepositAmountCurr
+		}
+		sum.Add(sum, parseAmount(amount))
+	}
+	return sum
+}
+
+// validateDeposits checks the settlement of advance payments. The
+// AlreadyClaimed* amounts of each TaxSubTotal must match the TaxedDeposits
+// at its rate, and LegalMonetaryTotal claims the sum of the subtotals. When
+// the invoice lists NonTaxedDeposits, PaidDepositsAmount must match them.
+// Documents converted from UBL or CII carry only the amounts. The
+// Difference* amounts are the amounts less the claimed ones, and
+// PayableAmount is the difference less paid deposits plus rounding.
+func validateDeposits(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+	tol := opts.tolerance()
+	check := func(field string, got, want *big.Rat, msg string) {
+		if new(big.Rat).Sub(got, want).Abs(new(big.Rat).Sub(got, want)).Cmp(tol) <= 0 {
+			return
+		}
+		errs = append(errs, &ValidationError{
+			Field:    field,
+			Code:     ErrCodeTotalMismatch,
+			Severity: severity,
+			Msg:      fmt.Sprintf("%s is %s, expected %s", msg, formatAmount(got, 2), formatAmount(want, 2)),
+		})
+	}
+	// checkDifference checks a Difference* amount, if present
+	checkDifference := func(field string, difference, amount, claimed types.Decimal) {
+		if difference.IsZero() {
+			return
+		}
+		want := new(big.Rat).Sub(parseAmount(amount), parseAmount(claimed))
+		check(field, parseAmount(difference), want, field[strings.LastIndexByte(field, '.')+1:])
+	}
+
+	claims := depositClaims(inv)
+	claimedTaxable, claimedInclusive := new(big.Rat), new(big.Rat)
+	for i := range inv.TaxTotal.TaxSubTotal {
+		st := &inv.TaxTotal.TaxSubTotal[i]
+		path := fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d]", i)
+		percent := st.TaxCategory.Percent
+		claimedTaxable.Add(claimedTaxable, parseAmount(st.AlreadyClaimedTaxableAmount))
+		claimedInclusive.Add(claimedInclusive, parseAmount(st.AlreadyClaimedTaxInclusiveAmount))
+
+		key := taxRateKey(percent, st.TaxCategory.LocalReverseChargeFlag.Bool())
+		c, ok := claims.byRate[key]
+		delete(claims.byRate, key)
+		if !ok {
+			c = newDepositClaim()
+		}
+		if inv.TaxedDeposits != nil {
+			check(path+".AlreadyClaimedTaxableAmount", parseAmount(st.AlreadyClaimedTaxableAmount), c.taxable,
+				fmt.Sprintf("AlreadyClaimedTaxableAmount at %s %% with TaxedDeposits", percent))
+			check(path+".AlreadyClaimedTaxInclusiveAmount", parseAmount(st.AlreadyClaimedTaxInclusiveAmount), c.inclusive,
+				fmt.Sprintf("AlreadyClaimedTaxInclusiveAmount at %s %% with TaxedDeposits", percent))
+		}
+		check(path+".AlreadyClaimedTaxAmount", parseAmount(st.AlreadyClaimedTaxAmount),
+			new(big.Rat).Sub(parseAmount(st.AlreadyClaimedTaxInclusiveAmount), parseAmount(st.AlreadyClaimedTaxableAmount)),
+			fmt.Sprintf("AlreadyClaimedTaxAmount at %s %%", percent))
+
+		checkDifference(path+".DifferenceTaxableAmount", st.DifferenceTaxableAmount, st.TaxableAmount, st.AlreadyClaimedTaxableAmount)
+		checkDifference(path+".DifferenceTaxAmount", st.DifferenceTaxAmount, st.TaxAmount, st.AlreadyClaimedTaxAmount)
+		checkDifference(path+".DifferenceTaxInclusiveAmount", st.DifferenceTaxInclusiveAmount, st.TaxInclusiveAmount, st.AlreadyClaimedTaxInclusiveAmount)
+	}
+	for _, key := range claims.rates {
+		if c, ok := claims.byRate[key]; ok {
+			errs = append(errs, &ValidationError{
+				Field:    "Invoice.TaxTotal.TaxSubTotal",
+				Code:     ErrCodeTotalMismatch,
+				Severity: severity,
+				Msg:      fmt.Sprintf("no TaxSubTotal claims the TaxedDeposits at %s %%", c.category.Percent),
+			})
+		}
+	}
+
+	total := &inv.LegalMonetaryTotal
+	const path = "Invoice.LegalMonetaryTotal"
+	check(path+".AlreadyClaimedTaxExclusiveAmount", parseAmount(total.AlreadyClaimedTaxExclusiveAmount), claimedTaxable,
+		"AlreadyClaimedTaxExclusiveAmount with the tax subtotals")
+	check(path+".AlreadyClaimedTaxInclusiveAmount", parseAmount(total.AlreadyClaimedTaxInclusiveAmount), claimedInclusive,
+		"AlreadyClaimedTaxInclusiveAmount with the tax subtotals")
+	checkDifference(path+".DifferenceTaxExclusiveAmount", total.DifferenceTaxExclusiveAmount, total.TaxExclusiveAmount, total.AlreadyClaimedTaxExclusiveAmount)
+	checkDifference(path+".DifferenceTaxInclusiveAmount", total.DifferenceTaxInclusiveAmount, total.TaxInclusiveAmount, total.AlreadyClaimedTaxInclusiveAmount)
+
+	// The sign of PaidDepositsAmount varies between producers
+	if inv.NonTaxedDeposits != nil {
+		paid := nonTaxedSum(inv, false)
+		check(path+".PaidDepositsAmount", new(big.Rat).Abs(parseAmount(total.PaidDepositsAmount)), paid.Abs(paid),
+			"PaidDepositsAmount without sign with NonTaxedDeposits")
+	}
+
+	difference := total.DifferenceTaxInclusiveAmount
+	if difference.IsZero() {
+		difference = formatAmount(new(big.Rat).Sub(parseAmount(total.TaxInclusiveAmount), parseAmount(total.AlreadyClaimedTaxInclusiveAmount)), 2)
+	}
+	check(path+".PayableAmount", parseAmount(total.PayableAmount),
+		parseAmount(payableAmount(difference, total.PaidDepositsAmount, total.PayableRoundingAmount)), "PayableAmount")
+
+	return errs
+}
@@ -0,0 +1,247 @@
+package isdoc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// advanceDocument returns a document of the given type with a line at
+// each of the rates and prices, paid under variable symbol 2024001.
+func advanceDocument(t *testing.T, docType types.DocumentType, id string, lines ...[2]string) *schema.Invoice {
+	t.Helper()
+	inv := createValidInvoice()
+	inv.DocumentType = docType
+	inv.ID = id
+	inv.VATApplicable = docType != types.DocumentTypeAdvanceInvoice
+	inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{{
+		PaymentMeansCode: types.PaymentMeansBankTransfer,
+		Details:          &schema.PaymentDetails{VariableSymbol: "2024001"},
+	}}}
+	template := inv.InvoiceLines.InvoiceLine[0]
+	template.ClassifiedTaxCategory.VATApplicable = inv.VATApplicable
+	inv.InvoiceLines.InvoiceLine = nil
+	for _, l := range lines {
+		line := template
+		line.ClassifiedTaxCategory.Percent = types.Decimal(l[0])
+		line.UnitPrice = types.Decimal(l[1])
+		inv.InvoiceLines.InvoiceLine = append(inv.InvoiceLines.InvoiceLine, line)
+	}
+	if err := FillDefaults(inv); err != nil {
+		t.Fatalf("FillDefaults() error = %v", err)
+	}
+	return inv
+}
+
+// settledInvoice returns an invoice of 1000 CZK at 21 % settling an
+// advance invoice of 200 CZK and a tax document for a payment of 500 CZK at
+// 21 % and 100 CZK at 12 %.
+func settledInvoice(t *testing.T) *schema.Invoice {
+	t.Helper()
+	inv := createValidInvoice()
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	err := SettleDeposits(inv,
+		advanceDocument(t, types.DocumentTypeAdvanceInvoice, "ZL-1", [2]string{"0", "200"}),
+		advanceDocument(t, types.DocumentTypeAdvanceTaxDocument, "DZ-1", [2]string{"21", "500"}, [2]string{"12", "100"}),
+	)
+	if err != nil {
+		t.Fatalf("SettleDeposits() error = %v", err)
+	}
+	return inv
+}
+
+func TestSettleDeposits(t *testing.T) {
+	inv := settledInvoice(t)
+
+	if d := inv.NonTaxedDeposits; d == nil || len(d.NonTaxedDeposit) != 1 || d.NonTaxedDeposit[0].ID != "ZL-1" ||
+		d.NonTaxedDeposit[0].VariableSymbol != "2024001" || d.NonTaxedDeposit[0].DepositAmount != "200.00" {
+		t.Errorf("NonTaxedDeposits = %+v", d)
+	}
+	if d := inv.TaxedDeposits; d == nil || len(d.TaxedDeposit) != 2 ||
+		d.TaxedDeposit[1].TaxableDepositAmount != "100.00" || d.TaxedDeposit[1].TaxInclusiveDepositAmount != "112.00" {
+		t.Errorf("TaxedDeposits = %+v", d)
+	}
+
+	subtotals := inv.TaxTotal.TaxSubTotal
+	if len(subtotals) != 2 {
+		t.Fatalf("TaxSubTotal = %+v", subtotals)
+	}
+	for i, want := range [][6]types.Decimal{
+		{"500.00", "105.00", "605.00", "500.00", "105.00", "605.00"},
+		{"100.00", "12.00", "112.00", "-100.00", "-12.00", "-112.00"},
+	} {
+		st := subtotals[i]
+		got := [6]types.Decimal{st.AlreadyClaimedTaxableAmount, st.AlreadyClaimedTaxAmount, st.AlreadyClaimedTaxInclusiveAmount,
+			st.DifferenceTaxableAmount, st.DifferenceTaxAmount, st.DifferenceTaxInclusiveAmount}
+		if got != want {
+			t.Errorf("TaxSubTotal[%d] claimed and difference = %v, want %v", i, got, want)
+		}
+	}
+
+	total := inv.LegalMonetaryTotal
+	got := [6]types.Decimal{total.AlreadyClaimedTaxExclusiveAmount, total.AlreadyClaimedTaxInclusiveAmount,
+		total.DifferenceTaxExclusiveAmount, total.DifferenceTaxInclusiveAmount, total.PaidDepositsAmount, total.PayableAmount}
+	if want := [6]types.Decimal{"600.00", "717.00", "400.00", "493.00", "-200.00", "293.00"}; got != want {
+		t.Errorf("LegalMonetaryTotal = %v, want %v", got, want)
+	}
+
+	if errs := ValidateInvoiceWithOptions(inv, ValidateOptions{Strict: true}); errs.HasErrors() {
+		t.Errorf("ValidateInvoice() = %v", errs)
+	}
+
+	// Recomputing keeps the subtotal claimed only by a deposit
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	if len(inv.TaxTotal.TaxSubTotal) != 2 || inv.LegalMonetaryTotal.PayableAmount != "293.00" {
+		t.Errorf("after ComputeTotals: %d subtotals, PayableAmount %s", len(inv.TaxTotal.TaxSubTotal), inv.LegalMonetaryTotal.PayableAmount)
+	}
+	if errs := validateDeposits(inv, ValidateOptions{Strict: true}); len(errs) > 0 {
+		t.Errorf("validateDeposits() after ComputeTotals = %v", errs)
+	}
+}
+
+func TestSettleDepositsCreditNote(t *testing.T) {
+	inv := createValidInvoice()
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	payment := advanceDocument(t, types.DocumentTypeAdvanceTaxDocument, "DZ-1", [2]string{"21", "500"})
+	refund, err := NewCreditNote(payment, []LineCorrection{{LineID: "1", UnitPrice: "100"}})
+	if err != nil {
+		t.Fatalf("NewCreditNote() error = %v", err)
+	}
+	refund.ID = "DD-1"
+
+	if err := SettleDeposits(inv, payment, refund); err != nil {
+		t.Fatalf("SettleDeposits() error = %v", err)
+	}
+	if got := inv.TaxTotal.TaxSubTotal[0].AlreadyClaimedTaxableAmount; got != "400.00" {
+		t.Errorf("AlreadyClaimedTaxableAmount = %s, want 400.00", got)
+	}
+	if got := inv.LegalMonetaryTotal.PayableAmount; got != "726.00" {
+		t.Errorf("PayableAmount = %s, want 726.00", got)
+	}
+}
+
+func TestSettleDepositsForeignCurrency(t *testing.T) {
+	inv := createValidInvoice()
+	inv.ForeignCurrencyCode = "EUR"
+	inv.CurrRate = "25"
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	if err := FillForeignAmounts(inv, nil); err != nil {
+		t.Fatalf("FillForeignAmounts() error = %v", err)
+	}
+
+	advance := advanceDocument(t, types.DocumentTypeAdvanceInvoice, "ZL-1", [2]string{"0", "250"})
+	advance.ForeignCurrencyCode = "EUR"
+	advance.LegalMonetaryTotal.PayableAmountCurr = "10.00"
+
+	if err := SettleDeposits(inv, advance); err != nil {
+		t.Fatalf("SettleDeposits() error = %v", err)
+	}
+	total := inv.LegalMonetaryTotal
+	if total.PaidDepositsAmountCurr != "-10.00" || total.PayableAmountCurr != "38.40" {
+		t.Errorf("PaidDepositsAmountCurr = %s, PayableAmountCurr = %s, want -10.00, 38.40",
+			total.PaidDepositsAmountCurr, total.PayableAmountCurr)
+	}
+	if got := inv.NonTaxedDeposits.NonTaxedDeposit[0].DepositAmountCurr; got != "10.00" {
+		t.Errorf("DepositAmountCurr = %s, want 10.00", got)
+	}
+}
+
+func TestSettleDepositsErrors(t *testing.T) {
+	inv := createValidInvoice()
+	if err := SettleDeposits(inv, createValidInvoice()); !errors.Is(err, ErrNotAdvance) {
+		t.Errorf("invoice as advance: %v", err)
+	}
+
+	advance := advanceDocument(t, types.DocumentTypeAdvanceInvoice, "ZL-1", [2]string{"0", "200"})
+	advance.LocalCurrencyCode = "EUR"
+	if err := SettleDeposits(inv, advance); err == nil || !strings.Contains(err.Error(), "currency") {
+		t.Errorf("advance in another currency: %v", err)
+	}
+
+	inv.DocumentType = types.DocumentTypeCreditNote
+	if err := SettleDeposits(inv); err == nil {
+		t.Error("deposits settled on a credit note")
+	}
+}
+
+func TestValidateDeposits(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *schema.Invoice)
+		field  string
+	}{
+		{
+			name:   "claim above deposits",
+			modify: func(inv *schema.Invoice) { inv.TaxTotal.TaxSubTotal[0].AlreadyClaimedTaxableAmount = "600.00" },
+			field:  "Invoice.TaxTotal.TaxSubTotal[0].AlreadyClaimedTaxableAmount",
+		},
+		{
+			name:   "claimed tax",
+			modify: func(inv *schema.Invoice) { inv.TaxTotal.TaxSubTotal[1].AlreadyClaimedTaxAmount = "21.00" },
+			field:  "Invoice.TaxTotal.TaxSubTotal[1].AlreadyClaimedTaxAmount",
+		},
+		{
+			name:   "subtotal difference",
+			modify: func(inv *schema.Invoice) { inv.TaxTotal.TaxSubTotal[0].DifferenceTaxInclusiveAmount = "1210.00" },
+			field:  "Invoice.TaxTotal.TaxSubTotal[0].DifferenceTaxInclusiveAmount",
+		},
+		{
+			name:   "missing subtotal",
+			modify: func(inv *schema.Invoice) { inv.TaxTotal.TaxSubTotal = inv.TaxTotal.TaxSubTotal[:1] },
+			field:  "Invoice.TaxTotal.TaxSubTotal",
+		},
+		{
+			name:   "total claim",
+			modify: func(inv *schema.Invoice) { inv.LegalMonetaryTotal.AlreadyClaimedTaxInclusiveAmount = "605.00" },
+			field:  "Invoice.LegalMonetaryTotal.AlreadyClaimedTaxInclusiveAmount",
+		},
+		{
+			name:   "paid deposits",
+			modify: func(inv *schema.Invoice) { inv.LegalMonetaryTotal.PaidDepositsAmount = "-300.00" },
+			field:  "Invoice.LegalMonetaryTotal.PaidDepositsAmount",
+		},
+		{
+			name:   "deposit not deducted",
+			modify: func(inv *schema.Invoice) { inv.LegalMonetaryTotal.PayableAmount = "493.00" },
+			field:  "Invoice.LegalMonetaryTotal.PayableAmount",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := settledInvoice(t)
+			tc.modify(inv)
+			if !hasIssue(ValidateInvoice(inv), tc.field, ErrCodeTotalMismatch) {
+				t.Errorf("no %s issue at %s", ErrCodeTotalMismatch, tc.field)
+			}
+		})
+	}
+}
+
+func TestValidateDepositsSeverity(t *testing.T) {
+	inv := settledInvoice(t)
+	inv.LegalMonetaryTotal.PayableAmount = "293.01"
+
+	if errs := validateDeposits(inv, DefaultValidateOptions()); len(errs) != 0 {
+		t.Errorf("difference within tolerance reported: %v", errs)
+	}
+	errs := validateDeposits(inv, ValidateOptions{})
+	if len(errs) != 1 || errs[0].Severity != SeverityWarning {
+		t.Errorf("validateDeposits() without tolerance = %v", errs)
+	}
+	errs = validateDeposits(inv, ValidateOptions{Strict: true})
+	if len(errs) != 1 || errs[0].Severity != SeverityError {
+		t.Errorf("validateDeposits() in strict mode = %v", errs)
+	}
+}
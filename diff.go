@@ -0,0 +1,229 @@
+package isdoc
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// ChangeAdded is a value present only in the new document.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a value present only in the old document.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified is a value present in both documents that differs.
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a difference between two invoices.
+type Change struct {
+	// Field is the path to the field in the notation of
+	// ValidationError.Field, e.g. "Invoice.InvoiceLines.InvoiceLine[0].UnitPrice".
+	// Invoice lines are indexed as in the new document; removed lines are
+	// selected by ID, e.g. "Invoice.InvoiceLines.InvoiceLine[?ID=='3'].ID".
+	Field string `json:"field"`
+	// Kind tells whether the value was added, removed or modified.
+	Kind ChangeKind `json:"kind"`
+	// Old is the value in the old document as written in XML, "" if absent.
+	Old string `json:"old,omitempty"`
+	// New is the value in the new document as written in XML, "" if absent.
+	New string `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Field, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+var invoiceLineType = reflect.TypeOf(schema.InvoiceLine{})
+
+// Diff returns the differences between the old invoice a and the new
+// invoice b, e.g. an invoice and its corrected version. Every field is
+// compared, decimals by value, so "100" equals "100.00". Invoice lines are
+// matched by ID and reported at their index in b, or by ID when removed;
+// other repeated elements are matched by position. Unmodeled content kept
+// by lossless decoding is not compared.
+func Diff(a, b *schema.Invoice) []Change {
+	var changes []Change
+	diffValue(&changes, "Invoice", reflect.TypeOf(*a), reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem())
+	return changes
+}
+
+// diffValue appends the changes between a and b, values of type t, to
+// changes. An invalid value stands for an absent element.
+func diffValue(changes *[]Change, path string, t reflect.Type, a, b reflect.Value) {
+	switch {
+	case t == dateType || t == bytesType ||
+		t.Kind() != reflect.Struct && t.Kind() != reflect.Pointer && t.Kind() != reflect.Slice:
+		diffLeaf(changes, path, t, a, b)
+
+	case t.Kind() == reflect.Pointer:
+		if isAbsent(a) && isAbsent(b) {
+			return
+		}
+		diffValue(changes, path, t.Elem(), elem(a), elem(b))
+
+	case t.Kind() == reflect.Slice && t.Elem() == invoiceLineType:
+		diffLines(changes, path, a, b)
+
+	case t.Kind() == reflect.Slice:
+		for i := range max(length(a), length(b)) {
+			diffValue(changes, fmt.Sprintf("%s[%d]", path, i), t.Elem(), index(a, i), index(b, i))
+		}
+
+	default:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.Type == unknownType || f.Type == xmlNameType || !f.IsExported() {
+				continue
+			}
+			diffValue(changes, fieldPath(path, f), f.Type, field(a, i), field(b, i))
+		}
+	}
+}
+
+// diffLines matches invoice lines by ID, in order among lines with the
+// same ID.
+func diffLines(changes *[]Change, path string, a, b reflect.Value) {
+	unmatched := make(map[string][]int)
+	for i := range length(a) {
+		id := a.Index(i).Interface().(schema.InvoiceLine).ID
+		unmatched[id] = append(unmatched[id], i)
+	}
+	for j := range length(b) {
+		id := b.Index(j).Interface().(schema.InvoiceLine).ID
+		var old reflect.Value
+		if is := unmatched[id]; len(is) > 0 {
+			old = a.Index(is[0])
+			unmatched[id] = is[1:]
+		}
+		diffValue(changes, fmt.Sprintf("%s[%d]", path, j), invoiceLineType, old, b.Index(j))
+	}
+	for i := range length(a) {
+		id := a.Index(i).Interface().(schema.InvoiceLine).ID
+		if is := unmatched[id]; len(is) > 0 && is[0] == i {
+			diffValue(changes, path+lineSelector(id), invoiceLineType, a.Index(i), reflect.Value{})
+			unmatched[id] = is[1:]
+		}
+	}
+}
+
+// lineSelector selects a removed line by ID in query notation, as its
+// index in the old document may name another line in the new one.
+func lineSelector(id string) string {
+	if strings.Contains(id, "'") {
+		return `[?ID=="` + id + `"]`
+	}
+	return "[?ID=='" + id + "']"
+}
+
+// diffLeaf compares two values of type t written as XML text.
+func diffLeaf(changes *[]Change, path string, t reflect.Type, a, b reflect.Value) {
+	x, y := leafText(a), leafText(b)
+	switch {
+	case x == y:
+		return
+	case x == "":
+		*changes = append(*changes, Change{Field: path, Kind: ChangeAdded, New: y})
+	case y == "":
+		*changes = append(*changes, Change{Field: path, Kind: ChangeRemoved, Old: x})
+	default:
+		if t == decimalType && equalDecimals(x, y) {
+			return
+		}
+		*changes = append(*changes, Change{Field: path, Kind: ChangeModified, Old: x, New: y})
+	}
+}
+
+// leafText returns a value as written in XML. Absent values and empty
+// strings are "", numbers always have their value, 0 included.
+func leafText(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return ""
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Slice:
+		return strings.TrimSpace(string(v.Bytes()))
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// equalDecimals reports whether two decimals have the same value.
+func equalDecimals(a, b string) bool {
+	x, errX := ratFromDecimal(types.Decimal(a), "")
+	y, errY := ratFromDecimal(types.Decimal(b), "")
+	return errX == nil && errY == nil && x.Cmp(y) == 0
+}
+
+// fieldPath returns the path of a struct field: attributes as @name, the
+// character data as the element itself.
+func fieldPath(path string, f reflect.StructField) string {
+	name, opts, _ := strings.Cut(f.Tag.Get("xml"), ",")
+	switch {
+	case opts == "chardata":
+		return path
+	case strings.HasPrefix(opts, "attr"):
+		return path + ".@" + name
+	default:
+		return path + "." + f.Name
+	}
+}
+
+// isAbsent reports whether the pointer v is absent or nil.
+func isAbsent(v reflect.Value) bool {
+	return !v.IsValid() || v.IsNil()
+}
+
+// elem returns the element the pointer v points to, or an invalid value if
+// there is none.
+func elem(v reflect.Value) reflect.Value {
+	if isAbsent(v) {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+// field returns the i-th field of the struct v, or an invalid value if v is
+// absent.
+func field(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+	return v.Field(i)
+}
+
+// length returns the length of the slice v, 0 if v is absent.
+func length(v reflect.Value) int {
+	if !v.IsValid() {
+		return 0
+	}
+	return v.Len()
+}
+
+// index returns the i-th element of the slice v, or an invalid value if
+// there is none.
+func index(v reflect.Value, i int) reflect.Value {
+	if i >= length(v) {
+		return reflect.Value{}
+	}
+	return v.Index(i)
+}
@@ -0,0 +1,118 @@
+package isdoc
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestDiffIdentical(t *testing.T) {
+	data, err := os.ReadFile("testdata/fixtures/multi-partytax.isdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes() error = %v", err)
+	}
+	b, _ := DecodeBytes(data)
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Errorf("Diff() of the same document = %v", changes)
+	}
+
+	b = clone(a)
+	b.LegalMonetaryTotal.PayableAmount += "00"
+	if changes := Diff(a, b); len(changes) != 0 {
+		t.Errorf("Diff() of equal decimals = %v", changes)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := createValidInvoice()
+	second := a.InvoiceLines.InvoiceLine[0]
+	second.ID = "2"
+	second.Item.Description = "Second Item"
+	a.InvoiceLines.InvoiceLine = append(a.InvoiceLines.InvoiceLine, second)
+
+	b := clone(a)
+	b.DocumentType = types.DocumentTypeCreditNote
+	b.VATApplicable = false
+	b.Note = &schema.Note{Value: "Opravená faktura", LanguageID: "cs"}
+	b.AccountingCustomerParty.Party.PostalAddress.StreetName = ""
+	// Lines reordered, line 1 repriced, line 2 removed and line 3 added
+	third := b.InvoiceLines.InvoiceLine[1]
+	third.ID = "3"
+	third.Item.Description = ""
+	first := b.InvoiceLines.InvoiceLine[0]
+	first.UnitPrice = "900"
+	b.InvoiceLines.InvoiceLine = []schema.InvoiceLine{third, first}
+
+	changes := Diff(a, b)
+	want := []Change{
+		{Field: "Invoice.DocumentType", Kind: ChangeModified, Old: "1", New: "2"},
+		{Field: "Invoice.VATApplicable", Kind: ChangeModified, Old: "true", New: "false"},
+		{Field: "Invoice.Note", Kind: ChangeAdded, New: "Opravená faktura"},
+		{Field: "Invoice.Note.@languageID", Kind: ChangeAdded, New: "cs"},
+		{Field: "Invoice.AccountingCustomerParty.Party.PostalAddress.StreetName", Kind: ChangeRemoved, Old: "Second Street"},
+		{Field: "Invoice.InvoiceLines.InvoiceLine[0].ID", Kind: ChangeAdded, New: "3"},
+		{Field: "Invoice.InvoiceLines.InvoiceLine[1].UnitPrice", Kind: ChangeModified, Old: "1000.00", New: "900"},
+		{Field: "Invoice.InvoiceLines.InvoiceLine[?ID=='2'].ID", Kind: ChangeRemoved, Old: "2"},
+		{Field: "Invoice.InvoiceLines.InvoiceLine[?ID=='2'].Item.Description", Kind: ChangeRemoved, Old: "Second Item"},
+	}
+	for _, c := range want {
+		if !slices.Contains(changes, c) {
+			t.Errorf("missing change %v", c)
+		}
+	}
+	// Removed lines are selected by ID in the old document
+	q, err := ParseQuery("Invoice.InvoiceLines.InvoiceLine[?ID=='2'].Item.Description")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	if got := q.Select(a); len(got) != 1 || got[0].Value != "Second Item" {
+		t.Errorf("Select() of a removed line = %v", got)
+	}
+	// The added line 3 carries the amounts of line 2
+	if i := slices.IndexFunc(changes, func(c Change) bool {
+		return c.Field == "Invoice.InvoiceLines.InvoiceLine[0].UnitPrice"
+	}); i < 0 || changes[i].Kind != ChangeAdded {
+		t.Errorf("added line not reported field by field: %v", changes)
+	}
+	if n := len(changes); n > 30 {
+		t.Errorf("Diff() reported %d changes: %v", n, changes)
+	}
+}
+
+func TestDiffZeroValue(t *testing.T) {
+	a := createValidInvoice()
+	a.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod = types.VATCalculationFromBottom
+	b := clone(a)
+	b.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod = types.VATCalculationFromTop
+
+	field := "Invoice.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod"
+	if got, want := Diff(a, b), []Change{{Field: field, Kind: ChangeModified, Old: "0", New: "1"}}; !slices.Equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got, want := Diff(b, a), []Change{{Field: field, Kind: ChangeModified, Old: "1", New: "0"}}; !slices.Equal(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	tests := []struct {
+		change Change
+		want   string
+	}{
+		{Change{Field: "Invoice.ID", Kind: ChangeModified, Old: "1", New: "2"}, "~ Invoice.ID: 1 -> 2"},
+		{Change{Field: "Invoice.Note", Kind: ChangeAdded, New: "x"}, "+ Invoice.Note: x"},
+		{Change{Field: "Invoice.Note", Kind: ChangeRemoved, Old: "x"}, "- Invoice.Note: x"},
+	}
+	for _, tc := range tests {
+		if got := tc.change.String(); got != tc.want {
+			t.Errorf("String() = %q, want %q", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package isdoc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/codelist"
+	"github.com/xseman/isdoc/schema"
+)
+
+// validICO reports whether s is a Czech company identification number
+// (IČO): eight digits, the last one the check digit of the others.
+func validICO(s string) bool {
+	return len(s) == 8 && allDigits(s) && s[7]-'0' == icoCheckDigit(s)
+}
+
+// icoCheckDigit returns the check digit of the first seven digits of s:
+// the digits weighted 8 to 2 are summed and the sum subtracted from 11,
+// modulo 10.
+func icoCheckDigit(s string) byte {
+	sum := 0
+	for i := range 7 {
+		sum += int(s[i]-'0') * (8 - i)
+	}
+	return byte((11 - sum%11) % 10)
+}
+
+// validDIC reports whether s is a Czech VAT number (DIČ): CZ followed by the
+// IČO of a legal entity, by the nine digits of a birth number issued before
+// 1954 or of a special number, or by a ten-digit birth number divisible by
+// 11, or whose first nine digits leave 10 and which ends with 0. Nine-digit
+// numbers have no check digit; their date and special number ranges are not
+// checked.
+func validDIC(s string) bool {
+	digits, ok := strings.CutPrefix(strings.ReplaceAll(s, " ", ""), "CZ")
+	if !ok || !allDigits(digits) {
+		return false
+	}
+	switch len(digits) {
+	case 8:
+		return validICO(digits)
+	case 9:
+		return true
+	case 10:
+		n, _ := strconv.ParseUint(digits, 10, 64)
+		return n%11 == 0 || n/10%11 == 10 && n%10 == 0
+	}
+	return false
+}
+
+func allDigits(s string) bool {
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// validatePartyIdentifiers checks the IČO of a Czech party and any Czech
+// DIČ of a party against their check digits.
+func validatePartyIdentifiers(path string, party *schema.Party, severity Severity) ValidationErrors {
+	var errs ValidationErrors
+
+	if id := party.PartyIdentification.ID; id != "" && party.PostalAddress.Country.IdentificationCode == "CZ" && !validICO(id) {
+		errs = append(errs, &ValidationError{
+			Field:    path + ".PartyIdentification.ID",
+			Code:     ErrCodeInvalidPattern,
+			Severity: severity,
+			Msg:      fmt.Sprintf("%q is not a valid IČO: expected 8 digits with a valid check digit", id),
+		})
+	}
+
+	for i, s := range party.PartyTaxScheme {
+		if !strings.HasPrefix(s.CompanyID, "CZ") || validDIC(s.CompanyID) {
+			continue
+		}
+		errs = append(errs, &ValidationError{
+			Field:    fmt.Sprintf("%s.PartyTaxScheme[%d].CompanyID", path, i),
+			Code:     ErrCodeInvalidPattern,
+			Severity: severity,
+			Msg:      fmt.Sprintf("%q is not a valid Czech DIČ: expected CZ and an IČO or a birth number", s.CompanyID),
+		})
+	}
+
+	return errs
+}
+
+// languagePattern is the pattern of xs:language.
+var languagePattern = regexp.MustCompile(`^[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*$`)
+
+// validateDocumentContent checks the note and the supplements common to
+// Invoice and CommonDocument.
+func validateDocumentContent(root string, note *schema.Note, list *schema.SupplementsList, opts ValidateOptions) ValidationErrors {
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+	errs := validateNote(root+".Note", note, severity)
+	return append(errs, validateSupplements(root+".SupplementsList", list, severity)...)
+}
+
+// validateNote checks that the languageID of a note is a language tag
+// whose two-letter primary subtag is an ISO 639-1 code.
+func validateNote(path string, note *schema.Note, severity Severity) ValidationErrors {
+	if note == nil || note.LanguageID == "" {
+		return nil
+	}
+	field := path + ".@languageID"
+	if !languagePattern.MatchString(note.LanguageID) {
+		return ValidationErrors{{
+			Field:    field,
+			Code:     ErrCodeInvalidPattern,
+			Severity: severity,
+			Msg:      fmt.Sprintf("languageID %q is not a language tag such as \"cs\" or \"cs-CZ\"", note.LanguageID),
+		}}
+	}
+	primary, _, _ := strings.Cut(note.LanguageID, "-")
+	if len(primary) == 2 {
+		if _, ok := codelist.LookupLanguage(strings.ToLower(primary)); !ok {
+			return ValidationErrors{unknownCode(field, "ISO 639-1 language", primary, severity)}
+		}
+	}
+	return nil
+}
+
+// validateSupplements checks that each supplement names a file and that no
+// file is listed twice.
+func validateSupplements(path string, list *schema.SupplementsList, severity Severity) ValidationErrors {
+	if list == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	seen := make(map[string]int)
+	for i, s := range list.Supplement {
+		field := fmt.Sprintf("%s.Supplement[%d].Filename", path, i)
+		if s.Filename == "" {
+			errs = append(errs, &ValidationError{
+				Field:    field,
+				Code:     ErrCodeRequiredField,
+				Severity: severity,
+				Msg:      "Filename is required",
+			})
+			continue
+		}
+		if j, ok := seen[s.Filename]; ok {
+			errs = append(errs, &ValidationError{
+				Field:    field,
+				Code:     ErrCodeDuplicateID,
+				Severity: severity,
+				Msg:      fmt.Sprintf("supplement %q is already listed as Supplement[%d]", s.Filename, j),
+			})
+			continue
+		}
+		seen[s.Filename] = i
+	}
+	return errs
+}
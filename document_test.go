@@ -0,0 +1,68 @@
+package isdoc
+
+import (
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+func TestValidICO(t *testing.T) {
+	for _, id := range []string{"25097563", "00006947", "12345679", "27082440"} {
+		if !validICO(id) {
+			t.Errorf("validICO(%q) = false", id)
+		}
+	}
+	for _, id := range []string{"", "12345678", "2509756", "250975630", "2509756X", "CZ25097563"} {
+		if validICO(id) {
+			t.Errorf("validICO(%q) = true", id)
+		}
+	}
+}
+
+func TestValidDIC(t *testing.T) {
+	for _, dic := range []string{"CZ25097563", "CZ 00006947", "CZ530101123", "CZ7103192745", "CZ6952020010"} {
+		if !validDIC(dic) {
+			t.Errorf("validDIC(%q) = false", dic)
+		}
+	}
+	for _, dic := range []string{"", "25097563", "SK25097563", "CZ12345678", "CZ7103192746", "CZ1234567", "CZ12345678901"} {
+		if validDIC(dic) {
+			t.Errorf("validDIC(%q) = true", dic)
+		}
+	}
+}
+
+func TestValidateNote(t *testing.T) {
+	tests := []struct {
+		languageID string
+		code       string
+	}{
+		{"", ""},
+		{"cs", ""},
+		{"cs-CZ", ""},
+		{"EN", ""},
+		{"ces", ""},
+		{"cz", ErrCodeInvalidEnum},
+		{"cs_CZ", ErrCodeInvalidPattern},
+		{"čeština", ErrCodeInvalidPattern},
+	}
+	for _, tc := range tests {
+		errs := validateNote("Invoice.Note", &schema.Note{Value: "Poznámka", LanguageID: tc.languageID}, SeverityWarning)
+		if tc.code == "" && len(errs) > 0 || tc.code != "" && !hasIssue(errs, "Invoice.Note.@languageID", tc.code) {
+			t.Errorf("validateNote(%q) = %v, want %q", tc.languageID, errs, tc.code)
+		}
+	}
+}
+
+func TestValidateInvoiceSupplements(t *testing.T) {
+	inv := createValidInvoice()
+	inv.SupplementsList = &schema.SupplementsList{Supplement: []schema.Supplement{
+		{Filename: "faktura.pdf"}, {Filename: ""}, {Filename: "priloha.pdf"}, {Filename: "faktura.pdf"},
+	}}
+	errs := ValidateInvoice(inv)
+	if !hasIssue(errs, "Invoice.SupplementsList.Supplement[1].Filename", ErrCodeRequiredField) ||
+		!hasIssue(errs, "Invoice.SupplementsList.Supplement[3].Filename", ErrCodeDuplicateID) ||
+		hasIssue(errs, "Invoice.SupplementsList.Supplement[2].Filename", ErrCodeDuplicateID) {
+		t.Errorf("ValidateInvoice() = %v", errs)
+	}
+}
@@ -0,0 +1,452 @@
+// Package dph aggregates ISDOC invoices into the Czech VAT control
+// statement (kontrolní hlášení, DPHKH1) and VAT return (přiznání k DPH,
+// DPHDP3) and writes both in the EPO XML format of the tax portal.
+//
+// An Aggregator is created for the DIČ of the filing taxpayer. Each invoice
+// added is classified as issued or received by comparing the DIČs of its
+// parties with it:
+//
+//	agg := dph.NewAggregator("CZ12345678")
+//	for _, inv := range invoices {
+//	    if err := agg.Add(inv); err != nil {
+//	        log.Printf("%s: %v", inv.ID, err)
+//	    }
+//	}
+//	data, err := agg.ControlStatement().MarshalEPO(filing)
+//
+// Bases and tax are taken per rate from TaxTotal.TaxSubTotal in the local
+// currency, using the difference amounts so that final invoices settling
+// advance payments report only what was not claimed before. Issued
+// invoices go to section A.4 when the buyer has a Czech DIČ and the
+// document exceeds Threshold including VAT, and to A.5 otherwise; received
+// invoices go to B.2 and B.3 alike. Lines with a LocalReverseCharge code
+// are reported per code in A.1 (issued) or B.1 (received), with the tax of
+// received lines computed from their rate. Subtotals flagged with
+// LocalReverseChargeFlag are left out of the other sections.
+//
+// Advance invoices (DocumentType 4) are not tax documents and invoices
+// without VAT are not reported; Add ignores both. Zero rate subtotals are
+// ignored as well.
+package dph
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// Threshold is the amount including VAT in CZK above which a tax document
+// for a VAT payer is reported individually in sections A.4 and B.2.
+const Threshold = 10000
+
+// Errors returned by Aggregator.Add.
+var (
+	// ErrNotParty is returned for invoices whose seller and buyer both
+	// have a DIČ other than the taxpayer's.
+	ErrNotParty = errors.New("invoice is neither issued nor received by the taxpayer")
+	// ErrNotReportable is returned for invoices that cannot be placed in
+	// the control statement, such as those in another local currency.
+	ErrNotReportable = errors.New("invoice cannot be reported")
+)
+
+// Amounts holds bases and tax for the three rate columns of the control
+// statement: the basic rate, the first and the second reduced rate.
+type Amounts struct {
+	Base1, Tax1 types.Decimal
+	Base2, Tax2 types.Decimal
+	Base3, Tax3 types.Decimal
+}
+
+// Record is a row of section A.1, A.4, B.1 or B.2.
+type Record struct {
+	// DIC is the DIČ of the other party without the CZ prefix.
+	DIC string
+	// DocumentID is the number of the tax document, the invoice ID.
+	DocumentID string
+	// TaxPointDate is the date of the taxable supply.
+	TaxPointDate types.Date
+	// ReverseChargeCode is the code of the supply in sections A.1 and B.1.
+	// Section A.1 has no tax and uses Base1 for the whole base.
+	ReverseChargeCode string
+	Amounts
+}
+
+// ControlStatement is the content of a VAT control statement.
+type ControlStatement struct {
+	// DIC is the taxpayer's DIČ without the CZ prefix.
+	DIC string
+
+	A1 []Record
+	A4 []Record
+	A5 Amounts
+	B1 []Record
+	B2 []Record
+	B3 Amounts
+}
+
+// VATReturn holds the lines of the VAT return filled from the invoices,
+// in whole CZK. Fields are named after the line numbers of the form.
+type VATReturn struct {
+	// DIC is the taxpayer's DIČ without the CZ prefix.
+	DIC string
+
+	// Lines 1 and 2: taxable supplies at the basic and reduced rates.
+	Line1Base, Line1Tax int64
+	Line2Base, Line2Tax int64
+	// Lines 10 and 11: supplies received under local reverse charge.
+	Line10Base, Line10Tax int64
+	Line11Base, Line11Tax int64
+	// Line 25: supplies made under local reverse charge.
+	Line25 int64
+	// Lines 40 and 41: supplies received from domestic VAT payers.
+	Line40Base, Line40Tax int64
+	Line41Base, Line41Tax int64
+	// Lines 43 and 44: deduction for supplies received under local reverse
+	// charge, assuming full entitlement.
+	Line43Base, Line43Tax int64
+	Line44Base, Line44Tax int64
+	// Line 46: total deduction.
+	Line46 int64
+	// Lines 62 to 65: output tax, deduction, tax liability and excess
+	// deduction.
+	Line62, Line63, Line64, Line65 int64
+}
+
+// sums accumulates bases and tax per rate column.
+type sums struct {
+	base, tax [3]big.Rat
+}
+
+func (s *sums) add(col int, base, tax *big.Rat) {
+	s.base[col].Add(&s.base[col], base)
+	s.tax[col].Add(&s.tax[col], tax)
+}
+
+func (s *sums) total() *big.Rat {
+	t := new(big.Rat)
+	for i := range s.base {
+		t.Add(t, &s.base[i])
+		t.Add(t, &s.tax[i])
+	}
+	return t
+}
+
+func (s *sums) amounts() Amounts {
+	f := func(r *big.Rat) types.Decimal {
+		if r.Sign() == 0 {
+			return ""
+		}
+		return en16931.FormatAmount(r)
+	}
+	return Amounts{
+		Base1: f(&s.base[0]), Tax1: f(&s.tax[0]),
+		Base2: f(&s.base[1]), Tax2: f(&s.tax[1]),
+		Base3: f(&s.base[2]), Tax3: f(&s.tax[2]),
+	}
+}
+
+type record struct {
+	dic, id, code string
+	date          types.Date
+	sums
+}
+
+// Aggregator collects invoices of one taxpayer and tax period.
+type Aggregator struct {
+	dic          string
+	a1, a4       []*record
+	b1, b2       []*record
+	a5, b3       sums
+	outputOther  sums // issued, not in A.1
+	inputRegular sums // received, not in B.1
+}
+
+// NewAggregator returns an Aggregator for the taxpayer with the given DIČ,
+// with or without the CZ prefix.
+func NewAggregator(dic string) *Aggregator {
+	return &Aggregator{dic: normalizeDIC(dic)}
+}
+
+// normalizeDIC returns a Czech DIČ without the CZ prefix and spaces, or ""
+// for other identifiers.
+func normalizeDIC(id string) string {
+	id = strings.ToUpper(strings.ReplaceAll(id, " ", ""))
+	if s, ok := strings.CutPrefix(id, "CZ"); ok {
+		return s
+	}
+	if id != "" && id[0] >= '0' && id[0] <= '9' {
+		return id
+	}
+	return ""
+}
+
+// partyDIC returns the Czech DIČ of a party without the CZ prefix.
+func partyDIC(p *schema.Party) string {
+	for _, s := range p.PartyTaxScheme {
+		if s.TaxScheme == "VAT" {
+			if dic := normalizeDIC(s.CompanyID); dic != "" {
+				return dic
+			}
+		}
+	}
+	return ""
+}
+
+// column returns the control statement column of a VAT rate in force since
+// the control statement was introduced in 2016: 0 for the basic rate, 1 for
+// the first reduced rate (15 %, 12 % since 2024) and 2 for the second
+// (10 % until 2023).
+func column(percent types.Decimal) (int, bool) {
+	switch en16931.ParseRat(percent).RatString() {
+	case "21":
+		return 0, true
+	case "15", "12":
+		return 1, true
+	case "10":
+		return 2, true
+	}
+	return 0, false
+}
+
+// Add classifies inv and adds its amounts to the control statement and
+// VAT return. Advance invoices and invoices without VAT are ignored.
+func (a *Aggregator) Add(inv *schema.Invoice) error {
+	if inv.DocumentType == types.DocumentTypeAdvanceInvoice || !inv.VATApplicable.Bool() {
+		return nil
+	}
+	if inv.LocalCurrencyCode != "CZK" {
+		return fmt.Errorf("%w: local currency is %s, not CZK", ErrNotReportable, inv.LocalCurrencyCode)
+	}
+
+	seller := partyDIC(&inv.AccountingSupplierParty.Party)
+	var buyer string
+	if inv.AccountingCustomerParty != nil {
+		buyer = partyDIC(&inv.AccountingCustomerParty.Party)
+	}
+	var issued bool
+	switch a.dic {
+	case seller:
+		issued = true
+	case buyer:
+	default:
+		return ErrNotParty
+	}
+
+	var regular sums
+	reverseCharge := false
+	for i, st := range inv.TaxTotal.TaxSubTotal {
+		if st.TaxCategory.LocalReverseChargeFlag.Bool() {
+			reverseCharge = true
+			continue
+		}
+		base, tax := st.DifferenceTaxableAmount, st.DifferenceTaxAmount
+		if base == "" {
+			base, tax = st.TaxableAmount, st.TaxAmount
+		}
+		if en16931.ParseRat(st.TaxCategory.Percent).Sign() == 0 {
+			continue
+		}
+		col, ok := column(st.TaxCategory.Percent)
+		if !ok {
+			return fmt.Errorf("%w: TaxSubTotal[%d] has unknown rate %s", ErrNotReportable, i, st.TaxCategory.Percent)
+		}
+		regular.add(col, en16931.ParseRat(base), en16931.ParseRat(tax))
+	}
+
+	rc, err := reverseChargeRecords(inv, issued)
+	if err != nil {
+		return err
+	}
+	if reverseCharge && len(rc) == 0 {
+		return fmt.Errorf("%w: reverse charge without LocalReverseChargeCode on lines", ErrNotReportable)
+	}
+
+	if issued {
+		a.a1 = append(a.a1, rc...)
+		addSums(&a.outputOther, &regular)
+		if buyer != "" && inv.DocumentType != types.DocumentTypeSimplified && exceeds(&regular) {
+			a.a4 = append(a.a4, &record{dic: buyer, id: inv.ID, date: taxPointDate(inv), sums: regular})
+		} else {
+			addSums(&a.a5, &regular)
+		}
+		return nil
+	}
+
+	if seller == "" && !isZero(&regular) {
+		return fmt.Errorf("%w: VAT charged by a supplier without Czech DIČ", ErrNotReportable)
+	}
+	a.b1 = append(a.b1, rc...)
+	addSums(&a.inputRegular, &regular)
+	if inv.DocumentType != types.DocumentTypeSimplified && exceeds(&regular) {
+		a.b2 = append(a.b2, &record{dic: seller, id: inv.ID, date: taxPointDate(inv), sums: regular})
+	} else {
+		addSums(&a.b3, &regular)
+	}
+	return nil
+}
+
+var zero = new(big.Rat)
+
+func addSums(dst, src *sums) {
+	for i := range src.base {
+		dst.add(i, &src.base[i], &src.tax[i])
+	}
+}
+
+func isZero(s *sums) bool {
+	for i := range s.base {
+		if s.base[i].Sign() != 0 || s.tax[i].Sign() != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// exceeds reports whether the amount including VAT is above Threshold.
+// Corrections are compared by their absolute value.
+func exceeds(s *sums) bool {
+	t := s.total()
+	return t.Abs(t).Cmp(big.NewRat(Threshold, 1)) > 0
+}
+
+// taxPointDate returns the date of the taxable supply, defaulting to the
+// issue date.
+func taxPointDate(inv *schema.Invoice) types.Date {
+	if !inv.TaxPointDate.IsZero() {
+		return inv.TaxPointDate
+	}
+	return inv.IssueDate
+}
+
+// reverseChargeRecords returns the A.1 or B.1 records of the lines with a
+// LocalReverseCharge code, one per code.
+func reverseChargeRecords(inv *schema.Invoice, issued bool) ([]*record, error) {
+	dic := partyDIC(&inv.AccountingSupplierParty.Party)
+	if issued {
+		dic = ""
+		if inv.AccountingCustomerParty != nil {
+			dic = partyDIC(&inv.AccountingCustomerParty.Party)
+		}
+	}
+
+	var records []*record
+	for i, l := range inv.InvoiceLines.InvoiceLine {
+		rc := l.ClassifiedTaxCategory.LocalReverseCharge
+		if rc == nil || rc.LocalReverseChargeCode == "" {
+			continue
+		}
+		if dic == "" {
+			return nil, fmt.Errorf("%w: reverse charge line %d for a party without Czech DIČ", ErrNotReportable, i)
+		}
+		if _, ok := isdoc.LookupReverseChargeCode(rc.LocalReverseChargeCode); !ok {
+			return nil, fmt.Errorf("%w: line %d has unknown LocalReverseChargeCode %q", ErrNotReportable, i, rc.LocalReverseChargeCode)
+		}
+		idx := slices.IndexFunc(records, func(r *record) bool { return r.code == rc.LocalReverseChargeCode })
+		if idx < 0 {
+			records = append(records, &record{dic: dic, id: inv.ID, code: rc.LocalReverseChargeCode, date: taxPointDate(inv)})
+			idx = len(records) - 1
+		}
+
+		base := en16931.ParseRat(l.LineExtensionAmount)
+		if issued {
+			records[idx].add(0, base, zero)
+			continue
+		}
+		col, ok := column(l.ClassifiedTaxCategory.Percent)
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d has unknown reverse charge rate %s",
+				ErrNotReportable, i, l.ClassifiedTaxCategory.Percent)
+		}
+		tax := new(big.Rat).Mul(base, en16931.ParseRat(l.ClassifiedTaxCategory.Percent))
+		tax.Quo(tax, big.NewRat(100, 1))
+		records[idx].add(col, base, en16931.ParseRat(en16931.FormatAmount(tax)))
+	}
+	return records, nil
+}
+
+func records(rs []*record) []Record {
+	out := make([]Record, len(rs))
+	for i, r := range rs {
+		out[i] = Record{
+			DIC:               r.dic,
+			DocumentID:        r.id,
+			TaxPointDate:      r.date,
+			ReverseChargeCode: r.code,
+			Amounts:           r.amounts(),
+		}
+	}
+	return out
+}
+
+// ControlStatement returns the control statement of the invoices added.
+func (a *Aggregator) ControlStatement() *ControlStatement {
+	return &ControlStatement{
+		DIC: a.dic,
+		A1:  records(a.a1),
+		A4:  records(a.a4),
+		A5:  a.a5.amounts(),
+		B1:  records(a.b1),
+		B2:  records(a.b2),
+		B3:  a.b3.amounts(),
+	}
+}
+
+// VATReturn returns the VAT return lines of the invoices added.
+func (a *Aggregator) VATReturn() *VATReturn {
+	var received, supplied sums
+	for _, r := range a.b1 {
+		addSums(&received, &r.sums)
+	}
+	for _, r := range a.a1 {
+		addSums(&supplied, &r.sums)
+	}
+	reduced := func(s *sums, tax bool) *big.Rat {
+		v := &s.base
+		if tax {
+			v = &s.tax
+		}
+		return new(big.Rat).Add(&v[1], &v[2])
+	}
+
+	r := &VATReturn{
+		DIC:        a.dic,
+		Line1Base:  crowns(&a.outputOther.base[0]),
+		Line1Tax:   crowns(&a.outputOther.tax[0]),
+		Line2Base:  crowns(reduced(&a.outputOther, false)),
+		Line2Tax:   crowns(reduced(&a.outputOther, true)),
+		Line10Base: crowns(&received.base[0]),
+		Line10Tax:  crowns(&received.tax[0]),
+		Line11Base: crowns(reduced(&received, false)),
+		Line11Tax:  crowns(reduced(&received, true)),
+		Line25:     crowns(supplied.total()),
+		Line40Base: crowns(&a.inputRegular.base[0]),
+		Line40Tax:  crowns(&a.inputRegular.tax[0]),
+		Line41Base: crowns(reduced(&a.inputRegular, false)),
+		Line41Tax:  crowns(reduced(&a.inputRegular, true)),
+	}
+	r.Line43Base, r.Line43Tax = r.Line10Base, r.Line10Tax
+	r.Line44Base, r.Line44Tax = r.Line11Base, r.Line11Tax
+	r.Line46 = r.Line40Tax + r.Line41Tax + r.Line43Tax + r.Line44Tax
+	r.Line62 = r.Line1Tax + r.Line2Tax + r.Line10Tax + r.Line11Tax
+	r.Line63 = r.Line46
+	if d := r.Line62 - r.Line63; d >= 0 {
+		r.Line64 = d
+	} else {
+		r.Line65 = -d
+	}
+	return r
+}
+
+// crowns rounds r to whole CZK, halves away from zero.
+func crowns(r *big.Rat) int64 {
+	n, _ := strconv.ParseInt(r.FloatString(0), 10, 64)
+	return n
+}
@@ -0,0 +1,255 @@
+package dph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+const (
+	self  = "CZ12345678"
+	other = "CZ87654321"
+)
+
+// invoice returns a tax document from seller to buyer with one 21 %
+// subtotal.
+func invoice(id, seller, buyer string, base, tax types.Decimal) *schema.Invoice {
+	party := func(dic string) schema.Party {
+		if dic == "" {
+			return schema.Party{}
+		}
+		return schema.Party{PartyTaxScheme: []schema.PartyTaxScheme{{CompanyID: dic, TaxScheme: "VAT"}}}
+	}
+	return &schema.Invoice{
+		DocumentType:            1,
+		ID:                      id,
+		IssueDate:               types.NewDate(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)),
+		VATApplicable:           true,
+		LocalCurrencyCode:       "CZK",
+		AccountingSupplierParty: schema.AccountingSupplierParty{Party: party(seller)},
+		AccountingCustomerParty: &schema.AccountingCustomerParty{Party: party(buyer)},
+		TaxTotal: schema.TaxTotal{TaxSubTotal: []schema.TaxSubTotal{{
+			TaxableAmount: base,
+			TaxAmount:     tax,
+			TaxCategory:   schema.TaxCategory{Percent: "21"},
+		}}},
+	}
+}
+
+func add(t *testing.T, a *Aggregator, invs ...*schema.Invoice) {
+	t.Helper()
+	for _, inv := range invs {
+		if err := a.Add(inv); err != nil {
+			t.Fatalf("Add(%s) error = %v", inv.ID, err)
+		}
+	}
+}
+
+func TestControlStatementSections(t *testing.T) {
+	a := NewAggregator(self)
+	add(t, a,
+		invoice("FV-1", self, other, "10000.00", "2100.00"),
+		invoice("FV-2", self, other, "1000.00", "210.00"),
+		invoice("FV-3", self, "", "20000.00", "4200.00"),
+		invoice("FP-1", other, self, "50000.00", "10500.00"),
+		invoice("FP-2", other, self, "100.00", "21.00"),
+	)
+	cs := a.ControlStatement()
+
+	if cs.DIC != "12345678" {
+		t.Errorf("DIC = %q", cs.DIC)
+	}
+	if len(cs.A4) != 1 || cs.A4[0].DocumentID != "FV-1" || cs.A4[0].DIC != "87654321" ||
+		cs.A4[0].Base1 != "10000.00" || cs.A4[0].Tax1 != "2100.00" {
+		t.Errorf("A4 = %+v", cs.A4)
+	}
+	if cs.A5.Base1 != "21000.00" || cs.A5.Tax1 != "4410.00" {
+		t.Errorf("A5 = %+v, want FV-2 and FV-3", cs.A5)
+	}
+	if len(cs.B2) != 1 || cs.B2[0].DocumentID != "FP-1" || cs.B2[0].DIC != "87654321" {
+		t.Errorf("B2 = %+v", cs.B2)
+	}
+	if cs.B3.Base1 != "100.00" || cs.B3.Tax1 != "21.00" {
+		t.Errorf("B3 = %+v", cs.B3)
+	}
+	if got := cs.A4[0].TaxPointDate.String(); got != "2024-03-05" {
+		t.Errorf("TaxPointDate = %s, want issue date", got)
+	}
+}
+
+func TestDifferenceAmounts(t *testing.T) {
+	inv := invoice("FV-1", self, other, "20000.00", "4200.00")
+	st := &inv.TaxTotal.TaxSubTotal[0]
+	st.DifferenceTaxableAmount, st.DifferenceTaxAmount = "5000.00", "1050.00"
+
+	a := NewAggregator(self)
+	add(t, a, inv)
+	cs := a.ControlStatement()
+	if len(cs.A4) != 0 || cs.A5.Base1 != "5000.00" {
+		t.Errorf("A4 = %+v, A5 = %+v, want the difference in A5", cs.A4, cs.A5)
+	}
+}
+
+func TestReverseCharge(t *testing.T) {
+	rc := func(inv *schema.Invoice) *schema.Invoice {
+		inv.TaxTotal.TaxSubTotal[0].TaxCategory.LocalReverseChargeFlag = true
+		inv.InvoiceLines.InvoiceLine = []schema.InvoiceLine{
+			{LineExtensionAmount: "3000.00", ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
+				Percent: "21", LocalReverseCharge: &schema.LocalReverseCharge{LocalReverseChargeCode: "4"},
+			}},
+			{LineExtensionAmount: "1000.00", ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
+				Percent: "12", LocalReverseCharge: &schema.LocalReverseCharge{LocalReverseChargeCode: "4"},
+			}},
+		}
+		return inv
+	}
+
+	a := NewAggregator(self)
+	add(t, a,
+		rc(invoice("FV-1", self, other, "4000.00", "0.00")),
+		rc(invoice("FP-1", other, self, "4000.00", "0.00")),
+	)
+	cs := a.ControlStatement()
+
+	if len(cs.A1) != 1 || cs.A1[0].ReverseChargeCode != "4" || cs.A1[0].Base1 != "4000.00" || cs.A1[0].Tax1 != "" {
+		t.Errorf("A1 = %+v", cs.A1)
+	}
+	if len(cs.B1) != 1 {
+		t.Fatalf("B1 = %+v", cs.B1)
+	}
+	b1 := cs.B1[0]
+	if b1.Base1 != "3000.00" || b1.Tax1 != "630.00" || b1.Base2 != "1000.00" || b1.Tax2 != "120.00" {
+		t.Errorf("B1 = %+v", b1)
+	}
+	if cs.A5 != (Amounts{}) || cs.B3 != (Amounts{}) {
+		t.Errorf("A5 = %+v, B3 = %+v, want empty", cs.A5, cs.B3)
+	}
+
+	r := a.VATReturn()
+	if r.Line25 != 4000 || r.Line10Tax != 630 || r.Line11Tax != 120 || r.Line43Tax != 630 || r.Line44Tax != 120 {
+		t.Errorf("VATReturn() = %+v", r)
+	}
+	if r.Line64 != 0 || r.Line65 != 0 {
+		t.Errorf("reverse charge must be neutral, got %d/%d", r.Line64, r.Line65)
+	}
+}
+
+func TestAddIgnoredAndErrors(t *testing.T) {
+	a := NewAggregator(self)
+
+	advance := invoice("ZF-1", self, other, "100000.00", "21000.00")
+	advance.DocumentType = 4
+	noVAT := invoice("FV-0", self, other, "100000.00", "0.00")
+	noVAT.VATApplicable = false
+	add(t, a, advance, noVAT)
+	if cs := a.ControlStatement(); len(cs.A4) != 0 || cs.A5 != (Amounts{}) {
+		t.Errorf("ignored invoices reported: %+v", cs)
+	}
+
+	if err := a.Add(invoice("X", other, "CZ11111111", "1.00", "0.21")); !errors.Is(err, ErrNotParty) {
+		t.Errorf("Add() error = %v, want ErrNotParty", err)
+	}
+	eur := invoice("X", self, other, "1.00", "0.21")
+	eur.LocalCurrencyCode = "EUR"
+	if err := a.Add(eur); !errors.Is(err, ErrNotReportable) {
+		t.Errorf("Add() error = %v, want ErrNotReportable", err)
+	}
+	unknown := invoice("X", self, other, "1.00", "0.00")
+	unknown.InvoiceLines.InvoiceLine = []schema.InvoiceLine{{ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
+		Percent: "21", LocalReverseCharge: &schema.LocalReverseCharge{LocalReverseChargeCode: "99"},
+	}}}
+	if err := a.Add(unknown); !errors.Is(err, ErrNotReportable) {
+		t.Errorf("Add() error = %v, want ErrNotReportable", err)
+	}
+	rate := invoice("X", self, other, "1.00", "0.19")
+	rate.TaxTotal.TaxSubTotal[0].TaxCategory.Percent = "19"
+	if err := a.Add(rate); !errors.Is(err, ErrNotReportable) {
+		t.Errorf("Add() error = %v, want ErrNotReportable", err)
+	}
+}
+
+func TestVATReturn(t *testing.T) {
+	a := NewAggregator(self)
+	reduced := invoice("FV-2", self, other, "1000.00", "120.00")
+	reduced.TaxTotal.TaxSubTotal[0].TaxCategory.Percent = "12"
+	add(t, a,
+		invoice("FV-1", self, other, "20000.40", "4200.08"),
+		reduced,
+		invoice("FP-1", other, self, "50000.00", "10500.00"),
+	)
+	r := a.VATReturn()
+
+	want := VATReturn{
+		DIC:       "12345678",
+		Line1Base: 20000, Line1Tax: 4200,
+		Line2Base: 1000, Line2Tax: 120,
+		Line40Base: 50000, Line40Tax: 10500,
+		Line46: 10500,
+		Line62: 4320, Line63: 10500, Line65: 6180,
+	}
+	if *r != want {
+		t.Errorf("VATReturn() = %+v, want %+v", *r, want)
+	}
+}
+
+func TestMarshalEPO(t *testing.T) {
+	a := NewAggregator(self)
+	add(t, a,
+		invoice("FV-1", self, other, "10000.00", "2100.00"),
+		invoice("FP-1", other, self, "100.00", "21.00"),
+	)
+	f := Filing{
+		Year:            2024,
+		Month:           3,
+		Date:            time.Date(2024, 4, 20, 0, 0, 0, 0, time.UTC),
+		FinancialOffice: "451",
+		Workplace:       "2001",
+		Name:            "Seller s.r.o.",
+	}
+
+	data, err := a.ControlStatement().MarshalEPO(f)
+	if err != nil {
+		t.Fatalf("MarshalEPO() error = %v", err)
+	}
+	for _, want := range []string{
+		`<DPHKH1 verzePis="03.01">`,
+		`<VetaD dokument="KH1" k_uladis="DPH" khdph_forma="B" rok="2024" mesic="3" d_poddp="20.04.2024">`,
+		`dic="12345678" typ_ds="P" zkrobchjm="Seller s.r.o."`,
+		`<VetaA4 c_radku="1" dic_odb="87654321" c_evid_dd="FV-1" dppd="05.03.2024" zakl_dane1="10000.00" dan1="2100.00" kod_rezim_pl="0" zdph_44="N">`,
+		`<VetaB3 zakl_dane1="100.00" dan1="21.00">`,
+		`<VetaC obrat23="10000.00" pln23="100.00">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("control statement missing %s in\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "VetaA5") {
+		t.Errorf("empty section A.5 written:\n%s", data)
+	}
+
+	f.Activity = "620000"
+	data, err = a.VATReturn().MarshalEPO(f)
+	if err != nil {
+		t.Fatalf("MarshalEPO() error = %v", err)
+	}
+	for _, want := range []string{
+		`<DPHDP3 verzePis="01.02">`,
+		`dapdph_forma="B" typ_platce="P" rok="2024" mesic="3" d_poddp="20.04.2024" c_okec="620000"`,
+		`<Veta1 obrat23="10000" dan23="2100">`,
+		`<Veta4 pln23="100" odp_tuz23_nar="21" odp_sum_nar="21">`,
+		`<Veta6 dan_zocelk="2100" odp_zocelk="21" dano_da="2079">`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("VAT return missing %s in\n%s", want, data)
+		}
+	}
+
+	f.Quarter = 1
+	if _, err := a.VATReturn().MarshalEPO(f); !errors.Is(err, ErrPeriod) {
+		t.Errorf("MarshalEPO() error = %v, want ErrPeriod", err)
+	}
+}
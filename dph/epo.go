@@ -0,0 +1,400 @@
+package dph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/types"
+)
+
+// Form versions written in the verzePis attribute.
+const (
+	versionKH1 = "03.01"
+	versionDP3 = "01.02"
+)
+
+// Filing holds the header of an EPO filing: the tax period and the
+// taxpayer's identification.
+type Filing struct {
+	// Year and Month or Quarter give the tax period. Legal persons file
+	// the control statement monthly.
+	Year    int
+	Month   int
+	Quarter int
+	// Date is the filing date, today when zero.
+	Date time.Time
+
+	// FinancialOffice is the code of the regional financial office
+	// (c_ufo) and Workplace the code of its territorial workplace
+	// (c_pracufo), e.g. "451" and "2001" for Prague.
+	FinancialOffice string
+	Workplace       string
+	// Activity is the CZ-NACE code of the main economic activity, written
+	// to the VAT return only.
+	Activity string
+
+	// Name is the name of a legal person. FirstName and LastName are set
+	// instead for a natural person.
+	Name                string
+	FirstName, LastName string
+
+	Street, BuildingNumber, City, PostalZone string
+	Country                                  string
+	Email                                    string
+}
+
+// ErrPeriod is returned by MarshalEPO when the filing has no valid tax
+// period.
+var ErrPeriod = errors.New("filing needs a year and either a month or a quarter")
+
+type pisemnost struct {
+	XMLName xml.Name `xml:"Pisemnost"`
+	NazevSW string   `xml:"nazevSW,attr"`
+	KH1     *dphkh1  `xml:"DPHKH1,omitempty"`
+	DP3     *dphdp3  `xml:"DPHDP3,omitempty"`
+}
+
+type vetaD struct {
+	Dokument    string `xml:"dokument,attr"`
+	KUladis     string `xml:"k_uladis,attr"`
+	KhdphForma  string `xml:"khdph_forma,attr,omitempty"`
+	DapdphForma string `xml:"dapdph_forma,attr,omitempty"`
+	TypPlatce   string `xml:"typ_platce,attr,omitempty"`
+	Rok         int    `xml:"rok,attr"`
+	Mesic       int    `xml:"mesic,attr,omitempty"`
+	Ctvrt       int    `xml:"ctvrt,attr,omitempty"`
+	DPoddp      string `xml:"d_poddp,attr"`
+	COkec       string `xml:"c_okec,attr,omitempty"`
+}
+
+type vetaP struct {
+	CPracufo  string `xml:"c_pracufo,attr,omitempty"`
+	CUfo      string `xml:"c_ufo,attr,omitempty"`
+	DIC       string `xml:"dic,attr"`
+	TypDs     string `xml:"typ_ds,attr"`
+	Zkrobchjm string `xml:"zkrobchjm,attr,omitempty"`
+	Jmeno     string `xml:"jmeno,attr,omitempty"`
+	Prijmeni  string `xml:"prijmeni,attr,omitempty"`
+	Ulice     string `xml:"ulice,attr,omitempty"`
+	CPop      string `xml:"c_pop,attr,omitempty"`
+	NazObce   string `xml:"naz_obce,attr,omitempty"`
+	PSC       string `xml:"psc,attr,omitempty"`
+	Stat      string `xml:"stat,attr,omitempty"`
+	Email     string `xml:"email,attr,omitempty"`
+}
+
+// rates holds the base and tax attributes of the three rate columns.
+type rates struct {
+	ZaklDane1 types.Decimal `xml:"zakl_dane1,attr,omitempty"`
+	Dan1      types.Decimal `xml:"dan1,attr,omitempty"`
+	ZaklDane2 types.Decimal `xml:"zakl_dane2,attr,omitempty"`
+	Dan2      types.Decimal `xml:"dan2,attr,omitempty"`
+	ZaklDane3 types.Decimal `xml:"zakl_dane3,attr,omitempty"`
+	Dan3      types.Decimal `xml:"dan3,attr,omitempty"`
+}
+
+func ratesOf(a Amounts) rates {
+	return rates{a.Base1, a.Tax1, a.Base2, a.Tax2, a.Base3, a.Tax3}
+}
+
+type vetaA1 struct {
+	CRadku    int           `xml:"c_radku,attr"`
+	DicOdb    string        `xml:"dic_odb,attr"`
+	CEvidDD   string        `xml:"c_evid_dd,attr"`
+	Duzp      string        `xml:"duzp,attr"`
+	ZaklDane1 types.Decimal `xml:"zakl_dane1,attr"`
+	KodPredPl string        `xml:"kod_pred_pl,attr"`
+}
+
+type vetaA4 struct {
+	CRadku  int    `xml:"c_radku,attr"`
+	DicOdb  string `xml:"dic_odb,attr"`
+	CEvidDD string `xml:"c_evid_dd,attr"`
+	Dppd    string `xml:"dppd,attr"`
+	rates
+	KodRezimPl string `xml:"kod_rezim_pl,attr"`
+	Zdph44     string `xml:"zdph_44,attr"`
+}
+
+type vetaB1 struct {
+	CRadku  int    `xml:"c_radku,attr"`
+	DicDod  string `xml:"dic_dod,attr"`
+	CEvidDD string `xml:"c_evid_dd,attr"`
+	Duzp    string `xml:"duzp,attr"`
+	rates
+	KodPredPl string `xml:"kod_pred_pl,attr"`
+}
+
+type vetaB2 struct {
+	CRadku  int    `xml:"c_radku,attr"`
+	DicDod  string `xml:"dic_dod,attr"`
+	CEvidDD string `xml:"c_evid_dd,attr"`
+	Dppd    string `xml:"dppd,attr"`
+	rates
+	Pomer  string `xml:"pomer,attr"`
+	Zdph44 string `xml:"zdph_44,attr"`
+}
+
+type vetaC struct {
+	Obrat23    types.Decimal `xml:"obrat23,attr,omitempty"`
+	Obrat5     types.Decimal `xml:"obrat5,attr,omitempty"`
+	Pln23      types.Decimal `xml:"pln23,attr,omitempty"`
+	Pln5       types.Decimal `xml:"pln5,attr,omitempty"`
+	PlnRezPren types.Decimal `xml:"pln_rez_pren,attr,omitempty"`
+	RezPren23  types.Decimal `xml:"rez_pren23,attr,omitempty"`
+	RezPren5   types.Decimal `xml:"rez_pren5,attr,omitempty"`
+}
+
+type dphkh1 struct {
+	VerzePis string   `xml:"verzePis,attr"`
+	VetaD    vetaD    `xml:"VetaD"`
+	VetaP    vetaP    `xml:"VetaP"`
+	VetaA1   []vetaA1 `xml:"VetaA1"`
+	VetaA4   []vetaA4 `xml:"VetaA4"`
+	VetaA5   *rates   `xml:"VetaA5,omitempty"`
+	VetaB1   []vetaB1 `xml:"VetaB1"`
+	VetaB2   []vetaB2 `xml:"VetaB2"`
+	VetaB3   *rates   `xml:"VetaB3,omitempty"`
+	VetaC    vetaC    `xml:"VetaC"`
+}
+
+type dphdp3 struct {
+	VerzePis string `xml:"verzePis,attr"`
+	VetaD    vetaD  `xml:"VetaD"`
+	VetaP    vetaP  `xml:"VetaP"`
+	Veta1    veta1  `xml:"Veta1"`
+	Veta2    veta2  `xml:"Veta2"`
+	Veta4    veta4  `xml:"Veta4"`
+	Veta6    veta6  `xml:"Veta6"`
+}
+
+type veta1 struct {
+	Obrat23    int64 `xml:"obrat23,attr,omitempty"`
+	Dan23      int64 `xml:"dan23,attr,omitempty"`
+	Obrat5     int64 `xml:"obrat5,attr,omitempty"`
+	Dan5       int64 `xml:"dan5,attr,omitempty"`
+	RezPren23  int64 `xml:"rez_pren23,attr,omitempty"`
+	DanRpren23 int64 `xml:"dan_rpren23,attr,omitempty"`
+	RezPren5   int64 `xml:"rez_pren5,attr,omitempty"`
+	DanRpren5  int64 `xml:"dan_rpren5,attr,omitempty"`
+}
+
+type veta2 struct {
+	PlnRezPren int64 `xml:"pln_rez_pren,attr,omitempty"`
+}
+
+type veta4 struct {
+	Pln23       int64 `xml:"pln23,attr,omitempty"`
+	OdpTuz23Nar int64 `xml:"odp_tuz23_nar,attr,omitempty"`
+	Pln5        int64 `xml:"pln5,attr,omitempty"`
+	OdpTuz5Nar  int64 `xml:"odp_tuz5_nar,attr,omitempty"`
+	NarZdp23    int64 `xml:"nar_zdp23,attr,omitempty"`
+	OdZdp23     int64 `xml:"od_zdp23,attr,omitempty"`
+	NarZdp5     int64 `xml:"nar_zdp5,attr,omitempty"`
+	OdZdp5      int64 `xml:"od_zdp5,attr,omitempty"`
+	OdpSumNar   int64 `xml:"odp_sum_nar,attr,omitempty"`
+}
+
+type veta6 struct {
+	DanZocelk int64 `xml:"dan_zocelk,attr"`
+	OdpZocelk int64 `xml:"odp_zocelk,attr"`
+	DanoDa    int64 `xml:"dano_da,attr,omitempty"`
+	DanoNo    int64 `xml:"dano_no,attr,omitempty"`
+}
+
+// header returns the VetaD and VetaP records of a filing.
+func (f *Filing) header(dic, dokument string) (vetaD, vetaP, error) {
+	if f.Year == 0 || (f.Month == 0) == (f.Quarter == 0) {
+		return vetaD{}, vetaP{}, ErrPeriod
+	}
+	date := f.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	d := vetaD{
+		Dokument: dokument,
+		KUladis:  "DPH",
+		Rok:      f.Year,
+		Mesic:    f.Month,
+		Ctvrt:    f.Quarter,
+		DPoddp:   date.Format(dateFormat),
+	}
+	p := vetaP{
+		CPracufo:  f.Workplace,
+		CUfo:      f.FinancialOffice,
+		DIC:       dic,
+		TypDs:     "P",
+		Zkrobchjm: f.Name,
+		Ulice:     f.Street,
+		CPop:      f.BuildingNumber,
+		NazObce:   f.City,
+		PSC:       f.PostalZone,
+		Stat:      f.Country,
+		Email:     f.Email,
+	}
+	if f.LastName != "" {
+		p.TypDs, p.Zkrobchjm = "F", ""
+		p.Jmeno, p.Prijmeni = f.FirstName, f.LastName
+	}
+	return d, p, nil
+}
+
+// dateFormat is the date format of EPO attributes.
+const dateFormat = "02.01.2006"
+
+func formatDate(d types.Date) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Time.Format(dateFormat)
+}
+
+// MarshalEPO encodes the control statement as an EPO DPHKH1 filing.
+func (s *ControlStatement) MarshalEPO(f Filing) ([]byte, error) {
+	d, p, err := f.header(s.DIC, "KH1")
+	if err != nil {
+		return nil, err
+	}
+	d.KhdphForma = "B"
+
+	kh := &dphkh1{VerzePis: versionKH1, VetaD: d, VetaP: p}
+	for i, r := range s.A1 {
+		kh.VetaA1 = append(kh.VetaA1, vetaA1{
+			CRadku:    i + 1,
+			DicOdb:    r.DIC,
+			CEvidDD:   r.DocumentID,
+			Duzp:      formatDate(r.TaxPointDate),
+			ZaklDane1: r.Base1,
+			KodPredPl: r.ReverseChargeCode,
+		})
+	}
+	for i, r := range s.A4 {
+		kh.VetaA4 = append(kh.VetaA4, vetaA4{
+			CRadku:     i + 1,
+			DicOdb:     r.DIC,
+			CEvidDD:    r.DocumentID,
+			Dppd:       formatDate(r.TaxPointDate),
+			rates:      ratesOf(r.Amounts),
+			KodRezimPl: "0",
+			Zdph44:     "N",
+		})
+	}
+	if s.A5 != (Amounts{}) {
+		r := ratesOf(s.A5)
+		kh.VetaA5 = &r
+	}
+	for i, r := range s.B1 {
+		kh.VetaB1 = append(kh.VetaB1, vetaB1{
+			CRadku:    i + 1,
+			DicDod:    r.DIC,
+			CEvidDD:   r.DocumentID,
+			Duzp:      formatDate(r.TaxPointDate),
+			rates:     ratesOf(r.Amounts),
+			KodPredPl: r.ReverseChargeCode,
+		})
+	}
+	for i, r := range s.B2 {
+		kh.VetaB2 = append(kh.VetaB2, vetaB2{
+			CRadku:  i + 1,
+			DicDod:  r.DIC,
+			CEvidDD: r.DocumentID,
+			Dppd:    formatDate(r.TaxPointDate),
+			rates:   ratesOf(r.Amounts),
+			Pomer:   "N",
+			Zdph44:  "N",
+		})
+	}
+	if s.B3 != (Amounts{}) {
+		r := ratesOf(s.B3)
+		kh.VetaB3 = &r
+	}
+
+	// control rows compare the bases with the VAT return
+	kh.VetaC.Obrat23, kh.VetaC.Obrat5 = bases(append(slices.Clone(s.A4), Record{Amounts: s.A5}))
+	kh.VetaC.Pln23, kh.VetaC.Pln5 = bases(append(slices.Clone(s.B2), Record{Amounts: s.B3}))
+	kh.VetaC.PlnRezPren, _ = bases(s.A1)
+	kh.VetaC.RezPren23, kh.VetaC.RezPren5 = bases(s.B1)
+
+	return encode(pisemnost{KH1: kh})
+}
+
+// MarshalEPO encodes the VAT return as an EPO DPHDP3 filing.
+func (r *VATReturn) MarshalEPO(f Filing) ([]byte, error) {
+	d, p, err := f.header(r.DIC, "DP3")
+	if err != nil {
+		return nil, err
+	}
+	d.DapdphForma = "B"
+	d.TypPlatce = "P"
+	d.COkec = f.Activity
+
+	return encode(pisemnost{DP3: &dphdp3{
+		VerzePis: versionDP3,
+		VetaD:    d,
+		VetaP:    p,
+		Veta1: veta1{
+			Obrat23:    r.Line1Base,
+			Dan23:      r.Line1Tax,
+			Obrat5:     r.Line2Base,
+			Dan5:       r.Line2Tax,
+			RezPren23:  r.Line10Base,
+			DanRpren23: r.Line10Tax,
+			RezPren5:   r.Line11Base,
+			DanRpren5:  r.Line11Tax,
+		},
+		Veta2: veta2{PlnRezPren: r.Line25},
+		Veta4: veta4{
+			Pln23:       r.Line40Base,
+			OdpTuz23Nar: r.Line40Tax,
+			Pln5:        r.Line41Base,
+			OdpTuz5Nar:  r.Line41Tax,
+			NarZdp23:    r.Line43Base,
+			OdZdp23:     r.Line43Tax,
+			NarZdp5:     r.Line44Base,
+			OdZdp5:      r.Line44Tax,
+			OdpSumNar:   r.Line46,
+		},
+		Veta6: veta6{
+			DanZocelk: r.Line62,
+			OdpZocelk: r.Line63,
+			DanoDa:    r.Line64,
+			DanoNo:    r.Line65,
+		},
+	}})
+}
+
+func encode(p pisemnost) ([]byte, error) {
+	p.NazevSW = "github.com/xseman/isdoc"
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// bases returns the sum of the bases at the basic rate and at the reduced
+// rates of rs, or "" for zero.
+func bases(rs []Record) (basic, reduced types.Decimal) {
+	var b, r big.Rat
+	for _, rec := range rs {
+		b.Add(&b, en16931.ParseRat(rec.Base1))
+		r.Add(&r, en16931.ParseRat(rec.Base2))
+		r.Add(&r, en16931.ParseRat(rec.Base3))
+	}
+	f := func(v *big.Rat) types.Decimal {
+		if v.Sign() == 0 {
+			return ""
+		}
+		return en16931.FormatAmount(v)
+	}
+	return f(&b), f(&r)
+}
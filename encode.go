@@ -8,7 +8,6 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/xseman/isdoc/internal/ordering"
 	"github.com/xseman/isdoc/schema"
 )
 
@@ -42,21 +41,14 @@ func (e *Encoder) SetXMLDeclaration(add bool) {
 func (e *Encoder) Encode(inv *schema.Invoice) error {
 	var buf bytes.Buffer
 
-	if e.addXMLDecl {
-		buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	}
-
-	// Write root element with namespace
-	buf.WriteString(fmt.Sprintf(`<Invoice xmlns="%s" version="%s">`,
-		schema.Namespace, inv.Version))
-	buf.WriteString("\n")
+	e.writeRootStart(&buf, "Invoice", inv.Version, &inv.Unknown)
 
 	// Encode child elements in XSD order
 	if err := e.encodeInvoiceContent(&buf, inv, 1); err != nil {
 		return err
 	}
 
-	buf.WriteString("</Invoice>\n")
+	e.writeRootEnd(&buf, "Invoice", &inv.Unknown)
 
 	_, err := e.writer.Write(buf.Bytes())
 	return err
@@ -64,56 +56,188 @@ func (e *Encoder) Encode(inv *schema.Invoice) error {
 
 // encodeInvoiceContent encodes the content of an Invoice element.
 func (e *Encoder) encodeInvoiceContent(buf *bytes.Buffer, inv *schema.Invoice, depth int) error {
-	// Get element order from XSD
-	order := ordering.Sequence["Invoice"]
+	return e.encodeChildren(buf, reflect.ValueOf(inv).Elem(), depth)
+}
 
-	// Create a map of field values by XML element name
-	fields := e.extractFields(reflect.ValueOf(inv).Elem())
+// writeRootStart writes the XML declaration, any preserved prolog and the
+// root start tag with the ISDOC namespace.
+func (e *Encoder) writeRootStart(buf *bytes.Buffer, name, version string, unknown *schema.Unknown) {
+	if e.addXMLDecl {
+		buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	}
+	for _, raw := range unknown.Prolog {
+		buf.Write(raw)
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString(fmt.Sprintf(`<%s xmlns="%s" version="%s"`,
+		name, schema.Namespace, version))
+	for _, a := range unknown.Attrs {
+		if a.Element == "" {
+			writeAttr(buf, a.Attr)
+		}
+	}
+	buf.WriteString(">\n")
+}
+
+// writeRootEnd writes the root end tag and any preserved epilog.
+func (e *Encoder) writeRootEnd(buf *bytes.Buffer, name string, unknown *schema.Unknown) {
+	buf.WriteString("</" + name + ">\n")
+	for _, raw := range unknown.Epilog {
+		buf.Write(raw)
+		buf.WriteString("\n")
+	}
+}
 
-	// Write elements in order
-	for _, elemName := range order {
-		if val, ok := fields[elemName]; ok {
-			if err := e.encodeValue(buf, elemName, val, depth); err != nil {
+// encodeChildren encodes the child elements of struct v in XSD order,
+// interleaving preserved unknown nodes.
+func (e *Encoder) encodeChildren(buf *bytes.Buffer, v reflect.Value, depth int) error {
+	c := e.newChildWriter(v, depth)
+	c.writeLeading(buf)
+	for _, elemName := range c.info.order {
+		if err := c.writeElement(buf, elemName); err != nil {
+			return err
+		}
+	}
+	c.writeRemaining(buf)
+	return nil
+}
+
+// childWriter writes the children of a single struct element. It tracks
+// which unknown nodes were written so each is emitted exactly once.
+type childWriter struct {
+	e       *Encoder
+	v       reflect.Value
+	info    *xmlTypeInfo
+	unknown *schema.Unknown
+	written []bool
+	depth   int
+}
+
+func (e *Encoder) newChildWriter(v reflect.Value, depth int) *childWriter {
+	c := &childWriter{
+		e:       e,
+		v:       v,
+		info:    getTypeInfo(v.Type()),
+		unknown: unknownOf(v),
+		depth:   depth,
+	}
+	if c.unknown != nil {
+		c.written = make([]bool, len(c.unknown.Nodes))
+	}
+	return c
+}
+
+// writeElement writes every occurrence of the named element, each followed
+// by the unknown nodes anchored to it.
+func (c *childWriter) writeElement(buf *bytes.Buffer, elemName string) error {
+	f, ok := c.info.elements[elemName]
+	if !ok {
+		return nil
+	}
+	val := c.v.Field(f.index)
+
+	if val.Kind() == reflect.Slice {
+		n := val.Len()
+		for i := 0; i < n; i++ {
+			if err := c.e.encodeElement(buf, elemName, val.Index(i), c.depth, c.attrs(elemName, i), f.omitEmpty); err != nil {
 				return err
 			}
+			c.writeNodes(buf, elemName, i, i)
 		}
+		c.writeNodes(buf, elemName, n, -1)
+		return nil
 	}
 
+	if err := c.e.encodeElement(buf, elemName, val, c.depth, c.attrs(elemName, 0), f.omitEmpty); err != nil {
+		return err
+	}
+	c.writeNodes(buf, elemName, 0, -1)
 	return nil
 }
 
-// extractFields extracts field values by their XML element name.
-func (e *Encoder) extractFields(v reflect.Value) map[string]reflect.Value {
-	fields := make(map[string]reflect.Value)
-	t := v.Type()
+// writeLeading writes unknown nodes that precede all known children.
+func (c *childWriter) writeLeading(buf *bytes.Buffer) {
+	c.writeNodes(buf, "", 0, -1)
+}
 
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		xmlTag := field.Tag.Get("xml")
-		if xmlTag == "" || xmlTag == "-" {
-			continue
+// writeRemaining writes unknown nodes not yet written, e.g. those anchored
+// to an element that has been removed from the struct.
+func (c *childWriter) writeRemaining(buf *bytes.Buffer) {
+	if c.unknown == nil {
+		return
+	}
+	for i, node := range c.unknown.Nodes {
+		if !c.written[i] {
+			c.writeNode(buf, i, node)
 		}
+	}
+}
 
-		// Parse XML tag: "ElementName,omitempty"
-		parts := strings.Split(xmlTag, ",")
-		elemName := parts[0]
+// skipNodes marks the unknown nodes anchored to the given elements, and
+// those preceding all known children, as written.
+func (c *childWriter) skipNodes(elemNames []string) {
+	if c.unknown == nil {
+		return
+	}
+	skip := map[string]bool{"": true}
+	for _, name := range elemNames {
+		skip[name] = true
+	}
+	for i, node := range c.unknown.Nodes {
+		if skip[node.After] {
+			c.written[i] = true
+		}
+	}
+}
 
-		// Skip attributes and special fields
-		if strings.HasPrefix(elemName, "@") || elemName == "xmlns" {
+// writeNodes writes the unknown nodes anchored to occurrences from..to of
+// the named element. A negative to means no upper bound.
+func (c *childWriter) writeNodes(buf *bytes.Buffer, after string, from, to int) {
+	if c.unknown == nil {
+		return
+	}
+	for i, node := range c.unknown.Nodes {
+		if c.written[i] || node.After != after || node.AfterIndex < from {
 			continue
 		}
-		if elemName == "" {
+		if to >= 0 && node.AfterIndex > to {
 			continue
 		}
-
-		fields[elemName] = v.Field(i)
+		c.writeNode(buf, i, node)
 	}
+}
+
+func (c *childWriter) writeNode(buf *bytes.Buffer, i int, node schema.UnknownNode) {
+	buf.WriteString(strings.Repeat(c.e.indent, c.depth))
+	buf.Write(node.Raw)
+	buf.WriteString("\n")
+	c.written[i] = true
+}
 
-	return fields
+// attrs returns the preserved attributes of a leaf child occurrence.
+func (c *childWriter) attrs(elemName string, index int) []xml.Attr {
+	if c.unknown == nil {
+		return nil
+	}
+	var attrs []xml.Attr
+	for _, a := range c.unknown.Attrs {
+		if a.Element == elemName && a.Index == index {
+			attrs = append(attrs, a.Attr)
+		}
+	}
+	return attrs
 }
 
 // encodeValue encodes a single value as XML.
 func (e *Encoder) encodeValue(buf *bytes.Buffer, name string, v reflect.Value, depth int) error {
+	return e.encodeElement(buf, name, v, depth, nil, false)
+}
+
+// encodeElement encodes a single value as an XML element. Extra attributes
+// are added to the start tag. omitEmpty drops character data elements whose
+// fields are all empty.
+func (e *Encoder) encodeElement(buf *bytes.Buffer, name string, v reflect.Value, depth int, extra []xml.Attr, omitEmpty bool) error {
 	indent := strings.Repeat(e.indent, depth)
 
 	// Handle pointers
@@ -143,43 +267,22 @@ func (e *Encoder) encodeValue(buf *bytes.Buffer, name string, v reflect.Value, d
 		if s == "" {
 			return nil
 		}
-		buf.WriteString(indent)
-		buf.WriteString("<")
-		buf.WriteString(name)
-		buf.WriteString(">")
-		xml.EscapeText(buf, []byte(s))
-		buf.WriteString("</")
-		buf.WriteString(name)
-		buf.WriteString(">\n")
+		writeTextElement(buf, indent, name, extra, s)
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		buf.WriteString(indent)
-		buf.WriteString("<")
-		buf.WriteString(name)
-		buf.WriteString(">")
-		buf.WriteString(fmt.Sprintf("%d", v.Int()))
-		buf.WriteString("</")
-		buf.WriteString(name)
-		buf.WriteString(">\n")
+		writeTextElement(buf, indent, name, extra, fmt.Sprintf("%d", v.Int()))
 
 	case reflect.Bool:
-		buf.WriteString(indent)
-		buf.WriteString("<")
-		buf.WriteString(name)
-		buf.WriteString(">")
 		if v.Bool() {
-			buf.WriteString("true")
+			writeTextElement(buf, indent, name, extra, "true")
 		} else {
-			buf.WriteString("false")
+			writeTextElement(buf, indent, name, extra, "false")
 		}
-		buf.WriteString("</")
-		buf.WriteString(name)
-		buf.WriteString(">\n")
 
 	case reflect.Slice:
 		// For slices, encode each element
 		for i := 0; i < v.Len(); i++ {
-			if err := e.encodeValue(buf, name, v.Index(i), depth); err != nil {
+			if err := e.encodeElement(buf, name, v.Index(i), depth, extra, omitEmpty); err != nil {
 				return err
 			}
 		}
@@ -187,67 +290,20 @@ func (e *Encoder) encodeValue(buf *bytes.Buffer, name string, v reflect.Value, d
 	case reflect.Struct:
 		// Check if type implements xml.Marshaler (like Date, Decimal, etc.)
 		if _, ok := v.Interface().(xml.Marshaler); ok {
-			// For types with custom marshaling, we need to wrap them with our element name
-			buf.WriteString(indent)
-			buf.WriteString("<")
-			buf.WriteString(name)
-			buf.WriteString(">")
-
-			// Get the string value using Stringer interface
+			// For types with custom marshaling, use the Stringer value
+			// wrapped with our element name
+			var s string
 			if stringer, ok := v.Interface().(fmt.Stringer); ok {
-				xml.EscapeText(buf, []byte(stringer.String()))
-			}
-
-			buf.WriteString("</")
-			buf.WriteString(name)
-			buf.WriteString(">\n")
-		} else {
-			// For complex structs (like AccountingSupplierParty), encode fields recursively
-			buf.WriteString(indent)
-			buf.WriteString("<")
-			buf.WriteString(name)
-			buf.WriteString(">\n")
-
-			// Encode struct fields - get ordered fields for this type
-			typeName := v.Type().Name()
-			order, hasOrder := ordering.Sequence[typeName]
-
-			fields := e.extractFields(v)
-
-			if hasOrder {
-				// Use XSD order
-				for _, elemName := range order {
-					if val, ok := fields[elemName]; ok {
-						if err := e.encodeValue(buf, elemName, val, depth+1); err != nil {
-							return err
-						}
-					}
-				}
-			} else {
-				// Encode fields in struct order
-				for i := 0; i < v.NumField(); i++ {
-					field := v.Type().Field(i)
-					xmlTag := field.Tag.Get("xml")
-					if xmlTag == "" || xmlTag == "-" {
-						continue
-					}
-					parts := strings.Split(xmlTag, ",")
-					elemName := parts[0]
-					if elemName == "" || strings.Contains(elemName, ",attr") {
-						continue
-					}
-					if err := e.encodeValue(buf, elemName, v.Field(i), depth+1); err != nil {
-						return err
-					}
-				}
+				s = stringer.String()
 			}
-
-			buf.WriteString(indent)
-			buf.WriteString("</")
-			buf.WriteString(name)
-			buf.WriteString(">\n")
+			writeTextElement(buf, indent, name, extra, s)
+			return nil
 		}
 
+		// For complex structs (like AccountingSupplierParty), encode
+		// attributes, then content or child elements
+		return e.encodeStruct(buf, name, v, depth, extra, omitEmpty)
+
 	default:
 		// For other types (like custom types), try String() method first
 		if stringer, ok := v.Interface().(fmt.Stringer); ok {
@@ -255,14 +311,7 @@ func (e *Encoder) encodeValue(buf *bytes.Buffer, name string, v reflect.Value, d
 			if s == "" {
 				return nil
 			}
-			buf.WriteString(indent)
-			buf.WriteString("<")
-			buf.WriteString(name)
-			buf.WriteString(">")
-			xml.EscapeText(buf, []byte(s))
-			buf.WriteString("</")
-			buf.WriteString(name)
-			buf.WriteString(">\n")
+			writeTextElement(buf, indent, name, extra, s)
 		} else {
 			// Fall back to xml.Marshal
 			data, err := xml.MarshalIndent(v.Interface(), strings.Repeat(e.indent, depth-1), e.indent)
@@ -277,6 +326,135 @@ func (e *Encoder) encodeValue(buf *bytes.Buffer, name string, v reflect.Value, d
 	return nil
 }
 
+// encodeStruct encodes a struct element. Character data and inner XML
+// fields are written inline; other structs are encoded recursively.
+func (e *Encoder) encodeStruct(buf *bytes.Buffer, name string, v reflect.Value, depth int, extra []xml.Attr, omitEmpty bool) error {
+	indent := strings.Repeat(e.indent, depth)
+	ti := getTypeInfo(v.Type())
+
+	if ti.leaf && omitEmpty && len(extra) == 0 && isEmptyStruct(v, ti) {
+		return nil
+	}
+
+	buf.WriteString(indent)
+	buf.WriteString("<")
+	buf.WriteString(name)
+	for _, f := range ti.fields {
+		if !f.attr {
+			continue
+		}
+		s, ok := attrValue(v.Field(f.index))
+		if !ok || (f.omitEmpty && (s == "" || s == "false")) {
+			continue
+		}
+		writeAttr(buf, xml.Attr{Name: xml.Name{Local: f.name}, Value: s})
+	}
+	if unknown := unknownOf(v); unknown != nil {
+		for _, a := range unknown.Attrs {
+			if a.Element == "" {
+				writeAttr(buf, a.Attr)
+			}
+		}
+	}
+	for _, a := range extra {
+		writeAttr(buf, a)
+	}
+	buf.WriteString(">")
+
+	if ti.leaf {
+		for _, f := range ti.fields {
+			field := v.Field(f.index)
+			switch {
+			case f.innerxml:
+				if field.Kind() == reflect.Slice {
+					buf.Write(field.Bytes())
+				} else {
+					buf.WriteString(field.String())
+				}
+			case f.chardata:
+				if s, ok := attrValue(field); ok {
+					xml.EscapeText(buf, []byte(s))
+				}
+			}
+		}
+	} else {
+		buf.WriteString("\n")
+		if err := e.encodeChildren(buf, v, depth+1); err != nil {
+			return err
+		}
+		buf.WriteString(indent)
+	}
+
+	buf.WriteString("</")
+	buf.WriteString(name)
+	buf.WriteString(">\n")
+	return nil
+}
+
+// isEmptyStruct reports whether all XML fields of a leaf struct are empty.
+func isEmptyStruct(v reflect.Value, ti *xmlTypeInfo) bool {
+	for _, f := range ti.fields {
+		field := v.Field(f.index)
+		if f.innerxml {
+			if field.Len() > 0 {
+				return false
+			}
+			continue
+		}
+		if s, ok := attrValue(field); ok && s != "" && !(f.attr && s == "false") {
+			return false
+		}
+	}
+	return true
+}
+
+// attrValue returns the text form of a simple value.
+func attrValue(v reflect.Value) (string, bool) {
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String(), true
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		if v.Bool() {
+			return "true", true
+		}
+		return "false", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d", v.Int()), true
+	}
+	return "", false
+}
+
+// writeTextElement writes a simple element with escaped text content.
+func writeTextElement(buf *bytes.Buffer, indent, name string, attrs []xml.Attr, s string) {
+	buf.WriteString(indent)
+	buf.WriteString("<")
+	buf.WriteString(name)
+	for _, a := range attrs {
+		writeAttr(buf, a)
+	}
+	buf.WriteString(">")
+	xml.EscapeText(buf, []byte(s))
+	buf.WriteString("</")
+	buf.WriteString(name)
+	buf.WriteString(">\n")
+}
+
+// writeAttr writes a single attribute, keeping its namespace prefix.
+func writeAttr(buf *bytes.Buffer, a xml.Attr) {
+	buf.WriteString(" ")
+	if a.Name.Space != "" {
+		buf.WriteString(a.Name.Space)
+		buf.WriteString(":")
+	}
+	buf.WriteString(a.Name.Local)
+	buf.WriteString(`="`)
+	xml.EscapeText(buf, []byte(a.Value))
+	buf.WriteString(`"`)
+}
+
 // isZero checks if a value is the zero value for its type.
 func (e *Encoder) isZero(v reflect.Value) bool {
 	switch v.Kind() {
@@ -313,7 +491,7 @@ func (e *Encoder) isZero(v reflect.Value) bool {
 //
 //	invoice := &schema.Invoice{
 //	    Version: "6.0.2",
-//	    DocumentType: 1,
+//	    DocumentType: types.DocumentTypeInvoice,
 //	    ID: "FV-2025-001",
 //	    // ... other fields
 //	}
@@ -334,21 +512,14 @@ func EncodeBytes(inv *schema.Invoice) ([]byte, error) {
 func (e *Encoder) EncodeCommonDocument(doc *schema.CommonDocument) error {
 	var buf bytes.Buffer
 
-	if e.addXMLDecl {
-		buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	}
-
-	// Write root element with namespace
-	buf.WriteString(fmt.Sprintf(`<CommonDocument xmlns="%s" version="%s">`,
-		schema.Namespace, doc.Version))
-	buf.WriteString("\n")
+	e.writeRootStart(&buf, "CommonDocument", doc.Version, &doc.Unknown)
 
 	// Encode child elements in XSD order
 	if err := e.encodeCommonDocumentContent(&buf, doc, 1); err != nil {
 		return err
 	}
 
-	buf.WriteString("</CommonDocument>\n")
+	e.writeRootEnd(&buf, "CommonDocument", &doc.Unknown)
 
 	_, err := e.writer.Write(buf.Bytes())
 	return err
@@ -356,22 +527,7 @@ func (e *Encoder) EncodeCommonDocument(doc *schema.CommonDocument) error {
 
 // encodeCommonDocumentContent encodes the content of a CommonDocument element.
 func (e *Encoder) encodeCommonDocumentContent(buf *bytes.Buffer, doc *schema.CommonDocument, depth int) error {
-	// Get element order from XSD
-	order := ordering.Sequence["CommonDocument"]
-
-	// Create a map of field values by XML element name
-	fields := e.extractFields(reflect.ValueOf(doc).Elem())
-
-	// Write elements in order
-	for _, elemName := range order {
-		if val, ok := fields[elemName]; ok {
-			if err := e.encodeValue(buf, elemName, val, depth); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+	return e.encodeChildren(buf, reflect.ValueOf(doc).Elem(), depth)
 }
 
 // EncodeCommonDocumentBytes encodes an ISDOC CommonDocument to XML and returns the bytes.
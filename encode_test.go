@@ -261,3 +261,43 @@ func TestEncodeWriter(t *testing.T) {
 		t.Error("Missing expected content")
 	}
 }
+
+func TestEncodeInvoiceElementOrder(t *testing.T) {
+	invoice := createValidInvoice()
+	invoice.SubDocumentType = "IN"
+	invoice.SubDocumentTypeOrigin = "Vendor"
+	invoice.AnonymousCustomerParty = &schema.AnonymousCustomerParty{ID: "C-1"}
+	invoice.BuyerCustomerParty = &schema.BuyerCustomerParty{Party: invoice.AccountingCustomerParty.Party}
+
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	s := string(out)
+
+	// The XSD places the subtype right after DocumentType, and the customer
+	// choice (AnonymousCustomerParty, AccountingCustomerParty?) between the
+	// seller and the buyer.
+	order := []string{
+		"<DocumentType>",
+		"<SubDocumentType>",
+		"<SubDocumentTypeOrigin>",
+		"<ID>",
+		"<AccountingSupplierParty>",
+		"<AnonymousCustomerParty>",
+		"<AccountingCustomerParty>",
+		"<BuyerCustomerParty>",
+		"<InvoiceLines>",
+	}
+	prev := -1
+	for _, tag := range order {
+		i := strings.Index(s, tag)
+		if i < 0 {
+			t.Fatalf("output missing %s", tag)
+		}
+		if i < prev {
+			t.Errorf("%s out of XSD order", tag)
+		}
+		prev = i
+	}
+}
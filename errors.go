@@ -41,7 +41,13 @@ const (
 	ErrCodeReferenceNotFound = "REFERENCE_NOT_FOUND"
 	ErrCodeDuplicateID       = "DUPLICATE_ID"
 	ErrCodeInvalidXML        = "INVALID_XML"
+	ErrCodeInvalidJSON       = "INVALID_JSON"
 	ErrCodeSchemaViolation   = "SCHEMA_VIOLATION"
+	ErrCodeInvalidExtension  = "INVALID_EXTENSION"
+	ErrCodeMissingManifest   = "MISSING_MANIFEST"
+	ErrCodeMissingSupplement = "MISSING_SUPPLEMENT"
+	ErrCodeDigestMismatch    = "DIGEST_MISMATCH"
+	ErrCodeLossyConversion   = "LOSSY_CONVERSION"
 )
 
 // DecodeError represents an error during XML decoding.
@@ -50,6 +56,14 @@ type DecodeError struct {
 	Path string
 	// Err is the underlying error.
 	Err error
+	// Code is a machine-readable error code, if known
+	// (ErrCodeInvalidXML, ErrCodeInvalidJSON, ErrCodeDuplicateID or
+	// ErrCodeReferenceNotFound).
+	Code string
+	// Line and Column locate the error in the source document.
+	// They are zero when the position is unknown.
+	Line   int
+	Column int
 }
 
 func (e *DecodeError) Error() string {
@@ -63,11 +77,22 @@ func (e *DecodeError) Unwrap() error {
 	return e.Err
 }
 
+// Position returns the source position of the error, if known.
+func (e *DecodeError) Position() Position {
+	return Position{Line: e.Line, Column: e.Column}
+}
+
 // NewDecodeError creates a new DecodeError.
 func NewDecodeError(path string, err error) *DecodeError {
 	return &DecodeError{Path: path, Err: err}
 }
 
+// withCode sets the error code and returns e.
+func (e *DecodeError) withCode(code string) *DecodeError {
+	e.Code = code
+	return e
+}
+
 // DecodeErrors is a collection of decode errors.
 type DecodeErrors []*DecodeError
 
@@ -101,12 +126,25 @@ type ValidationError struct {
 	Severity Severity
 	// Msg is a human-readable error message.
 	Msg string
+	// Rule is the official identifier of the business rule that failed, if
+	// any (e.g. "BR-CO-15" from ubl.Validate). The ISDOC schematron has no
+	// rule identifiers, so ISDOC checks leave it empty.
+	Rule string
+	// Line and Column locate the field in the source document. They are set
+	// when validating with a SourceMap and are zero otherwise.
+	Line   int
+	Column int
 }
 
 func (e *ValidationError) Error() string {
 	return fmt.Sprintf("[%s] %s: %s (%s)", e.Severity, e.Field, e.Msg, e.Code)
 }
 
+// Position returns the source position of the field, if known.
+func (e *ValidationError) Position() Position {
+	return Position{Line: e.Line, Column: e.Column}
+}
+
 // ValidationErrors is a collection of validation errors.
 type ValidationErrors []*ValidationError
 
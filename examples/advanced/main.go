@@ -31,7 +31,7 @@ func directAPIExample() {
 	// Create an invoice
 	invoice := &schema.Invoice{
 		Version:           "6.0.2",
-		DocumentType:      1,
+		DocumentType:      types.DocumentTypeInvoice,
 		ID:                "FV-2024-003",
 		UUID:              types.UUID("12345678-AAAA-BBBB-CCCC-123456789012"),
 		IssueDate:         types.MustParseDate("2024-03-01"),
@@ -134,7 +134,7 @@ func strictValidationExample() {
 	// Create a minimal/incomplete invoice
 	invoice := &schema.Invoice{
 		Version:           "6.0.2",
-		DocumentType:      1,
+		DocumentType:      types.DocumentTypeInvoice,
 		ID:                "INCOMPLETE-001",
 		UUID:              types.UUID("00000000-0000-0000-0000-000000000001"),
 		IssueDate:         types.MustParseDate("2024-01-01"),
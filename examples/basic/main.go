@@ -136,7 +136,7 @@ func createExample() {
 	// Create a new invoice programmatically
 	invoice := &schema.Invoice{
 		Version:           "6.0.2",
-		DocumentType:      1, // Invoice
+		DocumentType:      types.DocumentTypeInvoice,
 		ID:                "FV-2024-002",
 		UUID:              types.UUID("ABCDEF00-1234-5678-9ABC-DEF012345678"),
 		IssueDate:         types.MustParseDate("2024-02-20"),
@@ -186,7 +186,7 @@ func createExample() {
 					UnitPriceTaxInclusive:           types.MustDecimal("121.00"),
 					ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
 						Percent:              types.MustDecimal("21"),
-						VATCalculationMethod: 0,
+						VATCalculationMethod: types.VATCalculationFromBottom,
 					},
 					Item: schema.Item{Description: "Widget A"},
 				},
@@ -203,7 +203,7 @@ func createExample() {
 					UnitPriceTaxInclusive:           types.MustDecimal("121.00"),
 					ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
 						Percent:              types.MustDecimal("21"),
-						VATCalculationMethod: 0,
+						VATCalculationMethod: types.VATCalculationFromBottom,
 					},
 					Item: schema.Item{Description: "Widget B"},
 				},
@@ -243,7 +243,7 @@ func createExample() {
 			Payment: []schema.Payment{
 				{
 					PaidAmount:       types.MustDecimal("968.00"),
-					PaymentMeansCode: 42, // Bank transfer
+					PaymentMeansCode: types.PaymentMeansBankTransfer,
 					Details: &schema.PaymentDetails{
 						PaymentDueDate: types.MustParseDate("2024-03-20"),
 						VariableSymbol: "20240002",
@@ -0,0 +1,251 @@
+package isdoc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+const (
+	headExtensionNS = "http://anydomain.cz/branch/developer/head"
+	erpExtensionNS  = "urn:example:erp"
+)
+
+// headFields models the header extensions used in the ISDOC sample fixtures.
+type headFields struct {
+	UserfieldName          string `xml:"UserfieldName"`
+	AdditionalHeadDiscount int    `xml:"AdditionalHeadDiscount"`
+}
+
+// warehouseInfo is a structured ERP extension with its own validation.
+type warehouseInfo struct {
+	Warehouse struct {
+		Code string `xml:"Code"`
+		Bin  string `xml:"Bin,omitempty"`
+	} `xml:"Warehouse"`
+}
+
+func (w *warehouseInfo) Validate() error {
+	if w.Warehouse.Code == "" {
+		return errors.New("warehouse code is required")
+	}
+	return nil
+}
+
+func init() {
+	schema.RegisterExtension(headExtensionNS, headFields{})
+	schema.RegisterExtension(erpExtensionNS, warehouseInfo{})
+}
+
+func TestExtensionsDecode(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "multi-partytax.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	invoice, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+
+	var head headFields
+	if err := invoice.Extensions.Decode(&head); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if head.UserfieldName != "my user data" {
+		t.Errorf("UserfieldName = %q, want %q", head.UserfieldName, "my user data")
+	}
+	if head.AdditionalHeadDiscount != 10 {
+		t.Errorf("AdditionalHeadDiscount = %d, want 10", head.AdditionalHeadDiscount)
+	}
+
+	// Line extensions use a different namespace
+	var lineHead headFields
+	err = invoice.InvoiceLines.InvoiceLine[0].Extensions.Decode(&lineHead)
+	if !errors.Is(err, schema.ErrExtensionNotFound) {
+		t.Errorf("Decode line extensions: got %v, want ErrExtensionNotFound", err)
+	}
+}
+
+func TestExtensionsDecodeUnregistered(t *testing.T) {
+	type unregistered struct{}
+
+	ext := &schema.Extensions{Raw: []byte(`<x:A xmlns:x="urn:x"/>`)}
+	if err := ext.Decode(&unregistered{}); !errors.Is(err, schema.ErrExtensionNotRegistered) {
+		t.Errorf("Decode: got %v, want ErrExtensionNotRegistered", err)
+	}
+}
+
+func TestExtensionsSet(t *testing.T) {
+	ext := &schema.Extensions{Raw: []byte(
+		"\n    <!-- vendor data -->\n" +
+			`    <e:Old xmlns:e="urn:example:erp"><e:Code>X</e:Code></e:Old>` + "\n" +
+			`    <h:Keep xmlns:h="urn:other">1</h:Keep>` + "\n  ")}
+
+	var info warehouseInfo
+	info.Warehouse.Code = "W01"
+	info.Warehouse.Bin = "A-3"
+	if err := ext.Set(&info); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	raw := string(ext.Raw)
+	if strings.Contains(raw, "Old") {
+		t.Errorf("existing namespace elements not replaced:\n%s", raw)
+	}
+	for _, want := range []string{
+		"<!-- vendor data -->",
+		`<Warehouse xmlns="urn:example:erp"><Code>W01</Code><Bin>A-3</Bin></Warehouse>`,
+		`<h:Keep xmlns:h="urn:other">1</h:Keep>`,
+	} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("Raw missing %q:\n%s", want, raw)
+		}
+	}
+	if strings.Index(raw, "<Warehouse") > strings.Index(raw, "<h:Keep") {
+		t.Errorf("payload did not keep the position of replaced elements:\n%s", raw)
+	}
+
+	var got warehouseInfo
+	if err := ext.Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got.Warehouse.Code != "W01" || got.Warehouse.Bin != "A-3" {
+		t.Errorf("Decode after Set = %+v", got.Warehouse)
+	}
+}
+
+func TestExtensionsEncodeRoundTrip(t *testing.T) {
+	invoice := createValidInvoice()
+	invoice.Extensions = &schema.Extensions{}
+
+	var info warehouseInfo
+	info.Warehouse.Code = "W02"
+	if err := invoice.Extensions.Set(&info); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	decoded, err := DecodeBytes(out)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+
+	values, err := decoded.Extensions.Registered()
+	if err != nil {
+		t.Fatalf("Registered failed: %v", err)
+	}
+	if len(values) != 1 {
+		t.Fatalf("Registered returned %d values, want 1", len(values))
+	}
+	got, ok := values[0].(*warehouseInfo)
+	if !ok || got.Warehouse.Code != "W02" {
+		t.Errorf("Registered value = %#v", values[0])
+	}
+}
+
+func TestExtensionsPrefixDeclaredOnRoot(t *testing.T) {
+	invoice := createValidInvoice()
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+
+	// The prefix is declared on <Invoice>, as ISDOC producers usually do
+	s := strings.Replace(string(out), `<Invoice `, `<Invoice xmlns:erp="urn:example:erp" `, 1)
+	s = strings.Replace(s, "<AccountingSupplierParty>",
+		"<Extensions><erp:Warehouse><erp:Bin>A-3</erp:Bin></erp:Warehouse></Extensions><AccountingSupplierParty>", 1)
+
+	decoded, err := DecodeBytes([]byte(s))
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+
+	namespaces, err := decoded.Extensions.Namespaces()
+	if err != nil {
+		t.Fatalf("Namespaces failed: %v", err)
+	}
+	if len(namespaces) != 1 || namespaces[0] != erpExtensionNS {
+		t.Errorf("Namespaces = %q, want [%s]", namespaces, erpExtensionNS)
+	}
+
+	var info warehouseInfo
+	if err := decoded.Extensions.Decode(&info); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if info.Warehouse.Bin != "A-3" {
+		t.Errorf("Bin = %q, want A-3", info.Warehouse.Bin)
+	}
+
+	// The payload is validated, so the missing Code is reported
+	found := false
+	for _, err := range ValidateInvoice(decoded) {
+		if err.Field == "Invoice.Extensions" && err.Code == ErrCodeInvalidExtension {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected INVALID_EXTENSION for the payload without Code")
+	}
+}
+
+func TestValidateExtensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		wantCode string
+	}{
+		{
+			name: "valid registered extension",
+			raw:  `<Warehouse xmlns="urn:example:erp"><Code>W01</Code></Warehouse>`,
+		},
+		{
+			name: "unregistered namespace",
+			raw:  `<x:Any xmlns:x="urn:unknown">anything</x:Any>`,
+		},
+		{
+			name:     "registered extension fails Validate",
+			raw:      `<Warehouse xmlns="urn:example:erp"><Bin>A-3</Bin></Warehouse>`,
+			wantCode: ErrCodeInvalidExtension,
+		},
+		{
+			name:     "registered extension does not decode",
+			raw:      `<h:AdditionalHeadDiscount xmlns:h="http://anydomain.cz/branch/developer/head">ten</h:AdditionalHeadDiscount>`,
+			wantCode: ErrCodeInvalidExtension,
+		},
+		{
+			name:     "element without namespace",
+			raw:      `<Plain>1</Plain>`,
+			wantCode: ErrCodeSchemaViolation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := createValidInvoice()
+			invoice.InvoiceLines.InvoiceLine[0].Extensions = &schema.Extensions{Raw: []byte(tt.raw)}
+
+			var found *ValidationError
+			for _, err := range ValidateInvoice(invoice) {
+				if err.Field == "Invoice.InvoiceLines.InvoiceLine[0].Extensions" {
+					found = err
+				}
+			}
+
+			switch {
+			case tt.wantCode == "" && found != nil:
+				t.Errorf("unexpected error: %v", found)
+			case tt.wantCode != "" && found == nil:
+				t.Errorf("expected %s error", tt.wantCode)
+			case tt.wantCode != "" && found.Code != tt.wantCode:
+				t.Errorf("Code = %s, want %s", found.Code, tt.wantCode)
+			}
+		})
+	}
+}
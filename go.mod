@@ -2,7 +2,10 @@ module github.com/xseman/isdoc
 
 go 1.25.5
 
-require github.com/pdfcpu/pdfcpu v0.11.1
+require (
+	github.com/pdfcpu/pdfcpu v0.11.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
@@ -0,0 +1,115 @@
+package en16931
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// ParseRat parses d, treating empty or invalid values as zero. Only the
+// xs:decimal syntax is accepted, not fractions such as "1/3" or exponents
+// such as "1e3".
+func ParseRat(d types.Decimal) *big.Rat {
+	if _, err := types.NewDecimal(string(d)); err != nil {
+		return new(big.Rat)
+	}
+	r, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// FormatAmount rounds r to two decimal places, halves away from zero.
+func FormatAmount(r *big.Rat) types.Decimal {
+	return types.Decimal(r.FloatString(2))
+}
+
+// FormatPrice formats a unit price with two to six decimal places.
+func FormatPrice(r *big.Rat) types.Decimal {
+	s := r.FloatString(6)
+	for strings.HasSuffix(s, "0") && len(s)-strings.IndexByte(s, '.') > 3 {
+		s = s[:len(s)-1]
+	}
+	return types.Decimal(s)
+}
+
+// FormatRate formats an exchange rate with up to four decimal places.
+func FormatRate(r *big.Rat) types.Decimal {
+	return types.Decimal(strings.TrimRight(strings.TrimRight(r.FloatString(4), "0"), "."))
+}
+
+// Negate returns -d, keeping its formatting.
+func Negate(d types.Decimal) types.Decimal {
+	if ParseRat(d).Sign() == 0 {
+		return d
+	}
+	if s, ok := strings.CutPrefix(string(d), "-"); ok {
+		return types.Decimal(s)
+	}
+	return "-" + d
+}
+
+// Sum returns a+b rounded to two decimal places.
+func Sum(a, b types.Decimal) types.Decimal {
+	return FormatAmount(new(big.Rat).Add(ParseRat(a), ParseRat(b)))
+}
+
+// TaxTotal is a VAT total of a document in one currency.
+type TaxTotal struct {
+	CurrencyID string
+	Amount     types.Decimal
+}
+
+// ExchangeRate derives the rate from the document currency to the tax
+// currency from the VAT totals stated in both, rounded to four decimal
+// places. It reports false if either total is missing or the document
+// total is zero.
+func ExchangeRate(totals []TaxTotal, documentCurrency, taxCurrency string) (*big.Rat, bool) {
+	var docTax, localTax *big.Rat
+	for _, t := range totals {
+		switch t.CurrencyID {
+		case documentCurrency:
+			docTax = ParseRat(t.Amount)
+		case taxCurrency:
+			localTax = ParseRat(t.Amount)
+		}
+	}
+	if docTax == nil || localTax == nil || docTax.Sign() == 0 {
+		return nil, false
+	}
+	rate, _ := new(big.Rat).SetString(new(big.Rat).Quo(localTax, docTax).FloatString(4))
+	return rate, true
+}
+
+// SetAmounts sets the local and foreign currency amounts of v, an amount in
+// the document currency. With a nil rate the document currency is local.
+func SetAmounts(v types.Decimal, rate *big.Rat, local, curr *types.Decimal) {
+	if rate == nil {
+		*local = v
+		return
+	}
+	*curr = v
+	*local = FormatAmount(new(big.Rat).Mul(ParseRat(v), rate))
+}
+
+// ComputeLine sets the tax and tax-inclusive amounts of line from its net
+// amount, unit price and VAT rate, and in foreign currency invoices the
+// tax-inclusive foreign amount.
+func ComputeLine(line *schema.InvoiceLine, foreign bool) {
+	factor := big.NewRat(1, 1)
+	if line.ClassifiedTaxCategory.VATApplicable.Bool() {
+		factor.Add(factor, new(big.Rat).Quo(ParseRat(line.ClassifiedTaxCategory.Percent), big.NewRat(100, 1)))
+	}
+	net := ParseRat(line.LineExtensionAmount)
+	gross, _ := new(big.Rat).SetString(new(big.Rat).Mul(net, factor).FloatString(2))
+	line.LineExtensionAmountTaxInclusive = FormatAmount(gross)
+	line.LineExtensionTaxAmount = FormatAmount(new(big.Rat).Sub(gross, net))
+	line.UnitPriceTaxInclusive = FormatPrice(new(big.Rat).Mul(ParseRat(line.UnitPrice), factor))
+	if foreign {
+		curr := ParseRat(line.LineExtensionAmountCurr)
+		line.LineExtensionAmountTaxInclusiveCurr = FormatAmount(curr.Mul(curr, factor))
+	}
+}
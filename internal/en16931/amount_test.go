@@ -0,0 +1,41 @@
+package en16931
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/xseman/isdoc/types"
+)
+
+func TestParseRat(t *testing.T) {
+	tests := []struct {
+		in   types.Decimal
+		want *big.Rat
+	}{
+		{"100.50", big.NewRat(201, 2)},
+		{"-.5", big.NewRat(-1, 2)},
+		{"", new(big.Rat)},
+		{"abc", new(big.Rat)},
+		{"1/3", new(big.Rat)},
+		{"1e3", new(big.Rat)},
+	}
+	for _, tc := range tests {
+		if got := ParseRat(tc.in); got.Cmp(tc.want) != 0 {
+			t.Errorf("ParseRat(%q) = %s, want %s", tc.in, got.RatString(), tc.want.RatString())
+		}
+	}
+}
+
+func TestExchangeRate(t *testing.T) {
+	totals := []TaxTotal{{"EUR", "21.00"}, {"CZK", "525.00"}}
+	rate, ok := ExchangeRate(totals, "EUR", "CZK")
+	if !ok || FormatRate(rate) != "25" {
+		t.Errorf("ExchangeRate() = %v, %v, want 25", rate, ok)
+	}
+	if _, ok := ExchangeRate(totals[:1], "EUR", "CZK"); ok {
+		t.Error("ExchangeRate() without the tax currency total succeeded")
+	}
+	if _, ok := ExchangeRate([]TaxTotal{{"EUR", "0"}, {"CZK", "0"}}, "EUR", "CZK"); ok {
+		t.Error("ExchangeRate() from a zero total succeeded")
+	}
+}
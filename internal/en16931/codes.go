@@ -0,0 +1,209 @@
+// Package en16931 contains the code lists and amount helpers shared by the
+// EN 16931 syntaxes, UBL 2.1 and UN/CEFACT CII.
+package en16931
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/types"
+)
+
+// UNCL1001 document type codes.
+const (
+	TypeCodeInvoice              = "380"
+	TypeCodeCreditNote           = "381"
+	TypeCodeDebitNote            = "383"
+	TypeCodeCorrected            = "384"
+	TypeCodePrepayment           = "386"
+	TypeCodeSelfBilled           = "389"
+	TypeCodeSelfBilledCreditNote = "261"
+)
+
+// typeCodes maps ISDOC DocumentType to UNCL1001 codes.
+var typeCodes = map[types.DocumentType]string{
+	types.DocumentTypeInvoice:            TypeCodeInvoice,
+	types.DocumentTypeCreditNote:         TypeCodeCreditNote,
+	types.DocumentTypeDebitNote:          TypeCodeDebitNote,
+	types.DocumentTypeAdvanceInvoice:     TypeCodePrepayment,
+	types.DocumentTypeAdvanceTaxDocument: TypeCodePrepayment,
+	types.DocumentTypeAdvanceCreditNote:  TypeCodeCreditNote,
+	types.DocumentTypeSimplified:         TypeCodeInvoice,
+}
+
+// TypeCode returns the UNCL1001 code of an ISDOC DocumentType.
+func TypeCode(documentType types.DocumentType) string {
+	return typeCodes[documentType]
+}
+
+// IsCreditNote reports whether an ISDOC DocumentType is a credit note.
+func IsCreditNote(documentType types.DocumentType) bool {
+	return documentType == types.DocumentTypeCreditNote || documentType == types.DocumentTypeAdvanceCreditNote
+}
+
+// DocumentType returns the ISDOC DocumentType of a UNCL1001 code and
+// whether the code has an ISDOC equivalent. creditNote is set for UBL
+// CreditNote documents, whose type codes are all credit notes.
+func DocumentType(code string, creditNote bool) (types.DocumentType, bool) {
+	if creditNote {
+		return types.DocumentTypeCreditNote, code == TypeCodeCreditNote || code == TypeCodeSelfBilledCreditNote
+	}
+	switch code {
+	case TypeCodeInvoice, TypeCodeSelfBilled:
+		return types.DocumentTypeInvoice, true
+	case TypeCodeDebitNote, TypeCodeCorrected:
+		return types.DocumentTypeDebitNote, true
+	case TypeCodePrepayment:
+		return types.DocumentTypeAdvanceTaxDocument, true
+	case TypeCodeCreditNote, TypeCodeSelfBilledCreditNote:
+		return types.DocumentTypeCreditNote, true
+	}
+	return types.DocumentTypeInvoice, false
+}
+
+// UNCL5305 VAT category codes.
+const (
+	CategoryStandard      = "S"
+	CategoryZero          = "Z"
+	CategoryExempt        = "E"
+	CategoryReverseCharge = "AE"
+	CategoryIntraEU       = "K"
+	CategoryExport        = "G"
+	CategoryOutOfScope    = "O"
+)
+
+// Category is a UNCL5305 VAT category with its rate and exemption reason.
+type Category struct {
+	ID         string
+	Percent    types.Decimal
+	ReasonCode string
+	Reason     string
+}
+
+// TaxCategory returns the UNCL5305 category of an ISDOC VAT rate.
+func TaxCategory(vatApplicable bool, percent types.Decimal, reverseCharge bool) Category {
+	switch {
+	case !vatApplicable:
+		return Category{ID: CategoryOutOfScope, Reason: "Not subject to VAT"}
+	case reverseCharge:
+		return Category{ID: CategoryReverseCharge, Percent: "0", ReasonCode: "VATEX-EU-AE", Reason: "Reverse charge"}
+	case ParseRat(percent).Sign() == 0:
+		return Category{ID: CategoryExempt, Percent: "0", Reason: "Exempt from VAT"}
+	}
+	return Category{ID: CategoryStandard, Percent: percent}
+}
+
+// paymentMeansCodes maps UNCL4461 codes without an ISDOC equivalent to the
+// closest ISDOC code. ISDOC codes are themselves UNCL4461 codes.
+var paymentMeansCodes = map[int]types.PaymentMeansCode{
+	1:  types.PaymentMeansBankTransfer, // instrument not defined
+	30: types.PaymentMeansBankTransfer, // credit transfer
+	57: types.PaymentMeansBankTransfer, // standing agreement
+	58: types.PaymentMeansBankTransfer, // SEPA credit transfer
+	54: types.PaymentMeansCard,         // credit card
+	55: types.PaymentMeansCard,         // debit card
+	59: types.PaymentMeansDirectDebit,  // SEPA direct debit
+}
+
+// PaymentMeansCode returns the ISDOC payment means code of a UNCL4461 code
+// and whether the code is kept as is.
+func PaymentMeansCode(code string) (types.PaymentMeansCode, bool) {
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return types.PaymentMeansBankTransfer, false
+	}
+	if c := types.PaymentMeansCode(n); c.IsValid() {
+		return c, true
+	}
+	if m, ok := paymentMeansCodes[n]; ok {
+		return m, false
+	}
+	return types.PaymentMeansBankTransfer, false
+}
+
+// EndpointSchemes maps VAT number prefixes to Peppol EAS codes, used for
+// the electronic address of parties without a GLN.
+var EndpointSchemes = map[string]string{
+	"CZ": "9929",
+	"SK": "9950",
+}
+
+// Identifier schemes of the ISO 6523 ICD list.
+const (
+	SchemeGLN  = "0088"
+	SchemeGTIN = "0160"
+)
+
+// IsGLN reports whether id is a 13-digit GLN.
+func IsGLN(id string) bool {
+	return len(id) == 13 && isDigits(id)
+}
+
+// IsGTIN reports whether id has the length of a GTIN.
+func IsGTIN(id string) bool {
+	switch len(id) {
+	case 8, 12, 13, 14:
+		return isDigits(id)
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// IsIBAN reports whether id has the shape of an IBAN.
+func IsIBAN(id string) bool {
+	if len(id) < 15 || len(id) > 34 {
+		return false
+	}
+	for i, r := range id {
+		switch {
+		case i < 2 && (r < 'A' || r > 'Z'):
+			return false
+		case i >= 2 && i < 4 && (r < '0' || r > '9'):
+			return false
+		case (r < 'A' || r > 'Z') && (r < '0' || r > '9'):
+			return false
+		}
+	}
+	return true
+}
+
+// CzechAccount returns the domestic account number and bank code of a
+// Czech IBAN, e.g. "19-2000145399" and "0800".
+func CzechAccount(iban string) (id, bankCode string, ok bool) {
+	if len(iban) != 24 || !strings.HasPrefix(iban, "CZ") || !isDigits(iban[2:]) {
+		return "", "", false
+	}
+	prefix := strings.TrimLeft(iban[8:14], "0")
+	number := strings.TrimLeft(iban[14:], "0")
+	if prefix != "" {
+		number = prefix + "-" + number
+	}
+	return number, iban[4:8], true
+}
+
+// Prefix returns the two-letter country prefix of a VAT number.
+func Prefix(vatID string) string {
+	if len(vatID) < 2 {
+		return ""
+	}
+	return strings.ToUpper(vatID[:2])
+}
+
+// JoinNonEmpty joins the non-empty parts with sep.
+func JoinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
@@ -0,0 +1,71 @@
+package en16931
+
+import (
+	"testing"
+
+	"github.com/xseman/isdoc/types"
+)
+
+func TestDocumentType(t *testing.T) {
+	tests := []struct {
+		code       string
+		creditNote bool
+		want       types.DocumentType
+		exact      bool
+	}{
+		{"380", false, 1, true},
+		{"389", false, 1, true},
+		{"383", false, 3, true},
+		{"386", false, 5, true},
+		{"381", false, 2, true},
+		{"381", true, 2, true},
+		{"261", true, 2, true},
+		{"261", false, 2, true},
+		{"396", true, 2, false},
+		{"326", false, 1, false},
+	}
+	for _, tt := range tests {
+		got, exact := DocumentType(tt.code, tt.creditNote)
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("DocumentType(%s, %v) = %d, %v, want %d, %v", tt.code, tt.creditNote, got, exact, tt.want, tt.exact)
+		}
+	}
+}
+
+func TestPaymentMeansCode(t *testing.T) {
+	tests := []struct {
+		code  string
+		want  types.PaymentMeansCode
+		exact bool
+	}{
+		{"42", 42, true},
+		{"10", 10, true},
+		{"58", 42, false},
+		{"54", 48, false},
+		{"59", 49, false},
+		{"ZZZ", 42, false},
+	}
+	for _, tt := range tests {
+		got, exact := PaymentMeansCode(tt.code)
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("PaymentMeansCode(%s) = %d, %v, want %d, %v", tt.code, got, exact, tt.want, tt.exact)
+		}
+	}
+}
+
+func TestCzechAccount(t *testing.T) {
+	tests := []struct {
+		iban, id, bank string
+		ok             bool
+	}{
+		{"CZ6508000000192000145399", "19-2000145399", "0800", true},
+		{"CZ9455000000001011038930", "1011038930", "5500", true},
+		{"SK3112000000198742637541", "", "", false},
+	}
+	for _, tt := range tests {
+		id, bank, ok := CzechAccount(tt.iban)
+		if ok != tt.ok || (ok && (id != tt.id || bank != tt.bank)) {
+			t.Errorf("CzechAccount(%s) = %s, %s, %v", tt.iban, id, bank, ok)
+		}
+	}
+}
@@ -29,7 +29,7 @@ var Sequence = map[string][]string{
 	"DeliveryType":                           {"Party"},
 	"DetailsType":                            {"DocumentID", "IssueDate", "PaymentDueDate", "VariableSymbol", "ConstantSymbol", "SpecificSymbol"},
 	"EgovClassifiersType":                    {"EgovClassifier"},
-	"Invoice":                                {"DocumentType", "TargetConsolidator", "ClientOnTargetConsolidator", "ClientBankAccount", "ID", "UUID", "EgovFlag", "ISDS_ID", "FileReference", "ReferenceNumber", "EgovClassifiers", "IssuingSystem", "IssueDate", "TaxPointDate", "VATApplicable", "ElectronicPossibilityAgreementReference", "Note", "LocalCurrencyCode", "ForeignCurrencyCode", "CurrRate", "RefCurrRate", "Extensions", "AccountingSupplierParty", "SellerSupplierParty", "BuyerCustomerParty", "OrderReferences", "DeliveryNoteReferences", "OriginalDocumentReferences", "ContractReferences", "Delivery", "InvoiceLines", "NonTaxedDeposits", "TaxedDeposits", "TaxTotal", "LegalMonetaryTotal", "PaymentMeans", "SupplementsList", "SubDocumentType", "SubDocumentTypeOrigin", "AccountingCustomerParty", "AnonymousCustomerParty", "AccountingCustomerParty"},
+	"Invoice":                                {"DocumentType", "SubDocumentType", "SubDocumentTypeOrigin", "TargetConsolidator", "ClientOnTargetConsolidator", "ClientBankAccount", "ID", "UUID", "EgovFlag", "ISDS_ID", "FileReference", "ReferenceNumber", "EgovClassifiers", "IssuingSystem", "IssueDate", "TaxPointDate", "VATApplicable", "ElectronicPossibilityAgreementReference", "Note", "LocalCurrencyCode", "ForeignCurrencyCode", "CurrRate", "RefCurrRate", "Extensions", "AccountingSupplierParty", "SellerSupplierParty", "AnonymousCustomerParty", "AccountingCustomerParty", "BuyerCustomerParty", "OrderReferences", "DeliveryNoteReferences", "OriginalDocumentReferences", "ContractReferences", "Delivery", "InvoiceLines", "NonTaxedDeposits", "TaxedDeposits", "TaxTotal", "LegalMonetaryTotal", "PaymentMeans", "SupplementsList"},
 	"InvoiceLineType":                        {"ID", "OrderReference", "DeliveryNoteReference", "OriginalDocumentReference", "ContractReference", "EgovClassifier", "InvoicedQuantity", "LineExtensionAmountCurr", "LineExtensionAmount", "LineExtensionAmountBeforeDiscount", "LineExtensionAmountTaxInclusiveCurr", "LineExtensionAmountTaxInclusive", "LineExtensionAmountTaxInclusiveBeforeDiscount", "LineExtensionTaxAmount", "UnitPrice", "UnitPriceTaxInclusive", "ClassifiedTaxCategory", "Note", "VATNote", "Item", "Extensions"},
 	"InvoiceLinesType":                       {"InvoiceLine"},
 	"ItemType":                               {"Description", "CatalogueItemIdentification", "SellersItemIdentification", "SecondarySellersItemIdentification", "TertiarySellersItemIdentification", "BuyersItemIdentification", "StoreBatches"},
@@ -0,0 +1,495 @@
+package isdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+//go:generate go run ./cmd/isdoc schema -o schema/isdoc.schema.json
+
+// JSONVersion is the version of the JSON representation. It changes when
+// keys or value formats change incompatibly.
+const JSONVersion = "1"
+
+// ErrUnsupportedJSONVersion is returned when decoding JSON with a missing or
+// unknown jsonVersion.
+var ErrUnsupportedJSONVersion = errors.New("unsupported ISDOC JSON version")
+
+// The JSON representation mirrors the XML document:
+//
+//	{
+//	  "jsonVersion": "1",
+//	  "invoice": {
+//	    "version": "6.0.2",
+//	    "documentType": 1,
+//	    "id": "FV-2025-001",
+//	    "issueDate": "2025-01-20",
+//	    "invoiceLines": [
+//	      {"id": "1", "invoicedQuantity": {"value": "2", "unitCode": "KS"}, ...}
+//	    ],
+//	    ...
+//	  }
+//	}
+//
+// Keys are the lowerCamel XML element and attribute names; an attribute
+// named like an element of the same object, such as the id attribute of
+// references next to their ID element, is prefixed with "@". Character data
+// of elements with attributes is stored under "value". Collection elements
+// holding a single repeated element, such as InvoiceLines, become arrays.
+// Decimals are strings to keep their precision, dates use the ISO 8601
+// YYYY-MM-DD form and Extensions are a string of raw XML. Content preserved
+// by lossless decoding is not represented.
+
+var (
+	dateType    = reflect.TypeOf(types.Date{})
+	decimalType = reflect.TypeOf(types.Decimal(""))
+	uuidType    = reflect.TypeOf(types.UUID(""))
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+// EncodeJSON encodes an Invoice in the versioned JSON representation.
+func EncodeJSON(inv *schema.Invoice) ([]byte, error) {
+	return encodeJSONDocument("invoice", reflect.ValueOf(inv).Elem())
+}
+
+// EncodeCommonDocumentJSON encodes a CommonDocument in the versioned JSON
+// representation.
+func EncodeCommonDocumentJSON(doc *schema.CommonDocument) ([]byte, error) {
+	return encodeJSONDocument("commonDocument", reflect.ValueOf(doc).Elem())
+}
+
+func encodeJSONDocument(key string, v reflect.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"jsonVersion":`)
+	writeJSONString(&buf, JSONVersion)
+	buf.WriteString(`,"` + key + `":`)
+	if err := writeJSONValue(&buf, v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// writeJSONValue writes v. Objects keep the XSD element order.
+func writeJSONValue(buf *bytes.Buffer, v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == dateType:
+		writeJSONString(buf, v.Interface().(types.Date).String())
+		return nil
+	case v.Type() == bytesType:
+		writeJSONString(buf, string(v.Bytes()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		writeJSONString(buf, v.String())
+
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+
+	case reflect.Slice:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case reflect.Struct:
+		if inline, ok := jsonInlineField(v.Type()); ok {
+			return writeJSONValue(buf, v.Field(inline.index))
+		}
+
+		buf.WriteByte('{')
+		first := true
+		for _, f := range jsonFields(v.Type()) {
+			field := v.Field(f.index)
+			if (f.omitEmpty || f.attr) && isEmptyJSON(field) {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			writeJSONString(buf, f.key)
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, field); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	default:
+		return fmt.Errorf("encoding JSON: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	// Marshaling a string cannot fail
+	data, _ := json.Marshal(s)
+	buf.Write(data)
+}
+
+// isEmptyJSON reports whether an optional field is left out of the output.
+func isEmptyJSON(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice:
+		return v.Len() == 0
+	case reflect.String:
+		return v.String() == ""
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Struct:
+		if v.Type() == dateType {
+			return v.Interface().(types.Date).IsZero()
+		}
+		if inline, ok := jsonInlineField(v.Type()); ok {
+			return isEmptyJSON(v.Field(inline.index))
+		}
+	}
+	return false
+}
+
+// jsonField is a struct field with its JSON key.
+type jsonField struct {
+	xmlField
+	key string
+}
+
+var jsonFieldCache sync.Map // reflect.Type -> []jsonField
+
+// jsonFields returns the fields of struct type t in output order:
+// attributes and content first, then elements in XSD order.
+func jsonFields(t reflect.Type) []jsonField {
+	if fields, ok := jsonFieldCache.Load(t); ok {
+		return fields.([]jsonField)
+	}
+
+	ti := getTypeInfo(t)
+	elements := make([]jsonField, 0, len(ti.order))
+	elementKeys := make(map[string]bool, len(ti.order))
+	for _, name := range ti.order {
+		key := lowerCamel(name)
+		elements = append(elements, jsonField{ti.elements[name], key})
+		elementKeys[key] = true
+	}
+
+	fields := make([]jsonField, 0, len(ti.fields))
+	for _, f := range ti.fields {
+		switch {
+		case f.chardata || f.innerxml:
+			fields = append(fields, jsonField{f, "value"})
+		case f.attr:
+			key := lowerCamel(f.name)
+			if elementKeys[key] {
+				key = "@" + key
+			}
+			fields = append(fields, jsonField{f, key})
+		}
+	}
+	fields = append(fields, elements...)
+
+	actual, _ := jsonFieldCache.LoadOrStore(t, fields)
+	return actual.([]jsonField)
+}
+
+// jsonInlineField returns the only field of types represented by its value
+// instead of an object: collections such as InvoiceLines, which become JSON
+// arrays, and elements holding just character data or inner XML.
+func jsonInlineField(t reflect.Type) (xmlField, bool) {
+	ti := getTypeInfo(t)
+	if len(ti.fields) != 1 {
+		return xmlField{}, false
+	}
+	f := ti.fields[0]
+	switch {
+	case f.chardata, f.innerxml:
+		return f, true
+	case !f.attr && t.Field(f.index).Type.Kind() == reflect.Slice:
+		return f, true
+	}
+	return xmlField{}, false
+}
+
+// lowerCamel converts an XML name to lowerCamel case: "VATApplicable"
+// becomes "vatApplicable", "ID" becomes "id" and "ISDS_ID" becomes "isdsId".
+func lowerCamel(name string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		r := []rune(part)
+		if i == 0 {
+			// Lower the leading capitals, keeping the last one of an
+			// acronym followed by a lowercase letter: "VATNote" -> "vatNote"
+			n := 0
+			for n < len(r) && unicode.IsUpper(r[n]) {
+				n++
+			}
+			if n > 1 && n < len(r) {
+				n--
+			}
+			for j := 0; j < n; j++ {
+				r[j] = unicode.ToLower(r[j])
+			}
+		} else {
+			r = []rune(strings.ToLower(part))
+			r[0] = unicode.ToUpper(r[0])
+		}
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// DecodeJSON decodes an Invoice from the versioned JSON representation and
+// resolves its references, like DecodeBytes.
+func DecodeJSON(data []byte) (*schema.Invoice, error) {
+	raw, err := decodeJSONDocument(data, "invoice")
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice schema.Invoice
+	if err := readJSONValue(raw, reflect.ValueOf(&invoice).Elem(), "Invoice"); err != nil {
+		return nil, err
+	}
+
+	if errs := resolveReferences(&invoice); len(errs) > 0 {
+		return &invoice, errs
+	}
+	return &invoice, nil
+}
+
+// DecodeCommonDocumentJSON decodes a CommonDocument from the versioned JSON
+// representation.
+func DecodeCommonDocumentJSON(data []byte) (*schema.CommonDocument, error) {
+	raw, err := decodeJSONDocument(data, "commonDocument")
+	if err != nil {
+		return nil, err
+	}
+
+	var doc schema.CommonDocument
+	if err := readJSONValue(raw, reflect.ValueOf(&doc).Elem(), "CommonDocument"); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// JSONRoot returns the document key of a JSON document, "invoice" or
+// "commonDocument".
+func JSONRoot(data []byte) (string, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", newJSONDecodeError("", err)
+	}
+	for _, key := range []string{"invoice", "commonDocument"} {
+		if _, ok := envelope[key]; ok {
+			return key, nil
+		}
+	}
+	return "", newJSONDecodeError("", errors.New(`missing "invoice" or "commonDocument"`))
+}
+
+// decodeJSONDocument checks the envelope and returns the document under key.
+func decodeJSONDocument(data []byte, key string) (json.RawMessage, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, newJSONDecodeError("", err)
+	}
+
+	var version string
+	if raw, ok := envelope["jsonVersion"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, newJSONDecodeError("jsonVersion", err)
+		}
+	}
+	if version != JSONVersion {
+		return nil, newJSONDecodeError("jsonVersion",
+			fmt.Errorf("%w: %q (want %q)", ErrUnsupportedJSONVersion, version, JSONVersion))
+	}
+
+	raw, ok := envelope[key]
+	if !ok {
+		return nil, newJSONDecodeError("", fmt.Errorf("missing %q", key))
+	}
+	for k := range envelope {
+		if k != "jsonVersion" && k != key {
+			return nil, newJSONDecodeError("", fmt.Errorf("unknown field %q", k))
+		}
+	}
+	return raw, nil
+}
+
+// readJSONValue decodes raw into v. path is the field path used in errors,
+// in the same syntax as ValidationError.Field.
+func readJSONValue(raw json.RawMessage, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Ptr {
+		if string(raw) == "null" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == dateType:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		if s == "" {
+			v.Set(reflect.Zero(dateType))
+			return nil
+		}
+		d, err := types.ParseDate(s)
+		if err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		v.Set(reflect.ValueOf(d))
+		return nil
+	case v.Type() == bytesType:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		v.SetBytes([]byte(s))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		if err := checkJSONString(v.Type(), s); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		v.SetString(s)
+
+	case reflect.Bool:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		v.SetInt(n)
+
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := readJSONValue(item, s.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+	case reflect.Struct:
+		if inline, ok := jsonInlineField(v.Type()); ok {
+			if inline.chardata || inline.innerxml {
+				return readJSONValue(raw, v.Field(inline.index), path)
+			}
+			return readJSONValue(raw, v.Field(inline.index), path+"."+inline.name)
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return newJSONDecodeError(path, err)
+		}
+		fields := make(map[string]jsonField)
+		for _, f := range jsonFields(v.Type()) {
+			fields[f.key] = f
+		}
+		for _, key := range sortedKeys(obj) {
+			f, ok := fields[key]
+			if !ok {
+				return newJSONDecodeError(path, fmt.Errorf("unknown field %q", key))
+			}
+			fieldPath := path + "." + f.name
+			if f.attr {
+				fieldPath = path + ".@" + f.name
+			}
+			if err := readJSONValue(obj[key], v.Field(f.index), fieldPath); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return newJSONDecodeError(path, fmt.Errorf("unsupported type %s", v.Type()))
+	}
+	return nil
+}
+
+// checkJSONString validates strings of the restricted types.
+func checkJSONString(t reflect.Type, s string) error {
+	if s == "" {
+		return nil
+	}
+	var err error
+	switch t {
+	case decimalType:
+		_, err = types.NewDecimal(s)
+	case uuidType:
+		_, err = types.NewUUID(s)
+	}
+	return err
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newJSONDecodeError(path string, err error) *DecodeError {
+	return NewDecodeError(path, fmt.Errorf("JSON parsing: %w", err)).withCode(ErrCodeInvalidJSON)
+}
@@ -0,0 +1,239 @@
+package isdoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestLowerCamel(t *testing.T) {
+	tests := map[string]string{
+		"ID":                      "id",
+		"UUID":                    "uuid",
+		"VATApplicable":           "vatApplicable",
+		"ISDS_ID":                 "isdsId",
+		"LineExtensionAmountCurr": "lineExtensionAmountCurr",
+		"languageID":              "languageID",
+		"IBAN":                    "iban",
+		"Algorithm":               "algorithm",
+	}
+	for in, want := range tests {
+		if got := lowerCamel(in); got != want {
+			t.Errorf("lowerCamel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "fixtures", "*.isdoc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			var want, got, encoded []byte
+			if root, _ := RootElement(data); root == "CommonDocument" {
+				doc, err := DecodeCommonDocumentBytes(data)
+				if err != nil {
+					t.Fatalf("DecodeCommonDocumentBytes failed: %v", err)
+				}
+				want, _ = EncodeCommonDocumentBytes(doc)
+				if encoded, err = EncodeCommonDocumentJSON(doc); err != nil {
+					t.Fatalf("EncodeCommonDocumentJSON failed: %v", err)
+				}
+				decoded, err := DecodeCommonDocumentJSON(encoded)
+				if err != nil {
+					t.Fatalf("DecodeCommonDocumentJSON failed: %v", err)
+				}
+				got, _ = EncodeCommonDocumentBytes(decoded)
+			} else {
+				invoice, _ := DecodeBytes(data)
+				want, _ = EncodeBytes(invoice)
+				if encoded, err = EncodeJSON(invoice); err != nil {
+					t.Fatalf("EncodeJSON failed: %v", err)
+				}
+				decoded, err := DecodeJSON(encoded)
+				if err != nil {
+					t.Fatalf("DecodeJSON failed: %v", err)
+				}
+				got, _ = EncodeBytes(decoded)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("XML after JSON round trip differs:\n%s", got)
+			}
+			if err := checkJSONSchema(t, encoded); err != nil {
+				t.Errorf("encoded JSON does not match the schema: %v", err)
+			}
+		})
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	invoice := createValidInvoice()
+	data, err := EncodeJSON(invoice)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+
+	if err := checkJSONSchema(t, data); err != nil {
+		t.Errorf("encoded JSON does not match the schema: %v", err)
+	}
+
+	for _, want := range []string{
+		`{"jsonVersion":"1","invoice":{"version":"6.0.2","documentType":1,"id":"INV-001"`,
+		`"issueDate":"2024-01-15"`,
+		`"vatApplicable":true`,
+		`"invoiceLines":[{"id":"1"`,
+		`"invoicedQuantity":{"value":"1"}`,
+		`"lineExtensionAmount":"1000.00"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("output missing %s:\n%s", want, data)
+		}
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		wantPath string
+	}{
+		{"missing version", `{"invoice":{}}`, "jsonVersion"},
+		{"unknown version", `{"jsonVersion":"0","invoice":{}}`, "jsonVersion"},
+		{"unknown field", `{"jsonVersion":"1","invoice":{"invoiceLines":[{"amount":"1"}]}}`, "Invoice.InvoiceLines.InvoiceLine[0]"},
+		{"invalid decimal", `{"jsonVersion":"1","invoice":{"currRate":"1,5"}}`, "Invoice.CurrRate"},
+		{"number for decimal", `{"jsonVersion":"1","invoice":{"currRate":1.5}}`, "Invoice.CurrRate"},
+		{"invalid date", `{"jsonVersion":"1","invoice":{"issueDate":"20.1.2025"}}`, "Invoice.IssueDate"},
+		{"common document", `{"jsonVersion":"1","commonDocument":{}}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeJSON([]byte(tt.json))
+
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				t.Fatalf("expected DecodeError, got %v", err)
+			}
+			if decodeErr.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", decodeErr.Path, tt.wantPath)
+			}
+			if decodeErr.Code != ErrCodeInvalidJSON {
+				t.Errorf("Code = %q, want %q", decodeErr.Code, ErrCodeInvalidJSON)
+			}
+		})
+	}
+
+	_, err := DecodeJSON([]byte(`{"invoice":{}}`))
+	if !errors.Is(err, ErrUnsupportedJSONVersion) {
+		t.Errorf("got %v, want ErrUnsupportedJSONVersion", err)
+	}
+}
+
+func TestJSONSchemaFile(t *testing.T) {
+	want, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join("schema", "isdoc.schema.json"))
+	if err != nil {
+		t.Fatalf("Failed to read schema file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("schema/isdoc.schema.json is out of date; run go generate")
+	}
+}
+
+// checkJSONSchema validates data against JSONSchema, supporting the subset
+// of keywords the generator emits.
+func checkJSONSchema(t *testing.T, data []byte) error {
+	t.Helper()
+
+	raw, err := JSONSchema()
+	if err != nil {
+		return err
+	}
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	defs := root["$defs"].(map[string]any)
+	return checkSchemaValue(defs, root, doc, "$")
+}
+
+func checkSchemaValue(defs map[string]any, s map[string]any, v any, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		return checkSchemaValue(defs, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), v, path)
+	}
+	if c, ok := s["const"]; ok && c != v {
+		return errors.New(path + ": const mismatch")
+	}
+
+	switch s["type"] {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return errors.New(path + ": want string")
+		}
+		if p, ok := s["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(str) {
+			return errors.New(path + ": pattern mismatch: " + str)
+		}
+	case "integer":
+		if n, ok := v.(float64); !ok || n != float64(int64(n)) {
+			return errors.New(path + ": want integer")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return errors.New(path + ": want boolean")
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return errors.New(path + ": want array")
+		}
+		for _, item := range items {
+			if err := checkSchemaValue(defs, s["items"].(map[string]any), item, path+"[]"); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return errors.New(path + ": want object")
+		}
+		props := s["properties"].(map[string]any)
+		for key, value := range obj {
+			p, ok := props[key]
+			if !ok {
+				return errors.New(path + ": unexpected property " + key)
+			}
+			if err := checkSchemaValue(defs, p.(map[string]any), value, path+"."+key); err != nil {
+				return err
+			}
+		}
+		required, _ := s["required"].([]any)
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				return errors.New(path + ": missing required property " + key.(string))
+			}
+		}
+	}
+	return nil
+}
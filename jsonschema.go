@@ -0,0 +1,125 @@
+package isdoc
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+// JSONSchemaID identifies the JSON Schema of the current JSONVersion.
+const JSONSchemaID = "https://github.com/xseman/isdoc/schema/isdoc.schema.json"
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the JSON
+// representation read by DecodeJSON and DecodeCommonDocumentJSON.
+//
+// Fields that are required in the XML schema are required in the JSON
+// Schema too. The schema is also available as schema/isdoc.schema.json.
+func JSONSchema() ([]byte, error) {
+	g := &jsonSchemaGenerator{defs: make(map[string]any)}
+	root := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         JSONSchemaID,
+		"title":       "ISDOC JSON",
+		"description": "JSON representation of ISDOC 6.0.2 documents, version " + JSONVersion,
+		"type":        "object",
+		"properties": map[string]any{
+			"jsonVersion":    map[string]any{"const": JSONVersion},
+			"invoice":        g.schemaOf(reflect.TypeOf(schema.Invoice{})),
+			"commonDocument": g.schemaOf(reflect.TypeOf(schema.CommonDocument{})),
+		},
+		"required": []string{"jsonVersion"},
+		"oneOf": []any{
+			map[string]any{"required": []string{"invoice"}},
+			map[string]any{"required": []string{"commonDocument"}},
+		},
+		"additionalProperties": false,
+		"$defs":                g.defs,
+	}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type jsonSchemaGenerator struct {
+	defs map[string]any
+}
+
+// schemaOf returns the schema of a value of type t. Struct types are added
+// to defs and referenced.
+func (g *jsonSchemaGenerator) schemaOf(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case dateType:
+		return g.define("Date", map[string]any{"type": "string", "format": "date"})
+	case decimalType:
+		return g.define("Decimal", map[string]any{
+			"type":        "string",
+			"description": "Decimal number; an empty string means unset",
+			"pattern":     `^(-?(\d+\.?\d*|\d*\.?\d+))?$`,
+		})
+	case uuidType:
+		return g.define("UUID", map[string]any{
+			"type":        "string",
+			"description": "UUID; an empty string means unset",
+			"pattern":     `^([0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})?$`,
+		})
+	case bytesType:
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if inline, ok := jsonInlineField(t); ok {
+			return g.schemaOf(t.Field(inline.index).Type)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name before recursing into fields
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.objectSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// define adds a named schema to defs and returns a reference to it.
+func (g *jsonSchemaGenerator) define(name string, s map[string]any) map[string]any {
+	g.defs[name] = s
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+func (g *jsonSchemaGenerator) objectSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	required := []string{}
+	for _, f := range jsonFields(t) {
+		properties[f.key] = g.schemaOf(t.Field(f.index).Type)
+		if !f.omitEmpty && t.Field(f.index).Type.Kind() != reflect.Ptr {
+			required = append(required, f.key)
+		}
+	}
+
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
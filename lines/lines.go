@@ -0,0 +1,343 @@
+// Package lines converts ISDOC invoice lines to and from CSV for review and
+// editing in spreadsheets.
+//
+// Each InvoiceLine becomes one row. The header names the columns, so rows
+// written by WriteCSV can be read back with ReadCSV:
+//
+//	err := lines.WriteCSV(w, invoice.InvoiceLines.InvoiceLine, lines.Options{})
+//
+//	l, err := lines.ReadCSV(r, lines.Options{
+//	    Mapping: map[string]string{lines.ColumnDescription: "Název"},
+//	})
+//	invoice.InvoiceLines.InvoiceLine = l
+//	err = isdoc.ComputeTotals(invoice)
+package lines
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// Column names used in CSV headers and Options.Mapping.
+const (
+	ColumnID                     = "id"
+	ColumnDescription            = "description"
+	ColumnQuantity               = "quantity"
+	ColumnUnitCode               = "unitCode"
+	ColumnUnitPrice              = "unitPrice"
+	ColumnUnitPriceTaxInclusive  = "unitPriceTaxInclusive"
+	ColumnAmount                 = "lineExtensionAmount"
+	ColumnTaxAmount              = "lineExtensionTaxAmount"
+	ColumnAmountTaxInclusive     = "lineExtensionAmountTaxInclusive"
+	ColumnVATPercent             = "vatPercent"
+	ColumnVATCalculationMethod   = "vatCalculationMethod"
+	ColumnCatalogueItemID        = "catalogueItemId"
+	ColumnSellersItemID          = "sellersItemId"
+	ColumnSecondarySellersItemID = "secondarySellersItemId"
+	ColumnTertiarySellersItemID  = "tertiarySellersItemId"
+	ColumnBuyersItemID           = "buyersItemId"
+	ColumnNote                   = "note"
+)
+
+// Columns lists all columns in the order written by WriteCSV.
+var Columns = []string{
+	ColumnID,
+	ColumnDescription,
+	ColumnQuantity,
+	ColumnUnitCode,
+	ColumnUnitPrice,
+	ColumnUnitPriceTaxInclusive,
+	ColumnAmount,
+	ColumnTaxAmount,
+	ColumnAmountTaxInclusive,
+	ColumnVATPercent,
+	ColumnVATCalculationMethod,
+	ColumnCatalogueItemID,
+	ColumnSellersItemID,
+	ColumnSecondarySellersItemID,
+	ColumnTertiarySellersItemID,
+	ColumnBuyersItemID,
+	ColumnNote,
+}
+
+// ErrUnknownColumn is returned for mappings of unknown column names.
+var ErrUnknownColumn = errors.New("unknown column")
+
+// Options configures CSV reading and writing.
+type Options struct {
+	// Comma is the field delimiter. Default is ','.
+	Comma rune
+
+	// Mapping maps column names to the CSV header used for them, for
+	// example {"description": "Název"}. Unmapped columns use their own
+	// name; header matching is case-insensitive.
+	Mapping map[string]string
+}
+
+func (o Options) header(column string) string {
+	if h, ok := o.Mapping[column]; ok {
+		return h
+	}
+	return column
+}
+
+func (o Options) check() error {
+	for column := range o.Mapping {
+		if !isColumn(column) {
+			return fmt.Errorf("%w: %q", ErrUnknownColumn, column)
+		}
+	}
+	return nil
+}
+
+func isColumn(name string) bool {
+	for _, c := range Columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseError reports an invalid value in a CSV row.
+type ParseError struct {
+	Row    int // 1-based row number, including the header
+	Column string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("row %d, column %s: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// WriteCSV writes lines as CSV with a header row.
+func WriteCSV(w io.Writer, lines []schema.InvoiceLine, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+
+	header := make([]string, len(Columns))
+	for i, c := range Columns {
+		header[i] = opts.header(c)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := range lines {
+		row := make([]string, len(Columns))
+		for j, c := range Columns {
+			row[j] = get(&lines[i], c)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads lines from CSV with a header row. Columns missing from the
+// header are left empty and unknown headers are ignored. Decimal values may
+// use a decimal comma and spaces as thousands separators.
+//
+// Amounts are read as given; use isdoc.ComputeTotals to recompute them.
+func ReadCSV(r io.Reader, opts Options) ([]schema.InvoiceLine, error) {
+	if err := opts.check(); err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("missing header row")
+		}
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	for i, h := range header {
+		h = strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")
+		for _, c := range Columns {
+			if strings.EqualFold(h, opts.header(c)) {
+				index[c] = i
+			}
+		}
+	}
+	if len(index) == 0 {
+		return nil, errors.New("header row has no known columns")
+	}
+
+	var lines []schema.InvoiceLine
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isBlank(record) {
+			continue
+		}
+
+		var line schema.InvoiceLine
+		for _, c := range Columns {
+			i, ok := index[c]
+			if !ok || i >= len(record) {
+				continue
+			}
+			if err := set(&line, c, strings.TrimSpace(record[i])); err != nil {
+				return nil, &ParseError{Row: row, Column: header[i], Err: err}
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func get(line *schema.InvoiceLine, column string) string {
+	switch column {
+	case ColumnID:
+		return line.ID
+	case ColumnDescription:
+		return line.Item.Description
+	case ColumnQuantity:
+		return line.InvoicedQuantity.Value.String()
+	case ColumnUnitCode:
+		return line.InvoicedQuantity.UnitCode
+	case ColumnUnitPrice:
+		return line.UnitPrice.String()
+	case ColumnUnitPriceTaxInclusive:
+		return line.UnitPriceTaxInclusive.String()
+	case ColumnAmount:
+		return line.LineExtensionAmount.String()
+	case ColumnTaxAmount:
+		return line.LineExtensionTaxAmount.String()
+	case ColumnAmountTaxInclusive:
+		return line.LineExtensionAmountTaxInclusive.String()
+	case ColumnVATPercent:
+		return line.ClassifiedTaxCategory.Percent.String()
+	case ColumnVATCalculationMethod:
+		return strconv.Itoa(int(line.ClassifiedTaxCategory.VATCalculationMethod))
+	case ColumnCatalogueItemID:
+		return itemID(line.Item.CatalogueItemIdentification)
+	case ColumnSellersItemID:
+		return itemID(line.Item.SellersItemIdentification)
+	case ColumnSecondarySellersItemID:
+		return itemID(line.Item.SecondarySellersItemIdentification)
+	case ColumnTertiarySellersItemID:
+		return itemID(line.Item.TertiarySellersItemIdentification)
+	case ColumnBuyersItemID:
+		return itemID(line.Item.BuyersItemIdentification)
+	case ColumnNote:
+		return line.Note
+	}
+	return ""
+}
+
+func set(line *schema.InvoiceLine, column, v string) error {
+	var err error
+	switch column {
+	case ColumnID:
+		line.ID = v
+	case ColumnDescription:
+		line.Item.Description = v
+	case ColumnQuantity:
+		line.InvoicedQuantity.Value, err = parseDecimal(v)
+	case ColumnUnitCode:
+		line.InvoicedQuantity.UnitCode = v
+	case ColumnUnitPrice:
+		line.UnitPrice, err = parseDecimal(v)
+	case ColumnUnitPriceTaxInclusive:
+		line.UnitPriceTaxInclusive, err = parseDecimal(v)
+	case ColumnAmount:
+		line.LineExtensionAmount, err = parseDecimal(v)
+	case ColumnTaxAmount:
+		line.LineExtensionTaxAmount, err = parseDecimal(v)
+	case ColumnAmountTaxInclusive:
+		line.LineExtensionAmountTaxInclusive, err = parseDecimal(v)
+	case ColumnVATPercent:
+		line.ClassifiedTaxCategory.Percent, err = parseDecimal(strings.TrimSuffix(v, "%"))
+	case ColumnVATCalculationMethod:
+		if v != "" {
+			var n int
+			n, err = strconv.Atoi(v)
+			line.ClassifiedTaxCategory.VATCalculationMethod = types.VATCalculationMethod(n)
+			if err == nil && !line.ClassifiedTaxCategory.VATCalculationMethod.IsValid() {
+				err = fmt.Errorf("VAT calculation method must be 0 or 1")
+			}
+		}
+	case ColumnCatalogueItemID:
+		line.Item.CatalogueItemIdentification = newItemID(v)
+	case ColumnSellersItemID:
+		line.Item.SellersItemIdentification = newItemID(v)
+	case ColumnSecondarySellersItemID:
+		line.Item.SecondarySellersItemIdentification = newItemID(v)
+	case ColumnTertiarySellersItemID:
+		line.Item.TertiarySellersItemIdentification = newItemID(v)
+	case ColumnBuyersItemID:
+		line.Item.BuyersItemIdentification = newItemID(v)
+	case ColumnNote:
+		line.Note = v
+	}
+	return err
+}
+
+func itemID(id *schema.ItemIdentification) string {
+	if id == nil {
+		return ""
+	}
+	return id.ID
+}
+
+func newItemID(v string) *schema.ItemIdentification {
+	if v == "" {
+		return nil
+	}
+	return &schema.ItemIdentification{ID: v}
+}
+
+// parseDecimal parses spreadsheet numbers such as "1 234,50".
+func parseDecimal(v string) (types.Decimal, error) {
+	if v == "" {
+		return "", nil
+	}
+	v = strings.NewReplacer(" ", "", "\u00a0", "").Replace(v)
+	if strings.Contains(v, ",") && !strings.Contains(v, ".") {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	return types.NewDecimal(v)
+}
@@ -0,0 +1,127 @@
+package lines
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", "test001.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	invoice, err := isdoc.DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	want := invoice.InvoiceLines.InvoiceLine
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, want, Options{}); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	if header, _, _ := strings.Cut(buf.String(), "\n"); header != strings.Join(Columns, ",") {
+		t.Errorf("header = %s", header)
+	}
+
+	got, err := ReadCSV(&buf, Options{})
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(got), len(want))
+	}
+	for i := range want {
+		for _, c := range Columns {
+			if g, w := get(&got[i], c), get(&want[i], c); g != w {
+				t.Errorf("line %d %s = %q, want %q", i, c, g, w)
+			}
+		}
+	}
+}
+
+func TestReadCSVMapping(t *testing.T) {
+	input := "\ufeffKód;Název;Množství;MJ;Cena;DPH\n" +
+		"A1;Šroub M6;1 000;ks;1,50;21 %\n" +
+		";;;;;\n" +
+		"A2;Matice;10;ks;0,8;21\n"
+
+	got, err := ReadCSV(strings.NewReader(input), Options{
+		Comma: ';',
+		Mapping: map[string]string{
+			ColumnSellersItemID: "kód",
+			ColumnDescription:   "Název",
+			ColumnQuantity:      "Množství",
+			ColumnUnitCode:      "MJ",
+			ColumnUnitPrice:     "Cena",
+			ColumnVATPercent:    "DPH",
+		},
+	})
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d lines, want 2", len(got))
+	}
+
+	line := got[0]
+	if line.Item.SellersItemIdentification == nil || line.Item.SellersItemIdentification.ID != "A1" {
+		t.Errorf("SellersItemIdentification = %+v", line.Item.SellersItemIdentification)
+	}
+	for name, pair := range map[string][2]string{
+		"Description": {line.Item.Description, "Šroub M6"},
+		"Quantity":    {line.InvoicedQuantity.Value.String(), "1000"},
+		"UnitCode":    {line.InvoicedQuantity.UnitCode, "ks"},
+		"UnitPrice":   {line.UnitPrice.String(), "1.50"},
+		"Percent":     {line.ClassifiedTaxCategory.Percent.String(), "21"},
+	} {
+		if pair[0] != pair[1] {
+			t.Errorf("%s = %q, want %q", name, pair[0], pair[1])
+		}
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("description,unitPrice\nA,1\nB,abc\n"), Options{})
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+	if perr.Row != 3 || perr.Column != "unitPrice" {
+		t.Errorf("got row %d column %s, want row 3 column unitPrice", perr.Row, perr.Column)
+	}
+
+	if _, err := ReadCSV(strings.NewReader("foo,bar\n1,2\n"), Options{}); err == nil {
+		t.Error("expected error for header without known columns")
+	}
+
+	_, err = ReadCSV(strings.NewReader("id\n1\n"), Options{Mapping: map[string]string{"price": "Cena"}})
+	if !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("got %v, want ErrUnknownColumn", err)
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"12":       "12",
+		"1,5":      "1.5",
+		"1 234,50": "1234.50",
+		"-0.25":    "-0.25",
+	}
+	for in, want := range tests {
+		got, err := parseDecimal(in)
+		if err != nil || got.String() != want {
+			t.Errorf("parseDecimal(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := parseDecimal("1,234.50,1"); err == nil {
+		t.Error("expected error for malformed number")
+	}
+}
@@ -0,0 +1,239 @@
+package isdoc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// losslessFixture returns sample.isdoc with a signature, a vendor element,
+// a processing instruction and extra namespace declarations added.
+func losslessFixture(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	s := string(data)
+	s = strings.Replace(s, `version="6.0.1"`,
+		`version="6.0.1" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`, 1)
+	s = strings.Replace(s, "<UUID>",
+		`<v:Tracking xmlns:v="urn:vendor">T-42</v:Tracking><?vendor hint?><UUID>`, 1)
+	s = strings.Replace(s, "<LineID>1</LineID>",
+		`<LineID xsi:type="string">1</LineID>`, 1)
+	s = strings.Replace(s, "</Invoice>",
+		`<ds:Signature Id="sig"><ds:SignedInfo/></ds:Signature></Invoice>`, 1)
+	s += "<!-- trailing -->\n"
+	return []byte(s)
+}
+
+func TestLosslessRoundTrip(t *testing.T) {
+	data := losslessFixture(t)
+
+	invoice, err := DecodeBytesWithOptions(data, DecodeOptions{Lossless: true})
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	s := string(out)
+
+	for _, want := range []string{
+		`<!-- Formát Faktury ISDOC -->`,
+		`xmlns:ds="http://www.w3.org/2000/09/xmldsig#"`,
+		`<v:Tracking xmlns:v="urn:vendor">T-42</v:Tracking>`,
+		`<?vendor hint?>`,
+		`<LineID xsi:type="string">1</LineID>`,
+		`<ds:Signature Id="sig"><ds:SignedInfo/></ds:Signature>`,
+		"</Invoice>\n<!-- trailing -->\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+
+	// Unknown nodes keep their position relative to known siblings
+	id := strings.Index(s, "<ID>FV-111999/2011</ID>")
+	tracking := strings.Index(s, "<v:Tracking")
+	uuid := strings.Index(s, "<UUID>")
+	if !(id < tracking && tracking < uuid) {
+		t.Errorf("vendor element not between ID and UUID: ID=%d Tracking=%d UUID=%d", id, tracking, uuid)
+	}
+	if sig := strings.Index(s, "<ds:Signature"); sig < strings.Index(s, "</PaymentMeans>") {
+		t.Errorf("signature not after PaymentMeans")
+	}
+
+	// A second round trip is stable
+	again, err := DecodeBytesWithOptions(out, DecodeOptions{Lossless: true})
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+	out2, err := EncodeBytes(again)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	if !bytes.Equal(out, out2) {
+		t.Errorf("second round trip differs\nfirst:\n%s\nsecond:\n%s", out, out2)
+	}
+}
+
+func TestLosslessForeignElementWithISDOCName(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	s := strings.Replace(string(data), "<Note>Nepovinná poznámka k dokladu</Note>",
+		`<Note>Nepovinná poznámka k dokladu</Note><v:Note xmlns:v="urn:vendor">interní</v:Note>`, 1)
+
+	for _, lossless := range []bool{false, true} {
+		invoice, err := DecodeBytesWithOptions([]byte(s), DecodeOptions{Lossless: lossless})
+		if err != nil {
+			t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+		}
+		if got := invoice.Note.Value; got != "Nepovinná poznámka k dokladu" {
+			t.Errorf("lossless=%v: Note = %q, want the ISDOC note", lossless, got)
+		}
+
+		out, err := EncodeBytes(invoice)
+		if err != nil {
+			t.Fatalf("EncodeBytes failed: %v", err)
+		}
+		if strings.Contains(string(out), "<Note>interní</Note>") {
+			t.Errorf("lossless=%v: vendor note encoded as ISDOC note", lossless)
+		}
+		vendor := strings.Contains(string(out), `<v:Note xmlns:v="urn:vendor">interní</v:Note>`)
+		if vendor != lossless {
+			t.Errorf("lossless=%v: vendor note kept = %v", lossless, vendor)
+		}
+	}
+}
+
+func TestHasForeignNamespaces(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`<Invoice xmlns="http://isdoc.cz/namespace/2013"><ID>1</ID></Invoice>`, false},
+		{`<Invoice xmlns='http://isdoc.cz/namespace/2013' xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"/>`, false},
+		{`<Invoice xmlns="http://isdoc.cz/namespace/2013" xmlns:v="urn:vendor"/>`, true},
+		{`<Invoice xmlns="http://isdoc.cz/namespace/2013"><Note xmlns = 'urn:vendor'/></Invoice>`, true},
+	}
+	for _, tc := range tests {
+		if got := hasForeignNamespaces([]byte(tc.data)); got != tc.want {
+			t.Errorf("hasForeignNamespaces(%s) = %v, want %v", tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeWithoutLosslessDropsUnknown(t *testing.T) {
+	invoice, err := DecodeBytes(losslessFixture(t))
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	if !invoice.Unknown.IsZero() {
+		t.Errorf("Unknown = %+v, want empty", invoice.Unknown)
+	}
+
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	if strings.Contains(string(out), "Signature") {
+		t.Error("signature should be dropped without lossless decoding")
+	}
+}
+
+func TestDecoderSetLossless(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(losslessFixture(t)))
+	dec.SetLossless(true)
+
+	invoice, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(invoice.Unknown.Nodes) == 0 {
+		t.Error("expected unknown nodes to be captured")
+	}
+}
+
+func TestLosslessStreamMatchesEncode(t *testing.T) {
+	invoice, err := DecodeBytesWithOptions(losslessFixture(t), DecodeOptions{Lossless: true})
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+
+	want, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf)
+	if err := enc.WriteHeader(invoice); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	for i := range invoice.InvoiceLines.InvoiceLine {
+		if err := enc.WriteLine(&invoice.InvoiceLines.InvoiceLine[i]); err != nil {
+			t.Fatalf("WriteLine failed: %v", err)
+		}
+	}
+	if err := enc.WriteFooter(invoice); err != nil {
+		t.Fatalf("WriteFooter failed: %v", err)
+	}
+
+	if buf.String() != string(want) {
+		t.Errorf("stream output differs from Encode output\nstream:\n%s\nencode:\n%s", buf.String(), want)
+	}
+}
+
+func TestEncodeAttributesAndContent(t *testing.T) {
+	invoice := createValidInvoice()
+	invoice.Note = &schema.Note{Value: "Poznámka", LanguageID: "cs"}
+	invoice.Extensions = &schema.Extensions{Raw: []byte(`<x:Field xmlns:x="urn:x">1</x:Field>`)}
+	invoice.OrderReferences = &schema.OrderReferences{
+		OrderReference: []schema.OrderReference{{ID: "O1", SalesOrderID: "PO-1"}},
+	}
+	line := &invoice.InvoiceLines.InvoiceLine[0]
+	line.InvoicedQuantity = schema.Quantity{Value: types.MustDecimal("2"), UnitCode: "KGM"}
+	line.OrderReference = &schema.OrderLineReference{Ref: "O1", LineID: "1"}
+
+	out, err := EncodeBytes(invoice)
+	if err != nil {
+		t.Fatalf("EncodeBytes failed: %v", err)
+	}
+	s := string(out)
+
+	for _, want := range []string{
+		`<Note languageID="cs">Poznámka</Note>`,
+		`<Extensions><x:Field xmlns:x="urn:x">1</x:Field></Extensions>`,
+		`<OrderReference id="O1">`,
+		`<OrderReference ref="O1">`,
+		`<InvoicedQuantity unitCode="KGM">2</InvoicedQuantity>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output missing %q\n%s", want, s)
+		}
+	}
+
+	decoded, err := DecodeBytes(out)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	got := decoded.InvoiceLines.InvoiceLine[0]
+	if got.InvoicedQuantity.Value != "2" || got.InvoicedQuantity.UnitCode != "KGM" {
+		t.Errorf("InvoicedQuantity = %+v, want 2 KGM", got.InvoicedQuantity)
+	}
+	if got.OrderReference == nil || got.OrderReference.Ref != "O1" {
+		t.Errorf("OrderReference.Ref not preserved: %+v", got.OrderReference)
+	}
+}
@@ -3,5 +3,6 @@
 // ISDOC.PDF files are PDF/A-3 documents with an embedded ISDOC XML attachment.
 // This package allows:
 //   - Extracting ISDOC XML from PDF files
+//   - Extracting the CII XML of Factur-X and ZUGFeRD PDF files
 //   - Embedding ISDOC XML into existing PDF files
 package pdf
@@ -6,14 +6,34 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 )
 
-// ErrNoISDOCFound is returned when no ISDOC XML is found in the PDF.
+// ErrNoISDOCFound is returned when neither ISDOC nor CII XML is found in
+// the PDF.
 var ErrNoISDOCFound = errors.New("no ISDOC XML found in PDF")
 
+// Formats of the XML returned in ReadResult.
+const (
+	// FormatISDOC is an ISDOC Invoice or CommonDocument.
+	FormatISDOC = "isdoc"
+	// FormatCII is a UN/CEFACT CrossIndustryInvoice from a Factur-X or
+	// ZUGFeRD PDF, converted with the cii package.
+	FormatCII = "cii"
+)
+
+// ciiFileNames are the attachment names used for CII XML by Factur-X,
+// ZUGFeRD 1 and 2 and XRechnung, compared case-insensitively.
+var ciiFileNames = []string{
+	"factur-x.xml",
+	"zugferd-invoice.xml",
+	"zugferd_invoice.xml",
+	"xrechnung.xml",
+}
+
 // Attachment represents an embedded file extracted from a PDF.
 type Attachment struct {
 	Name string
@@ -22,8 +42,10 @@ type Attachment struct {
 
 // ReadResult contains the extracted ISDOC XML and any supplements.
 type ReadResult struct {
-	// XML is the extracted ISDOC XML content.
+	// XML is the extracted ISDOC or CII XML content.
 	XML []byte
+	// Format is FormatISDOC or FormatCII.
+	Format string
 	// Supplements contains any additional embedded files.
 	Supplements []Attachment
 }
@@ -47,7 +69,9 @@ func (r *Reader) ReadFile(filename string) (*ReadResult, error) {
 	return r.Read(f)
 }
 
-// Read extracts ISDOC XML from a PDF reader.
+// Read extracts ISDOC XML from a PDF reader. Factur-X and ZUGFeRD PDFs
+// are read as well, returning their CII XML with Format set to FormatCII.
+// A PDF carrying both is read as ISDOC and its CII XML is a supplement.
 func (r *Reader) Read(rs io.ReadSeeker) (*ReadResult, error) {
 	conf := model.NewDefaultConfiguration()
 	conf.ValidationMode = model.ValidationRelaxed
@@ -59,6 +83,7 @@ func (r *Reader) Read(rs io.ReadSeeker) (*ReadResult, error) {
 	}
 
 	var result ReadResult
+	var cii *Attachment
 
 	for _, att := range attachments {
 		content := att.Reader
@@ -72,10 +97,14 @@ func (r *Reader) Read(rs io.ReadSeeker) (*ReadResult, error) {
 		}
 
 		// Check if this is the ISDOC XML
-		if r.isISDOCXML(data) {
+		if result.XML == nil && r.isISDOCXML(data) {
 			result.XML = data
 			continue
 		}
+		if cii == nil && r.isCIIXML(att.FileName, data) {
+			cii = &Attachment{Name: att.FileName, Data: data}
+			continue
+		}
 
 		// Otherwise treat as a supplement
 		result.Supplements = append(result.Supplements, Attachment{
@@ -84,7 +113,16 @@ func (r *Reader) Read(rs io.ReadSeeker) (*ReadResult, error) {
 		})
 	}
 
-	if result.XML == nil {
+	switch {
+	case result.XML != nil:
+		result.Format = FormatISDOC
+		if cii != nil {
+			result.Supplements = append(result.Supplements, *cii)
+		}
+	case cii != nil:
+		result.XML = cii.Data
+		result.Format = FormatCII
+	default:
 		return nil, ErrNoISDOCFound
 	}
 
@@ -107,7 +145,23 @@ func (r *Reader) isISDOCXML(data []byte) bool {
 	return bytes.Contains(data, []byte("<Invoice"))
 }
 
+// isCIIXML checks if an attachment is the CII XML of a Factur-X or
+// ZUGFeRD PDF, by its file name or its CrossIndustryInvoice root.
+func (r *Reader) isCIIXML(name string, data []byte) bool {
+	for _, n := range ciiFileNames {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n\ufeff"), []byte("<")) {
+		return false
+	}
+	return bytes.Contains(data, []byte("CrossIndustryInvoice"))
+}
+
 // ExtractXML is a convenience function to extract ISDOC XML from a file.
+// For Factur-X and ZUGFeRD PDFs it returns the CII XML; use Reader to
+// tell the two apart.
 func ExtractXML(filename string) ([]byte, error) {
 	r := NewReader()
 	result, err := r.ReadFile(filename)
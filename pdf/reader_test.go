@@ -1,10 +1,14 @@
 package pdf
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	pdfcpuapi "github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
 )
 
 // TestReaderExtractFromEmbeddedPDF tests the full round-trip of embedding and extracting.
@@ -229,6 +233,95 @@ func TestIsISDOCXML(t *testing.T) {
 	}
 }
 
+func TestIsCIIXML(t *testing.T) {
+	reader := NewReader()
+
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		expected bool
+	}{
+		{"Factur-X name", "factur-x.xml", []byte("<x/>"), true},
+		{"ZUGFeRD name", "ZUGFeRD-invoice.xml", []byte("<x/>"), true},
+		{"CII root", "invoice.xml", []byte(`<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"/>`), true},
+		{"ISDOC", "invoice.isdoc", []byte(`<?xml version="1.0"?><Invoice xmlns="http://isdoc.cz/namespace/2013"/>`), false},
+		{"not XML", "notes.txt", []byte("CrossIndustryInvoice"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := reader.isCIIXML(tc.fileName, tc.data); got != tc.expected {
+				t.Errorf("isCIIXML() = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+// attach returns the test001.pdf fixture with the given files attached.
+func attach(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	pdfData, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", "test001.pdf"))
+	if err != nil {
+		t.Skip("PDF fixture not found")
+	}
+
+	dir := t.TempDir()
+	var paths []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var out bytes.Buffer
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	if err := pdfcpuapi.AddAttachments(bytes.NewReader(pdfData), &out, paths, false, conf); err != nil {
+		t.Fatalf("AddAttachments failed: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestReaderFacturX(t *testing.T) {
+	const ciiXML = `<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"/>`
+	const isdocXML = `<?xml version="1.0"?><Invoice xmlns="http://isdoc.cz/namespace/2013"/>`
+
+	t.Run("CII only", func(t *testing.T) {
+		data := attach(t, map[string]string{"factur-x.xml": ciiXML})
+		result, err := NewReader().Read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if result.Format != FormatCII {
+			t.Errorf("Format = %q, want %q", result.Format, FormatCII)
+		}
+		if string(result.XML) != ciiXML {
+			t.Errorf("XML = %s", result.XML)
+		}
+	})
+
+	t.Run("ISDOC preferred", func(t *testing.T) {
+		data := attach(t, map[string]string{"factur-x.xml": ciiXML, "invoice.isdoc": isdocXML})
+		result, err := NewReader().Read(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if result.Format != FormatISDOC {
+			t.Errorf("Format = %q, want %q", result.Format, FormatISDOC)
+		}
+		if string(result.XML) != isdocXML {
+			t.Errorf("XML = %s", result.XML)
+		}
+		if len(result.Supplements) != 1 || result.Supplements[0].Name != "factur-x.xml" {
+			t.Errorf("Supplements = %+v", result.Supplements)
+		}
+	})
+}
+
 // TestWriterEmbedWithFixtures tests embedding real ISDOC fixtures into PDF fixtures.
 func TestWriterEmbedWithFixtures(t *testing.T) {
 	tests := []struct {
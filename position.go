@@ -0,0 +1,163 @@
+package isdoc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Position is a location in an XML source document.
+type Position struct {
+	// Line is the 1-based line number.
+	Line int
+	// Column is the 1-based column, counted in characters.
+	Column int
+}
+
+// IsValid reports whether the position refers to a source location.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// SourceMap records where decoded elements appear in the source document.
+//
+// Pass the same SourceMap in DecodeOptions and ValidateOptions to get
+// validation errors annotated with source positions:
+//
+//	sm := isdoc.NewSourceMap()
+//	invoice, err := isdoc.DecodeBytesWithOptions(data, isdoc.DecodeOptions{SourceMap: sm})
+//	opts := isdoc.DefaultValidateOptions()
+//	opts.SourceMap = sm
+//	for _, e := range isdoc.ValidateInvoiceWithOptions(invoice, opts) {
+//	    fmt.Printf("invoice.isdoc:%d:%d: %s\n", e.Line, e.Column, e.Msg)
+//	}
+type SourceMap struct {
+	src        []byte
+	lineStarts []int
+	paths      map[string]int64
+}
+
+// NewSourceMap creates an empty SourceMap to be filled by decoding.
+func NewSourceMap() *SourceMap {
+	return &SourceMap{paths: make(map[string]int64)}
+}
+
+// reset prepares the map for recording positions in src.
+func (m *SourceMap) reset(src []byte) {
+	m.src = src
+	m.paths = make(map[string]int64)
+	m.lineStarts = []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			m.lineStarts = append(m.lineStarts, i+1)
+		}
+	}
+}
+
+// Position returns the position of the element at path, using the same
+// path syntax as ValidationError.Field (e.g. "Invoice.InvoiceLines.InvoiceLine[3].ID").
+//
+// If the element is not in the source, for example because a required
+// field is missing, the position of its nearest recorded ancestor is
+// returned. The result is false if no ancestor was recorded either.
+func (m *SourceMap) Position(path string) (Position, bool) {
+	if m == nil {
+		return Position{}, false
+	}
+	for path != "" {
+		if off, ok := m.paths[path]; ok {
+			return m.offsetPosition(int(off)), true
+		}
+		path = parentPath(path)
+	}
+	return Position{}, false
+}
+
+// Line returns the source text of the 1-based line n without its line
+// terminator, or "" if n is out of range.
+func (m *SourceMap) Line(n int) string {
+	if m == nil || n < 1 || n > len(m.lineStarts) {
+		return ""
+	}
+	start := m.lineStarts[n-1]
+	end := len(m.src)
+	if n < len(m.lineStarts) {
+		end = m.lineStarts[n] - 1
+	}
+	return strings.TrimRight(string(m.src[start:end]), "\r")
+}
+
+// Excerpt returns the source line at p followed by a caret marking the
+// column, or "" if p is not in the source.
+func (m *SourceMap) Excerpt(p Position) string {
+	line := m.Line(p.Line)
+	if line == "" || !p.IsValid() {
+		return ""
+	}
+
+	// Keep tabs in the caret line so it aligns with the source text.
+	var caret strings.Builder
+	col := 1
+	for _, r := range line {
+		if col >= p.Column {
+			break
+		}
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+		col++
+	}
+	caret.WriteRune('^')
+	return line + "\n" + caret.String()
+}
+
+// Annotate sets Line and Column of each validation error from its Field path.
+func (m *SourceMap) Annotate(errs ValidationErrors) {
+	for _, e := range errs {
+		if p, ok := m.Position(e.Field); ok {
+			e.Line, e.Column = p.Line, p.Column
+		}
+	}
+}
+
+// annotateDecodeErrors sets Line and Column of each decode error from its Path.
+func (m *SourceMap) annotateDecodeErrors(errs DecodeErrors) {
+	for _, e := range errs {
+		if p, ok := m.Position(e.Path); ok {
+			e.Line, e.Column = p.Line, p.Column
+		}
+	}
+}
+
+// offsetPosition converts a byte offset to a line and column.
+func (m *SourceMap) offsetPosition(off int) Position {
+	line := sort.Search(len(m.lineStarts), func(i int) bool {
+		return m.lineStarts[i] > off
+	})
+	start := m.lineStarts[line-1]
+	return Position{
+		Line:   line,
+		Column: utf8.RuneCount(m.src[start:off]) + 1,
+	}
+}
+
+// parentPath strips the last segment from a field path: an index, an
+// attribute or an element name.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		if i := strings.LastIndexByte(path, '['); i >= 0 {
+			return path[:i]
+		}
+	}
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package isdoc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func decodeWithSourceMap(t *testing.T, data []byte) (*SourceMap, error) {
+	t.Helper()
+	sm := NewSourceMap()
+	_, err := DecodeBytesWithOptions(data, DecodeOptions{SourceMap: sm})
+	return sm, err
+}
+
+func TestSourceMapPositions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	sm, err := decodeWithSourceMap(t, data)
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want Position
+	}{
+		{"Invoice", Position{Line: 3, Column: 1}},
+		{"Invoice.ID", Position{Line: 17, Column: 3}},
+		{"Invoice.OrderReferences.OrderReference[1]", Position{Line: 204, Column: 5}},
+		{"Invoice.InvoiceLines.InvoiceLine[0].OrderReference", Position{Line: 276, Column: 7}},
+		// Missing elements resolve to their nearest ancestor
+		{"Invoice.InvoiceLines.InvoiceLine[0].OrderReference.Missing", Position{Line: 276, Column: 7}},
+		{"Invoice.@version", Position{Line: 3, Column: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := sm.Position(tt.path)
+			if !ok {
+				t.Fatalf("Position(%q) not found", tt.path)
+			}
+			if got != tt.want {
+				t.Errorf("Position(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := sm.Position("CommonDocument.ID"); ok {
+		t.Error("Position of a path outside the document should not be found")
+	}
+}
+
+func TestSourceMapColumnsCountCharacters(t *testing.T) {
+	data := []byte("<Invoice xmlns=\"http://isdoc.cz/namespace/2013\" version=\"6.0.2\">\n" +
+		"<!-- čeština --><ID>1</ID>\n" +
+		"</Invoice>\n")
+	sm, err := decodeWithSourceMap(t, data)
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+
+	pos, _ := sm.Position("Invoice.ID")
+	if pos != (Position{Line: 2, Column: 17}) {
+		t.Errorf("Position = %v, want 2:17", pos)
+	}
+
+	want := "<!-- čeština --><ID>1</ID>\n                ^"
+	if got := sm.Excerpt(pos); got != want {
+		t.Errorf("Excerpt =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestValidationErrorsCarryPositions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	data = []byte(strings.Replace(string(data), "<ID>FV-111999/2011</ID>", "<ID></ID>", 1))
+
+	sm := NewSourceMap()
+	invoice, err := DecodeBytesWithOptions(data, DecodeOptions{SourceMap: sm})
+	if err != nil {
+		t.Fatalf("DecodeBytesWithOptions failed: %v", err)
+	}
+
+	opts := DefaultValidateOptions()
+	opts.SourceMap = sm
+	var found *ValidationError
+	for _, e := range ValidateInvoiceWithOptions(invoice, opts) {
+		if e.Field == "Invoice.ID" {
+			found = e
+		}
+	}
+	if found == nil {
+		t.Fatal("expected Invoice.ID error")
+	}
+	if found.Position() != (Position{Line: 17, Column: 3}) {
+		t.Errorf("Position = %v, want 17:3", found.Position())
+	}
+
+	// Without a SourceMap positions stay unset
+	for _, e := range ValidateInvoice(invoice) {
+		if e.Position().IsValid() {
+			t.Errorf("unexpected position on %v", e)
+		}
+	}
+}
+
+func TestDecodeErrorPositions(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "fixtures", "sample.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	t.Run("unresolved reference", func(t *testing.T) {
+		bad := []byte(strings.Replace(string(data), `ref="Obj1"`, `ref="Missing"`, 1))
+		_, err := decodeWithSourceMap(t, bad)
+
+		var errs DecodeErrors
+		if !errors.As(err, &errs) || len(errs) != 1 {
+			t.Fatalf("expected one DecodeError, got %v", err)
+		}
+		if errs[0].Position() != (Position{Line: 276, Column: 7}) {
+			t.Errorf("Position = %v, want 276:7", errs[0].Position())
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := DecodeBytes([]byte("<Invoice>\n  <ID>x</Foo>\n</Invoice>"))
+
+		var decodeErr *DecodeError
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("expected DecodeError, got %v", err)
+		}
+		if decodeErr.Line != 2 {
+			t.Errorf("Line = %d, want 2", decodeErr.Line)
+		}
+	})
+}
@@ -0,0 +1,573 @@
+package isdoc
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// Query is a compiled path expression selecting values from decoded
+// documents. Paths use the notation of ValidationError.Field, e.g.
+//
+//	Invoice.InvoiceLines.InvoiceLine[*].Item.Description
+//
+// A path starts with the root element, Invoice or CommonDocument, and names
+// fields by their Go names and attributes as @name. An element with
+// character data, such as InvoicedQuantity, is its own value. Repeated
+// elements take a selector: [n] selects by position, [*] all elements and
+// [?cond] the elements matching a filter; without a selector all elements
+// are selected.
+//
+// A filter compares a path relative to the element with a literal using
+// ==, !=, <, <=, > or >=, e.g. [?ClassifiedTaxCategory.Percent==12], and
+// holds when any selected value matches. Numbers compare by value, other
+// values as strings, so dates compare in time order. A path without a
+// comparison holds when it selects a value, and conditions combine with &&.
+// Literals may be quoted with ' or ". Filters apply to single elements too,
+// e.g. Invoice[?DocumentType==2].ID.
+//
+// The expression sum(path) adds up the selected decimals and count(path)
+// counts the selected values.
+type Query struct {
+	expr  string
+	fn    string // "", "sum" or "count"
+	root  string
+	steps []queryStep
+}
+
+// QueryResult is a value selected by a Query.
+type QueryResult struct {
+	// Field is the path to the value, e.g.
+	// "Invoice.InvoiceLines.InvoiceLine[0].Item.Description", or the
+	// expression for aggregates.
+	Field string `json:"field"`
+	// Value is the value as written in XML. Elements without character
+	// data are written as ISDOC JSON.
+	Value string `json:"value"`
+}
+
+// queryStep selects a field and filters the selected values.
+type queryStep struct {
+	field     int    // struct field index, -1 for the root
+	name      string // path segment: the field name or "@attr"
+	index     int    // selected position, -1 for all
+	filter    []queryCond
+	repeated  bool
+	valueType reflect.Type // type of the selected elements
+}
+
+// queryCond is a filter condition: path op literal, or path alone.
+type queryCond struct {
+	steps   []queryStep
+	op      string
+	literal string
+}
+
+// queryNode is a selected value and its path.
+type queryNode struct {
+	path string
+	v    reflect.Value
+}
+
+var queryRoots = map[string]reflect.Type{
+	"Invoice":        reflect.TypeOf(schema.Invoice{}),
+	"CommonDocument": reflect.TypeOf(schema.CommonDocument{}),
+}
+
+// ParseQuery compiles a path expression. Field names are checked against
+// the document types, so misspelled paths fail here rather than selecting
+// nothing.
+func ParseQuery(expr string) (*Query, error) {
+	p := &queryParser{s: expr}
+	q := &Query{expr: expr}
+
+	start := p.pos
+	name := p.name()
+	if p.peek() == '(' {
+		if name != "sum" && name != "count" {
+			return nil, p.errorAt(start, "unknown function %q", name)
+		}
+		q.fn = name
+		p.pos++
+		start = p.pos
+		name = p.name()
+	}
+
+	t, ok := queryRoots[name]
+	if !ok {
+		return nil, p.errorAt(start, "path must start with Invoice or CommonDocument")
+	}
+	q.root = name
+	root := queryStep{field: -1, name: name, index: -1, valueType: t}
+	if err := p.selectors(&root); err != nil {
+		return nil, err
+	}
+	steps, err := p.path(t, false)
+	if err != nil {
+		return nil, err
+	}
+	q.steps = append([]queryStep{root}, steps...)
+
+	if q.fn != "" {
+		if p.peek() != ')' {
+			return nil, p.errorAt(p.pos, "expected )")
+		}
+		p.pos++
+	}
+	if p.pos < len(p.s) {
+		return nil, p.errorAt(p.pos, "unexpected %q", p.s[p.pos:])
+	}
+
+	if q.fn == "sum" {
+		if t := queryValueType(q.steps[len(q.steps)-1].valueType); t != decimalType {
+			return nil, fmt.Errorf("query: cannot sum %s values", t.Name())
+		}
+	}
+	return q, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Aggregates reports whether q is a sum or count expression, evaluating
+// to a single result.
+func (q *Query) Aggregates() bool {
+	return q.fn != ""
+}
+
+// Select returns the values selected by the path of q in doc, a
+// *schema.Invoice or *schema.CommonDocument, ignoring any aggregation.
+// Documents of another root element select nothing. Empty values are
+// treated as absent.
+func (q *Query) Select(doc any) []QueryResult {
+	var v reflect.Value
+	switch d := doc.(type) {
+	case *schema.Invoice:
+		if q.root != "Invoice" || d == nil {
+			return nil
+		}
+		v = reflect.ValueOf(d).Elem()
+	case *schema.CommonDocument:
+		if q.root != "CommonDocument" || d == nil {
+			return nil
+		}
+		v = reflect.ValueOf(d).Elem()
+	default:
+		return nil
+	}
+
+	var results []QueryResult
+	for _, n := range selectQuery(q.steps, []queryNode{{path: q.root, v: v}}) {
+		if text := queryText(n.v); text != "" {
+			results = append(results, QueryResult{Field: n.path, Value: text})
+		}
+	}
+	return results
+}
+
+// Eval returns the values selected in docs or, for sum and count, a single
+// result aggregating the values of all docs.
+func (q *Query) Eval(docs ...any) ([]QueryResult, error) {
+	if q.Aggregates() {
+		a := q.Aggregator()
+		for _, doc := range docs {
+			if err := a.Add(doc); err != nil {
+				return nil, err
+			}
+		}
+		return []QueryResult{a.Result()}, nil
+	}
+
+	var results []QueryResult
+	for _, doc := range docs {
+		results = append(results, q.Select(doc)...)
+	}
+	return results, nil
+}
+
+// QueryAggregator computes a sum or count expression one document at a
+// time, so documents need not be kept until all are read.
+type QueryAggregator struct {
+	q      *Query
+	count  int
+	sum    *big.Rat
+	places int
+}
+
+// Aggregator returns an aggregator for q. Without a sum or count, the
+// aggregator counts the selected values.
+func (q *Query) Aggregator() *QueryAggregator {
+	return &QueryAggregator{q: q, sum: new(big.Rat)}
+}
+
+// Add adds the values selected in doc.
+func (a *QueryAggregator) Add(doc any) error {
+	results := a.q.Select(doc)
+	if a.q.fn == "sum" {
+		for _, r := range results {
+			x, err := ratFromDecimal(types.Decimal(r.Value), r.Field)
+			if err != nil {
+				return err
+			}
+			a.sum.Add(a.sum, x)
+			if _, frac, ok := strings.Cut(r.Value, "."); ok {
+				a.places = max(a.places, len(frac))
+			}
+		}
+	}
+	a.count += len(results)
+	return nil
+}
+
+// Result returns the aggregate of the documents added so far.
+func (a *QueryAggregator) Result() QueryResult {
+	if a.q.fn == "sum" {
+		return QueryResult{Field: a.q.expr, Value: a.sum.FloatString(a.places)}
+	}
+	return QueryResult{Field: a.q.expr, Value: strconv.Itoa(a.count)}
+}
+
+// selectQuery applies steps to nodes. The root step only applies its
+// filter.
+func selectQuery(steps []queryStep, nodes []queryNode) []queryNode {
+	for _, s := range steps {
+		var next []queryNode
+		for _, n := range nodes {
+			v, path := n.v, n.path
+			if s.field >= 0 {
+				v, path = v.Field(s.field), path+"."+s.name
+			}
+			if v.Kind() == reflect.Pointer {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+
+			if !s.repeated {
+				if s.matches(v) {
+					next = append(next, queryNode{path: path, v: v})
+				}
+				continue
+			}
+			for j := range v.Len() {
+				if s.index >= 0 && j != s.index {
+					continue
+				}
+				e := v.Index(j)
+				if e.Kind() == reflect.Pointer {
+					if e.IsNil() {
+						continue
+					}
+					e = e.Elem()
+				}
+				if s.matches(e) {
+					next = append(next, queryNode{path: fmt.Sprintf("%s[%d]", path, j), v: e})
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// matches reports whether v passes all filter conditions of s.
+func (s queryStep) matches(v reflect.Value) bool {
+	for _, c := range s.filter {
+		if !c.holds(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds reports whether any value selected from v satisfies c.
+func (c queryCond) holds(v reflect.Value) bool {
+	for _, n := range selectQuery(c.steps, []queryNode{{v: v}}) {
+		text := queryText(n.v)
+		if text == "" {
+			continue
+		}
+		if c.op == "" || compareQuery(text, c.op, c.literal) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareQuery compares a value with a literal, as numbers when both are
+// decimals.
+func compareQuery(value, op, literal string) bool {
+	var cmp int
+	x, errX := ratFromDecimal(types.Decimal(value), "")
+	y, errY := ratFromDecimal(types.Decimal(literal), "")
+	if errX == nil && errY == nil {
+		cmp = x.Cmp(y)
+	} else {
+		cmp = strings.Compare(value, literal)
+	}
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// queryText returns a selected value as written in XML, the character
+// data of elements holding it, and other elements as ISDOC JSON.
+func queryText(v reflect.Value) string {
+	t := v.Type()
+	if t.Kind() != reflect.Struct || t == dateType {
+		return leafText(v)
+	}
+	if f, ok := charDataField(t); ok {
+		return leafText(v.Field(f.index))
+	}
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, v); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// queryValueType returns the type of the values of elements of type t:
+// the type of the character data of elements holding it, t otherwise.
+func queryValueType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Struct && t != dateType {
+		if f, ok := charDataField(t); ok {
+			return t.Field(f.index).Type
+		}
+	}
+	return t
+}
+
+// charDataField returns the character data field of struct type t.
+func charDataField(t reflect.Type) (xmlField, bool) {
+	for _, f := range getTypeInfo(t).fields {
+		if f.chardata {
+			return f, true
+		}
+	}
+	return xmlField{}, false
+}
+
+// queryParser parses query expressions.
+type queryParser struct {
+	s   string
+	pos int
+}
+
+func (p *queryParser) errorAt(pos int, format string, args ...any) error {
+	return fmt.Errorf("query: %s at offset %d", fmt.Sprintf(format, args...), pos)
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// name reads an identifier.
+func (p *queryParser) name() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (p.pos == start || c < '0' || c > '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// path reads the field steps following an element of type t, each
+// starting with ".". The first step of a relative path, as in filters, has
+// no ".".
+func (p *queryParser) path(t reflect.Type, relative bool) ([]queryStep, error) {
+	var steps []queryStep
+	for {
+		if !relative || len(steps) > 0 {
+			if p.peek() != '.' {
+				return steps, nil
+			}
+			p.pos++
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, p.errorAt(p.pos, "%s has no fields", t.Name())
+		}
+		s, err := p.step(t)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+		t = s.valueType
+	}
+}
+
+// step reads a field of struct type t and its selectors.
+func (p *queryParser) step(t reflect.Type) (queryStep, error) {
+	start := p.pos
+	attr := p.peek() == '@'
+	if attr {
+		p.pos++
+	}
+	name := p.name()
+	if name == "" {
+		return queryStep{}, p.errorAt(start, "expected a field name")
+	}
+
+	s := queryStep{field: -1, index: -1}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Type == unknownType || f.Type == xmlNameType || !f.IsExported() {
+			continue
+		}
+		tag, opts, _ := strings.Cut(f.Tag.Get("xml"), ",")
+		isAttr := strings.HasPrefix(opts, "attr")
+		if attr && isAttr && tag == name || !attr && !isAttr && opts != "chardata" && f.Name == name {
+			s.field = i
+			s.name = fieldPath("", f)[1:]
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Slice && ft != bytesType {
+				s.repeated = true
+				ft = ft.Elem()
+				if ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+			}
+			s.valueType = ft
+			break
+		}
+	}
+	if s.field < 0 {
+		if attr {
+			name = "@" + name
+		}
+		return queryStep{}, p.errorAt(start, "%s has no field %s", t.Name(), name)
+	}
+	if err := p.selectors(&s); err != nil {
+		return queryStep{}, err
+	}
+	return s, nil
+}
+
+// selectors reads the [n], [*] and [?cond] selectors of s.
+func (p *queryParser) selectors(s *queryStep) error {
+	for p.peek() == '[' {
+		start := p.pos
+		p.pos++
+		switch c := p.peek(); {
+		case c == '*':
+			if !s.repeated {
+				return p.errorAt(start, "%s is not repeated", s.name)
+			}
+			p.pos++
+		case c == '?':
+			p.pos++
+			conds, err := p.filter(s.valueType)
+			if err != nil {
+				return err
+			}
+			s.filter = append(s.filter, conds...)
+		case c >= '0' && c <= '9':
+			if !s.repeated {
+				return p.errorAt(start, "%s is not repeated", s.name)
+			}
+			n := p.pos
+			for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+				p.pos++
+			}
+			s.index, _ = strconv.Atoi(p.s[n:p.pos])
+		default:
+			return p.errorAt(p.pos, "expected a position, * or ?")
+		}
+		if p.peek() != ']' {
+			return p.errorAt(p.pos, "expected ]")
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// filter reads conditions on elements of type t up to the closing bracket.
+func (p *queryParser) filter(t reflect.Type) ([]queryCond, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, p.errorAt(p.pos, "cannot filter %s values", t.Name())
+	}
+	var conds []queryCond
+	for {
+		p.skipSpace()
+		steps, err := p.path(t, true)
+		if err != nil {
+			return nil, err
+		}
+		c := queryCond{steps: steps}
+		p.skipSpace()
+		for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+			if strings.HasPrefix(p.s[p.pos:], op) {
+				c.op = op
+				p.pos += len(op)
+				break
+			}
+		}
+		if c.op != "" {
+			p.skipSpace()
+			if c.literal, err = p.literal(); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+		}
+		conds = append(conds, c)
+
+		if !strings.HasPrefix(p.s[p.pos:], "&&") {
+			return conds, nil
+		}
+		p.pos += 2
+	}
+}
+
+// literal reads a quoted or bare literal.
+func (p *queryParser) literal() (string, error) {
+	start := p.pos
+	if q := p.peek(); q == '\'' || q == '"' {
+		end := strings.IndexByte(p.s[p.pos+1:], q)
+		if end < 0 {
+			return "", p.errorAt(start, "unterminated string")
+		}
+		p.pos += end + 2
+		return p.s[start+1 : p.pos-1], nil
+	}
+	for p.pos < len(p.s) && !strings.ContainsRune("] &", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorAt(start, "expected a value")
+	}
+	return p.s[start:p.pos], nil
+}
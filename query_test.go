@@ -0,0 +1,137 @@
+package isdoc
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+func TestQuery(t *testing.T) {
+	data, err := os.ReadFile("testdata/fixtures/multi-partytax.isdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes() error = %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want []QueryResult
+	}{
+		{"Invoice.ID", []QueryResult{{"Invoice.ID", "FV-111999/2011"}}},
+		{"Invoice.InvoiceLines.InvoiceLine[1].ID", []QueryResult{{"Invoice.InvoiceLines.InvoiceLine[1].ID", "2"}}},
+		{"Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.Percent==19.0].LineExtensionAmount", []QueryResult{
+			{"Invoice.InvoiceLines.InvoiceLine[0].LineExtensionAmount", "107.93"},
+		}},
+		{"Invoice.AccountingSupplierParty.Party.PartyTaxScheme[?TaxScheme == 'VAT'].CompanyID", []QueryResult{
+			{"Invoice.AccountingSupplierParty.Party.PartyTaxScheme[0].CompanyID", "CZ25097563"},
+		}},
+		{"Invoice.InvoiceLines.InvoiceLine[0].InvoicedQuantity", []QueryResult{{"Invoice.InvoiceLines.InvoiceLine[0].InvoicedQuantity", "100"}}},
+		{"Invoice.InvoiceLines.InvoiceLine[0].InvoicedQuantity.@unitCode", []QueryResult{{"Invoice.InvoiceLines.InvoiceLine[0].InvoicedQuantity.@unitCode", "Ks"}}},
+		{"Invoice.Note", []QueryResult{{"Invoice.Note", "Nepovinná poznámka k dokladu"}}},
+		{"Invoice[?DocumentType==1 && IssueDate>=2013-01-01].ID", []QueryResult{{"Invoice.ID", "FV-111999/2011"}}},
+		{"Invoice[?DocumentType!=1].ID", nil},
+		{"Invoice.InvoiceLines.InvoiceLine[5].ID", nil},
+		{"CommonDocument.ID", nil},
+		{"count(Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.Percent<10])", []QueryResult{
+			{"count(Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.Percent<10])", "2"},
+		}},
+		{"Invoice.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod", []QueryResult{
+			{"Invoice.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod", "0"},
+		}},
+		{"count(Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.VATCalculationMethod==0])", []QueryResult{
+			{"count(Invoice.InvoiceLines.InvoiceLine[?ClassifiedTaxCategory.VATCalculationMethod==0])", "3"},
+		}},
+		{"sum(Invoice.TaxTotal.TaxSubTotal.TaxAmount)", []QueryResult{{"sum(Invoice.TaxTotal.TaxSubTotal.TaxAmount)", "45.18"}}},
+	}
+	for _, tc := range tests {
+		q, err := ParseQuery(tc.expr)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error = %v", tc.expr, err)
+			continue
+		}
+		got, err := q.Eval(inv)
+		if err != nil {
+			t.Errorf("Eval(%q) error = %v", tc.expr, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("Eval(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+
+	q, _ := ParseQuery("Invoice.InvoiceLines.InvoiceLine[*].Item.Description")
+	if got := q.Select(inv); len(got) != 3 || !strings.HasPrefix(got[2].Field, "Invoice.InvoiceLines.InvoiceLine[2].") {
+		t.Errorf("Select() = %v", got)
+	}
+	q, _ = ParseQuery("Invoice.AccountingSupplierParty.Party.PostalAddress.Country")
+	if got := q.Select(inv); len(got) != 1 || got[0].Value != `{"identificationCode":"CZ","name":"Česká republika"}` {
+		t.Errorf("Select() of an element = %v", got)
+	}
+}
+
+func TestQueryAggregatesDocuments(t *testing.T) {
+	a := createValidInvoice()
+	b := createValidInvoice()
+	b.LegalMonetaryTotal.PayableAmount = "10.5"
+	doc := validCommonDocument()
+
+	q, err := ParseQuery("sum(Invoice.LegalMonetaryTotal.PayableAmount)")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	got, err := q.Eval(a, b, doc)
+	if err != nil || len(got) != 1 || got[0].Value != "1220.50" {
+		t.Errorf("Eval() = %v, %v; want 1220.50", got, err)
+	}
+	agg := q.Aggregator()
+	for _, d := range []any{a, b, doc} {
+		if err := agg.Add(d); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if got := agg.Result(); got != (QueryResult{Field: q.String(), Value: "1220.50"}) {
+		t.Errorf("Result() = %v, want 1220.50", got)
+	}
+
+	b.LegalMonetaryTotal.PayableAmount = "n/a"
+	if _, err := q.Eval(a, b); err == nil {
+		t.Error("Eval() summed an invalid decimal")
+	}
+
+	q, _ = ParseQuery("count(CommonDocument)")
+	if got, _ := q.Eval(a, doc, &schema.CommonDocument{}); got[0].Value != "2" {
+		t.Errorf("count(CommonDocument) = %v", got)
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "path must start with Invoice or CommonDocument"},
+		{"Order.ID", "path must start with Invoice or CommonDocument"},
+		{"Invoice.Foo", "Invoice has no field Foo"},
+		{"Invoice.Note.@lang", "Note has no field @lang"},
+		{"Invoice.ID[0]", "ID is not repeated"},
+		{"Invoice.ID.Value", "string has no fields"},
+		{"Invoice.InvoiceLines.InvoiceLine[?ID=='1'", "expected ]"},
+		{"Invoice.InvoiceLines.InvoiceLine[?ID=='1]", "unterminated string"},
+		{"Invoice.InvoiceLines.InvoiceLine[x]", "expected a position, * or ?"},
+		{"max(Invoice.ID)", `unknown function "max"`},
+		{"sum(Invoice.ID)", "cannot sum string values"},
+		{"count(Invoice.ID", "expected )"},
+		{"Invoice.ID extra", "unexpected"},
+	}
+	for _, tc := range tests {
+		_, err := ParseQuery(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ParseQuery(%q) error = %v, want %q", tc.expr, err, tc.want)
+		}
+	}
+}
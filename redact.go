@@ -0,0 +1,264 @@
+package isdoc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+// Redact replaces the personal and business data of doc, a *schema.Invoice
+// or *schema.CommonDocument, so that it can be shared in bug reports and
+// test fixtures: party names, addresses, identifiers, contacts, register
+// entries, bank accounts, data box message IDs, notes and item
+// descriptions.
+//
+// Replacements are derived from the original values with HMAC-SHA256 keyed
+// by key: documents redacted with the same key replace the same value the
+// same way, so a party keeps one identity across a batch. Without a secret
+// key short values such as an IČO can be recovered by trying every
+// candidate.
+//
+// Amounts, dates, codes and the structure are kept, so validation reports
+// the same issues: an IČO, a Czech DIČ and an IBAN are replaced by ones
+// whose check digits are valid exactly when the original's are, and other
+// identifiers digit by digit and letter by letter. Extensions and content kept by lossless
+// decoding are not redacted.
+func Redact(doc any, key []byte) error {
+	r := redactor{key: key}
+	switch d := doc.(type) {
+	case *schema.Invoice:
+		r.walk(reflect.ValueOf(d).Elem())
+		d.ClientOnTargetConsolidator = r.mask("client", d.ClientOnTargetConsolidator)
+		d.ClientBankAccount = r.mask("account", d.ClientBankAccount)
+		d.ISDS_ID = r.mask("isds", d.ISDS_ID)
+	case *schema.CommonDocument:
+		r.walk(reflect.ValueOf(d).Elem())
+		d.ClientOnTargetConsolidator = r.mask("client", d.ClientOnTargetConsolidator)
+		d.ClientBankAccount = r.mask("account", d.ClientBankAccount)
+	default:
+		return fmt.Errorf("cannot redact %T", doc)
+	}
+	return nil
+}
+
+// redactor derives replacement values from a key.
+type redactor struct {
+	key []byte
+}
+
+// walk redacts the elements holding personal data within v.
+func (r redactor) walk(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			r.walk(v.Elem())
+		}
+	case reflect.Slice:
+		if v.Type() != bytesType {
+			for i := range v.Len() {
+				r.walk(v.Index(i))
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == unknownType || v.Type() == dateType {
+			return
+		}
+		for i := range v.NumField() {
+			r.walk(v.Field(i))
+		}
+		r.redact(v.Addr().Interface())
+	}
+}
+
+// redact replaces the personal data of a single element.
+func (r redactor) redact(p any) {
+	switch e := p.(type) {
+	case *schema.Party:
+		r.party(e)
+	case *schema.AnonymousCustomerParty:
+		e.ID = r.mask("anonymous", e.ID)
+	case *schema.BankAccount:
+		e.ID = r.mask("account", e.ID)
+		e.Name = r.text("Account", e.Name)
+		e.IBAN = r.iban(e.IBAN)
+	case *schema.Note:
+		e.Value = r.text("Note", e.Value)
+	case *schema.InvoiceLine:
+		e.Note = r.text("Note", e.Note)
+		e.VATNote = r.text("Note", e.VATNote)
+	case *schema.OrderReference:
+		e.ISDS_ID = r.mask("isds", e.ISDS_ID)
+	case *schema.ContractReference:
+		e.ISDS_ID = r.mask("isds", e.ISDS_ID)
+	case *schema.Item:
+		e.Description = r.text("Item", e.Description)
+	case *schema.StoreBatch:
+		e.Note = r.text("Note", e.Note)
+	}
+}
+
+func (r redactor) party(p *schema.Party) {
+	id := &p.PartyIdentification
+	id.UserID = r.mask("user", id.UserID)
+	id.CatalogFirmIdentification = r.mask("catalog", id.CatalogFirmIdentification)
+	id.ID = r.ico(id.ID)
+
+	p.PartyName.Name = r.text("Party", p.PartyName.Name)
+
+	a := &p.PostalAddress
+	a.StreetName = r.text("Street", a.StreetName)
+	a.BuildingNumber = r.mask("building", a.BuildingNumber)
+	a.CityName = r.text("City", a.CityName)
+	a.PostalZone = r.mask("postal", a.PostalZone)
+
+	for i := range p.PartyTaxScheme {
+		p.PartyTaxScheme[i].CompanyID = r.dic(p.PartyTaxScheme[i].CompanyID)
+	}
+
+	if reg := p.RegisterIdentification; reg != nil {
+		reg.Preformatted = r.text("Register", reg.Preformatted)
+		reg.RegisterFileRef = r.mask("register", reg.RegisterFileRef)
+	}
+
+	if c := p.Contact; c != nil {
+		c.Name = r.text("Contact", c.Name)
+		c.Telephone = r.mask("telephone", c.Telephone)
+		if c.ElectronicMail != "" {
+			c.ElectronicMail = "contact-" + r.token("mail", c.ElectronicMail) + "@example.com"
+		}
+	}
+}
+
+// sum returns n bytes derived from the kind and the value.
+func (r redactor) sum(kind, value string, n int) []byte {
+	var out []byte
+	for block := uint32(0); len(out) < n; block++ {
+		mac := hmac.New(sha256.New, r.key)
+		mac.Write([]byte(kind))
+		mac.Write([]byte{0})
+		mac.Write([]byte(value))
+		mac.Write(binary.BigEndian.AppendUint32(nil, block))
+		out = mac.Sum(out)
+	}
+	return out[:n]
+}
+
+// token returns six hexadecimal digits identifying the value.
+func (r redactor) token(kind, value string) string {
+	return hex.EncodeToString(r.sum(kind, value, 3))
+}
+
+// text replaces free text by a label and a token, e.g. "Party 1a2b3c".
+func (r redactor) text(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + " " + r.token(label, value)
+}
+
+// mask replaces each digit and letter of the value by another of the same
+// class and case, keeping the punctuation and length.
+func (r redactor) mask(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := r.sum(kind, value, len(value))
+	b := []byte(value)
+	for i, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+			b[i] = '0' + sum[i]%10
+		case c >= 'A' && c <= 'Z':
+			b[i] = 'A' + sum[i]%26
+		case c >= 'a' && c <= 'z':
+			b[i] = 'a' + sum[i]%26
+		}
+	}
+	return string(b)
+}
+
+// ico replaces an IČO by another whose check digit is valid if the
+// original's is, and any other identifier by mask.
+func (r redactor) ico(id string) string {
+	if len(id) != 8 || !allDigits(id) {
+		return r.mask("id", id)
+	}
+	digits := r.mask("ico", id)
+	check := icoCheckDigit(digits)
+	if !validICO(id) {
+		check = (check + 1) % 10
+	}
+	return digits[:7] + string('0'+check)
+}
+
+// dic replaces a Czech DIČ by CZ and the replacement of its IČO or a
+// birth number of the same length, valid if the original is, and other VAT
+// numbers by their country prefix and a mask.
+func (r redactor) dic(dic string) string {
+	prefix, digits := dic[:min(2, len(dic))], dic[min(2, len(dic)):]
+	if prefix != "CZ" || !allDigits(digits) {
+		if len(prefix) == 2 && prefix[0] >= 'A' && prefix[0] <= 'Z' && prefix[1] >= 'A' && prefix[1] <= 'Z' {
+			return prefix + r.mask("vat", digits)
+		}
+		return r.mask("vat", dic)
+	}
+	switch len(digits) {
+	case 8:
+		return prefix + r.ico(digits)
+	case 10:
+		n, _ := strconv.ParseUint(r.mask("dic", digits), 10, 64)
+		if validDIC(dic) {
+			n -= n % 11
+			return fmt.Sprintf("%s%010d", prefix, n)
+		}
+		for validDIC(fmt.Sprintf("%s%010d", prefix, n)) {
+			n = (n + 1) % 1e10
+		}
+		return fmt.Sprintf("%s%010d", prefix, n)
+	default:
+		return prefix + r.mask("dic", digits)
+	}
+}
+
+// iban replaces the account part of an IBAN, keeping the country and the
+// first four characters of the BBAN, usually the bank code, and computes
+// check digits for the result, wrong ones if the original's are wrong.
+func (r redactor) iban(iban string) string {
+	s := strings.ReplaceAll(iban, " ", "")
+	if len(s) < 9 {
+		return r.mask("iban", iban)
+	}
+	bban := s[4:8] + r.mask("iban", s[8:])
+	check := ibanCheckDigits(s[:2], bban)
+	if ibanCheckDigits(s[:2], s[4:]) != s[2:4] {
+		n, _ := strconv.Atoi(check)
+		check = fmt.Sprintf("%02d", (n-1)%97+2)
+	}
+	return s[:2] + check + bban
+}
+
+// ibanCheckDigits returns the ISO 7064 MOD 97-10 check digits of an IBAN.
+func ibanCheckDigits(country, bban string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(bban + country + "00") {
+		if c >= 'A' && c <= 'Z' {
+			b.WriteString(strconv.Itoa(int(c-'A') + 10))
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	if !ok {
+		return "00"
+	}
+	check := 98 - new(big.Int).Mod(n, big.NewInt(97)).Int64()
+	return fmt.Sprintf("%02d", check)
+}
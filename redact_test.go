@@ -0,0 +1,173 @@
+package isdoc
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+// issueKeys returns the fields and codes of the validation issues of inv.
+func issueKeys(inv *schema.Invoice) []string {
+	var keys []string
+	for _, e := range ValidateInvoice(inv) {
+		keys = append(keys, e.Field+" "+e.Code)
+	}
+	return keys
+}
+
+func TestRedact(t *testing.T) {
+	data, err := os.ReadFile("testdata/fixtures/multi-partytax.isdoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, err := DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes() error = %v", err)
+	}
+	original.ISDS_ID = "a1b2c3d"
+	original.OrderReferences.OrderReference[0].ISDS_ID = "e4f5g6h"
+	original.InvoiceLines.InvoiceLine[0].VATNote = "Přenesená daňová povinnost"
+	inv := clone(original)
+	if err := Redact(inv, []byte("secret")); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+
+	out, err := EncodeBytes(inv)
+	if err != nil {
+		t.Fatalf("EncodeBytes() error = %v", err)
+	}
+	for _, s := range []string{"ABRA", "Jeremiášova", "Praha 13", "25097563", "4475", "Josef Novák", "123456789",
+		"novak@abra.eu", "Nepovinná poznámka", "NAPPAX NP458", "Expresní zásilka", "CZ12345678901234567890",
+		"a1b2c3d", "e4f5g6h", "Přenesená daňová"} {
+		if bytes.Contains(out, []byte(s)) {
+			t.Errorf("redacted document contains %q", s)
+		}
+	}
+
+	if got, want := issueKeys(inv), issueKeys(original); !slices.Equal(got, want) {
+		t.Errorf("validation issues changed:\n got %v\nwant %v", got, want)
+	}
+	for _, c := range Diff(original, inv) {
+		if strings.Contains(c.Field, "Amount") || strings.Contains(c.Field, "Price") || strings.Contains(c.Field, "Date") {
+			t.Errorf("Redact() changed %s", c)
+		}
+	}
+
+	supplier := inv.AccountingSupplierParty.Party
+	ico := supplier.PartyIdentification.ID
+	if !validICO(ico) {
+		t.Errorf("IČO %q has an invalid check digit", ico)
+	}
+	if got := supplier.PartyTaxScheme[0].CompanyID; got != "CZ"+ico {
+		t.Errorf("DIČ = %q, want CZ%s", got, ico)
+	}
+	if got := supplier.PartyTaxScheme[1].CompanyID; !strings.HasPrefix(got, "SK") || len(got) != 10 {
+		t.Errorf("foreign VAT number = %q", got)
+	}
+	if seller := inv.SellerSupplierParty.Party; seller.PartyIdentification.ID != ico || seller.PartyName.Name != supplier.PartyName.Name {
+		t.Errorf("the supplier is redacted differently as seller: %+v", seller)
+	}
+	if got := supplier.PostalAddress.Country.IdentificationCode; got != "CZ" {
+		t.Errorf("Country = %q, want CZ", got)
+	}
+
+	// The same key redacts every document the same way, another key not
+	again := clone(original)
+	Redact(again, []byte("secret"))
+	if changes := Diff(inv, again); len(changes) > 0 {
+		t.Errorf("Redact() is not deterministic: %v", changes)
+	}
+	other := clone(original)
+	Redact(other, []byte("other"))
+	if other.AccountingSupplierParty.Party.PartyName.Name == supplier.PartyName.Name {
+		t.Error("Redact() ignores the key")
+	}
+}
+
+func TestRedactIdentifiers(t *testing.T) {
+	r := redactor{key: []byte("secret")}
+
+	for _, dic := range []string{"CZ25097563", "CZ7103192745", "CZ530101123"} {
+		got := r.dic(dic)
+		if !validDIC(got) || len(got) != len(dic) || got == dic {
+			t.Errorf("dic(%q) = %q", dic, got)
+		}
+	}
+	if got := r.ico("X-123"); len(got) != 5 || got[1] != '-' {
+		t.Errorf("ico(X-123) = %q", got)
+	}
+
+	iban := r.iban("CZ65 0800 0000 1920 0014 5399")
+	if !strings.HasPrefix(iban, "CZ") || iban[4:8] != "0800" || len(iban) != 24 {
+		t.Errorf("iban() = %q", iban)
+	}
+	if !checkIBAN(iban) {
+		t.Errorf("iban() = %q has invalid check digits", iban)
+	}
+}
+
+// checkIBAN reports whether the IBAN moved to the end leaves 1 modulo 97.
+func checkIBAN(iban string) bool {
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			digits.WriteString(big.NewInt(int64(c - 'A' + 10)).String())
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	return n.Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func TestRedactInvalidIdentifiers(t *testing.T) {
+	r := redactor{key: []byte("secret")}
+
+	if got := r.ico("12345678"); validICO("12345678") || validICO(got) || len(got) != 8 {
+		t.Errorf("ico(12345678) = %q, want an invalid IČO", got)
+	}
+	for _, dic := range []string{"CZ12345678", "CZ7103192746"} {
+		if got := r.dic(dic); validDIC(dic) || validDIC(got) || len(got) != len(dic) {
+			t.Errorf("dic(%q) = %q, want an invalid DIČ", dic, got)
+		}
+	}
+	if got := r.iban("CZ66 0800 0000 1920 0014 5399"); checkIBAN(got) || len(got) != 24 {
+		t.Errorf("iban() = %q, want invalid check digits", got)
+	}
+
+	// Across many values the validity of the original is kept
+	for i := range 200 {
+		ico := fmt.Sprintf("%08d", 10000000+i*7919)
+		if got := r.ico(ico); validICO(got) != validICO(ico) {
+			t.Errorf("ico(%s) = %s changes validity", ico, got)
+		}
+		dic := fmt.Sprintf("CZ%010d", 7103190000+i*37)
+		if got := r.dic(dic); validDIC(got) != validDIC(dic) {
+			t.Errorf("dic(%s) = %s changes validity", dic, got)
+		}
+	}
+}
+
+func TestRedactCommonDocument(t *testing.T) {
+	doc := validCommonDocument()
+	doc.Note = &schema.Note{Value: "Smlouva o dílo", LanguageID: "cs"}
+	if err := Redact(doc, nil); err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if doc.Note.Value == "Smlouva o dílo" || doc.Note.LanguageID != "cs" {
+		t.Errorf("Note = %+v", doc.Note)
+	}
+	if errs := ValidateCommonDocumentWithOptions(doc, ValidateOptions{Strict: true}); len(errs) > 0 {
+		t.Errorf("redacted document is invalid: %v", errs)
+	}
+
+	if err := Redact(schema.Invoice{}, nil); err == nil {
+		t.Error("Redact() accepted an Invoice value")
+	}
+}
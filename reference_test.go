@@ -292,7 +292,7 @@ func TestJSONExport(t *testing.T) {
 // TestDocumentTypes verifies all valid document types are accepted.
 // Based on TypeScript tests - DocumentType validation
 func TestDocumentTypes(t *testing.T) {
-	validTypes := []int{1, 2, 3, 4, 5, 6, 7}
+	validTypes := []types.DocumentType{1, 2, 3, 4, 5, 6, 7}
 
 	for _, docType := range validTypes {
 		t.Run(string(rune('0'+docType)), func(t *testing.T) {
@@ -303,7 +303,7 @@ func TestDocumentTypes(t *testing.T) {
 			}
 
 			// Validate document type
-			if invoice.DocumentType < 1 || invoice.DocumentType > 7 {
+			if !invoice.DocumentType.IsValid() {
 				t.Errorf("Invalid document type: %d", invoice.DocumentType)
 			}
 		})
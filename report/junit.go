@@ -0,0 +1,88 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Cases     []junitCase `xml:"testcase"`
+	SystemOut string      `xml:"system-out,omitempty"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML with one test suite per document.
+// Each error is a failed test case; a document without errors has a single
+// passing "validate" case. Warnings are listed in the suite's system-out.
+func WriteJUnit(w io.Writer, r *Report) error {
+	out := junitSuites{Name: "isdoc validate"}
+	for _, f := range r.Files {
+		suite := junitSuite{Name: f.Path}
+		var warnings []string
+		for _, issue := range f.Issues {
+			if issue.Severity != SeverityError {
+				warnings = append(warnings, issue.Text(f.Path))
+				continue
+			}
+			name := issue.Field
+			if name == "" {
+				name = issue.Code
+			}
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      name,
+				ClassName: f.Path,
+				Failure: &junitFailure{
+					Message: issue.Message,
+					Type:    issue.Code,
+					Text:    issue.Text(f.Path),
+				},
+			})
+			suite.Failures++
+		}
+		if len(suite.Cases) == 0 {
+			suite.Cases = []junitCase{{Name: "validate", ClassName: f.Path}}
+		}
+		if len(warnings) > 0 {
+			suite.SystemOut = strings.Join(warnings, "\n")
+		}
+		suite.Tests = len(suite.Cases)
+
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Suites = append(out.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
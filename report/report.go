@@ -0,0 +1,279 @@
+// Package report renders ISDOC validation results in machine-readable formats.
+//
+// A Report collects the issues of one or more validated documents. It can be
+// written as JSON, SARIF 2.1.0 (for code scanning tools) or JUnit XML (for CI
+// test dashboards):
+//
+//	var r report.Report
+//	r.Add(report.FromValidation("invoice.isdoc", errs))
+//	err := report.Write(os.Stdout, &r, report.FormatSARIF)
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/xseman/isdoc"
+)
+
+// Format is an output format for reports.
+type Format string
+
+// Supported report formats.
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatSARIF Format = "sarif"
+	FormatJUnit Format = "junit"
+)
+
+// ErrUnknownFormat is returned by ParseFormat for unsupported formats.
+var ErrUnknownFormat = errors.New("unknown report format")
+
+// ParseFormat parses a format name such as "json".
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatSARIF, FormatJUnit:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q (want text, json, sarif or junit)", ErrUnknownFormat, s)
+}
+
+// Severity names used in reports.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a single validation finding.
+type Issue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+	Rule     string `json:"rule,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// File is the validation result of a single document.
+type File struct {
+	Path   string  `json:"path,omitempty"`
+	Valid  bool    `json:"valid"`
+	Issues []Issue `json:"issues"`
+}
+
+// Errors returns the number of error-severity issues.
+func (f *File) Errors() int {
+	n := 0
+	for _, issue := range f.Issues {
+		if issue.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// Warnings returns the number of warning-severity issues.
+func (f *File) Warnings() int {
+	return len(f.Issues) - f.Errors()
+}
+
+// FromValidation builds the report of a document from its validation errors.
+func FromValidation(path string, errs isdoc.ValidationErrors) File {
+	f := File{Path: path, Valid: !errs.HasErrors(), Issues: make([]Issue, 0, len(errs))}
+	for _, e := range errs {
+		f.Issues = append(f.Issues, Issue{
+			Code:     e.Code,
+			Severity: severityName(e.Severity),
+			Field:    e.Field,
+			Message:  e.Msg,
+			Rule:     e.Rule,
+			Line:     e.Line,
+			Column:   e.Column,
+		})
+	}
+	return f
+}
+
+// FromError builds the report of a document that could not be validated,
+// e.g. because it failed to parse. Each DecodeError becomes an issue.
+func FromError(path string, err error) File {
+	f := File{Path: path, Valid: false}
+
+	var decodeErrs isdoc.DecodeErrors
+	var decodeErr *isdoc.DecodeError
+	switch {
+	case errors.As(err, &decodeErrs):
+		for _, e := range decodeErrs {
+			f.Issues = append(f.Issues, decodeIssue(e))
+		}
+	case errors.As(err, &decodeErr):
+		f.Issues = append(f.Issues, decodeIssue(decodeErr))
+	default:
+		f.Issues = append(f.Issues, Issue{
+			Code:     isdoc.ErrCodeInvalidXML,
+			Severity: SeverityError,
+			Message:  err.Error(),
+		})
+	}
+	return f
+}
+
+func decodeIssue(e *isdoc.DecodeError) Issue {
+	code := e.Code
+	if code == "" {
+		code = isdoc.ErrCodeInvalidXML
+	}
+	return Issue{
+		Code:     code,
+		Severity: SeverityError,
+		Field:    e.Path,
+		Message:  e.Err.Error(),
+		Line:     e.Line,
+		Column:   e.Column,
+	}
+}
+
+func severityName(s isdoc.Severity) string {
+	if s == isdoc.SeverityError {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// Report is the validation result of one or more documents.
+type Report struct {
+	Files []File `json:"files"`
+}
+
+// Add appends the result of a document.
+func (r *Report) Add(f File) {
+	r.Files = append(r.Files, f)
+}
+
+// Summary aggregates the results of all documents.
+type Summary struct {
+	Files    int `json:"files"`
+	Passed   int `json:"passed"`
+	Warnings int `json:"warnings"`
+	Failed   int `json:"failed"`
+
+	// ByCode counts issues per error code.
+	ByCode map[string]int `json:"byCode"`
+}
+
+// Codes returns the error codes in ByCode, most frequent first.
+func (s Summary) Codes() []string {
+	codes := make([]string, 0, len(s.ByCode))
+	for code := range s.ByCode {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if s.ByCode[codes[i]] != s.ByCode[codes[j]] {
+			return s.ByCode[codes[i]] > s.ByCode[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// Summary counts passed documents, documents passing with warnings and
+// failed documents, and groups issues by code.
+func (r *Report) Summary() Summary {
+	s := Summary{Files: len(r.Files), ByCode: make(map[string]int)}
+	for i := range r.Files {
+		f := &r.Files[i]
+		switch {
+		case !f.Valid:
+			s.Failed++
+		case len(f.Issues) > 0:
+			s.Warnings++
+		default:
+			s.Passed++
+		}
+		for _, issue := range f.Issues {
+			s.ByCode[issue.Code]++
+		}
+	}
+	return s
+}
+
+// Failed reports whether any document failed validation.
+func (r *Report) Failed() bool {
+	for i := range r.Files {
+		if !r.Files[i].Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders the report in the given format. FormatText writes one line
+// per issue in the "file:line:col: [SEVERITY] field: message" form.
+func Write(w io.Writer, r *Report, format Format) error {
+	switch format {
+	case FormatText:
+		return WriteText(w, r)
+	case FormatJSON:
+		return WriteJSON(w, r)
+	case FormatSARIF:
+		return WriteSARIF(w, r)
+	case FormatJUnit:
+		return WriteJUnit(w, r)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// WriteJSON writes the report and its summary as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	out := struct {
+		Files   []File  `json:"files"`
+		Summary Summary `json:"summary"`
+	}{Files: r.Files, Summary: r.Summary()}
+	if out.Files == nil {
+		out.Files = []File{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// WriteText writes one line per issue.
+func WriteText(w io.Writer, r *Report) error {
+	for _, f := range r.Files {
+		for _, issue := range f.Issues {
+			if _, err := fmt.Fprintln(w, issue.Text(f.Path)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Text formats the issue as "file:line:col: [SEVERITY] field: message",
+// omitting the parts that are unknown.
+func (i Issue) Text(path string) string {
+	var prefix string
+	switch {
+	case path != "" && i.Column > 0:
+		prefix = fmt.Sprintf("%s:%d:%d: ", path, i.Line, i.Column)
+	case path != "" && i.Line > 0:
+		prefix = fmt.Sprintf("%s:%d: ", path, i.Line)
+	case path != "":
+		prefix = path + ": "
+	}
+
+	severity := "WARNING"
+	if i.Severity == SeverityError {
+		severity = "ERROR"
+	}
+	if i.Field == "" {
+		return fmt.Sprintf("%s[%s] %s", prefix, severity, i.Message)
+	}
+	return fmt.Sprintf("%s[%s] %s: %s", prefix, severity, i.Field, i.Message)
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc"
+)
+
+func sampleReport() *Report {
+	var r Report
+	r.Add(FromValidation("ok.isdoc", nil))
+	r.Add(FromValidation("warn.isdoc", isdoc.ValidationErrors{
+		{Field: "Invoice.Note", Code: isdoc.ErrCodeSchemaViolation, Severity: isdoc.SeverityWarning, Msg: "note too long"},
+	}))
+	r.Add(FromValidation("bad.isdoc", isdoc.ValidationErrors{
+		{Field: "Invoice.ID", Code: isdoc.ErrCodeRequiredField, Severity: isdoc.SeverityError, Msg: "ID is required", Line: 17, Column: 3},
+		{Field: "Invoice.LegalMonetaryTotal.TaxInclusiveAmount", Code: isdoc.ErrCodeSchemaViolation, Severity: isdoc.SeverityError,
+			Msg: "TaxInclusiveAmount must equal TaxExclusiveAmount plus VAT", Rule: "BR-CO-15"},
+	}))
+	return &r
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "json", "sarif", "junit"} {
+		if f, err := ParseFormat(name); err != nil || string(f) != name {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, f, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat(xml): got %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestFromError(t *testing.T) {
+	err := isdoc.DecodeErrors{
+		{Path: "Invoice.InvoiceLines.InvoiceLine[0].OrderReference", Err: errors.New("ref not found"),
+			Code: isdoc.ErrCodeReferenceNotFound, Line: 276, Column: 7},
+	}
+	f := FromError("x.isdoc", fmt.Errorf("decoding: %w", err))
+	if f.Valid || len(f.Issues) != 1 {
+		t.Fatalf("FromError = %+v", f)
+	}
+	if got := f.Issues[0]; got.Code != isdoc.ErrCodeReferenceNotFound || got.Line != 276 || got.Severity != SeverityError {
+		t.Errorf("issue = %+v", got)
+	}
+
+	f = FromError("x.isdoc", errors.New("boom"))
+	if len(f.Issues) != 1 || f.Issues[0].Code != isdoc.ErrCodeInvalidXML {
+		t.Errorf("FromError(plain) = %+v", f)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	r := sampleReport()
+	s := r.Summary()
+	if s.Files != 3 || s.Passed != 1 || s.Warnings != 1 || s.Failed != 1 {
+		t.Errorf("Summary = %+v", s)
+	}
+	if got := s.Codes(); len(got) != 2 || got[0] != isdoc.ErrCodeSchemaViolation {
+		t.Errorf("Codes = %v, want SCHEMA_VIOLATION first", got)
+	}
+	if !r.Failed() {
+		t.Error("Failed = false, want true")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sampleReport(), FormatText); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "bad.isdoc:17:3: [ERROR] Invoice.ID: ID is required\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sampleReport(), FormatJSON); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var out struct {
+		Files   []File  `json:"files"`
+		Summary Summary `json:"summary"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(out.Files) != 3 || out.Summary.Failed != 1 {
+		t.Errorf("decoded = %+v", out)
+	}
+	if issue := out.Files[2].Issues[1]; issue.Rule != "BR-CO-15" {
+		t.Errorf("Rule = %q, want BR-CO-15", issue.Rule)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sampleReport(), FormatSARIF); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("log = %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+
+	first := results[1]
+	if first.RuleID != isdoc.ErrCodeRequiredField || first.Level != "error" {
+		t.Errorf("result = %+v", first)
+	}
+	region := first.Locations[0].PhysicalLocation.Region
+	if region == nil || region.StartLine != 17 || region.StartColumn != 3 {
+		t.Errorf("region = %+v", region)
+	}
+	if results[2].RuleID != "BR-CO-15" {
+		t.Errorf("RuleID = %q, want BR-CO-15", results[2].RuleID)
+	}
+	if len(log.Runs[0].Tool.Driver.Rules) != 3 {
+		t.Errorf("rules = %+v", log.Runs[0].Tool.Driver.Rules)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, sampleReport(), FormatJUnit); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var suites junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if suites.Tests != 4 || suites.Failures != 2 || len(suites.Suites) != 3 {
+		t.Errorf("testsuites tests=%d failures=%d suites=%d", suites.Tests, suites.Failures, len(suites.Suites))
+	}
+	if c := suites.Suites[0].Cases; len(c) != 1 || c[0].Failure != nil {
+		t.Errorf("passing document cases = %+v", c)
+	}
+	if !strings.Contains(suites.Suites[1].SystemOut, "note too long") {
+		t.Errorf("warning not in system-out: %q", suites.Suites[1].SystemOut)
+	}
+}
@@ -2,11 +2,18 @@ package isdoc
 
 import (
 	"encoding/xml"
+	"slices"
 	"testing"
 
+	"github.com/xseman/isdoc/schema"
 	"github.com/xseman/isdoc/types"
 )
 
+// hasIssue reports whether errs contains an issue with the field and code.
+func hasIssue(errs ValidationErrors, field, code string) bool {
+	return slices.ContainsFunc(errs, func(e *ValidationError) bool { return e.Field == field && e.Code == code })
+}
+
 // TestDecimalPatternValidation tests various decimal formats.
 // Based on PHP RestrictionTest.testDecimal
 func TestDecimalPatternValidation(t *testing.T) {
@@ -254,12 +261,12 @@ func TestCountryCodeValidation(t *testing.T) {
 
 // TestVATCalculationMethodValidation tests valid VAT calculation methods.
 func TestVATCalculationMethodValidation(t *testing.T) {
-	validMethods := []int{0, 1}
-	invalidMethods := []int{-1, 2, 99}
+	validMethods := []types.VATCalculationMethod{types.VATCalculationFromBottom, types.VATCalculationFromTop}
+	invalidMethods := []types.VATCalculationMethod{-1, 2, 99}
 
 	for _, method := range validMethods {
 		t.Run("valid", func(t *testing.T) {
-			if method != 0 && method != 1 {
+			if !method.IsValid() {
 				t.Errorf("VAT calculation method %d should be valid", method)
 			}
 		})
@@ -267,13 +274,63 @@ func TestVATCalculationMethodValidation(t *testing.T) {
 
 	for _, method := range invalidMethods {
 		t.Run("invalid", func(t *testing.T) {
-			if method >= 0 && method <= 1 {
+			if method.IsValid() {
 				t.Errorf("VAT calculation method %d should be invalid", method)
 			}
+			inv := createValidInvoice()
+			inv.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod = method
+			if !hasIssue(ValidateInvoice(inv), "Invoice.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATCalculationMethod", ErrCodeInvalidEnum) {
+				t.Errorf("VAT calculation method %d not reported", method)
+			}
 		})
 	}
 }
 
+// TestPaymentMeansCodeValidation tests the ISDOC payment means code list.
+func TestPaymentMeansCodeValidation(t *testing.T) {
+	for _, tt := range []struct {
+		code  types.PaymentMeansCode
+		valid bool
+	}{
+		{types.PaymentMeansCash, true},
+		{types.PaymentMeansBankTransfer, true},
+		{types.PaymentMeansOffsetting, true},
+		{0, false},
+		{30, false},
+		{58, false},
+	} {
+		inv := createValidInvoice()
+		inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{{PaidAmount: "1210.00", PaymentMeansCode: tt.code}}}
+		if got := hasIssue(ValidateInvoice(inv), "Invoice.PaymentMeans.Payment[0].PaymentMeansCode", ErrCodeInvalidEnum); got == tt.valid {
+			t.Errorf("PaymentMeansCode %d reported = %v, want %v", tt.code, got, !tt.valid)
+		}
+	}
+}
+
+// TestBatchOrSerialNumberValidation tests the store batch kind.
+func TestBatchOrSerialNumberValidation(t *testing.T) {
+	for _, tt := range []struct {
+		kind  types.BatchOrSerialNumber
+		valid bool
+	}{
+		{types.BatchNumber, true},
+		{types.SerialNumber, true},
+		{"", true},
+		{"b", false},
+		{"X", false},
+	} {
+		inv := createValidInvoice()
+		line := &inv.InvoiceLines.InvoiceLine[0]
+		line.Item.StoreBatches = &schema.StoreBatches{StoreBatch: []schema.StoreBatch{
+			{Name: "A1", Quantity: line.InvoicedQuantity, BatchOrSerialNumber: tt.kind},
+		}}
+		field := "Invoice.InvoiceLines.InvoiceLine[0].Item.StoreBatches.StoreBatch[0].BatchOrSerialNumber"
+		if got := hasIssue(ValidateInvoice(inv), field, ErrCodeInvalidEnum); got == tt.valid {
+			t.Errorf("BatchOrSerialNumber %q reported = %v, want %v", tt.kind, got, !tt.valid)
+		}
+	}
+}
+
 // TestDecimalPrecision tests decimal precision handling.
 func TestDecimalPrecision(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,232 @@
+package isdoc
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+// ReverseChargeCode describes a supply subject to the Czech local reverse
+// charge (§ 92a of the VAT Act), as used in LocalReverseChargeCode and in
+// sections A.1 and B.1 of the VAT control statement.
+type ReverseChargeCode struct {
+	// Code is the value of LocalReverseChargeCode.
+	Code string
+	// Section is the section of the VAT Act defining the supply.
+	Section string
+	// Description names the supply in Czech.
+	Description string
+	// Units lists the unit codes LocalReverseChargeQuantity is stated in,
+	// UN/ECE Recommendation 20 first. It is empty for supplies reported
+	// without a quantity.
+	Units []string
+}
+
+var (
+	unitsGram  = []string{"GRM", "g"}
+	unitsTonne = []string{"TNE", "t"}
+	unitsPiece = []string{"H87", "C62", "ks"}
+	unitsMWh   = []string{"MWH"}
+)
+
+// reverseChargeCodes is the code list of the control statement
+// (kód předmětu plnění).
+var reverseChargeCodes = []ReverseChargeCode{
+	{"1", "92b", "Dodání zlata", unitsGram},
+	{"3", "92c", "Dodání zboží uvedeného v příloze č. 5 zákona (odpad a šrot)", unitsTonne},
+	{"4", "92e", "Poskytnutí stavebních nebo montážních prací", nil},
+	{"5", "92d", "Dodání zboží uvedeného v příloze č. 6 zákona", nil},
+	{"11", "92f", "Převod povolenek na emise skleníkových plynů", nil},
+	{"12", "92f", "Dodání obilovin a technických plodin", unitsTonne},
+	{"13", "92f", "Dodání kovů", unitsTonne},
+	{"14", "92f", "Dodání mobilních telefonů", unitsPiece},
+	{"15", "92f", "Dodání integrovaných obvodů", unitsPiece},
+	{"16", "92f", "Dodání přenosných zařízení pro automatizované zpracování dat", unitsPiece},
+	{"17", "92f", "Dodání videoherních konzolí", unitsPiece},
+	{"18", "92f", "Dodání certifikátů elektřiny", nil},
+	{"19", "92f", "Dodání elektřiny soustavami nebo sítěmi", unitsMWh},
+	{"20", "92f", "Dodání plynu soustavami nebo sítěmi", unitsMWh},
+	{"21", "92f", "Poskytnutí telekomunikačních služeb", nil},
+}
+
+// ReverseChargeCodes returns the known LocalReverseChargeCode values.
+func ReverseChargeCodes() []ReverseChargeCode {
+	return slices.Clone(reverseChargeCodes)
+}
+
+// LookupReverseChargeCode returns the description of a LocalReverseChargeCode.
+func LookupReverseChargeCode(code string) (ReverseChargeCode, bool) {
+	i := slices.IndexFunc(reverseChargeCodes, func(c ReverseChargeCode) bool { return c.Code == code })
+	if i < 0 {
+		return ReverseChargeCode{}, false
+	}
+	return reverseChargeCodes[i], true
+}
+
+// hasUnit reports whether unit is one of the units of the code.
+func (c ReverseChargeCode) hasUnit(unit string) bool {
+	return slices.ContainsFunc(c.Units, func(u string) bool { return strings.EqualFold(u, unit) })
+}
+
+// validateLocalReverseCharge checks lines and tax subtotals under local
+// reverse charge: codes come from the code list, quantities are given in
+// the unit of the code, reverse charge lines carry no tax and are
+// recapitulated only in subtotals flagged with LocalReverseChargeFlag.
+func validateLocalReverseCharge(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	// Line bases per rate, with and without reverse charge
+	bases := make(map[string]*big.Rat)
+	for i, line := range inv.InvoiceLines.InvoiceLine {
+		path := fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i)
+		rc := line.ClassifiedTaxCategory.LocalReverseCharge
+
+		key := taxRateKey(line.ClassifiedTaxCategory.Percent, rc != nil)
+		if bases[key] == nil {
+			bases[key] = new(big.Rat)
+		}
+		bases[key].Add(bases[key], parseAmount(line.LineExtensionAmount))
+
+		if rc == nil {
+			continue
+		}
+		path += ".ClassifiedTaxCategory.LocalReverseCharge"
+
+		if parseAmount(line.LineExtensionTaxAmount).Sign() != 0 {
+			errs = append(errs, &ValidationError{
+				Field:    fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d].LineExtensionTaxAmount", i),
+				Code:     ErrCodeVATMismatch,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("line under local reverse charge must carry no tax, got %s", line.LineExtensionTaxAmount),
+			})
+		}
+
+		if rc.LocalReverseChargeCode == "" {
+			errs = append(errs, &ValidationError{
+				Field:    path + ".LocalReverseChargeCode",
+				Code:     ErrCodeRequiredField,
+				Severity: SeverityError,
+				Msg:      "LocalReverseChargeCode is required",
+			})
+			continue
+		}
+		code, ok := LookupReverseChargeCode(rc.LocalReverseChargeCode)
+		if !ok {
+			errs = append(errs, &ValidationError{
+				Field:    path + ".LocalReverseChargeCode",
+				Code:     ErrCodeInvalidEnum,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("unknown LocalReverseChargeCode %q", rc.LocalReverseChargeCode),
+			})
+			continue
+		}
+
+		switch {
+		case len(code.Units) == 0:
+			if !rc.LocalReverseChargeQuantity.IsZero() {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".LocalReverseChargeQuantity",
+					Code:     ErrCodeSchemaViolation,
+					Severity: severity,
+					Msg:      fmt.Sprintf("LocalReverseChargeCode %s is reported without a quantity", code.Code),
+				})
+			}
+		case rc.LocalReverseChargeQuantity.IsZero():
+			if !code.hasUnit(line.InvoicedQuantity.UnitCode) {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".LocalReverseChargeQuantity",
+					Code:     ErrCodeRequiredField,
+					Severity: severity,
+					Msg: fmt.Sprintf("LocalReverseChargeCode %s requires LocalReverseChargeQuantity in %s",
+						code.Code, code.Units[0]),
+				})
+			}
+		case code.hasUnit(line.InvoicedQuantity.UnitCode):
+			qty, invoiced := parseAmount(rc.LocalReverseChargeQuantity), parseAmount(line.InvoicedQuantity.Value)
+			if qty.Cmp(invoiced) != 0 {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".LocalReverseChargeQuantity",
+					Code:     ErrCodeTotalMismatch,
+					Severity: severity,
+					Msg: fmt.Sprintf("LocalReverseChargeQuantity %s differs from InvoicedQuantity %s %s",
+						rc.LocalReverseChargeQuantity, line.InvoicedQuantity.Value, line.InvoicedQuantity.UnitCode),
+				})
+			}
+		}
+	}
+
+	flagged := make(map[string]bool)
+	for i, st := range inv.TaxTotal.TaxSubTotal {
+		path := fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d]", i)
+		percent := st.TaxCategory.Percent
+
+		if !st.TaxCategory.LocalReverseChargeFlag.Bool() {
+			// Regular subtotals recapitulate only lines without reverse charge
+			rc := bases[taxRateKey(percent, true)]
+			if rc == nil {
+				continue
+			}
+			mixed := new(big.Rat).Set(rc)
+			if regular := bases[taxRateKey(percent, false)]; regular != nil {
+				mixed.Add(mixed, regular)
+			}
+			if parseAmount(st.TaxableAmount).Cmp(mixed) == 0 {
+				errs = append(errs, &ValidationError{
+					Field:    path + ".TaxableAmount",
+					Code:     ErrCodeVATMismatch,
+					Severity: SeverityError,
+					Msg:      fmt.Sprintf("lines at %s %% under local reverse charge are included in a regular VAT subtotal", percent),
+				})
+			}
+			continue
+		}
+
+		key := taxRateKey(percent, true)
+		flagged[key] = true
+		if parseAmount(st.TaxAmount).Sign() != 0 {
+			errs = append(errs, &ValidationError{
+				Field:    path + ".TaxAmount",
+				Code:     ErrCodeVATMismatch,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("subtotal under local reverse charge must carry no tax, got %s", st.TaxAmount),
+			})
+		}
+		if bases[key] == nil {
+			errs = append(errs, &ValidationError{
+				Field:    path + ".TaxCategory.LocalReverseChargeFlag",
+				Code:     ErrCodeVATMismatch,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("LocalReverseChargeFlag is set, but no line at %s %% has LocalReverseCharge", percent),
+			})
+		}
+	}
+
+	if len(inv.TaxTotal.TaxSubTotal) == 0 {
+		return errs
+	}
+	for _, line := range inv.InvoiceLines.InvoiceLine {
+		category := line.ClassifiedTaxCategory
+		if category.LocalReverseCharge == nil {
+			continue
+		}
+		key := taxRateKey(category.Percent, true)
+		if !flagged[key] {
+			errs = append(errs, &ValidationError{
+				Field:    "Invoice.TaxTotal.TaxSubTotal",
+				Code:     ErrCodeVATMismatch,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("no TaxSubTotal with LocalReverseChargeFlag for lines at %s %% under local reverse charge", category.Percent),
+			})
+			flagged[key] = true // report each rate once
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,176 @@
+package isdoc
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// reverseChargeInvoice returns an invoice with a regular line and a
+// construction line under local reverse charge, both at 21 %.
+func reverseChargeInvoice(t *testing.T) *schema.Invoice {
+	t.Helper()
+	inv := createValidInvoice()
+	rc := inv.InvoiceLines.InvoiceLine[0]
+	rc.ID = "2"
+	rc.ClassifiedTaxCategory.LocalReverseCharge = &schema.LocalReverseCharge{LocalReverseChargeCode: "4"}
+	rc.ClassifiedTaxCategory.VATApplicable = false
+	inv.InvoiceLines.InvoiceLine = append(inv.InvoiceLines.InvoiceLine, rc)
+	if err := ComputeTotals(inv); err != nil {
+		t.Fatalf("ComputeTotals() error = %v", err)
+	}
+	return inv
+}
+
+func TestLookupReverseChargeCode(t *testing.T) {
+	c, ok := LookupReverseChargeCode("14")
+	if !ok || c.Section != "92f" || !c.hasUnit("KS") || c.hasUnit("KGM") {
+		t.Errorf("LookupReverseChargeCode(14) = %+v, %v", c, ok)
+	}
+	if _, ok := LookupReverseChargeCode("2"); ok {
+		t.Error("code 2 is not in use")
+	}
+	codes := ReverseChargeCodes()
+	codes[0].Code = "X"
+	if _, ok := LookupReverseChargeCode("1"); !ok {
+		t.Error("ReverseChargeCodes() returned the table itself")
+	}
+}
+
+func TestValidateLocalReverseCharge(t *testing.T) {
+	if errs := validateLocalReverseCharge(reverseChargeInvoice(t), DefaultValidateOptions()); len(errs) > 0 {
+		t.Fatalf("computed invoice: %v", errs)
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func(inv *schema.Invoice)
+		field    string
+		code     string
+		severity Severity
+	}{
+		{
+			name: "unknown code",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode = "2"
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode",
+			code:     ErrCodeInvalidEnum,
+			severity: SeverityError,
+		},
+		{
+			name: "missing code",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode = ""
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode",
+			code:     ErrCodeRequiredField,
+			severity: SeverityError,
+		},
+		{
+			name: "line with tax",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].LineExtensionTaxAmount = types.MustDecimal("210.00")
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].LineExtensionTaxAmount",
+			code:     ErrCodeVATMismatch,
+			severity: SeverityError,
+		},
+		{
+			name: "quantity missing",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode = "13"
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeQuantity",
+			code:     ErrCodeRequiredField,
+			severity: SeverityWarning,
+		},
+		{
+			name: "quantity differs",
+			mutate: func(inv *schema.Invoice) {
+				line := &inv.InvoiceLines.InvoiceLine[1]
+				line.InvoicedQuantity = schema.Quantity{Value: types.MustDecimal("3"), UnitCode: "ks"}
+				line.ClassifiedTaxCategory.LocalReverseCharge = &schema.LocalReverseCharge{
+					LocalReverseChargeCode:     "14",
+					LocalReverseChargeQuantity: types.MustDecimal("2"),
+				}
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeQuantity",
+			code:     ErrCodeTotalMismatch,
+			severity: SeverityWarning,
+		},
+		{
+			name: "quantity without unit",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeQuantity = types.MustDecimal("1")
+			},
+			field:    "Invoice.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeQuantity",
+			code:     ErrCodeSchemaViolation,
+			severity: SeverityWarning,
+		},
+		{
+			name: "subtotal with tax",
+			mutate: func(inv *schema.Invoice) {
+				inv.TaxTotal.TaxSubTotal[1].TaxAmount = types.MustDecimal("210.00")
+			},
+			field:    "Invoice.TaxTotal.TaxSubTotal[1].TaxAmount",
+			code:     ErrCodeVATMismatch,
+			severity: SeverityError,
+		},
+		{
+			name: "mixed into regular subtotal",
+			mutate: func(inv *schema.Invoice) {
+				inv.TaxTotal.TaxSubTotal = inv.TaxTotal.TaxSubTotal[:1]
+				inv.TaxTotal.TaxSubTotal[0].TaxableAmount = types.MustDecimal("2000.00")
+			},
+			field:    "Invoice.TaxTotal.TaxSubTotal[0].TaxableAmount",
+			code:     ErrCodeVATMismatch,
+			severity: SeverityError,
+		},
+		{
+			name: "flagged subtotal missing",
+			mutate: func(inv *schema.Invoice) {
+				inv.TaxTotal.TaxSubTotal = inv.TaxTotal.TaxSubTotal[:1]
+			},
+			field:    "Invoice.TaxTotal.TaxSubTotal",
+			code:     ErrCodeVATMismatch,
+			severity: SeverityError,
+		},
+		{
+			name: "flag without reverse charge lines",
+			mutate: func(inv *schema.Invoice) {
+				inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge = nil
+			},
+			field:    "Invoice.TaxTotal.TaxSubTotal[1].TaxCategory.LocalReverseChargeFlag",
+			code:     ErrCodeVATMismatch,
+			severity: SeverityError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inv := reverseChargeInvoice(t)
+			tc.mutate(inv)
+			errs := validateLocalReverseCharge(inv, DefaultValidateOptions())
+			i := slices.IndexFunc(errs, func(e *ValidationError) bool { return e.Field == tc.field })
+			if i < 0 {
+				t.Fatalf("no issue on %s, got %v", tc.field, errs)
+			}
+			if errs[i].Code != tc.code || errs[i].Severity != tc.severity {
+				t.Errorf("got %v, want %s %s", errs[i], tc.severity, tc.code)
+			}
+		})
+	}
+}
+
+func TestValidateLocalReverseChargeStrict(t *testing.T) {
+	inv := reverseChargeInvoice(t)
+	inv.InvoiceLines.InvoiceLine[1].ClassifiedTaxCategory.LocalReverseCharge.LocalReverseChargeCode = "1"
+
+	errs := validateLocalReverseCharge(inv, ValidateOptions{Strict: true})
+	if len(errs) != 1 || errs[0].Severity != SeverityError {
+		t.Errorf("strict: %v, want one error for the missing quantity", errs)
+	}
+}
@@ -57,4 +57,7 @@ type CommonDocument struct {
 
 	// SupplementsList is a collection of document attachments.
 	SupplementsList *SupplementsList `xml:"SupplementsList,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
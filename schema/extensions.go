@@ -0,0 +1,398 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrExtensionNotRegistered is returned when a value's type has no
+	// registered extension namespace.
+	ErrExtensionNotRegistered = errors.New("extension type not registered")
+
+	// ErrExtensionNotFound is returned when Extensions holds no element in
+	// the namespace registered for a type.
+	ErrExtensionNotFound = errors.New("extension not found")
+)
+
+// ExtensionValidator is implemented by registered extension types that check
+// their own content. Validation reports a non-nil error as an invalid extension.
+type ExtensionValidator interface {
+	Validate() error
+}
+
+var extensionRegistry = struct {
+	sync.RWMutex
+	types      map[string]reflect.Type
+	namespaces map[reflect.Type]string
+}{
+	types:      make(map[string]reflect.Type),
+	namespaces: make(map[reflect.Type]string),
+}
+
+// RegisterExtension registers the Go type of the extension payload in
+// namespace ns.
+//
+// The type models the top-level elements of ns found in an Extensions block:
+// each struct field maps to one element by its local name. For example, an
+// ERP exporting
+//
+//	<Extensions>
+//	  <erp:Warehouse xmlns:erp="urn:example:erp">W01</erp:Warehouse>
+//	  <erp:Batch xmlns:erp="urn:example:erp">B-7</erp:Batch>
+//	</Extensions>
+//
+// is modeled as
+//
+//	type ERPFields struct {
+//	    Warehouse string `xml:"Warehouse"`
+//	    Batch     string `xml:"Batch"`
+//	}
+//
+//	schema.RegisterExtension("urn:example:erp", ERPFields{})
+//
+// The type must be a struct without an XMLName field. RegisterExtension
+// panics if ns is empty or the ISDOC namespace, or if ns or the type is
+// already registered to something else.
+func RegisterExtension(ns string, v any) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if ns == "" || ns == Namespace {
+		panic(fmt.Sprintf("schema: invalid extension namespace %q", ns))
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("schema: extension type for %q must be a struct", ns))
+	}
+
+	extensionRegistry.Lock()
+	defer extensionRegistry.Unlock()
+
+	if prev, ok := extensionRegistry.types[ns]; ok && prev != t {
+		panic(fmt.Sprintf("schema: extension namespace %q already registered to %s", ns, prev))
+	}
+	if prev, ok := extensionRegistry.namespaces[t]; ok && prev != ns {
+		panic(fmt.Sprintf("schema: extension type %s already registered to %q", t, prev))
+	}
+	extensionRegistry.types[ns] = t
+	extensionRegistry.namespaces[t] = ns
+}
+
+// RegisteredExtension returns the type registered for namespace ns.
+func RegisteredExtension(ns string) (reflect.Type, bool) {
+	extensionRegistry.RLock()
+	defer extensionRegistry.RUnlock()
+	t, ok := extensionRegistry.types[ns]
+	return t, ok
+}
+
+// extensionNamespace returns the namespace registered for the type of v.
+func extensionNamespace(v any) (string, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	extensionRegistry.RLock()
+	defer extensionRegistry.RUnlock()
+	ns, ok := extensionRegistry.namespaces[t]
+	if !ok {
+		return "", fmt.Errorf("%w: %v", ErrExtensionNotRegistered, t)
+	}
+	return ns, nil
+}
+
+// Namespaces returns the namespace URIs of the top-level extension elements
+// in document order, without duplicates. Prefixes declared outside Raw are
+// resolved with Scope. Elements in no namespace are reported with an empty
+// URI.
+func (e *Extensions) Namespaces() ([]string, error) {
+	var namespaces []string
+	seen := make(map[string]bool)
+	err := e.walk(func(d *xml.Decoder, start xml.StartElement, _ int64) error {
+		ns := e.space(start.Name.Space)
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+		return d.Skip()
+	})
+	return namespaces, err
+}
+
+// Decode decodes the elements in the namespace registered for the type of v
+// into v, which must be a pointer to the registered type.
+func (e *Extensions) Decode(v any) error {
+	ns, err := extensionNamespace(v)
+	if err != nil {
+		return err
+	}
+	return e.decodeNamespace(ns, v)
+}
+
+// Registered decodes the payload of every registered namespace present in
+// the extensions. Values are pointers to the registered types, in document
+// order. Unregistered namespaces are skipped.
+func (e *Extensions) Registered() ([]any, error) {
+	namespaces, err := e.Namespaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []any
+	for _, ns := range namespaces {
+		t, ok := RegisteredExtension(ns)
+		if !ok {
+			continue
+		}
+		v := reflect.New(t).Interface()
+		if err := e.decodeNamespace(ns, v); err != nil {
+			return values, fmt.Errorf("extension %s: %w", ns, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Set replaces the elements in the namespace registered for the type of v
+// with the encoding of v. Each top-level element declares the namespace.
+// Elements in other namespaces, comments and whitespace are kept.
+func (e *Extensions) Set(v any) error {
+	ns, err := extensionNamespace(v)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := encodeExtension(ns, v)
+	if err != nil {
+		return err
+	}
+
+	// Remove existing elements of the namespace, remembering where the
+	// first one was so the payload keeps its position.
+	var out bytes.Buffer
+	insertAt := -1
+	last := int64(0)
+	err = e.walk(func(d *xml.Decoder, start xml.StartElement, from int64) error {
+		if err := d.Skip(); err != nil {
+			return err
+		}
+		if e.space(start.Name.Space) != ns {
+			return nil
+		}
+		out.Write(e.Raw[last:from])
+		if insertAt < 0 {
+			insertAt = out.Len()
+		}
+		last = d.InputOffset()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	out.Write(e.Raw[last:])
+
+	raw := out.Bytes()
+	if insertAt < 0 {
+		insertAt = len(bytes.TrimRight(raw, " \t\r\n"))
+	}
+	e.Raw = append(append(append([]byte{}, raw[:insertAt]...), encoded...), raw[insertAt:]...)
+	return nil
+}
+
+// walk calls fn for each top-level element. fn must consume the element.
+func (e *Extensions) walk(fn func(d *xml.Decoder, start xml.StartElement, from int64) error) error {
+	if e == nil || len(e.Raw) == 0 {
+		return nil
+	}
+
+	d := e.decoder()
+	for {
+		from := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if err := fn(d, start, from); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeNamespace unmarshals the top-level elements in namespace ns into v,
+// as if they were the only children of an element of v's type.
+func (e *Extensions) decodeNamespace(ns string, v any) error {
+	found := false
+	if err := e.walk(func(d *xml.Decoder, start xml.StartElement, _ int64) error {
+		if e.space(start.Name.Space) == ns {
+			found = true
+		}
+		return d.Skip()
+	}); err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: %s", ErrExtensionNotFound, ns)
+	}
+
+	f := &namespaceFilter{e: e, d: e.decoder(), ns: ns}
+	return xml.NewTokenDecoder(f).Decode(v)
+}
+
+// decoder returns a decoder reading Raw with the default namespace of Scope.
+func (e *Extensions) decoder() *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(e.Raw))
+	d.DefaultSpace = e.Scope[""]
+	return d
+}
+
+// space resolves the namespace of a name read from Raw. The decoder leaves
+// prefixes declared outside Raw unresolved; they are looked up in Scope.
+func (e *Extensions) space(space string) string {
+	if uri, ok := e.Scope[space]; ok && space != "" {
+		return uri
+	}
+	return space
+}
+
+// namespaceFilter yields the top-level elements of a single namespace,
+// wrapped in a synthetic Extensions element.
+type namespaceFilter struct {
+	e     *Extensions
+	d     *xml.Decoder
+	ns    string
+	state int // 0: before wrapper, 1: inside, 2: after wrapper, 3: done
+	depth int
+}
+
+var extensionsName = xml.Name{Space: Namespace, Local: "Extensions"}
+
+func (f *namespaceFilter) Token() (xml.Token, error) {
+	switch f.state {
+	case 0:
+		f.state = 1
+		return xml.StartElement{Name: extensionsName}, nil
+	case 2:
+		f.state = 3
+		return xml.EndElement{Name: extensionsName}, nil
+	case 3:
+		return nil, io.EOF
+	}
+
+	for {
+		tok, err := f.d.Token()
+		if err == io.EOF {
+			f.state = 2
+			return f.Token()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			t.Name.Space = f.e.space(t.Name.Space)
+			if f.depth == 0 && t.Name.Space != f.ns {
+				if err := f.d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			f.depth++
+			tok = t
+		case xml.EndElement:
+			t.Name.Space = f.e.space(t.Name.Space)
+			f.depth--
+			tok = t
+		default:
+			if f.depth == 0 {
+				continue
+			}
+		}
+		return xml.CopyToken(tok), nil
+	}
+}
+
+// encodeExtension encodes the fields of v as top-level elements, each
+// carrying a default namespace declaration for ns.
+func encodeExtension(ns string, v any) ([]byte, error) {
+	var wrapped bytes.Buffer
+	if err := xml.NewEncoder(&wrapped).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: "Extensions"}}); err != nil {
+		return nil, fmt.Errorf("encoding extension %s: %w", ns, err)
+	}
+
+	var out bytes.Buffer
+	enc := xml.NewEncoder(&out)
+	d := xml.NewDecoder(&wrapped)
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				continue
+			}
+			t.Name.Space = elementSpace(t.Name.Space, ns, depth)
+			tok = dropNamespaceDecls(t)
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				continue
+			}
+			t.Name.Space = elementSpace(t.Name.Space, ns, depth+1)
+			tok = t
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// elementSpace returns the namespace to encode an element at depth with.
+// Top-level elements declare ns, nested elements in ns inherit it.
+func elementSpace(space, ns string, depth int) string {
+	switch {
+	case depth == 2 && space == "":
+		return ns
+	case depth > 2 && space == ns:
+		return ""
+	}
+	return space
+}
+
+// dropNamespaceDecls removes xmlns attributes, which the encoder derives
+// from element names again.
+func dropNamespaceDecls(start xml.StartElement) xml.StartElement {
+	attrs := start.Attr[:0:0]
+	for _, a := range start.Attr {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	start.Attr = attrs
+	return start
+}
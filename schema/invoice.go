@@ -13,6 +13,9 @@ import (
 // Namespace is the ISDOC XML namespace.
 const Namespace = "http://isdoc.cz/namespace/2013"
 
+// Version is the ISDOC schema version modeled by this package.
+const Version = "6.0.2"
+
 // Invoice is the root element of an ISDOC document.
 type Invoice struct {
 	XMLName xml.Name `xml:"Invoice"`
@@ -21,7 +24,7 @@ type Invoice struct {
 	Version string `xml:"version,attr"`
 
 	// DocumentType specifies the type of document (1-7).
-	DocumentType int `xml:"DocumentType"`
+	DocumentType types.DocumentType `xml:"DocumentType"`
 
 	// SubDocumentType is an optional document subtype.
 	SubDocumentType string `xml:"SubDocumentType,omitempty"`
@@ -142,6 +145,9 @@ type Invoice struct {
 
 	// SupplementsList is a collection of document attachments.
 	SupplementsList *SupplementsList `xml:"SupplementsList,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // Note represents a text note with optional language identifier.
@@ -153,11 +159,18 @@ type Note struct {
 // Extensions contains arbitrary user-defined XML elements.
 type Extensions struct {
 	Raw []byte `xml:",innerxml"`
+
+	// Scope maps the namespace prefixes in scope at the Extensions element
+	// to their URIs, with "" for the default namespace. Decoding fills it,
+	// so prefixes used in Raw but declared on an ancestor, typically the
+	// root element, resolve.
+	Scope map[string]string `xml:"-" json:"-"`
 }
 
 // EgovClassifiers is a collection of document classifiers.
 type EgovClassifiers struct {
 	EgovClassifier []EgovClassifier `xml:"EgovClassifier"`
+	Unknown        Unknown          `xml:"-" json:"-"`
 }
 
 // EgovClassifier represents a document classifier.
@@ -5,6 +5,7 @@ import "github.com/xseman/isdoc/types"
 // InvoiceLines is a collection of invoice line items.
 type InvoiceLines struct {
 	InvoiceLine []InvoiceLine `xml:"InvoiceLine"`
+	Unknown     Unknown       `xml:"-" json:"-"`
 }
 
 // InvoiceLine represents a single line item on the invoice.
@@ -71,6 +72,9 @@ type InvoiceLine struct {
 
 	// Extensions contains arbitrary user-defined elements.
 	Extensions *Extensions `xml:"Extensions,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // Quantity represents a quantity with optional unit code.
@@ -85,19 +89,23 @@ type ClassifiedTaxCategory struct {
 	Percent types.Decimal `xml:"Percent"`
 
 	// VATCalculationMethod is 0 (from bottom) or 1 (from top).
-	VATCalculationMethod int `xml:"VATCalculationMethod,omitempty"`
+	VATCalculationMethod types.VATCalculationMethod `xml:"VATCalculationMethod,omitempty"`
 
 	// VATApplicable indicates whether VAT is applicable.
 	VATApplicable types.Bool `xml:"VATApplicable,omitempty"`
 
 	// LocalReverseCharge contains reverse charge information.
 	LocalReverseCharge *LocalReverseCharge `xml:"LocalReverseCharge,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // LocalReverseCharge contains local reverse charge information.
 type LocalReverseCharge struct {
 	LocalReverseChargeCode     string        `xml:"LocalReverseChargeCode"`
 	LocalReverseChargeQuantity types.Decimal `xml:"LocalReverseChargeQuantity,omitempty"`
+	Unknown                    Unknown       `xml:"-" json:"-"`
 }
 
 // Item contains item details.
@@ -122,25 +130,31 @@ type Item struct {
 
 	// StoreBatches contains batch/serial number information.
 	StoreBatches *StoreBatches `xml:"StoreBatches,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // ItemIdentification contains an item identifier.
 type ItemIdentification struct {
-	ID string `xml:"ID"`
+	ID      string  `xml:"ID"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // StoreBatches is a collection of store batches.
 type StoreBatches struct {
 	StoreBatch []StoreBatch `xml:"StoreBatch"`
+	Unknown    Unknown      `xml:"-" json:"-"`
 }
 
 // StoreBatch contains batch/serial number information.
 type StoreBatch struct {
-	Name                string     `xml:"Name,omitempty"`
-	Note                string     `xml:"Note,omitempty"`
-	ExpirationDate      types.Date `xml:"ExpirationDate,omitempty"`
-	Specification       string     `xml:"Specification,omitempty"`
-	Quantity            Quantity   `xml:"Quantity,omitempty"`
-	BatchOrSerialNumber string     `xml:"BatchOrSerialNumber,omitempty"` // "B" or "S"
-	SealSeriesID        string     `xml:"SealSeriesID,omitempty"`
+	Name                string                    `xml:"Name,omitempty"`
+	Note                string                    `xml:"Note,omitempty"`
+	ExpirationDate      types.Date                `xml:"ExpirationDate,omitempty"`
+	Specification       string                    `xml:"Specification,omitempty"`
+	Quantity            Quantity                  `xml:"Quantity,omitempty"`
+	BatchOrSerialNumber types.BatchOrSerialNumber `xml:"BatchOrSerialNumber,omitempty"`
+	SealSeriesID        string                    `xml:"SealSeriesID,omitempty"`
+	Unknown             Unknown                   `xml:"-" json:"-"`
 }
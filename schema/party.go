@@ -4,33 +4,39 @@ import "github.com/xseman/isdoc/types"
 
 // AccountingSupplierParty is the supplier/accounting entity.
 type AccountingSupplierParty struct {
-	Party Party `xml:"Party"`
+	Party   Party   `xml:"Party"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // SellerSupplierParty is the supplier's invoicing address.
 type SellerSupplierParty struct {
-	Party Party `xml:"Party"`
+	Party   Party   `xml:"Party"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // AccountingCustomerParty is the customer/accounting entity.
 type AccountingCustomerParty struct {
-	Party Party `xml:"Party"`
+	Party   Party   `xml:"Party"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // BuyerCustomerParty is the purchaser's invoicing address.
 type BuyerCustomerParty struct {
-	Party Party `xml:"Party"`
+	Party   Party   `xml:"Party"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // AnonymousCustomerParty is for simplified tax documents.
 type AnonymousCustomerParty struct {
-	ID       string `xml:"ID"`
-	IDScheme string `xml:"IDScheme,omitempty"`
+	ID       string  `xml:"ID"`
+	IDScheme string  `xml:"IDScheme,omitempty"`
+	Unknown  Unknown `xml:"-" json:"-"`
 }
 
 // Delivery contains delivery information.
 type Delivery struct {
-	Party Party `xml:"Party"`
+	Party   Party   `xml:"Party"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // Party represents a business party (supplier, customer, etc.).
@@ -52,6 +58,9 @@ type Party struct {
 
 	// Contact contains contact information.
 	Contact *Contact `xml:"Contact,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // PartyIdentification contains identifiers for a party.
@@ -64,11 +73,15 @@ type PartyIdentification struct {
 
 	// ID is the company ID (IČO in Czech).
 	ID string `xml:"ID"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // PartyName contains the party's name.
 type PartyName struct {
-	Name string `xml:"Name"`
+	Name    string  `xml:"Name"`
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // PostalAddress contains address information.
@@ -78,12 +91,14 @@ type PostalAddress struct {
 	CityName       string  `xml:"CityName"`
 	PostalZone     string  `xml:"PostalZone"`
 	Country        Country `xml:"Country"`
+	Unknown        Unknown `xml:"-" json:"-"`
 }
 
 // Country contains country information.
 type Country struct {
-	IdentificationCode string `xml:"IdentificationCode"`
-	Name               string `xml:"Name,omitempty"`
+	IdentificationCode string  `xml:"IdentificationCode"`
+	Name               string  `xml:"Name,omitempty"`
+	Unknown            Unknown `xml:"-" json:"-"`
 }
 
 // PartyTaxScheme contains tax scheme information.
@@ -93,6 +108,9 @@ type PartyTaxScheme struct {
 
 	// TaxScheme is either "VAT" or "TIN".
 	TaxScheme string `xml:"TaxScheme"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // RegisterIdentification contains commercial register information.
@@ -101,11 +119,13 @@ type RegisterIdentification struct {
 	RegisterKeptAt  string     `xml:"RegisterKeptAt,omitempty"`
 	RegisterFileRef string     `xml:"RegisterFileRef,omitempty"`
 	RegisterDate    types.Date `xml:"RegisterDate,omitempty"`
+	Unknown         Unknown    `xml:"-" json:"-"`
 }
 
 // Contact contains contact information.
 type Contact struct {
-	Name           string `xml:"Name,omitempty"`
-	Telephone      string `xml:"Telephone,omitempty"`
-	ElectronicMail string `xml:"ElectronicMail,omitempty"`
+	Name           string  `xml:"Name,omitempty"`
+	Telephone      string  `xml:"Telephone,omitempty"`
+	ElectronicMail string  `xml:"ElectronicMail,omitempty"`
+	Unknown        Unknown `xml:"-" json:"-"`
 }
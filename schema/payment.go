@@ -9,6 +9,9 @@ type PaymentMeans struct {
 
 	// AlternateBankAccounts contains alternative bank accounts.
 	AlternateBankAccounts *AlternateBankAccounts `xml:"AlternateBankAccounts,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // Payment contains payment details.
@@ -16,13 +19,15 @@ type Payment struct {
 	// PaidAmount is the amount paid.
 	PaidAmount types.Decimal `xml:"PaidAmount"`
 
-	// PaymentMeansCode is the payment method code.
-	// Values: 10 (cash), 20 (cheque), 31 (credit transfer), 42 (transfer),
-	//         48 (card), 49 (direct debit), 50 (postgiro), 97 (composition)
-	PaymentMeansCode int `xml:"PaymentMeansCode"`
+	// PaymentMeansCode is the payment method code, e.g.
+	// types.PaymentMeansBankTransfer (42).
+	PaymentMeansCode types.PaymentMeansCode `xml:"PaymentMeansCode"`
 
 	// Details contains payment details.
 	Details *PaymentDetails `xml:"Details,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // PaymentDetails contains detailed payment information.
@@ -47,6 +52,9 @@ type PaymentDetails struct {
 
 	// BankAccount contains bank account details.
 	BankAccount *BankAccount `xml:"BankAccount,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // BankAccount contains bank account information.
@@ -65,16 +73,21 @@ type BankAccount struct {
 
 	// BIC is the bank identifier code (SWIFT).
 	BIC string `xml:"BIC,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // AlternateBankAccounts is a collection of alternative bank accounts.
 type AlternateBankAccounts struct {
 	AlternateBankAccount []BankAccount `xml:"AlternateBankAccount"`
+	Unknown              Unknown       `xml:"-" json:"-"`
 }
 
 // SupplementsList is a collection of document attachments.
 type SupplementsList struct {
 	Supplement []Supplement `xml:"Supplement"`
+	Unknown    Unknown      `xml:"-" json:"-"`
 }
 
 // Supplement represents a document attachment.
@@ -83,11 +96,23 @@ type Supplement struct {
 	Filename string `xml:"Filename"`
 
 	// DigestMethod is the hash algorithm used.
-	DigestMethod string `xml:"DigestMethod,omitempty"`
+	DigestMethod *DigestMethod `xml:"DigestMethod,omitempty"`
 
-	// DigestValue is the hash value.
+	// DigestValue is the base64-encoded hash value.
 	DigestValue string `xml:"DigestValue,omitempty"`
 
 	// Preview attribute indicates if this is the document preview.
 	Preview types.Bool `xml:"preview,attr,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
+}
+
+// DigestMethod identifies a hash algorithm by its XML Signature URI,
+// e.g. "http://www.w3.org/2000/09/xmldsig#sha1".
+type DigestMethod struct {
+	Algorithm string `xml:"Algorithm,attr"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
@@ -5,6 +5,7 @@ import "github.com/xseman/isdoc/types"
 // OrderReferences is a collection of referenced purchase orders.
 type OrderReferences struct {
 	OrderReference []OrderReference `xml:"OrderReference"`
+	Unknown        Unknown          `xml:"-" json:"-"`
 }
 
 // OrderReference contains information about a referenced purchase order.
@@ -35,6 +36,9 @@ type OrderReference struct {
 
 	// ReferenceNumber is the reference number.
 	ReferenceNumber string `xml:"ReferenceNumber,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // OrderLineReference references a line on a purchase order.
@@ -44,11 +48,15 @@ type OrderLineReference struct {
 
 	// LineID is the line number on the order.
 	LineID string `xml:"LineID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // DeliveryNoteReferences is a collection of referenced delivery notes.
 type DeliveryNoteReferences struct {
 	DeliveryNoteReference []DeliveryNoteReference `xml:"DeliveryNoteReference"`
+	Unknown               Unknown                 `xml:"-" json:"-"`
 }
 
 // DeliveryNoteReference contains information about a referenced delivery note.
@@ -64,6 +72,9 @@ type DeliveryNoteReference struct {
 
 	// UUID is the unique GUID identifier.
 	UUID types.UUID `xml:"UUID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // DeliveryNoteLineReference references a line on a delivery note.
@@ -73,11 +84,15 @@ type DeliveryNoteLineReference struct {
 
 	// LineID is the line number on the delivery note.
 	LineID string `xml:"LineID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // OriginalDocumentReferences is a collection of referenced original documents.
 type OriginalDocumentReferences struct {
 	OriginalDocumentReference []OriginalDocumentReference `xml:"OriginalDocumentReference"`
+	Unknown                   Unknown                     `xml:"-" json:"-"`
 }
 
 // OriginalDocumentReference contains information about a referenced original document.
@@ -93,6 +108,9 @@ type OriginalDocumentReference struct {
 
 	// UUID is the unique GUID identifier.
 	UUID types.UUID `xml:"UUID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // OriginalDocumentLineReference references a line on an original document.
@@ -102,11 +120,15 @@ type OriginalDocumentLineReference struct {
 
 	// LineID is the line number on the original document.
 	LineID string `xml:"LineID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // ContractReferences is a collection of related contracts.
 type ContractReferences struct {
 	ContractReference []ContractReference `xml:"ContractReference"`
+	Unknown           Unknown             `xml:"-" json:"-"`
 }
 
 // ContractReference contains information about a related contract.
@@ -137,6 +159,9 @@ type ContractReference struct {
 
 	// ReferenceNumber is the reference number.
 	ReferenceNumber string `xml:"ReferenceNumber,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // ContractLineReference references a related contract.
@@ -146,4 +171,7 @@ type ContractLineReference struct {
 
 	// ParagraphID is the contract paragraph identifier.
 	ParagraphID string `xml:"ParagraphID,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
@@ -12,6 +12,9 @@ type TaxTotal struct {
 
 	// TaxAmount is the total tax amount.
 	TaxAmount types.Decimal `xml:"TaxAmount"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // TaxSubTotal contains tax breakdown for a specific rate.
@@ -34,44 +37,47 @@ type TaxSubTotal struct {
 	// TaxInclusiveAmount is the tax-inclusive amount.
 	TaxInclusiveAmount types.Decimal `xml:"TaxInclusiveAmount"`
 
-	// AlreadyClaimedTaxableAmountCurr (for credit notes).
+	// AlreadyClaimedTaxableAmountCurr is the taxable amount claimed in foreign currency.
 	AlreadyClaimedTaxableAmountCurr types.Decimal `xml:"AlreadyClaimedTaxableAmountCurr,omitempty"`
 
-	// AlreadyClaimedTaxableAmount (for credit notes).
+	// AlreadyClaimedTaxableAmount is the taxable amount claimed by advance tax documents.
 	AlreadyClaimedTaxableAmount types.Decimal `xml:"AlreadyClaimedTaxableAmount,omitempty"`
 
-	// AlreadyClaimedTaxAmountCurr (for credit notes).
+	// AlreadyClaimedTaxAmountCurr is the tax amount claimed in foreign currency.
 	AlreadyClaimedTaxAmountCurr types.Decimal `xml:"AlreadyClaimedTaxAmountCurr,omitempty"`
 
-	// AlreadyClaimedTaxAmount (for credit notes).
+	// AlreadyClaimedTaxAmount is the tax amount claimed by advance tax documents.
 	AlreadyClaimedTaxAmount types.Decimal `xml:"AlreadyClaimedTaxAmount,omitempty"`
 
-	// AlreadyClaimedTaxInclusiveAmountCurr (for credit notes).
+	// AlreadyClaimedTaxInclusiveAmountCurr is the tax-inclusive amount claimed in foreign currency.
 	AlreadyClaimedTaxInclusiveAmountCurr types.Decimal `xml:"AlreadyClaimedTaxInclusiveAmountCurr,omitempty"`
 
-	// AlreadyClaimedTaxInclusiveAmount (for credit notes).
+	// AlreadyClaimedTaxInclusiveAmount is the tax-inclusive amount claimed by advance tax documents.
 	AlreadyClaimedTaxInclusiveAmount types.Decimal `xml:"AlreadyClaimedTaxInclusiveAmount,omitempty"`
 
-	// DifferenceTaxableAmountCurr (for credit notes).
+	// DifferenceTaxableAmountCurr is the taxable amount difference in foreign currency.
 	DifferenceTaxableAmountCurr types.Decimal `xml:"DifferenceTaxableAmountCurr,omitempty"`
 
-	// DifferenceTaxableAmount (for credit notes).
+	// DifferenceTaxableAmount is the taxable amount not yet claimed.
 	DifferenceTaxableAmount types.Decimal `xml:"DifferenceTaxableAmount,omitempty"`
 
-	// DifferenceTaxAmountCurr (for credit notes).
+	// DifferenceTaxAmountCurr is the tax amount difference in foreign currency.
 	DifferenceTaxAmountCurr types.Decimal `xml:"DifferenceTaxAmountCurr,omitempty"`
 
-	// DifferenceTaxAmount (for credit notes).
+	// DifferenceTaxAmount is the tax amount not yet claimed.
 	DifferenceTaxAmount types.Decimal `xml:"DifferenceTaxAmount,omitempty"`
 
-	// DifferenceTaxInclusiveAmountCurr (for credit notes).
+	// DifferenceTaxInclusiveAmountCurr is the tax-inclusive amount difference in foreign currency.
 	DifferenceTaxInclusiveAmountCurr types.Decimal `xml:"DifferenceTaxInclusiveAmountCurr,omitempty"`
 
-	// DifferenceTaxInclusiveAmount (for credit notes).
+	// DifferenceTaxInclusiveAmount is the tax-inclusive amount not yet claimed.
 	DifferenceTaxInclusiveAmount types.Decimal `xml:"DifferenceTaxInclusiveAmount,omitempty"`
 
 	// TaxCategory contains the tax category information.
 	TaxCategory TaxCategory `xml:"TaxCategory"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // TaxCategory contains tax category information in tax totals.
@@ -87,6 +93,9 @@ type TaxCategory struct {
 
 	// LocalReverseChargeFlag indicates local reverse charge.
 	LocalReverseChargeFlag types.Bool `xml:"LocalReverseChargeFlag,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // LegalMonetaryTotal contains document totals.
@@ -103,28 +112,28 @@ type LegalMonetaryTotal struct {
 	// TaxInclusiveAmountCurr is the total with tax in foreign currency.
 	TaxInclusiveAmountCurr types.Decimal `xml:"TaxInclusiveAmountCurr,omitempty"`
 
-	// AlreadyClaimedTaxExclusiveAmount (for credit notes).
+	// AlreadyClaimedTaxExclusiveAmount is the tax-exclusive amount claimed by advance tax documents.
 	AlreadyClaimedTaxExclusiveAmount types.Decimal `xml:"AlreadyClaimedTaxExclusiveAmount,omitempty"`
 
-	// AlreadyClaimedTaxExclusiveAmountCurr (for credit notes).
+	// AlreadyClaimedTaxExclusiveAmountCurr is the tax-exclusive amount claimed in foreign currency.
 	AlreadyClaimedTaxExclusiveAmountCurr types.Decimal `xml:"AlreadyClaimedTaxExclusiveAmountCurr,omitempty"`
 
-	// AlreadyClaimedTaxInclusiveAmount (for credit notes).
+	// AlreadyClaimedTaxInclusiveAmount is the tax-inclusive amount claimed by advance tax documents.
 	AlreadyClaimedTaxInclusiveAmount types.Decimal `xml:"AlreadyClaimedTaxInclusiveAmount,omitempty"`
 
-	// AlreadyClaimedTaxInclusiveAmountCurr (for credit notes).
+	// AlreadyClaimedTaxInclusiveAmountCurr is the tax-inclusive amount claimed in foreign currency.
 	AlreadyClaimedTaxInclusiveAmountCurr types.Decimal `xml:"AlreadyClaimedTaxInclusiveAmountCurr,omitempty"`
 
-	// DifferenceTaxExclusiveAmount (for credit notes).
+	// DifferenceTaxExclusiveAmount is the tax-exclusive amount not yet claimed.
 	DifferenceTaxExclusiveAmount types.Decimal `xml:"DifferenceTaxExclusiveAmount,omitempty"`
 
-	// DifferenceTaxExclusiveAmountCurr (for credit notes).
+	// DifferenceTaxExclusiveAmountCurr is the tax-exclusive amount difference in foreign currency.
 	DifferenceTaxExclusiveAmountCurr types.Decimal `xml:"DifferenceTaxExclusiveAmountCurr,omitempty"`
 
-	// DifferenceTaxInclusiveAmount (for credit notes).
+	// DifferenceTaxInclusiveAmount is the tax-inclusive amount not yet claimed.
 	DifferenceTaxInclusiveAmount types.Decimal `xml:"DifferenceTaxInclusiveAmount,omitempty"`
 
-	// DifferenceTaxInclusiveAmountCurr (for credit notes).
+	// DifferenceTaxInclusiveAmountCurr is the tax-inclusive amount difference in foreign currency.
 	DifferenceTaxInclusiveAmountCurr types.Decimal `xml:"DifferenceTaxInclusiveAmountCurr,omitempty"`
 
 	// PayableRoundingAmount is the rounding amount.
@@ -144,11 +153,15 @@ type LegalMonetaryTotal struct {
 
 	// PayableAmountCurr is the final payable amount in foreign currency.
 	PayableAmountCurr types.Decimal `xml:"PayableAmountCurr,omitempty"`
+
+	// Unknown holds unmodeled XML content preserved by lossless decoding.
+	Unknown Unknown `xml:"-" json:"-"`
 }
 
 // NonTaxedDeposits is a collection of proforma invoices (without VAT).
 type NonTaxedDeposits struct {
 	NonTaxedDeposit []NonTaxedDeposit `xml:"NonTaxedDeposit"`
+	Unknown         Unknown           `xml:"-" json:"-"`
 }
 
 // NonTaxedDeposit represents a proforma invoice deposit.
@@ -157,11 +170,13 @@ type NonTaxedDeposit struct {
 	VariableSymbol    string        `xml:"VariableSymbol,omitempty"`
 	DepositAmountCurr types.Decimal `xml:"DepositAmountCurr,omitempty"`
 	DepositAmount     types.Decimal `xml:"DepositAmount"`
+	Unknown           Unknown       `xml:"-" json:"-"`
 }
 
 // TaxedDeposits is a collection of taxed deposits.
 type TaxedDeposits struct {
 	TaxedDeposit []TaxedDeposit `xml:"TaxedDeposit"`
+	Unknown      Unknown        `xml:"-" json:"-"`
 }
 
 // TaxedDeposit represents a taxed deposit (advance invoice).
@@ -173,4 +188,5 @@ type TaxedDeposit struct {
 	TaxInclusiveDepositAmountCurr types.Decimal         `xml:"TaxInclusiveDepositAmountCurr,omitempty"`
 	TaxInclusiveDepositAmount     types.Decimal         `xml:"TaxInclusiveDepositAmount"`
 	ClassifiedTaxCategory         ClassifiedTaxCategory `xml:"ClassifiedTaxCategory"`
+	Unknown                       Unknown               `xml:"-" json:"-"`
 }
@@ -0,0 +1,58 @@
+package schema
+
+import "encoding/xml"
+
+// Unknown holds XML content that is not modeled by the schema structs.
+//
+// It is populated only by lossless decoding and is ignored by encoding/xml and
+// encoding/json. The ISDOC encoder re-emits it next to the known sibling it
+// originally followed, so decoding and re-encoding a document keeps vendor
+// elements, signatures, comments and processing instructions in place.
+type Unknown struct {
+	// Nodes are unknown child elements, comments, processing instructions and
+	// non-whitespace text of this element.
+	Nodes []UnknownNode
+
+	// Attrs are unknown attributes of this element and of its leaf children,
+	// including namespace declarations.
+	Attrs []UnknownAttr
+
+	// Prolog holds comments and processing instructions before the root
+	// element. It is only set on root documents.
+	Prolog [][]byte
+
+	// Epilog holds comments and processing instructions after the root
+	// element. It is only set on root documents.
+	Epilog [][]byte
+}
+
+// IsZero reports whether no unknown content was captured.
+func (u Unknown) IsZero() bool {
+	return len(u.Nodes) == 0 && len(u.Attrs) == 0 && len(u.Prolog) == 0 && len(u.Epilog) == 0
+}
+
+// UnknownNode is a single unknown node, stored verbatim.
+type UnknownNode struct {
+	// After is the local name of the known sibling element the node follows.
+	// Empty means the node precedes all known siblings.
+	After string
+
+	// AfterIndex is the zero-based occurrence of After among its siblings.
+	AfterIndex int
+
+	// Raw is the node markup exactly as it appeared in the source.
+	Raw []byte
+}
+
+// UnknownAttr is an attribute not modeled by the schema structs.
+type UnknownAttr struct {
+	// Element is the local name of the leaf child carrying the attribute.
+	// Empty means the attribute belongs to the element holding the Unknown.
+	Element string
+
+	// Index is the zero-based occurrence of Element among its siblings.
+	Index int
+
+	// Attr is the attribute with its original prefix in Name.Space.
+	Attr xml.Attr
+}
@@ -12,7 +12,7 @@ import (
 func TestSchematronOriginalDocumentLink(t *testing.T) {
 	tests := []struct {
 		name          string
-		documentType  int
+		documentType  types.DocumentType
 		hasReferences bool
 		expectError   bool
 	}{
@@ -469,6 +469,7 @@ func createValidInvoice() *schema.Invoice {
 		ID:                "INV-001",
 		UUID:              types.MustUUID("123e4567-e89b-12d3-a456-426614174000"),
 		IssueDate:         types.MustParseDate("2024-01-15"),
+		TaxPointDate:      types.MustParseDate("2024-01-15"),
 		VATApplicable:     types.Bool(true),
 		LocalCurrencyCode: "CZK",
 		CurrRate:          types.MustDecimal("1"),
@@ -0,0 +1,438 @@
+package isdoc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+var (
+	unmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+	extensionsType  = reflect.TypeOf(schema.Extensions{})
+)
+
+// walkSource walks the XML document a second time, mirroring the schema
+// structs. It records the namespaces in scope of each Extensions element.
+// With opts.Lossless it records content the structs do not model into their
+// Unknown fields; with opts.SourceMap it records the position of each
+// decoded element.
+//
+// root must point to a struct already populated by xml.Unmarshal from the
+// same data. Raw tokens are used so namespace prefixes are kept as written.
+func walkSource(data []byte, root any, opts DecodeOptions) error {
+	w := &sourceWalker{
+		data:      data,
+		d:         xml.NewDecoder(bytes.NewReader(data)),
+		lossless:  opts.Lossless,
+		positions: opts.SourceMap,
+	}
+	if w.positions != nil {
+		w.positions.reset(data)
+	}
+
+	rv := reflect.ValueOf(root).Elem()
+	u := w.unknownOf(rv)
+
+	inRoot := false
+	for {
+		off := w.d.InputOffset()
+		tok, err := w.token()
+		if err == io.EOF && inRoot {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			w.appendMisc(u, inRoot, off)
+		case xml.Comment, xml.Directive:
+			w.appendMisc(u, inRoot, off)
+		case xml.StartElement:
+			if inRoot {
+				return fmt.Errorf("unexpected element %s after root", t.Name.Local)
+			}
+			if u != nil {
+				for _, a := range t.Attr {
+					if a.Name.Space == "" && (a.Name.Local == "xmlns" || a.Name.Local == "version") {
+						continue
+					}
+					u.Attrs = append(u.Attrs, schema.UnknownAttr{Attr: a})
+				}
+			}
+			w.record(t.Name.Local, off)
+			if err := w.walk(rv, t.Name.Local); err != nil {
+				return err
+			}
+			inRoot = true
+		}
+	}
+}
+
+// sourceWalker mirrors the schema structs while reading raw XML tokens.
+type sourceWalker struct {
+	data      []byte
+	d         *xml.Decoder
+	lossless  bool
+	positions *SourceMap
+
+	// scopes holds the prefix to namespace bindings in scope for each open
+	// element. Raw tokens leave prefixes unresolved.
+	scopes []map[string]string
+}
+
+// token reads the next raw token and tracks namespace declarations.
+func (w *sourceWalker) token() (xml.Token, error) {
+	tok, err := w.d.RawToken()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case xml.StartElement:
+		var parent map[string]string
+		if len(w.scopes) > 0 {
+			parent = w.scopes[len(w.scopes)-1]
+		}
+		scope, copied := parent, false
+		for _, a := range t.Attr {
+			prefix, ok := "", false
+			switch {
+			case a.Name.Space == "" && a.Name.Local == "xmlns":
+				ok = true
+			case a.Name.Space == "xmlns":
+				prefix, ok = a.Name.Local, true
+			}
+			if !ok {
+				continue
+			}
+			if !copied {
+				scope = maps.Clone(parent)
+				if scope == nil {
+					scope = make(map[string]string)
+				}
+				copied = true
+			}
+			scope[prefix] = a.Value
+		}
+		w.scopes = append(w.scopes, scope)
+	case xml.EndElement:
+		if len(w.scopes) > 0 {
+			w.scopes = w.scopes[:len(w.scopes)-1]
+		}
+	}
+	return tok, nil
+}
+
+// foreign reports whether the element name, read by token, belongs to a
+// namespace other than ISDOC. Elements without a namespace count as ISDOC.
+func (w *sourceWalker) foreign(name xml.Name) bool {
+	space := name.Space
+	if len(w.scopes) > 0 {
+		if ns, ok := w.scopes[len(w.scopes)-1][name.Space]; ok {
+			space = ns
+		}
+	}
+	return isForeignNamespace(space)
+}
+
+// isForeignNamespace reports whether the resolved namespace space is neither
+// empty nor the ISDOC namespace.
+func isForeignNamespace(space string) bool {
+	return space != "" && space != schema.Namespace
+}
+
+// unknownOf returns the Unknown field of v when preserving content.
+func (w *sourceWalker) unknownOf(v reflect.Value) *schema.Unknown {
+	if !w.lossless {
+		return nil
+	}
+	return unknownOf(v)
+}
+
+// record stores the position of the element at path starting at off.
+func (w *sourceWalker) record(path string, off int64) {
+	if w.positions != nil {
+		w.positions.paths[path] = off
+	}
+}
+
+// raw returns a copy of the source bytes from off to the current offset.
+func (w *sourceWalker) raw(off int64) []byte {
+	return bytes.Clone(w.data[off:w.d.InputOffset()])
+}
+
+// appendMisc records a comment, processing instruction or directive found
+// outside the root element.
+func (w *sourceWalker) appendMisc(u *schema.Unknown, afterRoot bool, off int64) {
+	if u == nil {
+		return
+	}
+	if afterRoot {
+		u.Epilog = append(u.Epilog, w.raw(off))
+	} else {
+		u.Prolog = append(u.Prolog, w.raw(off))
+	}
+}
+
+// walk reads the children of struct v, found at path, up to its end element.
+func (w *sourceWalker) walk(v reflect.Value, path string) error {
+	ti := getTypeInfo(v.Type())
+	u := w.unknownOf(v)
+	counts := make(map[string]int)
+	after, afterIndex := "", 0
+
+	appendNode := func(off int64) {
+		if u != nil {
+			u.Nodes = append(u.Nodes, schema.UnknownNode{After: after, AfterIndex: afterIndex, Raw: w.raw(off)})
+		}
+	}
+
+	for {
+		off := w.d.InputOffset()
+		tok, err := w.token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			f, known := ti.elements[t.Name.Local]
+			if !known || w.foreign(t.Name) {
+				if err := w.skip(); err != nil {
+					return err
+				}
+				appendNode(off)
+				continue
+			}
+
+			index := counts[f.name]
+			counts[f.name]++
+			after, afterIndex = f.name, index
+
+			field := v.Field(f.index)
+			childPath := path + "." + f.name
+			if field.Kind() == reflect.Slice {
+				childPath = fmt.Sprintf("%s[%d]", childPath, index)
+			}
+			w.record(childPath, off)
+
+			child := childValue(field, index)
+			if child.IsValid() && child.Type() == extensionsType {
+				ext := child.Addr().Interface().(*schema.Extensions)
+				ext.Scope = maps.Clone(w.scopes[len(w.scopes)-1])
+			}
+			if !child.IsValid() || isLeafType(child.Type()) {
+				if u != nil {
+					for _, a := range unknownAttrs(child, t.Attr) {
+						u.Attrs = append(u.Attrs, schema.UnknownAttr{Element: f.name, Index: index, Attr: a})
+					}
+				}
+				if err := w.skip(); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if cu := w.unknownOf(child); cu != nil {
+				for _, a := range unknownAttrs(child, t.Attr) {
+					cu.Attrs = append(cu.Attrs, schema.UnknownAttr{Attr: a})
+				}
+			}
+			if err := w.walk(child, childPath); err != nil {
+				return err
+			}
+
+		case xml.EndElement:
+			return nil
+
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				appendNode(off)
+			}
+
+		case xml.Comment, xml.ProcInst, xml.Directive:
+			appendNode(off)
+		}
+	}
+}
+
+// skip reads tokens up to the end of the current element.
+func (w *sourceWalker) skip() error {
+	for depth := 1; depth > 0; {
+		tok, err := w.token()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// childValue returns the struct value decoded for the index-th occurrence
+// of a field, or an invalid value if there is none.
+func childValue(field reflect.Value, index int) reflect.Value {
+	switch field.Kind() {
+	case reflect.Ptr:
+		if field.IsNil() {
+			return reflect.Value{}
+		}
+		return field.Elem()
+	case reflect.Slice:
+		if index >= field.Len() {
+			return reflect.Value{}
+		}
+		return childValue(field.Index(index), 0)
+	}
+	return field
+}
+
+// isLeafType reports whether t is decoded from a single element without
+// child elements of its own.
+func isLeafType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	return getTypeInfo(t).leaf
+}
+
+// unknownAttrs returns the attributes not modeled by the struct type of v.
+func unknownAttrs(v reflect.Value, attrs []xml.Attr) []xml.Attr {
+	known := make(map[string]bool)
+	if v.IsValid() && v.Kind() == reflect.Struct && !reflect.PointerTo(v.Type()).Implements(unmarshalerType) {
+		for _, f := range getTypeInfo(v.Type()).fields {
+			if f.attr {
+				known[f.name] = true
+			}
+		}
+	}
+
+	var unknown []xml.Attr
+	for _, a := range attrs {
+		if a.Name.Space == "" && known[a.Name.Local] {
+			continue
+		}
+		unknown = append(unknown, a)
+	}
+	return unknown
+}
+
+// needsSourceWalk reports whether decoding data with opts requires
+// walkSource. Without Lossless or SourceMap it is needed only to record the
+// namespace scope of Extensions, which matters when data declares foreign
+// namespaces.
+func needsSourceWalk(data []byte, opts DecodeOptions) bool {
+	return opts.Lossless || opts.SourceMap != nil ||
+		bytes.Contains(data, []byte("Extensions")) && hasForeignNamespaces(data)
+}
+
+// xsiNamespace is the XML Schema instance namespace, used for attributes
+// only.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// hasForeignNamespaces reports whether data declares a namespace other than
+// ISDOC and XML Schema instance. Without one no element can be foreign, so
+// the scan saves tokenizing the document. Text that looks like a
+// declaration only costs that pass.
+func hasForeignNamespaces(data []byte) bool {
+	for {
+		i := bytes.Index(data, []byte("xmlns"))
+		if i < 0 {
+			return false
+		}
+		data = data[i+len("xmlns"):]
+		rest := bytes.TrimLeft(data, " \t\r\n")
+		if len(rest) > 0 && rest[0] == ':' {
+			j := bytes.IndexAny(rest, "= \t\r\n")
+			if j < 0 {
+				return false
+			}
+			rest = bytes.TrimLeft(rest[j:], " \t\r\n")
+		}
+		if len(rest) == 0 || rest[0] != '=' {
+			continue
+		}
+		rest = bytes.TrimLeft(rest[1:], " \t\r\n")
+		if len(rest) == 0 || rest[0] != '"' && rest[0] != '\'' {
+			continue
+		}
+		end := bytes.IndexByte(rest[1:], rest[0])
+		if end < 0 {
+			return false
+		}
+		if uri := string(rest[1 : 1+end]); uri != "" && uri != schema.Namespace && uri != xsiNamespace {
+			return true
+		}
+	}
+}
+
+// withoutForeignElements returns data with the elements of foreign
+// namespaces removed from the ISDOC structure, so xml.Unmarshal, which
+// matches elements by local name, does not decode them into ISDOC fields.
+// Extensions content is left as is. If data cannot be parsed it is returned
+// unchanged for xml.Unmarshal to report the error.
+func withoutForeignElements(data []byte) []byte {
+	if !hasForeignNamespaces(data) {
+		return data
+	}
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var cuts [][2]int64
+	depth := 0
+
+	for {
+		off := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return data
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				continue
+			}
+			foreign := isForeignNamespace(t.Name.Space)
+			if !foreign && t.Name.Local != "Extensions" {
+				continue
+			}
+			if err := d.Skip(); err != nil {
+				return data
+			}
+			depth--
+			if foreign {
+				cuts = append(cuts, [2]int64{off, d.InputOffset()})
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if len(cuts) == 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	prev := int64(0)
+	for _, c := range cuts {
+		out = append(out, data[prev:c[0]]...)
+		prev = c[1]
+	}
+	return append(out, data[prev:]...)
+}
@@ -0,0 +1,194 @@
+package isdoc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+// ErrStreamOrder is returned when StreamEncoder methods are called out of order.
+var ErrStreamOrder = errors.New("stream encoder methods called out of order")
+
+// streamState tracks which part of the document a StreamEncoder has written.
+type streamState int
+
+const (
+	streamInit streamState = iota
+	streamLines
+	streamDone
+)
+
+// StreamEncoder writes an ISDOC invoice incrementally.
+//
+// Unlike Encoder.Encode, which builds the whole document in memory, a
+// StreamEncoder writes each top-level element and each invoice line to the
+// underlying writer as soon as it is encoded. Memory use is bounded by the
+// largest single element, not by the number of lines.
+//
+// Elements are written in XSD order. Everything that precedes InvoiceLines is
+// written by WriteHeader, everything that follows it by WriteFooter:
+//
+//	enc := isdoc.NewStreamEncoder(w)
+//	if err := enc.WriteHeader(header); err != nil {
+//	    return err
+//	}
+//	for _, line := range lines {
+//	    if err := enc.WriteLine(&line); err != nil {
+//	        return err
+//	    }
+//	}
+//	if err := enc.WriteFooter(totals); err != nil {
+//	    return err
+//	}
+type StreamEncoder struct {
+	enc   *Encoder
+	buf   bytes.Buffer
+	state streamState
+	lines int
+
+	// lineNodes writes unknown content preserved inside InvoiceLines.
+	lineNodes *childWriter
+}
+
+// NewStreamEncoder creates a new StreamEncoder that writes to w.
+func NewStreamEncoder(w io.Writer) *StreamEncoder {
+	return &StreamEncoder{enc: NewEncoder(w)}
+}
+
+// SetIndent sets the indentation string. Default is two spaces.
+func (s *StreamEncoder) SetIndent(indent string) {
+	s.enc.SetIndent(indent)
+}
+
+// SetXMLDeclaration controls whether to add XML declaration. Default is true.
+func (s *StreamEncoder) SetXMLDeclaration(add bool) {
+	s.enc.SetXMLDeclaration(add)
+}
+
+// Lines returns the number of invoice lines written so far.
+func (s *StreamEncoder) Lines() int {
+	return s.lines
+}
+
+// WriteHeader writes the XML declaration, the root element and all invoice
+// elements that precede InvoiceLines. Lines in the InvoiceLines field of inv
+// are ignored.
+func (s *StreamEncoder) WriteHeader(inv *schema.Invoice) error {
+	if s.state != streamInit {
+		return fmt.Errorf("WriteHeader: %w", ErrStreamOrder)
+	}
+
+	s.enc.writeRootStart(&s.buf, "Invoice", inv.Version, &inv.Unknown)
+
+	header, _ := splitInvoiceSequence()
+	c := s.enc.newChildWriter(reflect.ValueOf(inv).Elem(), 1)
+	c.writeLeading(&s.buf)
+	for _, elemName := range header {
+		if err := s.writeElement(c, elemName); err != nil {
+			return err
+		}
+	}
+
+	s.buf.WriteString(s.enc.indent)
+	s.buf.WriteString("<InvoiceLines")
+	for _, a := range inv.InvoiceLines.Unknown.Attrs {
+		if a.Element == "" {
+			writeAttr(&s.buf, a.Attr)
+		}
+	}
+	s.buf.WriteString(">\n")
+	s.lineNodes = s.enc.newChildWriter(reflect.ValueOf(&inv.InvoiceLines).Elem(), 2)
+	s.lineNodes.writeLeading(&s.buf)
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	s.state = streamLines
+	return nil
+}
+
+// WriteLine encodes a single invoice line and writes it to the underlying writer.
+func (s *StreamEncoder) WriteLine(line *schema.InvoiceLine) error {
+	if s.state != streamLines {
+		return fmt.Errorf("WriteLine: %w", ErrStreamOrder)
+	}
+
+	if err := s.enc.encodeValue(&s.buf, "InvoiceLine", reflect.ValueOf(line).Elem(), 2); err != nil {
+		return err
+	}
+	s.lineNodes.writeNodes(&s.buf, "InvoiceLine", s.lines, s.lines)
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	s.lines++
+	return nil
+}
+
+// WriteFooter closes InvoiceLines and writes all invoice elements that follow
+// it (deposits, TaxTotal, LegalMonetaryTotal, PaymentMeans, ...), then closes
+// the root element. Only those fields of inv are used.
+func (s *StreamEncoder) WriteFooter(inv *schema.Invoice) error {
+	if s.state != streamLines {
+		return fmt.Errorf("WriteFooter: %w", ErrStreamOrder)
+	}
+
+	s.lineNodes.writeRemaining(&s.buf)
+	s.buf.WriteString(s.enc.indent)
+	s.buf.WriteString("</InvoiceLines>\n")
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	header, footer := splitInvoiceSequence()
+	c := s.enc.newChildWriter(reflect.ValueOf(inv).Elem(), 1)
+	c.skipNodes(header)
+	c.writeNodes(&s.buf, "InvoiceLines", 0, -1)
+	for _, elemName := range footer {
+		if err := s.writeElement(c, elemName); err != nil {
+			return err
+		}
+	}
+	c.writeRemaining(&s.buf)
+
+	s.enc.writeRootEnd(&s.buf, "Invoice", &inv.Unknown)
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	s.state = streamDone
+	return nil
+}
+
+// writeElement encodes a single top-level element and flushes it.
+func (s *StreamEncoder) writeElement(c *childWriter, elemName string) error {
+	if err := c.writeElement(&s.buf, elemName); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// flush writes the buffered output to the underlying writer and resets the buffer.
+func (s *StreamEncoder) flush() error {
+	if s.buf.Len() == 0 {
+		return nil
+	}
+	_, err := s.enc.writer.Write(s.buf.Bytes())
+	s.buf.Reset()
+	return err
+}
+
+// splitInvoiceSequence splits the Invoice element order around InvoiceLines.
+func splitInvoiceSequence() (header, footer []string) {
+	order := getTypeInfo(reflect.TypeOf(schema.Invoice{})).order
+	for i, elemName := range order {
+		if elemName == "InvoiceLines" {
+			return order[:i], order[i+1:]
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,161 @@
+package isdoc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+)
+
+func TestStreamEncoderMatchesEncode(t *testing.T) {
+	fixtures := []string{
+		"sample.isdoc",
+		"multi-partytax.isdoc",
+		"test001.isdoc",
+	}
+
+	for _, name := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "fixtures", name))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			invoice, err := DecodeBytes(data)
+			if err != nil {
+				t.Fatalf("DecodeBytes failed: %v", err)
+			}
+
+			want, err := EncodeBytes(invoice)
+			if err != nil {
+				t.Fatalf("EncodeBytes failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			enc := NewStreamEncoder(&buf)
+			if err := enc.WriteHeader(invoice); err != nil {
+				t.Fatalf("WriteHeader failed: %v", err)
+			}
+			for i := range invoice.InvoiceLines.InvoiceLine {
+				if err := enc.WriteLine(&invoice.InvoiceLines.InvoiceLine[i]); err != nil {
+					t.Fatalf("WriteLine failed: %v", err)
+				}
+			}
+			if err := enc.WriteFooter(invoice); err != nil {
+				t.Fatalf("WriteFooter failed: %v", err)
+			}
+
+			if enc.Lines() != len(invoice.InvoiceLines.InvoiceLine) {
+				t.Errorf("Lines() = %d, want %d", enc.Lines(), len(invoice.InvoiceLines.InvoiceLine))
+			}
+			if buf.String() != string(want) {
+				t.Errorf("stream output differs from Encode output\nstream:\n%s\nencode:\n%s", buf.String(), want)
+			}
+		})
+	}
+}
+
+// countingWriter records the size of each Write call.
+type countingWriter struct {
+	writes []int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return len(p), nil
+}
+
+func TestStreamEncoderFlushesPerLine(t *testing.T) {
+	invoice := createValidInvoice()
+	line := invoice.InvoiceLines.InvoiceLine[0]
+
+	w := &countingWriter{}
+	enc := NewStreamEncoder(w)
+	if err := enc.WriteHeader(invoice); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+
+	before := len(w.writes)
+	for i := 0; i < 1000; i++ {
+		if err := enc.WriteLine(&line); err != nil {
+			t.Fatalf("WriteLine failed: %v", err)
+		}
+	}
+	if got := len(w.writes) - before; got != 1000 {
+		t.Fatalf("expected one write per line, got %d writes", got)
+	}
+
+	// Each line is flushed on its own, so writes do not grow with the line count
+	first, last := w.writes[before], w.writes[len(w.writes)-1]
+	if first != last {
+		t.Errorf("line write size grew from %d to %d bytes", first, last)
+	}
+
+	if err := enc.WriteFooter(invoice); err != nil {
+		t.Fatalf("WriteFooter failed: %v", err)
+	}
+}
+
+func TestStreamEncoderOutputDecodes(t *testing.T) {
+	invoice := createValidInvoice()
+
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf)
+	if err := enc.WriteHeader(invoice); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		line := invoice.InvoiceLines.InvoiceLine[0]
+		line.ID = string(rune('1' + i))
+		if err := enc.WriteLine(&line); err != nil {
+			t.Fatalf("WriteLine failed: %v", err)
+		}
+	}
+	if err := enc.WriteFooter(invoice); err != nil {
+		t.Fatalf("WriteFooter failed: %v", err)
+	}
+
+	decoded, err := DecodeBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	if len(decoded.InvoiceLines.InvoiceLine) != 3 {
+		t.Errorf("expected 3 lines, got %d", len(decoded.InvoiceLines.InvoiceLine))
+	}
+	if decoded.ID != invoice.ID {
+		t.Errorf("ID mismatch: %q vs %q", decoded.ID, invoice.ID)
+	}
+	if !strings.HasSuffix(buf.String(), "</Invoice>\n") {
+		t.Error("missing closing Invoice element")
+	}
+}
+
+func TestStreamEncoderOrder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(&buf)
+
+	if err := enc.WriteLine(&schema.InvoiceLine{ID: "1"}); !errors.Is(err, ErrStreamOrder) {
+		t.Errorf("WriteLine before WriteHeader: expected ErrStreamOrder, got %v", err)
+	}
+	if err := enc.WriteFooter(&schema.Invoice{}); !errors.Is(err, ErrStreamOrder) {
+		t.Errorf("WriteFooter before WriteHeader: expected ErrStreamOrder, got %v", err)
+	}
+
+	inv := &schema.Invoice{Version: "6.0.2", ID: "X"}
+	if err := enc.WriteHeader(inv); err != nil {
+		t.Fatalf("WriteHeader failed: %v", err)
+	}
+	if err := enc.WriteHeader(inv); !errors.Is(err, ErrStreamOrder) {
+		t.Errorf("second WriteHeader: expected ErrStreamOrder, got %v", err)
+	}
+	if err := enc.WriteFooter(inv); err != nil {
+		t.Fatalf("WriteFooter failed: %v", err)
+	}
+	if err := enc.WriteLine(&schema.InvoiceLine{ID: "1"}); !errors.Is(err, ErrStreamOrder) {
+		t.Errorf("WriteLine after WriteFooter: expected ErrStreamOrder, got %v", err)
+	}
+}
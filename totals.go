@@ -0,0 +1,344 @@
+package isdoc
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// FillDefaults completes a hand-written invoice: it sets Version to
+// schema.Version, generates a UUID, defaults CurrRate and RefCurrRate to 1,
+// numbers lines without an ID, marks lines of a VAT-applicable invoice as
+// VATApplicable and computes totals with ComputeTotals. A single Payment
+// without PaidAmount is set to pay the PayableAmount.
+// Values already present are kept, except computed amounts.
+func FillDefaults(inv *schema.Invoice) error {
+	if inv.Version == "" {
+		inv.Version = schema.Version
+	}
+	if inv.UUID.IsZero() {
+		inv.UUID = types.NewRandomUUID()
+	}
+	if inv.CurrRate.IsZero() {
+		inv.CurrRate = "1"
+	}
+	if inv.RefCurrRate.IsZero() {
+		inv.RefCurrRate = "1"
+	}
+	for i := range inv.InvoiceLines.InvoiceLine {
+		line := &inv.InvoiceLines.InvoiceLine[i]
+		if line.ID == "" {
+			line.ID = fmt.Sprint(i + 1)
+		}
+		if inv.VATApplicable.Bool() && line.ClassifiedTaxCategory.LocalReverseCharge == nil {
+			line.ClassifiedTaxCategory.VATApplicable = true
+		}
+	}
+	if err := ComputeTotals(inv); err != nil {
+		return err
+	}
+	if pm := inv.PaymentMeans; pm != nil && len(pm.Payment) == 1 && pm.Payment[0].PaidAmount.IsZero() {
+		pm.Payment[0].PaidAmount = inv.LegalMonetaryTotal.PayableAmount
+	}
+	return nil
+}
+
+// ComputeTotals computes line amounts, the TaxTotal recapitulation and
+// LegalMonetaryTotal from line quantities, unit prices and VAT rates.
+//
+// Lines with VATCalculationMethod 1 (from top) are computed from
+// UnitPriceTaxInclusive, other lines from UnitPrice. A missing quantity
+// counts as 1; lines without a unit price keep their given amount. Lines
+// with local reverse charge, or on an invoice with VATApplicable false,
+// carry no tax. Amounts are rounded to two decimal places, halves away from
+// zero.
+//
+// TaxSubTotal entries are rebuilt per rate, keeping their AlreadyClaimed*
+// amounts; a rate claimed by deposits but without lines keeps its subtotal
+// with zero amounts. The Difference* totals are the totals less the
+// AlreadyClaimed* amounts. PayableAmount is the difference less paid
+// deposits, whatever their sign, plus PayableRoundingAmount. Foreign
+// currency (*Curr) amounts are left unchanged, except those of rebuilt
+// subtotals, which are dropped.
+func ComputeTotals(inv *schema.Invoice) error {
+	type subtotal struct {
+		category                schema.TaxCategory
+		taxable, tax, inclusive *big.Rat
+	}
+	var subtotals []*subtotal
+	byRate := make(map[string]*subtotal)
+
+	// Amounts claimed by earlier documents are kept per rate
+	prevSubtotals := inv.TaxTotal.TaxSubTotal
+	claimed := make(map[string]schema.TaxSubTotal)
+	for _, st := range prevSubtotals {
+		claimed[taxRateKey(st.TaxCategory.Percent, st.TaxCategory.LocalReverseChargeFlag.Bool())] = st
+	}
+
+	net, gross := new(big.Rat), new(big.Rat)
+	for i := range inv.InvoiceLines.InvoiceLine {
+		line := &inv.InvoiceLines.InvoiceLine[i]
+		p := fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i)
+
+		lineNet, lineTax, lineGross, err := computeLine(inv, line, p)
+		if err != nil {
+			return err
+		}
+		net.Add(net, lineNet)
+		gross.Add(gross, lineGross)
+
+		category := line.ClassifiedTaxCategory
+		key := taxRateKey(category.Percent, category.LocalReverseCharge != nil)
+		st, ok := byRate[key]
+		if !ok {
+			st = &subtotal{
+				category: schema.TaxCategory{
+					Percent:                category.Percent,
+					VATApplicable:          inv.VATApplicable,
+					LocalReverseChargeFlag: types.Bool(category.LocalReverseCharge != nil),
+				},
+				taxable:   new(big.Rat),
+				tax:       new(big.Rat),
+				inclusive: new(big.Rat),
+			}
+			byRate[key] = st
+			subtotals = append(subtotals, st)
+		}
+		st.taxable.Add(st.taxable, lineNet)
+		st.tax.Add(st.tax, lineTax)
+		st.inclusive.Add(st.inclusive, lineGross)
+	}
+
+	taxAmount := new(big.Rat)
+	inv.TaxTotal.TaxSubTotal = nil
+	for _, st := range subtotals {
+		taxAmount.Add(taxAmount, st.tax)
+		key := taxRateKey(st.category.Percent, st.category.LocalReverseChargeFlag.Bool())
+		prev := claimed[key]
+		delete(claimed, key)
+		inv.TaxTotal.TaxSubTotal = append(inv.TaxTotal.TaxSubTotal, schema.TaxSubTotal{
+			TaxableAmount:                        formatAmount(st.taxable, 2),
+			TaxAmount:                            formatAmount(st.tax, 2),
+			TaxInclusiveAmount:                   formatAmount(st.inclusive, 2),
+			AlreadyClaimedTaxableAmount:          prev.AlreadyClaimedTaxableAmount,
+			AlreadyClaimedTaxableAmountCurr:      prev.AlreadyClaimedTaxableAmountCurr,
+			AlreadyClaimedTaxAmount:              prev.AlreadyClaimedTaxAmount,
+			AlreadyClaimedTaxAmountCurr:          prev.AlreadyClaimedTaxAmountCurr,
+			AlreadyClaimedTaxInclusiveAmount:     prev.AlreadyClaimedTaxInclusiveAmount,
+			AlreadyClaimedTaxInclusiveAmountCurr: prev.AlreadyClaimedTaxInclusiveAmountCurr,
+			TaxCategory:                          st.category,
+		})
+	}
+	// Rates only claimed by deposits keep a subtotal with zero amounts
+	for _, st := range prevSubtotals {
+		key := taxRateKey(st.TaxCategory.Percent, st.TaxCategory.LocalReverseChargeFlag.Bool())
+		if _, ok := claimed[key]; !ok || !hasClaims(&st) {
+			continue
+		}
+		delete(claimed, key)
+		zero := formatAmount(new(big.Rat), 2)
+		st.TaxableAmount, st.TaxAmount, st.TaxInclusiveAmount = zero, zero, zero
+		st.TaxableAmountCurr, st.TaxAmountCurr, st.TaxInclusiveAmountCurr = "", "", ""
+		inv.TaxTotal.TaxSubTotal = append(inv.TaxTotal.TaxSubTotal, st)
+	}
+	inv.TaxTotal.TaxAmount = formatAmount(taxAmount, 2)
+
+	total := &inv.LegalMonetaryTotal
+	total.TaxExclusiveAmount = formatAmount(net, 2)
+	total.TaxInclusiveAmount = formatAmount(gross, 2)
+	computeDifferences(inv, false)
+
+	return nil
+}
+
+// computeDifferences sets the Difference* amounts of tax subtotals and
+// LegalMonetaryTotal to the amounts less the AlreadyClaimed* ones, and
+// PayableAmount to the difference less paid deposits plus rounding. With
+// curr set, the foreign currency amounts are computed too.
+func computeDifferences(inv *schema.Invoice, curr bool) {
+	for i := range inv.TaxTotal.TaxSubTotal {
+		st := &inv.TaxTotal.TaxSubTotal[i]
+		subtractClaimed(st.TaxableAmount, &st.AlreadyClaimedTaxableAmount, &st.DifferenceTaxableAmount)
+		subtractClaimed(st.TaxAmount, &st.AlreadyClaimedTaxAmount, &st.DifferenceTaxAmount)
+		subtractClaimed(st.TaxInclusiveAmount, &st.AlreadyClaimedTaxInclusiveAmount, &st.DifferenceTaxInclusiveAmount)
+		if curr {
+			subtractClaimed(st.TaxableAmountCurr, &st.AlreadyClaimedTaxableAmountCurr, &st.DifferenceTaxableAmountCurr)
+			subtractClaimed(st.TaxAmountCurr, &st.AlreadyClaimedTaxAmountCurr, &st.DifferenceTaxAmountCurr)
+			subtractClaimed(st.TaxInclusiveAmountCurr, &st.AlreadyClaimedTaxInclusiveAmountCurr, &st.DifferenceTaxInclusiveAmountCurr)
+		}
+	}
+
+	total := &inv.LegalMonetaryTotal
+	subtractClaimed(total.TaxExclusiveAmount, &total.AlreadyClaimedTaxExclusiveAmount, &total.DifferenceTaxExclusiveAmount)
+	subtractClaimed(total.TaxInclusiveAmount, &total.AlreadyClaimedTaxInclusiveAmount, &total.DifferenceTaxInclusiveAmount)
+	total.PayableAmount = payableAmount(total.DifferenceTaxInclusiveAmount, total.PaidDepositsAmount, total.PayableRoundingAmount)
+	if curr {
+		subtractClaimed(total.TaxExclusiveAmountCurr, &total.AlreadyClaimedTaxExclusiveAmountCurr, &total.DifferenceTaxExclusiveAmountCurr)
+		subtractClaimed(total.TaxInclusiveAmountCurr, &total.AlreadyClaimedTaxInclusiveAmountCurr, &total.DifferenceTaxInclusiveAmountCurr)
+		total.PayableAmountCurr = payableAmount(total.DifferenceTaxInclusiveAmountCurr, total.PaidDepositsAmountCurr, total.PayableRoundingAmountCurr)
+	}
+}
+
+// subtractClaimed sets diff to amount less claimed, writing an empty
+// claimed amount as zero.
+func subtractClaimed(amount types.Decimal, claimed, diff *types.Decimal) {
+	c := parseAmount(*claimed)
+	*claimed = formatAmount(c, 2)
+	*diff = formatAmount(c.Sub(parseAmount(amount), c), 2)
+}
+
+// payableAmount returns the tax-inclusive difference less paid deposits,
+// whatever their sign, plus rounding.
+func payableAmount(difference, deposits, rounding types.Decimal) types.Decimal {
+	payable := parseAmount(difference)
+	payable.Sub(payable, new(big.Rat).Abs(parseAmount(deposits)))
+	payable.Add(payable, parseAmount(rounding))
+	return formatAmount(payable, 2)
+}
+
+// hasClaims reports whether a subtotal has amounts claimed by deposits.
+func hasClaims(st *schema.TaxSubTotal) bool {
+	return parseAmount(st.AlreadyClaimedTaxableAmount).Sign() != 0 ||
+		parseAmount(st.AlreadyClaimedTaxInclusiveAmount).Sign() != 0
+}
+
+// taxRateKey groups tax subtotals by rate and reverse charge.
+func taxRateKey(percent types.Decimal, reverseCharge bool) string {
+	return fmt.Sprintf("%s|%t", formatAmount(parseAmount(percent), 2), reverseCharge)
+}
+
+// computeLine sets the amounts of line and returns its tax-exclusive, tax
+// and tax-inclusive amounts.
+func computeLine(inv *schema.Invoice, line *schema.InvoiceLine, p string) (net, tax, gross *big.Rat, err error) {
+	qty := big.NewRat(1, 1)
+	if !line.InvoicedQuantity.Value.IsZero() {
+		if qty, err = ratFromDecimal(line.InvoicedQuantity.Value, p+".InvoicedQuantity"); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	category := line.ClassifiedTaxCategory
+	rate := new(big.Rat)
+	if inv.VATApplicable.Bool() && category.LocalReverseCharge == nil {
+		if rate, err = optionalRat(category.Percent, p+".ClassifiedTaxCategory.Percent"); err != nil {
+			return nil, nil, nil, err
+		}
+		rate.Quo(rate, big.NewRat(100, 1))
+	}
+	factor := new(big.Rat).Add(big.NewRat(1, 1), rate)
+
+	if category.VATCalculationMethod == types.VATCalculationFromTop {
+		unit, err := optionalRat(line.UnitPriceTaxInclusive, p+".UnitPriceTaxInclusive")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch {
+		case unit.Sign() != 0:
+			gross = roundAmount(new(big.Rat).Mul(unit, qty), 2)
+			line.UnitPrice = formatPrice(new(big.Rat).Quo(unit, factor))
+		case !line.LineExtensionAmountTaxInclusive.IsZero():
+			if gross, err = ratFromDecimal(line.LineExtensionAmountTaxInclusive, p+".LineExtensionAmountTaxInclusive"); err != nil {
+				return nil, nil, nil, err
+			}
+		default:
+			return nil, nil, nil, newRequiredError(p+".UnitPriceTaxInclusive",
+				"UnitPriceTaxInclusive is required for VATCalculationMethod 1")
+		}
+		tax = roundAmount(new(big.Rat).Sub(gross, new(big.Rat).Quo(gross, factor)), 2)
+		net = new(big.Rat).Sub(gross, tax)
+	} else {
+		unit, err := optionalRat(line.UnitPrice, p+".UnitPrice")
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		switch {
+		case unit.Sign() != 0:
+			net = roundAmount(new(big.Rat).Mul(unit, qty), 2)
+			line.UnitPriceTaxInclusive = formatPrice(new(big.Rat).Mul(unit, factor))
+		case !line.LineExtensionAmount.IsZero():
+			if net, err = ratFromDecimal(line.LineExtensionAmount, p+".LineExtensionAmount"); err != nil {
+				return nil, nil, nil, err
+			}
+		default:
+			return nil, nil, nil, newRequiredError(p+".UnitPrice", "UnitPrice is required")
+		}
+		tax = roundAmount(new(big.Rat).Mul(net, rate), 2)
+		gross = new(big.Rat).Add(net, tax)
+	}
+
+	line.LineExtensionAmount = formatAmount(net, 2)
+	line.LineExtensionTaxAmount = formatAmount(tax, 2)
+	line.LineExtensionAmountTaxInclusive = formatAmount(gross, 2)
+	return net, tax, gross, nil
+}
+
+func newRequiredError(field, msg string) *ValidationError {
+	return &ValidationError{
+		Field:    field,
+		Code:     ErrCodeRequiredField,
+		Severity: SeverityError,
+		Msg:      msg,
+	}
+}
+
+// ratFromDecimal parses d, reporting a ValidationError for field on failure.
+func ratFromDecimal(d types.Decimal, field string) (*big.Rat, error) {
+	r, ok := parseDecimal(d)
+	if !ok {
+		return nil, &ValidationError{
+			Field:    field,
+			Code:     ErrCodeInvalidDecimal,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("invalid decimal %q", d),
+		}
+	}
+	return r, nil
+}
+
+// optionalRat parses d, treating an empty value as zero.
+func optionalRat(d types.Decimal, field string) (*big.Rat, error) {
+	if d.IsZero() {
+		return new(big.Rat), nil
+	}
+	return ratFromDecimal(d, field)
+}
+
+// parseAmount parses d, treating empty or invalid values as zero.
+func parseAmount(d types.Decimal) *big.Rat {
+	r, ok := parseDecimal(d)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// parseDecimal parses d if it is a valid xs:decimal. big.Rat alone also
+// accepts fractions, exponents and hexadecimal numbers.
+func parseDecimal(d types.Decimal) (*big.Rat, bool) {
+	if _, err := types.NewDecimal(string(d)); err != nil {
+		return nil, false
+	}
+	return new(big.Rat).SetString(string(d))
+}
+
+// roundAmount rounds r to prec decimal places, halves away from zero.
+func roundAmount(r *big.Rat, prec int) *big.Rat {
+	rounded, _ := new(big.Rat).SetString(r.FloatString(prec))
+	return rounded
+}
+
+func formatAmount(r *big.Rat, prec int) types.Decimal {
+	return types.Decimal(r.FloatString(prec))
+}
+
+// formatPrice formats a unit price with two to four decimal places.
+func formatPrice(r *big.Rat) types.Decimal {
+	s := r.FloatString(4)
+	for strings.HasSuffix(s, "0") && len(s)-strings.IndexByte(s, '.') > 3 {
+		s = s[:len(s)-1]
+	}
+	return types.Decimal(s)
+}
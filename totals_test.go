@@ -0,0 +1,192 @@
+package isdoc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func TestComputeTotalsFixtures(t *testing.T) {
+	for _, name := range []string{"test001.isdoc", "test002.isdoc"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "fixtures", name))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+			invoice, err := DecodeBytes(data)
+			if err != nil {
+				t.Fatalf("DecodeBytes failed: %v", err)
+			}
+			want := invoice.LegalMonetaryTotal
+			wantTax := invoice.TaxTotal.TaxAmount
+
+			invoice.LegalMonetaryTotal = schema.LegalMonetaryTotal{}
+			invoice.TaxTotal = schema.TaxTotal{}
+			if err := ComputeTotals(invoice); err != nil {
+				t.Fatalf("ComputeTotals failed: %v", err)
+			}
+
+			got := invoice.LegalMonetaryTotal
+			for _, c := range []struct {
+				name      string
+				got, want types.Decimal
+			}{
+				{"TaxAmount", invoice.TaxTotal.TaxAmount, wantTax},
+				{"TaxExclusiveAmount", got.TaxExclusiveAmount, want.TaxExclusiveAmount},
+				{"TaxInclusiveAmount", got.TaxInclusiveAmount, want.TaxInclusiveAmount},
+				{"DifferenceTaxInclusiveAmount", got.DifferenceTaxInclusiveAmount, want.DifferenceTaxInclusiveAmount},
+				{"PayableAmount", got.PayableAmount, want.PayableAmount},
+			} {
+				if !c.got.Equal(c.want) {
+					t.Errorf("%s = %s, want %s", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeTotals(t *testing.T) {
+	invoice := &schema.Invoice{
+		VATApplicable: true,
+		InvoiceLines: schema.InvoiceLines{InvoiceLine: []schema.InvoiceLine{
+			{
+				InvoicedQuantity:      schema.Quantity{Value: "3"},
+				UnitPrice:             "33.33",
+				ClassifiedTaxCategory: schema.ClassifiedTaxCategory{Percent: "21"},
+			},
+			{
+				UnitPriceTaxInclusive: "121",
+				ClassifiedTaxCategory: schema.ClassifiedTaxCategory{Percent: "21", VATCalculationMethod: 1},
+			},
+			{
+				InvoicedQuantity: schema.Quantity{Value: "2"},
+				UnitPrice:        "10",
+				ClassifiedTaxCategory: schema.ClassifiedTaxCategory{
+					Percent:            "21",
+					LocalReverseCharge: &schema.LocalReverseCharge{LocalReverseChargeCode: "1"},
+				},
+			},
+			{
+				UnitPrice:             "50",
+				ClassifiedTaxCategory: schema.ClassifiedTaxCategory{Percent: "12"},
+			},
+		}},
+		LegalMonetaryTotal: schema.LegalMonetaryTotal{PaidDepositsAmount: "100"},
+	}
+
+	if err := ComputeTotals(invoice); err != nil {
+		t.Fatalf("ComputeTotals failed: %v", err)
+	}
+
+	lines := invoice.InvoiceLines.InvoiceLine
+	lineTests := []struct {
+		net, tax, gross types.Decimal
+	}{
+		{"99.99", "21.00", "120.99"},
+		{"100.00", "21.00", "121.00"},
+		{"20.00", "0.00", "20.00"},
+		{"50.00", "6.00", "56.00"},
+	}
+	for i, tt := range lineTests {
+		if lines[i].LineExtensionAmount != tt.net || lines[i].LineExtensionTaxAmount != tt.tax ||
+			lines[i].LineExtensionAmountTaxInclusive != tt.gross {
+			t.Errorf("line %d = %s/%s/%s, want %s/%s/%s", i, lines[i].LineExtensionAmount,
+				lines[i].LineExtensionTaxAmount, lines[i].LineExtensionAmountTaxInclusive, tt.net, tt.tax, tt.gross)
+		}
+	}
+	if got := lines[1].UnitPrice; got != "100.00" {
+		t.Errorf("derived UnitPrice = %s, want 100.00", got)
+	}
+	if got := lines[0].UnitPriceTaxInclusive; got != "40.3293" {
+		t.Errorf("derived UnitPriceTaxInclusive = %s, want 40.3293", got)
+	}
+
+	subtotals := invoice.TaxTotal.TaxSubTotal
+	if len(subtotals) != 3 {
+		t.Fatalf("got %d tax subtotals, want 3", len(subtotals))
+	}
+	if subtotals[0].TaxableAmount != "199.99" || subtotals[0].TaxAmount != "42.00" {
+		t.Errorf("21%% subtotal = %s/%s, want 199.99/42.00", subtotals[0].TaxableAmount, subtotals[0].TaxAmount)
+	}
+	if !subtotals[1].TaxCategory.LocalReverseChargeFlag.Bool() {
+		t.Error("reverse charge subtotal is not flagged")
+	}
+
+	total := invoice.LegalMonetaryTotal
+	if total.TaxExclusiveAmount != "269.99" || total.TaxInclusiveAmount != "317.99" {
+		t.Errorf("totals = %s/%s, want 269.99/317.99", total.TaxExclusiveAmount, total.TaxInclusiveAmount)
+	}
+	if invoice.TaxTotal.TaxAmount != "48.00" {
+		t.Errorf("TaxAmount = %s, want 48.00", invoice.TaxTotal.TaxAmount)
+	}
+	if total.PayableAmount != "217.99" {
+		t.Errorf("PayableAmount = %s, want 217.99", total.PayableAmount)
+	}
+}
+
+func TestComputeTotalsErrors(t *testing.T) {
+	invoice := &schema.Invoice{InvoiceLines: schema.InvoiceLines{InvoiceLine: []schema.InvoiceLine{
+		{UnitPrice: "1"},
+		{ClassifiedTaxCategory: schema.ClassifiedTaxCategory{VATCalculationMethod: 1}},
+	}}}
+
+	var verr *ValidationError
+	err := ComputeTotals(invoice)
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if verr.Field != "Invoice.InvoiceLines.InvoiceLine[1].UnitPriceTaxInclusive" || verr.Code != ErrCodeRequiredField {
+		t.Errorf("got %s %s", verr.Field, verr.Code)
+	}
+
+	// big.Rat syntax that is not xs:decimal is rejected
+	for _, value := range []types.Decimal{"1/3", "1e3", "0x10"} {
+		invoice := &schema.Invoice{InvoiceLines: schema.InvoiceLines{InvoiceLine: []schema.InvoiceLine{
+			{UnitPrice: value},
+		}}}
+		err := ComputeTotals(invoice)
+		if !errors.As(err, &verr) || verr.Code != ErrCodeInvalidDecimal {
+			t.Errorf("UnitPrice %q: got %v, want %s", value, err, ErrCodeInvalidDecimal)
+		}
+	}
+}
+
+func TestFillDefaults(t *testing.T) {
+	invoice := &schema.Invoice{
+		VATApplicable: true,
+		InvoiceLines: schema.InvoiceLines{InvoiceLine: []schema.InvoiceLine{
+			{UnitPrice: "100"},
+			{ID: "X", UnitPrice: "50"},
+		}},
+		PaymentMeans: &schema.PaymentMeans{Payment: []schema.Payment{{PaymentMeansCode: 42}}},
+	}
+
+	if err := FillDefaults(invoice); err != nil {
+		t.Fatalf("FillDefaults failed: %v", err)
+	}
+	if invoice.Version != schema.Version {
+		t.Errorf("Version = %q, want %q", invoice.Version, schema.Version)
+	}
+	if invoice.UUID.IsZero() {
+		t.Error("UUID not generated")
+	}
+	if invoice.CurrRate != "1" || invoice.RefCurrRate != "1" {
+		t.Errorf("rates = %s/%s, want 1/1", invoice.CurrRate, invoice.RefCurrRate)
+	}
+	if id := invoice.InvoiceLines.InvoiceLine[0].ID; id != "1" {
+		t.Errorf("line ID = %q, want 1", id)
+	}
+	if id := invoice.InvoiceLines.InvoiceLine[1].ID; id != "X" {
+		t.Errorf("line ID = %q, want X", id)
+	}
+	if !invoice.InvoiceLines.InvoiceLine[0].ClassifiedTaxCategory.VATApplicable.Bool() {
+		t.Error("line not marked VATApplicable")
+	}
+	if paid := invoice.PaymentMeans.Payment[0].PaidAmount; paid != "150.00" {
+		t.Errorf("PaidAmount = %s, want 150.00", paid)
+	}
+}
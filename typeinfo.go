@@ -0,0 +1,149 @@
+package isdoc
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/xseman/isdoc/internal/ordering"
+	"github.com/xseman/isdoc/schema"
+)
+
+// xmlField describes how a struct field maps to XML.
+type xmlField struct {
+	index     int
+	name      string
+	attr      bool
+	chardata  bool
+	innerxml  bool
+	omitEmpty bool
+}
+
+// xmlTypeInfo describes the XML mapping of a schema struct type.
+type xmlTypeInfo struct {
+	// fields are the element, attribute and content fields in struct order.
+	fields []xmlField
+
+	// elements maps element names to their entry in fields.
+	elements map[string]xmlField
+
+	// order lists element names in XSD order. Elements missing from the
+	// XSD sequence follow in struct order.
+	order []string
+
+	// unknown is the index of the schema.Unknown field, or -1.
+	unknown int
+
+	// leaf is set for types holding character data or inner XML
+	// instead of child elements.
+	leaf bool
+}
+
+var (
+	typeInfoCache sync.Map // reflect.Type -> *xmlTypeInfo
+	unknownType   = reflect.TypeOf(schema.Unknown{})
+	xmlNameType   = reflect.TypeOf(xml.Name{})
+)
+
+// getTypeInfo returns the cached XML mapping of struct type t.
+func getTypeInfo(t reflect.Type) *xmlTypeInfo {
+	if ti, ok := typeInfoCache.Load(t); ok {
+		return ti.(*xmlTypeInfo)
+	}
+
+	ti := &xmlTypeInfo{elements: make(map[string]xmlField), unknown: -1}
+	var structOrder []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Type == unknownType {
+			ti.unknown = i
+			continue
+		}
+		if field.Type == xmlNameType {
+			continue
+		}
+
+		xmlTag := field.Tag.Get("xml")
+		if xmlTag == "" || xmlTag == "-" {
+			continue
+		}
+
+		// Parse XML tag: "ElementName,attr,omitempty"
+		parts := strings.Split(xmlTag, ",")
+		f := xmlField{index: i, name: parts[0]}
+		for _, opt := range parts[1:] {
+			switch opt {
+			case "attr":
+				f.attr = true
+			case "chardata":
+				f.chardata = true
+			case "innerxml":
+				f.innerxml = true
+			case "omitempty":
+				f.omitEmpty = true
+			}
+		}
+
+		switch {
+		case f.chardata || f.innerxml:
+			ti.leaf = true
+		case f.attr:
+		case f.name == "":
+			continue
+		default:
+			ti.elements[f.name] = f
+			structOrder = append(structOrder, f.name)
+		}
+		ti.fields = append(ti.fields, f)
+	}
+
+	ti.order = elementOrder(t, structOrder, ti.elements)
+
+	actual, _ := typeInfoCache.LoadOrStore(t, ti)
+	return actual.(*xmlTypeInfo)
+}
+
+// elementOrder returns the element names of t in XSD order.
+//
+// Sequences are keyed by XSD type name, which is the Go type name with a
+// "Type" suffix for complex types, or the plain name for root elements and
+// groups.
+func elementOrder(t reflect.Type, structOrder []string, elements map[string]xmlField) []string {
+	sequence, ok := ordering.Sequence[t.Name()]
+	if !ok {
+		sequence = ordering.Sequence[t.Name()+"Type"]
+	}
+
+	order := make([]string, 0, len(structOrder))
+	seen := make(map[string]bool, len(structOrder))
+	for _, name := range sequence {
+		if _, ok := elements[name]; ok && !seen[name] {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+	for _, name := range structOrder {
+		if !seen[name] {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+	return order
+}
+
+// unknownOf returns the Unknown field of struct value v, or nil if the type
+// has none. The result is only addressable into v when v is addressable.
+func unknownOf(v reflect.Value) *schema.Unknown {
+	ti := getTypeInfo(v.Type())
+	if ti.unknown < 0 {
+		return nil
+	}
+	field := v.Field(ti.unknown)
+	if field.CanAddr() {
+		return field.Addr().Interface().(*schema.Unknown)
+	}
+	u := field.Interface().(schema.Unknown)
+	return &u
+}
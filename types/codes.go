@@ -0,0 +1,318 @@
+package types
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// codeText holds the English and Czech names of a code.
+type codeText struct {
+	en, cs string
+}
+
+// describe returns the name of a code in lang, Czech for "cs" and English
+// otherwise, or "" for unknown codes.
+func describe[K comparable](texts map[K]codeText, k K, lang string) string {
+	t, ok := texts[k]
+	if !ok {
+		return ""
+	}
+	if strings.HasPrefix(strings.ToLower(lang), "cs") {
+		return t.cs
+	}
+	return t.en
+}
+
+// unmarshalInt decodes the text of an integer code element.
+func unmarshalInt(dec *xml.Decoder, start xml.StartElement, name string) (int, error) {
+	var s string
+	if err := dec.DecodeElement(&s, &start); err != nil {
+		return 0, err
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: must be an integer", name, s)
+	}
+	return n, nil
+}
+
+// DocumentType is the ISDOC document type code.
+type DocumentType int
+
+// Document types.
+const (
+	// DocumentTypeInvoice is an invoice, a tax document.
+	DocumentTypeInvoice DocumentType = 1
+	// DocumentTypeCreditNote is a corrective tax document lowering the
+	// original amount.
+	DocumentTypeCreditNote DocumentType = 2
+	// DocumentTypeDebitNote is a corrective tax document raising the
+	// original amount.
+	DocumentTypeDebitNote DocumentType = 3
+	// DocumentTypeAdvanceInvoice is a request for an advance payment, not a
+	// tax document.
+	DocumentTypeAdvanceInvoice DocumentType = 4
+	// DocumentTypeAdvanceTaxDocument is a tax document for a received
+	// advance payment.
+	DocumentTypeAdvanceTaxDocument DocumentType = 5
+	// DocumentTypeAdvanceCreditNote is a corrective tax document for a
+	// received advance payment.
+	DocumentTypeAdvanceCreditNote DocumentType = 6
+	// DocumentTypeSimplified is a simplified tax document.
+	DocumentTypeSimplified DocumentType = 7
+)
+
+var documentTypeTexts = map[DocumentType]codeText{
+	DocumentTypeInvoice:            {"invoice", "faktura – daňový doklad"},
+	DocumentTypeCreditNote:         {"credit note", "opravný daňový doklad – dobropis"},
+	DocumentTypeDebitNote:          {"debit note", "opravný daňový doklad – vrubopis"},
+	DocumentTypeAdvanceInvoice:     {"advance invoice", "zálohová faktura – nedaňový zálohový list"},
+	DocumentTypeAdvanceTaxDocument: {"tax document for a received payment", "daňový doklad při přijetí platby"},
+	DocumentTypeAdvanceCreditNote:  {"credit note for a received payment", "opravný daňový doklad při přijetí platby"},
+	DocumentTypeSimplified:         {"simplified tax document", "zjednodušený daňový doklad"},
+}
+
+// IsValid reports whether t is one of the document types 1 to 7.
+func (t DocumentType) IsValid() bool {
+	_, ok := documentTypeTexts[t]
+	return ok
+}
+
+// String returns the English name, or "DocumentType(n)" for unknown codes.
+func (t DocumentType) String() string {
+	if s := t.Description("en"); s != "" {
+		return s
+	}
+	return fmt.Sprintf("DocumentType(%d)", int(t))
+}
+
+// Description returns the name of the document type in Czech for lang "cs"
+// and in English otherwise, or "" for unknown codes.
+func (t DocumentType) Description(lang string) string {
+	return describe(documentTypeTexts, t, lang)
+}
+
+// IsCorrective reports whether t corrects an original document and so
+// requires OriginalDocumentReferences.
+func (t DocumentType) IsCorrective() bool {
+	return t == DocumentTypeCreditNote || t == DocumentTypeDebitNote || t == DocumentTypeAdvanceCreditNote
+}
+
+// MarshalXML implements xml.Marshaler for DocumentType.
+func (t DocumentType) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(int(t), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler for DocumentType. Codes outside
+// 1 to 7 are kept for validation to report.
+func (t *DocumentType) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	n, err := unmarshalInt(dec, start, "DocumentType")
+	*t = DocumentType(n)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler for DocumentType as a number.
+func (t DocumentType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for DocumentType.
+func (t *DocumentType) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*int)(t))
+}
+
+// PaymentMeansCode is the ISDOC payment means code, a subset of UN/CEFACT
+// UNCL4461.
+type PaymentMeansCode int
+
+// Payment means.
+const (
+	PaymentMeansCash           PaymentMeansCode = 10
+	PaymentMeansCheque         PaymentMeansCode = 20
+	PaymentMeansCreditTransfer PaymentMeansCode = 31
+	PaymentMeansBankTransfer   PaymentMeansCode = 42
+	PaymentMeansCard           PaymentMeansCode = 48
+	PaymentMeansDirectDebit    PaymentMeansCode = 49
+	PaymentMeansCashOnDelivery PaymentMeansCode = 50
+	PaymentMeansOffsetting     PaymentMeansCode = 97
+)
+
+var paymentMeansTexts = map[PaymentMeansCode]codeText{
+	PaymentMeansCash:           {"cash", "hotově"},
+	PaymentMeansCheque:         {"cheque", "šekem"},
+	PaymentMeansCreditTransfer: {"credit transfer", "převodem"},
+	PaymentMeansBankTransfer:   {"payment to bank account", "platba na bankovní účet"},
+	PaymentMeansCard:           {"bank card", "platební kartou"},
+	PaymentMeansDirectDebit:    {"direct debit", "inkasem"},
+	PaymentMeansCashOnDelivery: {"cash on delivery", "dobírkou"},
+	PaymentMeansOffsetting:     {"offsetting between partners", "zápočtem"},
+}
+
+// IsValid reports whether c is in the ISDOC payment means code list.
+func (c PaymentMeansCode) IsValid() bool {
+	_, ok := paymentMeansTexts[c]
+	return ok
+}
+
+// String returns the English name, or "PaymentMeansCode(n)" for unknown
+// codes.
+func (c PaymentMeansCode) String() string {
+	if s := c.Description("en"); s != "" {
+		return s
+	}
+	return fmt.Sprintf("PaymentMeansCode(%d)", int(c))
+}
+
+// Description returns the name of the payment means in Czech for lang "cs"
+// and in English otherwise, or "" for unknown codes.
+func (c PaymentMeansCode) Description(lang string) string {
+	return describe(paymentMeansTexts, c, lang)
+}
+
+// MarshalXML implements xml.Marshaler for PaymentMeansCode.
+func (c PaymentMeansCode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(int(c), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler for PaymentMeansCode. Codes
+// outside the code list are kept for validation to report.
+func (c *PaymentMeansCode) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	n, err := unmarshalInt(dec, start, "PaymentMeansCode")
+	*c = PaymentMeansCode(n)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler for PaymentMeansCode as a number.
+func (c PaymentMeansCode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(c))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for PaymentMeansCode.
+func (c *PaymentMeansCode) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*int)(c))
+}
+
+// VATCalculationMethod tells whether line VAT is computed from the
+// tax-exclusive or the tax-inclusive amount.
+type VATCalculationMethod int
+
+// VAT calculation methods.
+const (
+	// VATCalculationFromBottom computes VAT from the tax-exclusive amount.
+	VATCalculationFromBottom VATCalculationMethod = 0
+	// VATCalculationFromTop computes VAT from the tax-inclusive amount.
+	VATCalculationFromTop VATCalculationMethod = 1
+)
+
+var vatCalculationTexts = map[VATCalculationMethod]codeText{
+	VATCalculationFromBottom: {"from bottom", "zdola"},
+	VATCalculationFromTop:    {"from top", "shora"},
+}
+
+// IsValid reports whether m is 0 or 1.
+func (m VATCalculationMethod) IsValid() bool {
+	_, ok := vatCalculationTexts[m]
+	return ok
+}
+
+// String returns the English name, or "VATCalculationMethod(n)" for
+// unknown codes.
+func (m VATCalculationMethod) String() string {
+	if s := m.Description("en"); s != "" {
+		return s
+	}
+	return fmt.Sprintf("VATCalculationMethod(%d)", int(m))
+}
+
+// Description returns the name of the method in Czech for lang "cs" and in
+// English otherwise, or "" for unknown codes.
+func (m VATCalculationMethod) Description(lang string) string {
+	return describe(vatCalculationTexts, m, lang)
+}
+
+// MarshalXML implements xml.Marshaler for VATCalculationMethod.
+func (m VATCalculationMethod) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(int(m), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler for VATCalculationMethod.
+func (m *VATCalculationMethod) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	n, err := unmarshalInt(dec, start, "VATCalculationMethod")
+	*m = VATCalculationMethod(n)
+	return err
+}
+
+// MarshalJSON implements json.Marshaler for VATCalculationMethod as a
+// number.
+func (m VATCalculationMethod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(m))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for VATCalculationMethod.
+func (m *VATCalculationMethod) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*int)(m))
+}
+
+// BatchOrSerialNumber tells whether a StoreBatch is a batch or a serial
+// number.
+type BatchOrSerialNumber string
+
+// Store batch kinds.
+const (
+	BatchNumber  BatchOrSerialNumber = "B"
+	SerialNumber BatchOrSerialNumber = "S"
+)
+
+var batchOrSerialTexts = map[BatchOrSerialNumber]codeText{
+	BatchNumber:  {"batch", "šarže"},
+	SerialNumber: {"serial number", "sériové číslo"},
+}
+
+// IsValid reports whether b is "B" or "S".
+func (b BatchOrSerialNumber) IsValid() bool {
+	_, ok := batchOrSerialTexts[b]
+	return ok
+}
+
+// String returns the code itself.
+func (b BatchOrSerialNumber) String() string {
+	return string(b)
+}
+
+// Description returns the name of the kind in Czech for lang "cs" and in
+// English otherwise, or "" for unknown codes.
+func (b BatchOrSerialNumber) Description(lang string) string {
+	return describe(batchOrSerialTexts, b, lang)
+}
+
+// MarshalXML implements xml.Marshaler for BatchOrSerialNumber.
+func (b BatchOrSerialNumber) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(string(b), start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler for BatchOrSerialNumber.
+func (b *BatchOrSerialNumber) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := dec.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	*b = BatchOrSerialNumber(strings.TrimSpace(s))
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler for BatchOrSerialNumber.
+func (b BatchOrSerialNumber) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(b))
+}
+
+// UnmarshalJSON implements json.Unmarshaler for BatchOrSerialNumber.
+func (b *BatchOrSerialNumber) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*string)(b))
+}
@@ -5,4 +5,6 @@
 //   - Date: YYYY-MM-DD date format
 //   - Bool: Strict true/false only (rejects 0/1)
 //   - UUID: 36-character UUID with pattern validation
+//   - DocumentType, PaymentMeansCode, VATCalculationMethod and
+//     BatchOrSerialNumber: ISDOC code lists with Czech and English names
 package types
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"testing"
 	"time"
 )
@@ -166,6 +168,100 @@ func TestUUID(t *testing.T) {
 					t.Errorf("NewUUID(%q) expected error, got nil", tt.input)
 				}
 			}
+			// IsValid rejects the empty UUID of an absent element
+			if got := UUID(tt.input).IsValid(); got != (tt.valid && tt.input != "") {
+				t.Errorf("UUID(%q).IsValid() = %v", tt.input, got)
+			}
 		})
 	}
 }
+
+func TestNewRandomUUID(t *testing.T) {
+	a, b := NewRandomUUID(), NewRandomUUID()
+	if _, err := NewUUID(a.String()); err != nil {
+		t.Errorf("NewRandomUUID() = %q: %v", a, err)
+	}
+	if a == b {
+		t.Errorf("NewRandomUUID() returned %q twice", a)
+	}
+	if a[14] != '4' {
+		t.Errorf("NewRandomUUID() = %q, want version 4", a)
+	}
+}
+
+func TestDocumentType(t *testing.T) {
+	if !DocumentTypeSimplified.IsValid() || DocumentType(0).IsValid() || DocumentType(8).IsValid() {
+		t.Error("IsValid() accepts codes outside 1-7")
+	}
+	if got := DocumentTypeCreditNote.String(); got != "credit note" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := DocumentTypeCreditNote.Description("cs"); got != "opravný daňový doklad – dobropis" {
+		t.Errorf("Description(cs) = %q", got)
+	}
+	if got := DocumentType(9).String(); got != "DocumentType(9)" {
+		t.Errorf("String() = %q", got)
+	}
+	if !DocumentTypeAdvanceCreditNote.IsCorrective() || DocumentTypeAdvanceInvoice.IsCorrective() {
+		t.Error("IsCorrective() is wrong")
+	}
+}
+
+func TestPaymentMeansCode(t *testing.T) {
+	for _, c := range []PaymentMeansCode{10, 20, 31, 42, 48, 49, 50, 97} {
+		if !c.IsValid() || c.Description("cs") == "" {
+			t.Errorf("%d is an ISDOC payment means code", c)
+		}
+	}
+	if PaymentMeansCode(30).IsValid() {
+		t.Error("30 is not an ISDOC payment means code")
+	}
+	if got := PaymentMeansBankTransfer.Description("cs-CZ"); got != "platba na bankovní účet" {
+		t.Errorf("Description(cs-CZ) = %q", got)
+	}
+}
+
+func TestCodesXML(t *testing.T) {
+	type doc struct {
+		DocumentType DocumentType         `xml:"DocumentType"`
+		Method       VATCalculationMethod `xml:"VATCalculationMethod"`
+		Batch        BatchOrSerialNumber  `xml:"BatchOrSerialNumber"`
+	}
+
+	var d doc
+	if err := xml.Unmarshal([]byte("<d><DocumentType> 2 </DocumentType><VATCalculationMethod>1</VATCalculationMethod><BatchOrSerialNumber>S</BatchOrSerialNumber></d>"), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.DocumentType != DocumentTypeCreditNote || d.Method != VATCalculationFromTop || d.Batch != SerialNumber {
+		t.Errorf("Unmarshal = %+v", d)
+	}
+	out, err := xml.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<doc><DocumentType>2</DocumentType><VATCalculationMethod>1</VATCalculationMethod><BatchOrSerialNumber>S</BatchOrSerialNumber></doc>"; string(out) != want {
+		t.Errorf("Marshal = %s, want %s", out, want)
+	}
+
+	if err := xml.Unmarshal([]byte("<d><DocumentType>faktura</DocumentType></d>"), &d); err == nil {
+		t.Error("Unmarshal accepted a non-integer DocumentType")
+	}
+}
+
+func TestCodesJSON(t *testing.T) {
+	v := struct {
+		Code  PaymentMeansCode
+		Batch BatchOrSerialNumber
+	}{PaymentMeansCard, BatchNumber}
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"Code":48,"Batch":"B"}`; string(out) != want {
+		t.Errorf("Marshal = %s, want %s", out, want)
+	}
+	v.Code = 0
+	if err := json.Unmarshal(out, &v); err != nil || v.Code != PaymentMeansCard {
+		t.Errorf("Unmarshal = %v, %v", v.Code, err)
+	}
+}
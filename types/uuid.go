@@ -1,6 +1,7 @@
 package types
 
 import (
+	"crypto/rand"
 	"encoding/xml"
 	"fmt"
 	"regexp"
@@ -25,6 +26,16 @@ func NewUUID(s string) (UUID, error) {
 	return UUID(s), nil
 }
 
+// NewRandomUUID returns a random (version 4) UUID in upper case, as
+// commonly used in ISDOC documents.
+func NewRandomUUID() UUID {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return UUID(fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
 // MustUUID creates a UUID from a string, panicking on invalid format.
 func MustUUID(s string) UUID {
 	u, err := NewUUID(s)
@@ -44,6 +55,11 @@ func (u UUID) IsZero() bool {
 	return u == ""
 }
 
+// IsValid reports whether the UUID has the format of the ISDOC UUIDType.
+func (u UUID) IsValid() bool {
+	return uuidPattern.MatchString(string(u))
+}
+
 // MarshalXML implements xml.Marshaler for UUID.
 func (u UUID) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	return e.EncodeElement(string(u), start)
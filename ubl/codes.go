@@ -0,0 +1,40 @@
+package ubl
+
+import (
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/types"
+)
+
+// UNCL1001 document type codes.
+const (
+	TypeCodeInvoice              = en16931.TypeCodeInvoice
+	TypeCodeCreditNote           = en16931.TypeCodeCreditNote
+	TypeCodeDebitNote            = en16931.TypeCodeDebitNote
+	TypeCodeCorrected            = en16931.TypeCodeCorrected
+	TypeCodePrepayment           = en16931.TypeCodePrepayment
+	TypeCodeSelfBilled           = en16931.TypeCodeSelfBilled
+	TypeCodeSelfBilledCreditNote = en16931.TypeCodeSelfBilledCreditNote
+)
+
+// UNCL5305 VAT category codes.
+const (
+	CategoryStandard      = en16931.CategoryStandard
+	CategoryZero          = en16931.CategoryZero
+	CategoryExempt        = en16931.CategoryExempt
+	CategoryReverseCharge = en16931.CategoryReverseCharge
+	CategoryIntraEU       = en16931.CategoryIntraEU
+	CategoryExport        = en16931.CategoryExport
+	CategoryOutOfScope    = en16931.CategoryOutOfScope
+)
+
+// taxCategory returns the UNCL5305 category of an ISDOC VAT rate.
+func taxCategory(vatApplicable bool, percent types.Decimal, reverseCharge bool) TaxCategory {
+	c := en16931.TaxCategory(vatApplicable, percent, reverseCharge)
+	return TaxCategory{
+		ID:                     c.ID,
+		Percent:                c.Percent,
+		TaxExemptionReasonCode: c.ReasonCode,
+		TaxExemptionReason:     c.Reason,
+		TaxScheme:              TaxScheme{ID: "VAT"},
+	}
+}
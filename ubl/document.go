@@ -0,0 +1,458 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/xseman/isdoc/types"
+)
+
+// UBL 2.1 namespaces.
+const (
+	NamespaceInvoice    = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	NamespaceCreditNote = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	NamespaceCAC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	NamespaceCBC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// Peppol BIS Billing 3.0 identifiers written by FromISDOC.
+const (
+	CustomizationID = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	ProfileID       = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+)
+
+// Document is a UBL 2.1 Invoice or CreditNote restricted to the elements
+// used by EN 16931. It is written as a CreditNote when CreditNote is set.
+//
+// Elements are written with the "cac" and "cbc" prefixes; any prefixes are
+// accepted when reading.
+type Document struct {
+	CreditNote bool
+
+	CustomizationID string
+	ProfileID       string
+	ID              string
+	UUID            string
+	IssueDate       types.Date
+
+	// DueDate is only written for invoices. Credit notes carry the due
+	// date in PaymentMeans.
+	DueDate types.Date
+
+	// TypeCode is the UNCL1001 invoice or credit note type code.
+	TypeCode     string
+	Note         []Text
+	TaxPointDate types.Date
+
+	Body
+
+	Lines []Line
+}
+
+// Body holds the elements shared by Invoice and CreditNote in the order of
+// both schemas.
+type Body struct {
+	DocumentCurrencyCode      string              `xml:"cbc:DocumentCurrencyCode"`
+	TaxCurrencyCode           string              `xml:"cbc:TaxCurrencyCode,omitempty"`
+	BuyerReference            string              `xml:"cbc:BuyerReference,omitempty"`
+	OrderReference            *OrderReference     `xml:"cac:OrderReference,omitempty"`
+	BillingReference          []BillingReference  `xml:"cac:BillingReference,omitempty"`
+	DespatchDocumentReference []DocumentReference `xml:"cac:DespatchDocumentReference,omitempty"`
+	ContractDocumentReference []DocumentReference `xml:"cac:ContractDocumentReference,omitempty"`
+	AccountingSupplierParty   PartyWrapper        `xml:"cac:AccountingSupplierParty"`
+	AccountingCustomerParty   *PartyWrapper       `xml:"cac:AccountingCustomerParty,omitempty"`
+	Delivery                  *Delivery           `xml:"cac:Delivery,omitempty"`
+	PaymentMeans              []PaymentMeans      `xml:"cac:PaymentMeans,omitempty"`
+	AllowanceCharge           []AllowanceCharge   `xml:"cac:AllowanceCharge,omitempty"`
+	TaxTotal                  []TaxTotal          `xml:"cac:TaxTotal"`
+	LegalMonetaryTotal        MonetaryTotal       `xml:"cac:LegalMonetaryTotal"`
+}
+
+// Text is a text with an optional language.
+type Text struct {
+	Value      string `xml:",chardata"`
+	LanguageID string `xml:"languageID,attr,omitempty"`
+}
+
+// Identifier is an identifier with an optional scheme, e.g. "0088" for GLN.
+type Identifier struct {
+	Value    string `xml:",chardata"`
+	SchemeID string `xml:"schemeID,attr,omitempty"`
+}
+
+// Amount is a monetary amount in the currency given by CurrencyID.
+type Amount struct {
+	Value      types.Decimal `xml:",chardata"`
+	CurrencyID string        `xml:"currencyID,attr"`
+}
+
+// Quantity is a quantity with an optional UN/ECE Rec 20 unit code.
+type Quantity struct {
+	Value    types.Decimal `xml:",chardata"`
+	UnitCode string        `xml:"unitCode,attr,omitempty"`
+}
+
+// OrderReference references the buyer's purchase order.
+type OrderReference struct {
+	ID           string `xml:"cbc:ID"`
+	SalesOrderID string `xml:"cbc:SalesOrderID,omitempty"`
+}
+
+// BillingReference references a preceding invoice.
+type BillingReference struct {
+	InvoiceDocumentReference DocumentReference `xml:"cac:InvoiceDocumentReference"`
+}
+
+// DocumentReference references another document.
+type DocumentReference struct {
+	ID        string     `xml:"cbc:ID"`
+	UUID      string     `xml:"cbc:UUID,omitempty"`
+	IssueDate types.Date `xml:"cbc:IssueDate,omitempty"`
+}
+
+// PartyWrapper wraps the Party of AccountingSupplierParty and
+// AccountingCustomerParty.
+type PartyWrapper struct {
+	Party Party `xml:"cac:Party"`
+}
+
+// Party is a seller or buyer.
+type Party struct {
+	EndpointID          *Identifier      `xml:"cbc:EndpointID,omitempty"`
+	PartyIdentification []Identification `xml:"cac:PartyIdentification,omitempty"`
+	PartyName           *PartyName       `xml:"cac:PartyName,omitempty"`
+	PostalAddress       *Address         `xml:"cac:PostalAddress,omitempty"`
+	PartyTaxScheme      []PartyTaxScheme `xml:"cac:PartyTaxScheme,omitempty"`
+	PartyLegalEntity    *LegalEntity     `xml:"cac:PartyLegalEntity,omitempty"`
+	Contact             *Contact         `xml:"cac:Contact,omitempty"`
+}
+
+// Identification wraps a party or item identifier.
+type Identification struct {
+	ID Identifier `xml:"cbc:ID"`
+}
+
+// PartyName is the trading name of a party.
+type PartyName struct {
+	Name string `xml:"cbc:Name"`
+}
+
+// Address is a postal address.
+type Address struct {
+	StreetName     string  `xml:"cbc:StreetName,omitempty"`
+	BuildingNumber string  `xml:"cbc:BuildingNumber,omitempty"`
+	CityName       string  `xml:"cbc:CityName,omitempty"`
+	PostalZone     string  `xml:"cbc:PostalZone,omitempty"`
+	Country        Country `xml:"cac:Country"`
+}
+
+// Country identifies a country by its ISO 3166-1 alpha-2 code.
+type Country struct {
+	IdentificationCode string `xml:"cbc:IdentificationCode"`
+	Name               string `xml:"cbc:Name,omitempty"`
+}
+
+// PartyTaxScheme is a tax registration of a party.
+type PartyTaxScheme struct {
+	CompanyID string    `xml:"cbc:CompanyID"`
+	TaxScheme TaxScheme `xml:"cac:TaxScheme"`
+}
+
+// TaxScheme identifies a tax scheme, normally "VAT".
+type TaxScheme struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// LegalEntity is the legal registration of a party.
+type LegalEntity struct {
+	RegistrationName string      `xml:"cbc:RegistrationName"`
+	CompanyID        *Identifier `xml:"cbc:CompanyID,omitempty"`
+	CompanyLegalForm string      `xml:"cbc:CompanyLegalForm,omitempty"`
+}
+
+// Contact is a contact person.
+type Contact struct {
+	Name           string `xml:"cbc:Name,omitempty"`
+	Telephone      string `xml:"cbc:Telephone,omitempty"`
+	ElectronicMail string `xml:"cbc:ElectronicMail,omitempty"`
+}
+
+// Delivery is the place of delivery.
+type Delivery struct {
+	DeliveryLocation *DeliveryLocation `xml:"cac:DeliveryLocation,omitempty"`
+	DeliveryParty    *DeliveryParty    `xml:"cac:DeliveryParty,omitempty"`
+}
+
+// DeliveryLocation is the address of delivery.
+type DeliveryLocation struct {
+	Address *Address `xml:"cac:Address,omitempty"`
+}
+
+// DeliveryParty names the party receiving the delivery.
+type DeliveryParty struct {
+	PartyName PartyName `xml:"cac:PartyName"`
+}
+
+// PaymentMeans is a payment instruction.
+type PaymentMeans struct {
+	// PaymentMeansCode is a UNCL4461 code.
+	PaymentMeansCode      string            `xml:"cbc:PaymentMeansCode"`
+	PaymentDueDate        types.Date        `xml:"cbc:PaymentDueDate,omitempty"`
+	PaymentID             string            `xml:"cbc:PaymentID,omitempty"`
+	PayeeFinancialAccount *FinancialAccount `xml:"cac:PayeeFinancialAccount,omitempty"`
+}
+
+// FinancialAccount is the payee's bank account.
+type FinancialAccount struct {
+	ID                         string  `xml:"cbc:ID"`
+	Name                       string  `xml:"cbc:Name,omitempty"`
+	FinancialInstitutionBranch *Branch `xml:"cac:FinancialInstitutionBranch,omitempty"`
+}
+
+// Branch identifies a bank by its BIC.
+type Branch struct {
+	ID string `xml:"cbc:ID"`
+}
+
+// AllowanceCharge is a document level allowance or charge.
+type AllowanceCharge struct {
+	ChargeIndicator bool         `xml:"cbc:ChargeIndicator"`
+	ReasonCode      string       `xml:"cbc:AllowanceChargeReasonCode,omitempty"`
+	Reason          string       `xml:"cbc:AllowanceChargeReason,omitempty"`
+	Amount          Amount       `xml:"cbc:Amount"`
+	TaxCategory     *TaxCategory `xml:"cac:TaxCategory,omitempty"`
+}
+
+// TaxTotal is the VAT breakdown. A second TaxTotal without subtotals gives
+// the VAT total in TaxCurrencyCode.
+type TaxTotal struct {
+	TaxAmount   Amount        `xml:"cbc:TaxAmount"`
+	TaxSubtotal []TaxSubtotal `xml:"cac:TaxSubtotal,omitempty"`
+}
+
+// TaxSubtotal is the VAT of one category and rate.
+type TaxSubtotal struct {
+	TaxableAmount Amount      `xml:"cbc:TaxableAmount"`
+	TaxAmount     Amount      `xml:"cbc:TaxAmount"`
+	TaxCategory   TaxCategory `xml:"cac:TaxCategory"`
+}
+
+// TaxCategory is a VAT category with a UNCL5305 code.
+type TaxCategory struct {
+	ID                     string        `xml:"cbc:ID"`
+	Percent                types.Decimal `xml:"cbc:Percent,omitempty"`
+	TaxExemptionReasonCode string        `xml:"cbc:TaxExemptionReasonCode,omitempty"`
+	TaxExemptionReason     string        `xml:"cbc:TaxExemptionReason,omitempty"`
+	TaxScheme              TaxScheme     `xml:"cac:TaxScheme"`
+}
+
+// MonetaryTotal holds the document totals.
+type MonetaryTotal struct {
+	LineExtensionAmount   Amount  `xml:"cbc:LineExtensionAmount"`
+	TaxExclusiveAmount    Amount  `xml:"cbc:TaxExclusiveAmount"`
+	TaxInclusiveAmount    Amount  `xml:"cbc:TaxInclusiveAmount"`
+	AllowanceTotalAmount  *Amount `xml:"cbc:AllowanceTotalAmount,omitempty"`
+	ChargeTotalAmount     *Amount `xml:"cbc:ChargeTotalAmount,omitempty"`
+	PrepaidAmount         *Amount `xml:"cbc:PrepaidAmount,omitempty"`
+	PayableRoundingAmount *Amount `xml:"cbc:PayableRoundingAmount,omitempty"`
+	PayableAmount         Amount  `xml:"cbc:PayableAmount"`
+}
+
+// Line is an InvoiceLine or CreditNoteLine.
+type Line struct {
+	ID   string `xml:"cbc:ID"`
+	Note string `xml:"cbc:Note,omitempty"`
+
+	// InvoicedQuantity is set in invoices, CreditedQuantity in credit notes.
+	InvoicedQuantity *Quantity `xml:"cbc:InvoicedQuantity,omitempty"`
+	CreditedQuantity *Quantity `xml:"cbc:CreditedQuantity,omitempty"`
+
+	LineExtensionAmount Amount         `xml:"cbc:LineExtensionAmount"`
+	OrderLineReference  *LineReference `xml:"cac:OrderLineReference,omitempty"`
+	Item                Item           `xml:"cac:Item"`
+	Price               Price          `xml:"cac:Price"`
+}
+
+// Quantity returns the invoiced or credited quantity.
+func (l *Line) Quantity() *Quantity {
+	if l.CreditedQuantity != nil {
+		return l.CreditedQuantity
+	}
+	return l.InvoicedQuantity
+}
+
+// LineReference references a line of another document.
+type LineReference struct {
+	LineID string `xml:"cbc:LineID"`
+}
+
+// Item describes the goods or services of a line.
+type Item struct {
+	Description                string          `xml:"cbc:Description,omitempty"`
+	Name                       string          `xml:"cbc:Name"`
+	BuyersItemIdentification   *Identification `xml:"cac:BuyersItemIdentification,omitempty"`
+	SellersItemIdentification  *Identification `xml:"cac:SellersItemIdentification,omitempty"`
+	StandardItemIdentification *Identification `xml:"cac:StandardItemIdentification,omitempty"`
+	ClassifiedTaxCategory      TaxCategory     `xml:"cac:ClassifiedTaxCategory"`
+}
+
+// Price is the net unit price of a line.
+type Price struct {
+	PriceAmount  Amount    `xml:"cbc:PriceAmount"`
+	BaseQuantity *Quantity `xml:"cbc:BaseQuantity,omitempty"`
+}
+
+type header struct {
+	Xmlns           string     `xml:"xmlns,attr"`
+	XmlnsCAC        string     `xml:"xmlns:cac,attr"`
+	XmlnsCBC        string     `xml:"xmlns:cbc,attr"`
+	CustomizationID string     `xml:"cbc:CustomizationID,omitempty"`
+	ProfileID       string     `xml:"cbc:ProfileID,omitempty"`
+	ID              string     `xml:"cbc:ID"`
+	UUID            string     `xml:"cbc:UUID,omitempty"`
+	IssueDate       types.Date `xml:"cbc:IssueDate"`
+}
+
+// invoiceXML and creditNoteXML differ in the position of TaxPointDate,
+// the type code element and the line element.
+type invoiceXML struct {
+	XMLName xml.Name `xml:"Invoice"`
+	header
+	DueDate         types.Date `xml:"cbc:DueDate,omitempty"`
+	InvoiceTypeCode string     `xml:"cbc:InvoiceTypeCode"`
+	Note            []Text     `xml:"cbc:Note,omitempty"`
+	TaxPointDate    types.Date `xml:"cbc:TaxPointDate,omitempty"`
+	Body
+	InvoiceLine []Line `xml:"cac:InvoiceLine"`
+}
+
+type creditNoteXML struct {
+	XMLName xml.Name `xml:"CreditNote"`
+	header
+	TaxPointDate       types.Date `xml:"cbc:TaxPointDate,omitempty"`
+	CreditNoteTypeCode string     `xml:"cbc:CreditNoteTypeCode"`
+	Note               []Text     `xml:"cbc:Note,omitempty"`
+	Body
+	CreditNoteLine []Line `xml:"cac:CreditNoteLine"`
+}
+
+func (d *Document) header(ns string) header {
+	return header{
+		Xmlns:           ns,
+		XmlnsCAC:        NamespaceCAC,
+		XmlnsCBC:        NamespaceCBC,
+		CustomizationID: d.CustomizationID,
+		ProfileID:       d.ProfileID,
+		ID:              d.ID,
+		UUID:            d.UUID,
+		IssueDate:       d.IssueDate,
+	}
+}
+
+func (d *Document) setHeader(h header) {
+	d.CustomizationID = h.CustomizationID
+	d.ProfileID = h.ProfileID
+	d.ID = h.ID
+	d.UUID = h.UUID
+	d.IssueDate = h.IssueDate
+}
+
+// MarshalXML writes the document as an Invoice or CreditNote element.
+func (d *Document) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	if d.CreditNote {
+		return e.Encode(creditNoteXML{
+			header:             d.header(NamespaceCreditNote),
+			TaxPointDate:       d.TaxPointDate,
+			CreditNoteTypeCode: d.TypeCode,
+			Note:               d.Note,
+			Body:               d.Body,
+			CreditNoteLine:     d.Lines,
+		})
+	}
+	return e.Encode(invoiceXML{
+		header:          d.header(NamespaceInvoice),
+		DueDate:         d.DueDate,
+		InvoiceTypeCode: d.TypeCode,
+		Note:            d.Note,
+		TaxPointDate:    d.TaxPointDate,
+		Body:            d.Body,
+		InvoiceLine:     d.Lines,
+	})
+}
+
+// UnmarshalXML reads an Invoice or CreditNote element. Names are expected
+// in the form produced by prefixReader.
+func (d *Document) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "Invoice":
+		var v invoiceXML
+		if err := dec.DecodeElement(&v, &start); err != nil {
+			return err
+		}
+		*d = Document{
+			DueDate:      v.DueDate,
+			TypeCode:     v.InvoiceTypeCode,
+			Note:         v.Note,
+			TaxPointDate: v.TaxPointDate,
+			Body:         v.Body,
+			Lines:        v.InvoiceLine,
+		}
+		d.setHeader(v.header)
+	case "CreditNote":
+		var v creditNoteXML
+		if err := dec.DecodeElement(&v, &start); err != nil {
+			return err
+		}
+		*d = Document{
+			CreditNote:   true,
+			TypeCode:     v.CreditNoteTypeCode,
+			Note:         v.Note,
+			TaxPointDate: v.TaxPointDate,
+			Body:         v.Body,
+			Lines:        v.CreditNoteLine,
+		}
+		d.setHeader(v.header)
+	default:
+		return fmt.Errorf("%w: root element %s", ErrNotUBL, start.Name.Local)
+	}
+	return nil
+}
+
+// prefixReader rewrites element names from the UBL namespaces to the
+// prefixed names used in struct tags, so documents decode whatever
+// prefixes they use.
+type prefixReader struct {
+	d *xml.Decoder
+}
+
+func (r prefixReader) Token() (xml.Token, error) {
+	t, err := r.d.Token()
+	switch el := t.(type) {
+	case xml.StartElement:
+		el.Name = prefixedName(el.Name)
+		attrs := el.Attr[:0]
+		for _, a := range el.Attr {
+			if a.Name.Space == "" && a.Name.Local != "xmlns" {
+				attrs = append(attrs, a)
+			}
+		}
+		el.Attr = attrs
+		return el, err
+	case xml.EndElement:
+		el.Name = prefixedName(el.Name)
+		return el, err
+	}
+	return t, err
+}
+
+func prefixedName(n xml.Name) xml.Name {
+	switch n.Space {
+	case NamespaceCAC:
+		return xml.Name{Local: "cac:" + n.Local}
+	case NamespaceCBC:
+		return xml.Name{Local: "cbc:" + n.Local}
+	case NamespaceInvoice, NamespaceCreditNote:
+		return xml.Name{Local: n.Local}
+	}
+	return xml.Name{Local: "other:" + n.Local}
+}
@@ -0,0 +1,507 @@
+package ubl
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// FromISDOC converts an ISDOC invoice to a UBL document. DocumentType 2 and
+// 6 give a CreditNote, other types an Invoice. The warnings list ISDOC
+// fields, by their validation path, that have no UBL counterpart.
+func FromISDOC(inv *schema.Invoice) (*Document, isdoc.ValidationErrors) {
+	c := &fromISDOC{inv: inv, currency: inv.LocalCurrencyCode}
+	if inv.ForeignCurrencyCode != "" {
+		c.currency = inv.ForeignCurrencyCode
+		// CurrRate local units are worth RefCurrRate foreign units
+		if rate := en16931.ParseRat(inv.CurrRate); rate.Sign() != 0 {
+			c.rate = new(big.Rat).Quo(en16931.ParseRat(inv.RefCurrRate), rate)
+		}
+	}
+
+	creditNote := en16931.IsCreditNote(inv.DocumentType)
+	if creditNote {
+		total := inv.LegalMonetaryTotal.TaxExclusiveAmount
+		if en16931.ParseRat(total).Sign() == 0 {
+			total = inv.LegalMonetaryTotal.TaxInclusiveAmount
+		}
+		c.negate = en16931.ParseRat(total).Sign() < 0
+	}
+
+	doc := &Document{
+		CreditNote:      creditNote,
+		CustomizationID: CustomizationID,
+		ProfileID:       ProfileID,
+		ID:              inv.ID,
+		UUID:            string(inv.UUID),
+		IssueDate:       inv.IssueDate,
+		TypeCode:        en16931.TypeCode(inv.DocumentType),
+		TaxPointDate:    inv.TaxPointDate,
+	}
+	switch inv.DocumentType {
+	case types.DocumentTypeAdvanceInvoice, types.DocumentTypeAdvanceCreditNote, types.DocumentTypeSimplified:
+		c.warn("Invoice.DocumentType", "DocumentType %d is written as UBL type %s", inv.DocumentType, doc.TypeCode)
+	}
+	if inv.Note != nil && inv.Note.Value != "" {
+		doc.Note = []Text{{Value: inv.Note.Value, LanguageID: inv.Note.LanguageID}}
+	}
+
+	doc.DocumentCurrencyCode = c.currency
+	if inv.ForeignCurrencyCode != "" {
+		doc.TaxCurrencyCode = inv.LocalCurrencyCode
+		c.warn("Invoice.CurrRate", "only the VAT total is given in the local currency")
+	}
+
+	c.header()
+	c.references(doc)
+	doc.AccountingSupplierParty.Party = c.party("Invoice.AccountingSupplierParty.Party", &inv.AccountingSupplierParty.Party)
+	if inv.AccountingCustomerParty != nil {
+		doc.AccountingCustomerParty = &PartyWrapper{
+			Party: c.party("Invoice.AccountingCustomerParty.Party", &inv.AccountingCustomerParty.Party),
+		}
+	}
+	c.delivery(doc)
+	c.paymentMeans(doc)
+	c.lines(doc)
+	c.taxTotal(doc)
+	c.monetaryTotal(doc)
+
+	return doc, c.warnings
+}
+
+type fromISDOC struct {
+	inv      *schema.Invoice
+	currency string
+	// rate converts local amounts to the foreign currency; it is nil for
+	// invoices in the local currency
+	rate     *big.Rat
+	negate   bool
+	warnings isdoc.ValidationErrors
+}
+
+func (c *fromISDOC) warn(field, format string, args ...any) {
+	c.warnings = append(c.warnings, lossy(field, fmt.Sprintf(format, args...)))
+}
+
+// amount returns an amount in the document currency, taken from curr for
+// foreign currency invoices.
+func (c *fromISDOC) amount(local, curr types.Decimal) Amount {
+	v := c.value(local, curr)
+	if v == "" {
+		v = "0.00"
+	}
+	return Amount{Value: v, CurrencyID: c.currency}
+}
+
+func (c *fromISDOC) value(local, curr types.Decimal) types.Decimal {
+	v := local
+	if c.rate != nil {
+		switch {
+		case !curr.IsZero():
+			v = curr
+		case !local.IsZero():
+			v = en16931.FormatAmount(new(big.Rat).Mul(en16931.ParseRat(local), c.rate))
+		}
+	}
+	if c.negate {
+		v = en16931.Negate(v)
+	}
+	return v
+}
+
+// header reports header fields without a UBL counterpart.
+func (c *fromISDOC) header() {
+	inv := c.inv
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"SubDocumentType", inv.SubDocumentType != ""},
+		{"TargetConsolidator", inv.TargetConsolidator != ""},
+		{"ClientOnTargetConsolidator", inv.ClientOnTargetConsolidator != ""},
+		{"ClientBankAccount", inv.ClientBankAccount != ""},
+		{"EgovFlag", inv.EgovFlag.Bool()},
+		{"ISDS_ID", inv.ISDS_ID != ""},
+		{"FileReference", inv.FileReference != ""},
+		{"ReferenceNumber", inv.ReferenceNumber != ""},
+		{"EgovClassifiers", inv.EgovClassifiers != nil},
+		{"IssuingSystem", inv.IssuingSystem != ""},
+		{"ElectronicPossibilityAgreementReference", inv.ElectronicPossibilityAgreementReference.Value != ""},
+		{"Extensions", inv.Extensions != nil},
+		{"SellerSupplierParty", inv.SellerSupplierParty != nil},
+		{"AnonymousCustomerParty", inv.AnonymousCustomerParty != nil},
+		{"BuyerCustomerParty", inv.BuyerCustomerParty != nil},
+		{"NonTaxedDeposits", inv.NonTaxedDeposits != nil},
+		{"TaxedDeposits", inv.TaxedDeposits != nil},
+		{"SupplementsList", inv.SupplementsList != nil},
+	} {
+		if f.set {
+			c.warn("Invoice."+f.name, "%s has no UBL counterpart", f.name)
+		}
+	}
+}
+
+func (c *fromISDOC) references(doc *Document) {
+	inv := c.inv
+
+	if refs := inv.OrderReferences; refs != nil && len(refs.OrderReference) > 0 {
+		ref := refs.OrderReference[0]
+		doc.OrderReference = &OrderReference{ID: ref.ExternalOrderID, SalesOrderID: ref.SalesOrderID}
+		if ref.ExternalOrderID == "" {
+			// The buyer's order number is mandatory in UBL
+			doc.OrderReference.ID = "NA"
+		}
+		if !ref.IssueDate.IsZero() || !ref.ExternalOrderIssueDate.IsZero() || !ref.UUID.IsZero() ||
+			ref.ISDS_ID != "" || ref.FileReference != "" || ref.ReferenceNumber != "" {
+			c.warn("Invoice.OrderReferences.OrderReference[0]", "only the order numbers are converted")
+		}
+		if len(refs.OrderReference) > 1 {
+			c.warn("Invoice.OrderReferences", "only the first of %d order references is converted", len(refs.OrderReference))
+		}
+	}
+
+	if refs := inv.OriginalDocumentReferences; refs != nil {
+		for _, ref := range refs.OriginalDocumentReference {
+			doc.BillingReference = append(doc.BillingReference, BillingReference{
+				InvoiceDocumentReference: DocumentReference{
+					ID:        ref.OriginalDocumentID,
+					UUID:      string(ref.UUID),
+					IssueDate: ref.IssueDate,
+				},
+			})
+		}
+	}
+
+	if refs := inv.DeliveryNoteReferences; refs != nil {
+		for _, ref := range refs.DeliveryNoteReference {
+			doc.DespatchDocumentReference = append(doc.DespatchDocumentReference, DocumentReference{
+				ID:        ref.DeliveryNoteID,
+				UUID:      string(ref.UUID),
+				IssueDate: ref.IssueDate,
+			})
+		}
+	}
+
+	if refs := inv.ContractReferences; refs != nil {
+		for i, ref := range refs.ContractReference {
+			doc.ContractDocumentReference = append(doc.ContractDocumentReference, DocumentReference{
+				ID:        ref.ContractID,
+				UUID:      string(ref.UUID),
+				IssueDate: ref.IssueDate,
+			})
+			if !ref.LastValidDate.IsZero() || ref.LastValidDateUnbounded.Bool() || ref.ISDS_ID != "" ||
+				ref.FileReference != "" || ref.ReferenceNumber != "" {
+				c.warn(fmt.Sprintf("Invoice.ContractReferences.ContractReference[%d]", i),
+					"only the contract number, UUID and date are converted")
+			}
+		}
+	}
+}
+
+func (c *fromISDOC) party(path string, p *schema.Party) Party {
+	out := Party{
+		PartyName:     &PartyName{Name: p.PartyName.Name},
+		PostalAddress: address(&p.PostalAddress),
+		PartyLegalEntity: &LegalEntity{
+			RegistrationName: p.PartyName.Name,
+		},
+	}
+
+	id := p.PartyIdentification
+	if id.ID != "" {
+		out.PartyIdentification = append(out.PartyIdentification, Identification{ID: Identifier{Value: id.ID}})
+		out.PartyLegalEntity.CompanyID = &Identifier{Value: id.ID}
+	}
+	switch gln := id.CatalogFirmIdentification; {
+	case en16931.IsGLN(gln):
+		out.EndpointID = &Identifier{Value: gln, SchemeID: "0088"}
+		out.PartyIdentification = append(out.PartyIdentification, Identification{ID: Identifier{Value: gln, SchemeID: "0088"}})
+	case gln != "" && gln != "0":
+		c.warn(path+".PartyIdentification.CatalogFirmIdentification", "%q is not a GLN", gln)
+	}
+	if id.UserID != "" {
+		c.warn(path+".PartyIdentification.UserID", "UserID has no UBL counterpart")
+	}
+
+	for _, ts := range p.PartyTaxScheme {
+		out.PartyTaxScheme = append(out.PartyTaxScheme, PartyTaxScheme{
+			CompanyID: ts.CompanyID,
+			TaxScheme: TaxScheme{ID: ts.TaxScheme},
+		})
+		if scheme, ok := en16931.EndpointSchemes[en16931.Prefix(ts.CompanyID)]; ok && out.EndpointID == nil && ts.TaxScheme == "VAT" {
+			out.EndpointID = &Identifier{Value: ts.CompanyID, SchemeID: scheme}
+		}
+	}
+
+	if reg := p.RegisterIdentification; reg != nil {
+		out.PartyLegalEntity.CompanyLegalForm = reg.Preformatted
+		if reg.Preformatted == "" {
+			out.PartyLegalEntity.CompanyLegalForm = en16931.JoinNonEmpty(", ", reg.RegisterKeptAt, reg.RegisterFileRef)
+		}
+		if reg.RegisterKeptAt != "" || reg.RegisterFileRef != "" || !reg.RegisterDate.IsZero() {
+			c.warn(path+".RegisterIdentification", "the register entry is written as text")
+		}
+	}
+
+	if ct := p.Contact; ct != nil {
+		out.Contact = &Contact{Name: ct.Name, Telephone: ct.Telephone, ElectronicMail: ct.ElectronicMail}
+	}
+	return out
+}
+
+func address(a *schema.PostalAddress) *Address {
+	return &Address{
+		StreetName:     a.StreetName,
+		BuildingNumber: a.BuildingNumber,
+		CityName:       a.CityName,
+		PostalZone:     a.PostalZone,
+		Country: Country{
+			IdentificationCode: a.Country.IdentificationCode,
+			Name:               a.Country.Name,
+		},
+	}
+}
+
+func (c *fromISDOC) delivery(doc *Document) {
+	d := c.inv.Delivery
+	if d == nil {
+		return
+	}
+	doc.Delivery = &Delivery{
+		DeliveryLocation: &DeliveryLocation{Address: address(&d.Party.PostalAddress)},
+		DeliveryParty:    &DeliveryParty{PartyName: PartyName{Name: d.Party.PartyName.Name}},
+	}
+	p := d.Party
+	if p.PartyIdentification.ID != "" || len(p.PartyTaxScheme) > 0 || p.RegisterIdentification != nil || p.Contact != nil {
+		c.warn("Invoice.Delivery.Party", "only the name and address of the delivery party are converted")
+	}
+}
+
+func (c *fromISDOC) paymentMeans(doc *Document) {
+	pm := c.inv.PaymentMeans
+	if pm == nil {
+		return
+	}
+
+	payable := c.inv.LegalMonetaryTotal.PayableAmount
+	for i, p := range pm.Payment {
+		path := fmt.Sprintf("Invoice.PaymentMeans.Payment[%d]", i)
+		m := PaymentMeans{PaymentMeansCode: strconv.Itoa(int(p.PaymentMeansCode))}
+		if d := p.Details; d != nil {
+			switch {
+			case doc.CreditNote:
+				m.PaymentDueDate = d.PaymentDueDate
+			case doc.DueDate.IsZero():
+				doc.DueDate = d.PaymentDueDate
+			case !d.PaymentDueDate.IsZero() && d.PaymentDueDate != doc.DueDate:
+				c.warn(path+".Details.PaymentDueDate", "only the first due date is converted")
+			}
+			m.PaymentID = d.VariableSymbol
+			if d.BankAccount != nil {
+				m.PayeeFinancialAccount = c.financialAccount(path+".Details.BankAccount", d.BankAccount)
+			}
+			if d.DocumentID != "" || !d.IssueDate.IsZero() || d.ConstantSymbol != "" || d.SpecificSymbol != "" {
+				c.warn(path+".Details", "only the due date, variable symbol and bank account are converted")
+			}
+		}
+		if !p.PaidAmount.Equal(payable) {
+			c.warn(path+".PaidAmount", "payment amounts have no UBL counterpart")
+		}
+		doc.PaymentMeans = append(doc.PaymentMeans, m)
+	}
+
+	if alt := pm.AlternateBankAccounts; alt != nil && len(doc.PaymentMeans) > 0 {
+		first := doc.PaymentMeans[0]
+		for i := range alt.AlternateBankAccount {
+			path := fmt.Sprintf("Invoice.PaymentMeans.AlternateBankAccounts.AlternateBankAccount[%d]", i)
+			m := first
+			m.PayeeFinancialAccount = c.financialAccount(path, &alt.AlternateBankAccount[i])
+			doc.PaymentMeans = append(doc.PaymentMeans, m)
+		}
+	}
+}
+
+// financialAccount prefers the IBAN over the domestic account number.
+func (c *fromISDOC) financialAccount(path string, a *schema.BankAccount) *FinancialAccount {
+	fa := &FinancialAccount{ID: a.IBAN, Name: a.Name}
+	if fa.ID == "" {
+		fa.ID = a.ID
+		if a.BankCode != "" {
+			fa.ID += "/" + a.BankCode
+		}
+	} else if a.ID != "" {
+		if id, code, ok := en16931.CzechAccount(a.IBAN); !ok || id != a.ID || code != a.BankCode {
+			c.warn(path+".ID", "the domestic account number is replaced by the IBAN")
+		}
+	}
+	if a.BIC != "" {
+		fa.FinancialInstitutionBranch = &Branch{ID: a.BIC}
+	}
+	return fa
+}
+
+func (c *fromISDOC) lines(doc *Document) {
+	for i := range c.inv.InvoiceLines.InvoiceLine {
+		l := &c.inv.InvoiceLines.InvoiceLine[i]
+		path := fmt.Sprintf("Invoice.InvoiceLines.InvoiceLine[%d]", i)
+
+		qty := l.InvoicedQuantity.Value
+		if qty.IsZero() {
+			qty = "1"
+		}
+		if c.negate {
+			qty = en16931.Negate(qty)
+		}
+
+		price := en16931.ParseRat(l.UnitPrice)
+		if c.rate != nil {
+			if q := en16931.ParseRat(qty); !l.LineExtensionAmountCurr.IsZero() && q.Sign() != 0 {
+				price = new(big.Rat).Quo(en16931.ParseRat(c.value(l.LineExtensionAmount, l.LineExtensionAmountCurr)), q)
+			} else {
+				price.Mul(price, c.rate)
+			}
+		}
+		// UBL prices are never negative
+		if price.Sign() < 0 {
+			price.Neg(price)
+			qty = en16931.Negate(qty)
+		}
+
+		out := Line{
+			ID:                  l.ID,
+			Note:                l.Note,
+			LineExtensionAmount: c.amount(l.LineExtensionAmount, l.LineExtensionAmountCurr),
+			Item: Item{
+				Name: l.Item.Description,
+				ClassifiedTaxCategory: taxCategory(c.inv.VATApplicable.Bool(),
+					l.ClassifiedTaxCategory.Percent, l.ClassifiedTaxCategory.LocalReverseCharge != nil),
+			},
+			Price: Price{PriceAmount: Amount{Value: en16931.FormatPrice(price), CurrencyID: c.currency}},
+		}
+		q := &Quantity{Value: qty, UnitCode: l.InvoicedQuantity.UnitCode}
+		if doc.CreditNote {
+			out.CreditedQuantity = q
+		} else {
+			out.InvoicedQuantity = q
+		}
+
+		if ref := l.OrderReference; ref != nil && ref.LineID != "" {
+			out.OrderLineReference = &LineReference{LineID: ref.LineID}
+		}
+		if l.DeliveryNoteReference != nil || l.OriginalDocumentReference != nil || l.ContractReference != nil {
+			c.warn(path, "line references other than the order line have no UBL counterpart")
+		}
+
+		item := &l.Item
+		if id := item.SellersItemIdentification; id != nil {
+			out.Item.SellersItemIdentification = &Identification{ID: Identifier{Value: id.ID}}
+		}
+		if id := item.BuyersItemIdentification; id != nil {
+			out.Item.BuyersItemIdentification = &Identification{ID: Identifier{Value: id.ID}}
+		}
+		if id := item.CatalogueItemIdentification; id != nil {
+			std := &Identification{ID: Identifier{Value: id.ID}}
+			if en16931.IsGTIN(id.ID) {
+				std.ID.SchemeID = "0160"
+			}
+			out.Item.StandardItemIdentification = std
+		}
+
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"Item.SecondarySellersItemIdentification", hasID(item.SecondarySellersItemIdentification)},
+			{"Item.TertiarySellersItemIdentification", hasID(item.TertiarySellersItemIdentification)},
+			{"Item.StoreBatches", item.StoreBatches != nil},
+			{"EgovClassifier", l.EgovClassifier != ""},
+			{"VATNote", l.VATNote != ""},
+			{"Extensions", l.Extensions != nil},
+			{"LineExtensionAmountBeforeDiscount", !l.LineExtensionAmountBeforeDiscount.IsZero()},
+		} {
+			if f.set {
+				c.warn(path+"."+f.name, "%s has no UBL counterpart", f.name)
+			}
+		}
+		if l.ClassifiedTaxCategory.VATCalculationMethod == types.VATCalculationFromTop {
+			c.warn(path+".ClassifiedTaxCategory.VATCalculationMethod",
+				"VAT calculated from the tax-inclusive price is written as net amounts")
+		}
+		if rc := l.ClassifiedTaxCategory.LocalReverseCharge; rc != nil && (rc.LocalReverseChargeCode != "" || !rc.LocalReverseChargeQuantity.IsZero()) {
+			c.warn(path+".ClassifiedTaxCategory.LocalReverseCharge", "the reverse charge code has no UBL counterpart")
+		}
+
+		doc.Lines = append(doc.Lines, out)
+	}
+}
+
+func (c *fromISDOC) taxTotal(doc *Document) {
+	vat := c.inv.VATApplicable.Bool()
+	tt := &c.inv.TaxTotal
+
+	total := TaxTotal{TaxAmount: c.amount(tt.TaxAmount, tt.TaxAmountCurr)}
+	for i, st := range tt.TaxSubTotal {
+		reverseCharge := st.TaxCategory.LocalReverseChargeFlag.Bool()
+		total.TaxSubtotal = append(total.TaxSubtotal, TaxSubtotal{
+			TaxableAmount: c.amount(st.TaxableAmount, st.TaxableAmountCurr),
+			TaxAmount:     c.amount(st.TaxAmount, st.TaxAmountCurr),
+			TaxCategory:   taxCategory(vat, st.TaxCategory.Percent, reverseCharge),
+		})
+		if reverseCharge && en16931.ParseRat(st.TaxCategory.Percent).Sign() != 0 {
+			c.warn(fmt.Sprintf("Invoice.TaxTotal.TaxSubTotal[%d].TaxCategory.Percent", i),
+				"the reverse charge rate %s is written as 0", st.TaxCategory.Percent)
+		}
+	}
+	doc.TaxTotal = []TaxTotal{total}
+
+	if c.rate != nil {
+		tax := tt.TaxAmount
+		if c.negate {
+			tax = en16931.Negate(tax)
+		}
+		doc.TaxTotal = append(doc.TaxTotal, TaxTotal{
+			TaxAmount: Amount{Value: tax, CurrencyID: c.inv.LocalCurrencyCode},
+		})
+	}
+}
+
+func (c *fromISDOC) monetaryTotal(doc *Document) {
+	lmt := &c.inv.LegalMonetaryTotal
+
+	lines := new(big.Rat)
+	for _, l := range doc.Lines {
+		lines.Add(lines, en16931.ParseRat(l.LineExtensionAmount.Value))
+	}
+
+	total := MonetaryTotal{
+		LineExtensionAmount: Amount{Value: en16931.FormatAmount(lines), CurrencyID: c.currency},
+		TaxExclusiveAmount:  c.amount(lmt.TaxExclusiveAmount, lmt.TaxExclusiveAmountCurr),
+		TaxInclusiveAmount:  c.amount(lmt.TaxInclusiveAmount, lmt.TaxInclusiveAmountCurr),
+		PayableAmount:       c.amount(lmt.PayableAmount, lmt.PayableAmountCurr),
+	}
+
+	// Deposits and amounts claimed by earlier documents are prepaid
+	claimed := en16931.ParseRat(c.value(lmt.AlreadyClaimedTaxInclusiveAmount, lmt.AlreadyClaimedTaxInclusiveAmountCurr))
+	// ISDOC deducts paid deposits whatever their sign
+	deposits := new(big.Rat).Abs(en16931.ParseRat(c.value(lmt.PaidDepositsAmount, lmt.PaidDepositsAmountCurr)))
+	if c.negate {
+		deposits.Neg(deposits)
+	}
+	if prepaid := claimed.Add(claimed, deposits); prepaid.Sign() != 0 {
+		total.PrepaidAmount = &Amount{Value: en16931.FormatAmount(prepaid), CurrencyID: c.currency}
+	}
+	if !lmt.PayableRoundingAmount.IsZero() {
+		rounding := c.amount(lmt.PayableRoundingAmount, lmt.PayableRoundingAmountCurr)
+		total.PayableRoundingAmount = &rounding
+	}
+	doc.LegalMonetaryTotal = total
+}
+
+func hasID(id *schema.ItemIdentification) bool {
+	return id != nil && id.ID != ""
+}
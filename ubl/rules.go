@@ -0,0 +1,659 @@
+package ubl
+
+import (
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/types"
+)
+
+// Validate checks doc against the EN 16931 business rules (BR-*, BR-CO-*,
+// BR-DEC-* and the rules of each VAT category) and the Peppol BIS Billing
+// 3.0 rules (PEPPOL-EN16931-*) that apply to the elements of Document.
+// Each issue carries the official rule identifier in Rule and the UBL path
+// of the offending element in Field.
+//
+// Use it on the result of FromISDOC to check a converted invoice before
+// sending it:
+//
+//	doc, warnings := ubl.FromISDOC(invoice)
+//	if errs := ubl.Validate(doc); errs.HasErrors() {
+//		// not acceptable in the Peppol network
+//	}
+func Validate(doc *Document) isdoc.ValidationErrors {
+	v := &validator{doc: doc, root: "Invoice", line: "InvoiceLine", typeCode: "InvoiceTypeCode"}
+	if doc.CreditNote {
+		v.root, v.line, v.typeCode = "CreditNote", "CreditNoteLine", "CreditNoteTypeCode"
+	}
+
+	v.header()
+	v.parties()
+	v.payment()
+	v.lines()
+	v.totals()
+	v.vat()
+	v.peppol()
+
+	return v.errs
+}
+
+type validator struct {
+	doc                  *Document
+	root, line, typeCode string
+	errs                 isdoc.ValidationErrors
+}
+
+// fail reports a broken rule. All rules checked are fatal in EN 16931 and
+// Peppol BIS Billing 3.0.
+func (v *validator) fail(rule, field, code, format string, args ...any) {
+	v.errs = append(v.errs, &isdoc.ValidationError{
+		Field:    v.root + "." + field,
+		Code:     code,
+		Severity: isdoc.SeverityError,
+		Msg:      fmt.Sprintf(format, args...),
+		Rule:     rule,
+	})
+}
+
+// require reports rule when value is empty.
+func (v *validator) require(rule, field, value, what string) {
+	if strings.TrimSpace(value) == "" {
+		v.fail(rule, field, isdoc.ErrCodeRequiredField, "%s is required", what)
+	}
+}
+
+// decimals reports rule when an amount has more than two decimal places.
+func (v *validator) decimals(rule, field string, d types.Decimal) {
+	if _, frac, ok := strings.Cut(string(d), "."); ok && len(frac) > 2 {
+		v.fail(rule, field, isdoc.ErrCodeInvalidDecimal, "amount %s has more than two decimal places", d)
+	}
+}
+
+// mismatch reports rule when got differs from want by more than tolerance.
+func (v *validator) mismatch(rule, field, code string, got types.Decimal, want, tolerance *big.Rat, what string) {
+	diff := new(big.Rat).Sub(en16931.ParseRat(got), want)
+	if diff.Abs(diff).Cmp(tolerance) > 0 {
+		v.fail(rule, field, code, "%s is %s, expected %s", what, got, en16931.FormatAmount(want))
+	}
+}
+
+var zero = new(big.Rat)
+
+func (v *validator) header() {
+	d := v.doc
+	v.require("BR-01", "CustomizationID", d.CustomizationID, "specification identifier")
+	v.require("BR-02", "ID", d.ID, "invoice number")
+	if d.IssueDate.IsZero() {
+		v.fail("BR-03", "IssueDate", isdoc.ErrCodeRequiredField, "issue date is required")
+	}
+	v.require("BR-04", v.typeCode, d.TypeCode, "invoice type code")
+	v.require("BR-05", "DocumentCurrencyCode", d.DocumentCurrencyCode, "invoice currency code")
+	for i, r := range d.BillingReference {
+		v.require("BR-55", fmt.Sprintf("BillingReference[%d].InvoiceDocumentReference.ID", i),
+			r.InvoiceDocumentReference.ID, "preceding invoice reference")
+	}
+}
+
+func (v *validator) parties() {
+	seller := &v.doc.AccountingSupplierParty.Party
+	path := "AccountingSupplierParty.Party"
+	if seller.PartyLegalEntity == nil || strings.TrimSpace(seller.PartyLegalEntity.RegistrationName) == "" {
+		v.fail("BR-06", path+".PartyLegalEntity.RegistrationName", isdoc.ErrCodeRequiredField, "seller name is required")
+	}
+	if seller.PostalAddress == nil {
+		v.fail("BR-08", path+".PostalAddress", isdoc.ErrCodeRequiredField, "seller postal address is required")
+	} else {
+		v.require("BR-09", path+".PostalAddress.Country.IdentificationCode",
+			seller.PostalAddress.Country.IdentificationCode, "seller country code")
+	}
+	if len(seller.PartyIdentification) == 0 && legalID(seller) == "" && vatID(seller) == "" {
+		v.fail("BR-CO-26", path, isdoc.ErrCodeRequiredField,
+			"seller identifier, legal registration identifier or VAT identifier is required")
+	}
+	v.vatPrefix(path, seller)
+
+	path = "AccountingCustomerParty.Party"
+	buyer := v.buyer()
+	if buyer == nil {
+		v.fail("BR-07", "AccountingCustomerParty", isdoc.ErrCodeRequiredField, "buyer is required")
+		return
+	}
+	if buyer.PartyLegalEntity == nil || strings.TrimSpace(buyer.PartyLegalEntity.RegistrationName) == "" {
+		v.fail("BR-07", path+".PartyLegalEntity.RegistrationName", isdoc.ErrCodeRequiredField, "buyer name is required")
+	}
+	if buyer.PostalAddress == nil {
+		v.fail("BR-10", path+".PostalAddress", isdoc.ErrCodeRequiredField, "buyer postal address is required")
+	} else {
+		v.require("BR-11", path+".PostalAddress.Country.IdentificationCode",
+			buyer.PostalAddress.Country.IdentificationCode, "buyer country code")
+	}
+	v.vatPrefix(path, buyer)
+}
+
+// vatPrefix checks BR-CO-09: VAT identifiers start with a country code.
+func (v *validator) vatPrefix(path string, p *Party) {
+	for i, s := range p.PartyTaxScheme {
+		if s.TaxScheme.ID != "VAT" {
+			continue
+		}
+		if id := s.CompanyID; len(id) < 2 || !isUpper(id[0]) || !isUpper(id[1]) {
+			v.fail("BR-CO-09", fmt.Sprintf("%s.PartyTaxScheme[%d].CompanyID", path, i), isdoc.ErrCodeInvalidPattern,
+				"VAT identifier %q must start with an ISO 3166-1 alpha-2 country code", id)
+		}
+	}
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func (v *validator) buyer() *Party {
+	if v.doc.AccountingCustomerParty == nil {
+		return nil
+	}
+	return &v.doc.AccountingCustomerParty.Party
+}
+
+// vatID returns the VAT identifier of p, if any.
+func vatID(p *Party) string {
+	if p == nil {
+		return ""
+	}
+	for _, s := range p.PartyTaxScheme {
+		if s.TaxScheme.ID == "VAT" {
+			return s.CompanyID
+		}
+	}
+	return ""
+}
+
+// legalID returns the legal registration identifier of p, if any.
+func legalID(p *Party) string {
+	if p == nil || p.PartyLegalEntity == nil || p.PartyLegalEntity.CompanyID == nil {
+		return ""
+	}
+	return p.PartyLegalEntity.CompanyID.Value
+}
+
+func (v *validator) payment() {
+	d := v.doc
+	for i, pm := range d.PaymentMeans {
+		path := fmt.Sprintf("PaymentMeans[%d]", i)
+		v.require("BR-49", path+".PaymentMeansCode", pm.PaymentMeansCode, "payment means type code")
+		switch {
+		case pm.PayeeFinancialAccount != nil:
+			v.require("BR-50", path+".PayeeFinancialAccount.ID", pm.PayeeFinancialAccount.ID, "payment account identifier")
+		case pm.PaymentMeansCode == "30" || pm.PaymentMeansCode == "58":
+			v.fail("BR-61", path+".PayeeFinancialAccount", isdoc.ErrCodeRequiredField,
+				"payment account identifier is required for credit transfers")
+		}
+	}
+
+	if en16931.ParseRat(d.LegalMonetaryTotal.PayableAmount.Value).Sign() <= 0 || !d.DueDate.IsZero() {
+		return
+	}
+	for _, pm := range d.PaymentMeans {
+		if !pm.PaymentDueDate.IsZero() {
+			return
+		}
+	}
+	v.fail("BR-CO-25", "DueDate", isdoc.ErrCodeRequiredField,
+		"payment due date or payment terms are required when the amount due is positive")
+}
+
+func (v *validator) lines() {
+	if len(v.doc.Lines) == 0 {
+		v.fail("BR-16", v.line, isdoc.ErrCodeRequiredField, "at least one invoice line is required")
+	}
+	for i := range v.doc.Lines {
+		l := &v.doc.Lines[i]
+		path := fmt.Sprintf("%s[%d]", v.line, i)
+		v.require("BR-21", path+".ID", l.ID, "invoice line identifier")
+
+		q := l.Quantity()
+		quantity := "InvoicedQuantity"
+		if v.doc.CreditNote {
+			quantity = "CreditedQuantity"
+		}
+		if q == nil || q.Value == "" {
+			v.fail("BR-22", path+"."+quantity, isdoc.ErrCodeRequiredField, "invoiced quantity is required")
+		} else {
+			v.require("BR-23", path+"."+quantity+".unitCode", q.UnitCode, "unit of measure code")
+		}
+
+		v.require("BR-24", path+".LineExtensionAmount", string(l.LineExtensionAmount.Value), "invoice line net amount")
+		v.decimals("BR-DEC-23", path+".LineExtensionAmount", l.LineExtensionAmount.Value)
+		v.require("BR-25", path+".Item.Name", l.Item.Name, "item name")
+		if l.Price.PriceAmount.Value == "" {
+			v.fail("BR-26", path+".Price.PriceAmount", isdoc.ErrCodeRequiredField, "item net price is required")
+		} else if en16931.ParseRat(l.Price.PriceAmount.Value).Sign() < 0 {
+			v.fail("BR-27", path+".Price.PriceAmount", isdoc.ErrCodeInvalidDecimal, "item net price must not be negative")
+		}
+		v.require("BR-CO-04", path+".Item.ClassifiedTaxCategory.ID", l.Item.ClassifiedTaxCategory.ID, "invoiced item VAT category code")
+
+		if b := l.Price.BaseQuantity; b != nil && b.Value != "" {
+			if en16931.ParseRat(b.Value).Sign() <= 0 {
+				v.fail("PEPPOL-EN16931-R121", path+".Price.BaseQuantity", isdoc.ErrCodeInvalidDecimal,
+					"base quantity must be a positive number above zero")
+				continue
+			}
+			if q != nil && b.UnitCode != "" && b.UnitCode != q.UnitCode {
+				v.fail("PEPPOL-EN16931-R130", path+".Price.BaseQuantity.unitCode", isdoc.ErrCodeInvalidEnum,
+					"unit code of the base quantity %q differs from the invoiced quantity %q", b.UnitCode, q.UnitCode)
+			}
+		}
+		if q != nil && q.Value != "" && l.Price.PriceAmount.Value != "" && l.LineExtensionAmount.Value != "" {
+			want := new(big.Rat).Mul(en16931.ParseRat(q.Value), en16931.ParseRat(l.Price.PriceAmount.Value))
+			if b := l.Price.BaseQuantity; b != nil && en16931.ParseRat(b.Value).Sign() > 0 {
+				want.Quo(want, en16931.ParseRat(b.Value))
+			}
+			v.mismatch("PEPPOL-EN16931-R120", path+".LineExtensionAmount", isdoc.ErrCodeTotalMismatch,
+				l.LineExtensionAmount.Value, want, big.NewRat(2, 100), "invoice line net amount")
+		}
+	}
+}
+
+func (v *validator) totals() {
+	t := &v.doc.LegalMonetaryTotal
+	path := "LegalMonetaryTotal."
+	v.require("BR-12", path+"LineExtensionAmount", string(t.LineExtensionAmount.Value), "sum of invoice line net amounts")
+	v.require("BR-13", path+"TaxExclusiveAmount", string(t.TaxExclusiveAmount.Value), "invoice total amount without VAT")
+	v.require("BR-14", path+"TaxInclusiveAmount", string(t.TaxInclusiveAmount.Value), "invoice total amount with VAT")
+	v.require("BR-15", path+"PayableAmount", string(t.PayableAmount.Value), "amount due for payment")
+
+	v.decimals("BR-DEC-09", path+"LineExtensionAmount", t.LineExtensionAmount.Value)
+	v.decimals("BR-DEC-12", path+"TaxExclusiveAmount", t.TaxExclusiveAmount.Value)
+	v.decimals("BR-DEC-14", path+"TaxInclusiveAmount", t.TaxInclusiveAmount.Value)
+	v.decimals("BR-DEC-18", path+"PayableAmount", t.PayableAmount.Value)
+	for _, a := range []struct {
+		rule, name string
+		amount     *Amount
+	}{
+		{"BR-DEC-10", "AllowanceTotalAmount", t.AllowanceTotalAmount},
+		{"BR-DEC-11", "ChargeTotalAmount", t.ChargeTotalAmount},
+		{"BR-DEC-16", "PrepaidAmount", t.PrepaidAmount},
+		{"BR-DEC-17", "PayableRoundingAmount", t.PayableRoundingAmount},
+	} {
+		if a.amount != nil {
+			v.decimals(a.rule, path+a.name, a.amount.Value)
+		}
+	}
+
+	lines := new(big.Rat)
+	for _, l := range v.doc.Lines {
+		lines.Add(lines, en16931.ParseRat(l.LineExtensionAmount.Value))
+	}
+	v.mismatch("BR-CO-10", path+"LineExtensionAmount", isdoc.ErrCodeTotalMismatch,
+		t.LineExtensionAmount.Value, lines, zero, "sum of invoice line net amounts")
+
+	allowances, charges := new(big.Rat), new(big.Rat)
+	for i, ac := range v.doc.AllowanceCharge {
+		rule, sum := "BR-DEC-01", allowances
+		if ac.ChargeIndicator {
+			rule, sum = "BR-DEC-05", charges
+		}
+		v.decimals(rule, fmt.Sprintf("AllowanceCharge[%d].Amount", i), ac.Amount.Value)
+		sum.Add(sum, en16931.ParseRat(ac.Amount.Value))
+	}
+	allowanceTotal := optional(t.AllowanceTotalAmount)
+	if t.AllowanceTotalAmount != nil || allowances.Sign() != 0 {
+		v.mismatch("BR-CO-11", path+"AllowanceTotalAmount", isdoc.ErrCodeTotalMismatch,
+			allowanceTotal, allowances, zero, "sum of allowances on document level")
+	}
+	chargeTotal := optional(t.ChargeTotalAmount)
+	if t.ChargeTotalAmount != nil || charges.Sign() != 0 {
+		v.mismatch("BR-CO-12", path+"ChargeTotalAmount", isdoc.ErrCodeTotalMismatch,
+			chargeTotal, charges, zero, "sum of charges on document level")
+	}
+
+	exclusive := en16931.ParseRat(t.LineExtensionAmount.Value)
+	exclusive.Sub(exclusive, en16931.ParseRat(allowanceTotal))
+	exclusive.Add(exclusive, en16931.ParseRat(chargeTotal))
+	v.mismatch("BR-CO-13", path+"TaxExclusiveAmount", isdoc.ErrCodeTotalMismatch,
+		t.TaxExclusiveAmount.Value, exclusive, zero, "invoice total amount without VAT")
+
+	inclusive := en16931.ParseRat(t.TaxExclusiveAmount.Value)
+	if tax := v.taxTotal(); tax != nil {
+		inclusive.Add(inclusive, en16931.ParseRat(tax.TaxAmount.Value))
+	}
+	v.mismatch("BR-CO-15", path+"TaxInclusiveAmount", isdoc.ErrCodeTotalMismatch,
+		t.TaxInclusiveAmount.Value, inclusive, zero, "invoice total amount with VAT")
+
+	payable := en16931.ParseRat(t.TaxInclusiveAmount.Value)
+	payable.Sub(payable, en16931.ParseRat(optional(t.PrepaidAmount)))
+	payable.Add(payable, en16931.ParseRat(optional(t.PayableRoundingAmount)))
+	v.mismatch("BR-CO-16", path+"PayableAmount", isdoc.ErrCodeTotalMismatch,
+		t.PayableAmount.Value, payable, zero, "amount due for payment")
+}
+
+func optional(a *Amount) types.Decimal {
+	if a == nil {
+		return ""
+	}
+	return a.Value
+}
+
+// taxTotal returns the TaxTotal in the document currency, the one with the
+// VAT breakdown.
+func (v *validator) taxTotal() *TaxTotal {
+	for i := range v.doc.TaxTotal {
+		t := &v.doc.TaxTotal[i]
+		if len(t.TaxSubtotal) > 0 || t.TaxAmount.CurrencyID == v.doc.DocumentCurrencyCode {
+			return t
+		}
+	}
+	return nil
+}
+
+// categoryRules maps UNCL5305 codes to the prefix of their EN 16931 rules.
+var categoryRules = map[string]string{
+	CategoryStandard:      "S",
+	CategoryZero:          "Z",
+	CategoryExempt:        "E",
+	CategoryReverseCharge: "AE",
+	CategoryIntraEU:       "IC",
+	CategoryExport:        "G",
+	CategoryOutOfScope:    "O",
+	"L":                   "AF",
+	"M":                   "AG",
+}
+
+// vatKey identifies a VAT breakdown: the category and, for categories with
+// a rate, the rate.
+type vatKey struct {
+	category string
+	percent  string
+}
+
+func keyOf(c TaxCategory) vatKey {
+	k := vatKey{category: c.ID}
+	if c.Percent != "" {
+		k.percent = en16931.ParseRat(c.Percent).RatString()
+	}
+	if c.ID == CategoryOutOfScope {
+		k.percent = ""
+	}
+	return k
+}
+
+func (v *validator) vat() {
+	d := v.doc
+	tax := v.taxTotal()
+	if tax == nil || len(tax.TaxSubtotal) == 0 {
+		v.fail("BR-CO-18", "TaxTotal", isdoc.ErrCodeRequiredField, "at least one VAT breakdown is required")
+	}
+
+	// taxable amounts per breakdown computed from lines and allowances
+	basis := map[vatKey]*big.Rat{}
+	used := map[string]bool{}
+	add := func(c TaxCategory, amount *big.Rat) {
+		k := keyOf(c)
+		if basis[k] == nil {
+			basis[k] = new(big.Rat)
+		}
+		basis[k].Add(basis[k], amount)
+		used[c.ID] = true
+	}
+
+	seller, buyer := &d.AccountingSupplierParty.Party, v.buyer()
+	for i := range d.Lines {
+		c := d.Lines[i].Item.ClassifiedTaxCategory
+		path := fmt.Sprintf("%s[%d].Item.ClassifiedTaxCategory", v.line, i)
+		add(c, en16931.ParseRat(d.Lines[i].LineExtensionAmount.Value))
+		v.categoryRate(c, path, "05")
+	}
+	for i, ac := range d.AllowanceCharge {
+		path := fmt.Sprintf("AllowanceCharge[%d].TaxCategory", i)
+		if ac.TaxCategory == nil || ac.TaxCategory.ID == "" {
+			rule := "BR-32"
+			if ac.ChargeIndicator {
+				rule = "BR-37"
+			}
+			v.fail(rule, path, isdoc.ErrCodeRequiredField, "VAT category code of the allowance or charge is required")
+			continue
+		}
+		amount := en16931.ParseRat(ac.Amount.Value)
+		if !ac.ChargeIndicator {
+			amount.Neg(amount)
+		}
+		add(*ac.TaxCategory, amount)
+		suffix := "06"
+		if ac.ChargeIndicator {
+			suffix = "07"
+		}
+		v.categoryRate(*ac.TaxCategory, path, suffix)
+	}
+
+	categories := slices.Sorted(maps.Keys(used))
+	for _, c := range categories {
+		v.categoryParties(c, seller, buyer)
+	}
+	if tax == nil {
+		return
+	}
+
+	v.decimals("BR-DEC-13", "TaxTotal.TaxAmount", tax.TaxAmount.Value)
+	sum := new(big.Rat)
+	breakdowns := map[string]int{}
+	for i, s := range tax.TaxSubtotal {
+		path := fmt.Sprintf("TaxTotal.TaxSubtotal[%d]", i)
+		sum.Add(sum, en16931.ParseRat(s.TaxAmount.Value))
+		v.decimals("BR-DEC-19", path+".TaxableAmount", s.TaxableAmount.Value)
+		v.decimals("BR-DEC-20", path+".TaxAmount", s.TaxAmount.Value)
+		breakdowns[s.TaxCategory.ID]++
+
+		prefix, ok := categoryRules[s.TaxCategory.ID]
+		if !ok {
+			v.fail("BR-CL-17", path+".TaxCategory.ID", isdoc.ErrCodeInvalidEnum,
+				"unknown VAT category code %q", s.TaxCategory.ID)
+			continue
+		}
+		want := basis[keyOf(s.TaxCategory)]
+		if want == nil {
+			want = new(big.Rat)
+		}
+		v.mismatch("BR-"+prefix+"-08", path+".TaxableAmount", isdoc.ErrCodeVATMismatch,
+			s.TaxableAmount.Value, want, zero, "VAT category taxable amount")
+
+		switch prefix {
+		case "S", "AF", "AG":
+			// the CEN rules allow a difference of one currency unit
+			vat := new(big.Rat).Mul(en16931.ParseRat(s.TaxableAmount.Value), en16931.ParseRat(s.TaxCategory.Percent))
+			vat.Quo(vat, big.NewRat(100, 1))
+			v.mismatch("BR-"+prefix+"-09", path+".TaxAmount", isdoc.ErrCodeVATMismatch,
+				s.TaxAmount.Value, en16931.ParseRat(en16931.FormatAmount(vat)), big.NewRat(1, 1), "VAT category tax amount")
+		default:
+			v.mismatch("BR-"+prefix+"-09", path+".TaxAmount", isdoc.ErrCodeVATMismatch,
+				s.TaxAmount.Value, zero, zero, "VAT category tax amount")
+		}
+
+		reason := s.TaxCategory.TaxExemptionReasonCode != "" || s.TaxCategory.TaxExemptionReason != ""
+		switch prefix {
+		case "S", "Z", "AF", "AG":
+			if reason {
+				v.fail("BR-"+prefix+"-10", path+".TaxCategory.TaxExemptionReason", isdoc.ErrCodeVATMismatch,
+					"VAT category %s must not have an exemption reason", s.TaxCategory.ID)
+			}
+		default:
+			if !reason {
+				v.fail("BR-"+prefix+"-10", path+".TaxCategory.TaxExemptionReason", isdoc.ErrCodeRequiredField,
+					"VAT category %s requires an exemption reason code or text", s.TaxCategory.ID)
+			}
+		}
+	}
+	v.mismatch("BR-CO-14", "TaxTotal.TaxAmount", isdoc.ErrCodeTotalMismatch,
+		tax.TaxAmount.Value, sum, zero, "invoice total VAT amount")
+
+	for _, c := range categories {
+		prefix, ok := categoryRules[c]
+		if !ok {
+			continue
+		}
+		if breakdowns[c] == 0 {
+			v.fail("BR-"+prefix+"-01", "TaxTotal.TaxSubtotal", isdoc.ErrCodeRequiredField,
+				"a VAT breakdown with category %s is required", c)
+		} else if breakdowns[c] > 1 && prefix != "S" && prefix != "AF" && prefix != "AG" {
+			v.fail("BR-"+prefix+"-01", "TaxTotal.TaxSubtotal", isdoc.ErrCodeVATMismatch,
+				"only one VAT breakdown with category %s is allowed", c)
+		}
+	}
+}
+
+// categoryRate checks the VAT rate of a line, allowance or charge against
+// its category. The rule suffix is 05 for lines, 06 for allowances and 07
+// for charges.
+func (v *validator) categoryRate(c TaxCategory, path, suffix string) {
+	prefix, ok := categoryRules[c.ID]
+	if !ok {
+		return
+	}
+	rule := "BR-" + prefix + "-" + suffix
+	rate := en16931.ParseRat(c.Percent)
+	switch prefix {
+	case "S":
+		if rate.Sign() <= 0 {
+			v.fail(rule, path+".Percent", isdoc.ErrCodeVATMismatch, "VAT rate of category S must be greater than zero")
+		}
+	case "O":
+		if c.Percent != "" {
+			v.fail(rule, path+".Percent", isdoc.ErrCodeVATMismatch, "category O must not have a VAT rate")
+		}
+	case "AF", "AG":
+		if rate.Sign() < 0 {
+			v.fail(rule, path+".Percent", isdoc.ErrCodeVATMismatch, "VAT rate must not be negative")
+		}
+	default:
+		if rate.Sign() != 0 {
+			v.fail(rule, path+".Percent", isdoc.ErrCodeVATMismatch, "VAT rate of category %s must be 0", c.ID)
+		}
+	}
+}
+
+// categoryParties checks the VAT identifiers required or forbidden by a
+// category used in the invoice (BR-*-02).
+func (v *validator) categoryParties(category string, seller, buyer *Party) {
+	prefix, ok := categoryRules[category]
+	if !ok {
+		return
+	}
+	rule := "BR-" + prefix + "-02"
+	const sellerPath = "AccountingSupplierParty.Party.PartyTaxScheme"
+	const buyerPath = "AccountingCustomerParty.Party.PartyTaxScheme"
+
+	switch prefix {
+	case "O":
+		if vatID(seller) != "" {
+			v.fail(rule, sellerPath, isdoc.ErrCodeVATMismatch, "seller VAT identifier is not allowed with category O")
+		}
+		if vatID(buyer) != "" {
+			v.fail(rule, buyerPath, isdoc.ErrCodeVATMismatch, "buyer VAT identifier is not allowed with category O")
+		}
+		return
+	case "AF", "AG":
+		return
+	}
+	if vatID(seller) == "" {
+		v.fail(rule, sellerPath, isdoc.ErrCodeRequiredField, "seller VAT identifier is required with category %s", category)
+	}
+	switch prefix {
+	case "AE":
+		if vatID(buyer) == "" && legalID(buyer) == "" {
+			v.fail(rule, buyerPath, isdoc.ErrCodeRequiredField,
+				"buyer VAT identifier or legal registration identifier is required with category AE")
+		}
+	case "IC":
+		if vatID(buyer) == "" {
+			v.fail(rule, buyerPath, isdoc.ErrCodeRequiredField, "buyer VAT identifier is required with category K")
+		}
+	}
+}
+
+// Peppol BIS Billing 3.0 document type codes (PEPPOL-EN16931-P0100 and
+// P0101).
+var (
+	peppolInvoiceTypes = []string{
+		"71", "80", "82", "84", "102", "218", "219", "331", "380", "382", "383", "386", "388",
+		"393", "395", "553", "575", "623", "780", "817", "870", "875", "876", "877",
+	}
+	peppolCreditNoteTypes = []string{"81", "83", "261", "262", "296", "308", "381", "396", "420", "458", "532"}
+)
+
+func (v *validator) peppol() {
+	d := v.doc
+	v.require("PEPPOL-EN16931-R001", "ProfileID", d.ProfileID, "business process")
+	if !strings.HasPrefix(d.CustomizationID, CustomizationID) {
+		v.fail("PEPPOL-EN16931-R004", "CustomizationID", isdoc.ErrCodeInvalidEnum,
+			"specification identifier must start with %s", CustomizationID)
+	}
+
+	if len(d.Note) > 1 && !(country(&d.AccountingSupplierParty.Party) == "DE" && country(v.buyer()) == "DE") {
+		v.fail("PEPPOL-EN16931-R002", "Note", isdoc.ErrCodeSchemaViolation,
+			"only one note is allowed on document level unless seller and buyer are German")
+	}
+	if d.BuyerReference == "" && d.OrderReference == nil {
+		v.fail("PEPPOL-EN16931-R003", "BuyerReference", isdoc.ErrCodeRequiredField,
+			"buyer reference or purchase order reference is required")
+	}
+	if d.TaxCurrencyCode != "" && d.TaxCurrencyCode == d.DocumentCurrencyCode {
+		v.fail("PEPPOL-EN16931-R005", "TaxCurrencyCode", isdoc.ErrCodeInvalidEnum,
+			"VAT accounting currency code must differ from the invoice currency code")
+	}
+	if p := v.buyer(); p != nil && (p.EndpointID == nil || p.EndpointID.Value == "") {
+		v.fail("PEPPOL-EN16931-R010", "AccountingCustomerParty.Party.EndpointID", isdoc.ErrCodeRequiredField,
+			"buyer electronic address is required")
+	}
+	if e := d.AccountingSupplierParty.Party.EndpointID; e == nil || e.Value == "" {
+		v.fail("PEPPOL-EN16931-R020", "AccountingSupplierParty.Party.EndpointID", isdoc.ErrCodeRequiredField,
+			"seller electronic address is required")
+	}
+
+	var withSubtotals, withoutSubtotals []*TaxTotal
+	for i := range d.TaxTotal {
+		if len(d.TaxTotal[i].TaxSubtotal) > 0 {
+			withSubtotals = append(withSubtotals, &d.TaxTotal[i])
+		} else {
+			withoutSubtotals = append(withoutSubtotals, &d.TaxTotal[i])
+		}
+	}
+	if len(withSubtotals) > 1 {
+		v.fail("PEPPOL-EN16931-R053", "TaxTotal", isdoc.ErrCodeSchemaViolation,
+			"only one tax total with tax subtotals is allowed")
+	}
+	switch {
+	case d.TaxCurrencyCode != "" && len(withoutSubtotals) != 1:
+		v.fail("PEPPOL-EN16931-R054", "TaxTotal", isdoc.ErrCodeSchemaViolation,
+			"exactly one tax total without tax subtotals is required when a VAT accounting currency is given")
+	case d.TaxCurrencyCode == "" && len(withoutSubtotals) > 0:
+		v.fail("PEPPOL-EN16931-R054", "TaxTotal", isdoc.ErrCodeSchemaViolation,
+			"a tax total without tax subtotals is only allowed with a VAT accounting currency")
+	}
+	if len(withSubtotals) > 0 && len(withoutSubtotals) > 0 {
+		a := en16931.ParseRat(withSubtotals[0].TaxAmount.Value).Sign()
+		b := en16931.ParseRat(withoutSubtotals[0].TaxAmount.Value).Sign()
+		if a*b < 0 {
+			v.fail("PEPPOL-EN16931-R055", "TaxTotal", isdoc.ErrCodeVATMismatch,
+				"VAT totals in the invoice and accounting currency must have the same sign")
+		}
+		v.decimals("BR-DEC-15", "TaxTotal.TaxAmount", withoutSubtotals[0].TaxAmount.Value)
+	}
+
+	codes, rule := peppolInvoiceTypes, "PEPPOL-EN16931-P0100"
+	if d.CreditNote {
+		codes, rule = peppolCreditNoteTypes, "PEPPOL-EN16931-P0101"
+	}
+	if d.TypeCode != "" && !slices.Contains(codes, d.TypeCode) {
+		v.fail(rule, v.typeCode, isdoc.ErrCodeInvalidEnum, "type code %s is not allowed in Peppol BIS Billing 3.0", d.TypeCode)
+	}
+}
+
+func country(p *Party) string {
+	if p == nil || p.PostalAddress == nil {
+		return ""
+	}
+	return p.PostalAddress.Country.IdentificationCode
+}
@@ -0,0 +1,198 @@
+package ubl
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/types"
+)
+
+// validDocument returns an invoice that passes all rules checked by
+// Validate.
+func validDocument() *Document {
+	eur := func(v types.Decimal) Amount { return Amount{Value: v, CurrencyID: "EUR"} }
+	party := func(name, vat, endpoint string) Party {
+		return Party{
+			EndpointID:       &Identifier{Value: endpoint, SchemeID: "9920"},
+			PostalAddress:    &Address{CityName: "Praha", Country: Country{IdentificationCode: vat[:2]}},
+			PartyTaxScheme:   []PartyTaxScheme{{CompanyID: vat, TaxScheme: TaxScheme{ID: "VAT"}}},
+			PartyLegalEntity: &LegalEntity{RegistrationName: name},
+		}
+	}
+	standard := TaxCategory{ID: CategoryStandard, Percent: "21", TaxScheme: TaxScheme{ID: "VAT"}}
+	exempt := TaxCategory{ID: CategoryExempt, Percent: "0", TaxExemptionReason: "Exempt", TaxScheme: TaxScheme{ID: "VAT"}}
+
+	return &Document{
+		CustomizationID: CustomizationID,
+		ProfileID:       ProfileID,
+		ID:              "FV-1/2024",
+		IssueDate:       types.NewDate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		DueDate:         types.NewDate(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)),
+		TypeCode:        TypeCodeInvoice,
+		Body: Body{
+			DocumentCurrencyCode:    "EUR",
+			BuyerReference:          "REF-1",
+			AccountingSupplierParty: PartyWrapper{Party: party("Seller s.r.o.", "CZ12345678", "CZ12345678")},
+			AccountingCustomerParty: &PartyWrapper{Party: party("Buyer a.s.", "CZ87654321", "CZ87654321")},
+			PaymentMeans: []PaymentMeans{{
+				PaymentMeansCode:      "30",
+				PayeeFinancialAccount: &FinancialAccount{ID: "CZ6508000000192000145399"},
+			}},
+			AllowanceCharge: []AllowanceCharge{{
+				ChargeIndicator: false,
+				Reason:          "Discount",
+				Amount:          eur("10.00"),
+				TaxCategory:     &standard,
+			}},
+			TaxTotal: []TaxTotal{{
+				TaxAmount: eur("40.95"),
+				TaxSubtotal: []TaxSubtotal{
+					{TaxableAmount: eur("195.00"), TaxAmount: eur("40.95"), TaxCategory: standard},
+					{TaxableAmount: eur("50.00"), TaxAmount: eur("0.00"), TaxCategory: exempt},
+				},
+			}},
+			LegalMonetaryTotal: MonetaryTotal{
+				LineExtensionAmount:  eur("255.00"),
+				TaxExclusiveAmount:   eur("245.00"),
+				TaxInclusiveAmount:   eur("285.95"),
+				AllowanceTotalAmount: &Amount{Value: "10.00", CurrencyID: "EUR"},
+				PayableAmount:        eur("285.95"),
+			},
+		},
+		Lines: []Line{
+			{
+				ID:                  "1",
+				InvoicedQuantity:    &Quantity{Value: "2", UnitCode: "C62"},
+				LineExtensionAmount: eur("205.00"),
+				Item:                Item{Name: "Widget", ClassifiedTaxCategory: standard},
+				Price:               Price{PriceAmount: eur("102.50")},
+			},
+			{
+				ID:                  "2",
+				InvoicedQuantity:    &Quantity{Value: "1", UnitCode: "HUR"},
+				LineExtensionAmount: eur("50.00"),
+				Item:                Item{Name: "Training", ClassifiedTaxCategory: exempt},
+				Price:               Price{PriceAmount: eur("50.00")},
+			},
+		},
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	if errs := Validate(validDocument()); len(errs) > 0 {
+		t.Errorf("Validate() = %v", errs)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		rule   string
+		field  string
+		mutate func(d *Document)
+	}{
+		{"BR-02", "Invoice.ID", func(d *Document) { d.ID = "" }},
+		{"BR-06", "Invoice.AccountingSupplierParty.Party.PartyLegalEntity.RegistrationName", func(d *Document) {
+			d.AccountingSupplierParty.Party.PartyLegalEntity = nil
+		}},
+		{"BR-CO-09", "Invoice.AccountingCustomerParty.Party.PartyTaxScheme[0].CompanyID", func(d *Document) {
+			d.AccountingCustomerParty.Party.PartyTaxScheme[0].CompanyID = "87654321"
+		}},
+		{"BR-CO-10", "Invoice.LegalMonetaryTotal.LineExtensionAmount", func(d *Document) {
+			d.LegalMonetaryTotal.LineExtensionAmount.Value = "250.00"
+		}},
+		{"BR-CO-11", "Invoice.LegalMonetaryTotal.AllowanceTotalAmount", func(d *Document) {
+			d.LegalMonetaryTotal.AllowanceTotalAmount = nil
+		}},
+		{"BR-CO-15", "Invoice.LegalMonetaryTotal.TaxInclusiveAmount", func(d *Document) {
+			d.LegalMonetaryTotal.TaxInclusiveAmount.Value = "245.00"
+		}},
+		{"BR-CO-25", "Invoice.DueDate", func(d *Document) { d.DueDate = types.Date{} }},
+		{"BR-DEC-23", "Invoice.InvoiceLine[1].LineExtensionAmount", func(d *Document) {
+			d.Lines[1].LineExtensionAmount.Value = "50.000"
+		}},
+		{"BR-S-05", "Invoice.InvoiceLine[0].Item.ClassifiedTaxCategory.Percent", func(d *Document) {
+			d.Lines[0].Item.ClassifiedTaxCategory.Percent = "0"
+		}},
+		{"BR-S-08", "Invoice.TaxTotal.TaxSubtotal[0].TaxableAmount", func(d *Document) {
+			d.AllowanceCharge = nil
+			d.LegalMonetaryTotal.AllowanceTotalAmount = nil
+		}},
+		{"BR-S-09", "Invoice.TaxTotal.TaxSubtotal[0].TaxAmount", func(d *Document) {
+			d.TaxTotal[0].TaxSubtotal[0].TaxAmount.Value = "42.00"
+		}},
+		{"BR-E-10", "Invoice.TaxTotal.TaxSubtotal[1].TaxCategory.TaxExemptionReason", func(d *Document) {
+			d.TaxTotal[0].TaxSubtotal[1].TaxCategory.TaxExemptionReason = ""
+		}},
+		{"BR-E-01", "Invoice.TaxTotal.TaxSubtotal", func(d *Document) {
+			d.TaxTotal[0].TaxSubtotal = d.TaxTotal[0].TaxSubtotal[:1]
+		}},
+		{"BR-AE-02", "Invoice.AccountingCustomerParty.Party.PartyTaxScheme", func(d *Document) {
+			d.Lines[1].Item.ClassifiedTaxCategory.ID = CategoryReverseCharge
+			d.AccountingCustomerParty.Party.PartyTaxScheme = nil
+		}},
+		{"BR-61", "Invoice.PaymentMeans[0].PayeeFinancialAccount", func(d *Document) {
+			d.PaymentMeans[0].PayeeFinancialAccount = nil
+		}},
+		{"PEPPOL-EN16931-R003", "Invoice.BuyerReference", func(d *Document) { d.BuyerReference = "" }},
+		{"PEPPOL-EN16931-R020", "Invoice.AccountingSupplierParty.Party.EndpointID", func(d *Document) {
+			d.AccountingSupplierParty.Party.EndpointID = nil
+		}},
+		{"PEPPOL-EN16931-R054", "Invoice.TaxTotal", func(d *Document) { d.TaxCurrencyCode = "CZK" }},
+		{"PEPPOL-EN16931-R120", "Invoice.InvoiceLine[0].LineExtensionAmount", func(d *Document) {
+			d.Lines[0].InvoicedQuantity.Value = "3"
+		}},
+		{"PEPPOL-EN16931-P0100", "Invoice.InvoiceTypeCode", func(d *Document) { d.TypeCode = "381" }},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rule, func(t *testing.T) {
+			d := validDocument()
+			tc.mutate(d)
+			errs := Validate(d)
+			i := slices.IndexFunc(errs, func(e *isdoc.ValidationError) bool { return e.Rule == tc.rule })
+			if i < 0 {
+				t.Fatalf("rule %s not reported, got %v", tc.rule, errs)
+			}
+			if errs[i].Field != tc.field {
+				t.Errorf("Field = %q, want %q", errs[i].Field, tc.field)
+			}
+			if errs[i].Severity != isdoc.SeverityError {
+				t.Errorf("Severity = %v, want error", errs[i].Severity)
+			}
+		})
+	}
+}
+
+func TestValidateCreditNote(t *testing.T) {
+	d := validDocument()
+	d.CreditNote = true
+	d.TypeCode = TypeCodeCreditNote
+	d.Lines[0].CreditedQuantity, d.Lines[0].InvoicedQuantity = d.Lines[0].InvoicedQuantity, nil
+	d.Lines[1].CreditedQuantity = nil
+	d.Lines[1].InvoicedQuantity = nil
+
+	errs := Validate(d)
+	if len(errs) != 1 || errs[0].Rule != "BR-22" || errs[0].Field != "CreditNote.CreditNoteLine[1].CreditedQuantity" {
+		t.Errorf("Validate() = %v, want only BR-22 on CreditNoteLine[1]", errs)
+	}
+}
+
+func TestValidateConverted(t *testing.T) {
+	doc, _ := FromISDOC(loadFixture(t, "test001.isdoc"))
+	errs := Validate(doc)
+
+	// test001 has 0 % lines without a matching VAT breakdown and no buyer
+	// reference
+	for _, rule := range []string{"BR-E-01", "PEPPOL-EN16931-R003"} {
+		if !slices.ContainsFunc(errs, func(e *isdoc.ValidationError) bool { return e.Rule == rule }) {
+			t.Errorf("rule %s not reported, got %v", rule, errs)
+		}
+	}
+	for _, e := range errs {
+		if e.Rule == "" {
+			t.Errorf("issue without rule: %v", e)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+package ubl
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+// ToISDOC converts a UBL document to an ISDOC invoice. Line tax amounts and
+// tax-inclusive prices, which UBL does not carry, are computed from the VAT
+// rates; document totals are taken as given. Document level allowances and
+// charges become invoice lines. The warnings list UBL fields, by their
+// element path, that have no ISDOC counterpart.
+func ToISDOC(doc *Document) (*schema.Invoice, isdoc.ValidationErrors) {
+	root := "Invoice"
+	if doc.CreditNote {
+		root = "CreditNote"
+	}
+	c := &toISDOC{doc: doc, root: root, negate: doc.CreditNote}
+
+	docType, ok := en16931.DocumentType(doc.TypeCode, doc.CreditNote)
+	if !ok {
+		c.warn(root+".TypeCode", "type code %q is written as DocumentType %d", doc.TypeCode, docType)
+	}
+
+	inv := &schema.Invoice{
+		Version:       schema.Version,
+		DocumentType:  docType,
+		ID:            doc.ID,
+		IssueDate:     doc.IssueDate,
+		TaxPointDate:  doc.TaxPointDate,
+		VATApplicable: types.Bool(c.vatApplicable()),
+	}
+	c.inv = inv
+
+	if uuid, err := types.NewUUID(doc.UUID); err == nil && doc.UUID != "" {
+		inv.UUID = uuid
+	} else {
+		if doc.UUID != "" {
+			c.warn(root+".UUID", "%q is not a UUID and is replaced", doc.UUID)
+		}
+		inv.UUID = types.NewRandomUUID()
+	}
+
+	if len(doc.Note) > 0 {
+		texts := make([]string, len(doc.Note))
+		for i, n := range doc.Note {
+			texts[i] = n.Value
+		}
+		inv.Note = &schema.Note{Value: strings.Join(texts, "\n"), LanguageID: doc.Note[0].LanguageID}
+	}
+	if doc.BuyerReference != "" {
+		c.warn(root+".BuyerReference", "BuyerReference has no ISDOC counterpart")
+	}
+
+	c.currency()
+	c.references()
+
+	inv.AccountingSupplierParty.Party = c.party(root+".AccountingSupplierParty.Party", &doc.AccountingSupplierParty.Party)
+	if doc.AccountingCustomerParty != nil {
+		inv.AccountingCustomerParty = &schema.AccountingCustomerParty{
+			Party: c.party(root+".AccountingCustomerParty.Party", &doc.AccountingCustomerParty.Party),
+		}
+	}
+	c.delivery()
+	c.lines()
+	c.allowanceCharges()
+	c.taxTotal()
+	c.monetaryTotal()
+	c.paymentMeans()
+
+	return inv, c.warnings
+}
+
+type toISDOC struct {
+	doc  *Document
+	inv  *schema.Invoice
+	root string
+	// rate converts document amounts to the local currency; it is nil for
+	// documents in the local currency
+	rate     *big.Rat
+	negate   bool
+	warnings isdoc.ValidationErrors
+}
+
+func (c *toISDOC) warn(field, format string, args ...any) {
+	c.warnings = append(c.warnings, lossy(field, fmt.Sprintf(format, args...)))
+}
+
+// vatApplicable reports whether any VAT category is subject to VAT.
+func (c *toISDOC) vatApplicable() bool {
+	for _, tt := range c.doc.TaxTotal {
+		for _, st := range tt.TaxSubtotal {
+			if st.TaxCategory.ID != CategoryOutOfScope {
+				return true
+			}
+		}
+	}
+	for _, l := range c.doc.Lines {
+		if l.Item.ClassifiedTaxCategory.ID != CategoryOutOfScope {
+			return true
+		}
+	}
+	return false
+}
+
+// currency sets the ISDOC currencies. A TaxCurrencyCode other than the
+// document currency makes the document currency foreign; the exchange
+// rate is derived from the two VAT totals.
+func (c *toISDOC) currency() {
+	doc, inv := c.doc, c.inv
+	inv.LocalCurrencyCode = doc.DocumentCurrencyCode
+	inv.CurrRate = "1"
+	inv.RefCurrRate = "1"
+	if doc.TaxCurrencyCode == "" || doc.TaxCurrencyCode == doc.DocumentCurrencyCode {
+		return
+	}
+
+	totals := make([]en16931.TaxTotal, len(doc.TaxTotal))
+	for i, tt := range doc.TaxTotal {
+		totals[i] = en16931.TaxTotal{CurrencyID: tt.TaxAmount.CurrencyID, Amount: tt.TaxAmount.Value}
+	}
+	rate, ok := en16931.ExchangeRate(totals, doc.DocumentCurrencyCode, doc.TaxCurrencyCode)
+	if !ok {
+		c.warn(c.root+".TaxCurrencyCode", "the exchange rate to %s cannot be derived from the VAT totals", doc.TaxCurrencyCode)
+		return
+	}
+	c.rate = rate
+	inv.LocalCurrencyCode = doc.TaxCurrencyCode
+	inv.ForeignCurrencyCode = doc.DocumentCurrencyCode
+	inv.CurrRate = en16931.FormatRate(rate)
+	c.warn(c.root+".TaxCurrencyCode", "local currency amounts are computed with the rate %s derived from the VAT totals", inv.CurrRate)
+}
+
+// amounts sets the local and foreign currency amounts of a document amount.
+func (c *toISDOC) amounts(a Amount, local, curr *types.Decimal) {
+	v := a.Value
+	if c.negate {
+		v = en16931.Negate(v)
+	}
+	en16931.SetAmounts(v, c.rate, local, curr)
+}
+
+func (c *toISDOC) references() {
+	doc, inv := c.doc, c.inv
+
+	if ref := doc.OrderReference; ref != nil {
+		order := schema.OrderReference{ID: "order-1", SalesOrderID: ref.SalesOrderID}
+		if ref.ID != "NA" {
+			order.ExternalOrderID = ref.ID
+		}
+		inv.OrderReferences = &schema.OrderReferences{OrderReference: []schema.OrderReference{order}}
+	}
+
+	for i, ref := range doc.BillingReference {
+		if inv.OriginalDocumentReferences == nil {
+			inv.OriginalDocumentReferences = &schema.OriginalDocumentReferences{}
+		}
+		inv.OriginalDocumentReferences.OriginalDocumentReference = append(
+			inv.OriginalDocumentReferences.OriginalDocumentReference, schema.OriginalDocumentReference{
+				OriginalDocumentID: ref.InvoiceDocumentReference.ID,
+				UUID:               c.uuid(fmt.Sprintf("%s.BillingReference[%d].InvoiceDocumentReference.UUID", c.root, i), ref.InvoiceDocumentReference.UUID),
+				IssueDate:          ref.InvoiceDocumentReference.IssueDate,
+			})
+	}
+
+	for i, ref := range doc.DespatchDocumentReference {
+		if inv.DeliveryNoteReferences == nil {
+			inv.DeliveryNoteReferences = &schema.DeliveryNoteReferences{}
+		}
+		inv.DeliveryNoteReferences.DeliveryNoteReference = append(
+			inv.DeliveryNoteReferences.DeliveryNoteReference, schema.DeliveryNoteReference{
+				DeliveryNoteID: ref.ID,
+				UUID:           c.uuid(fmt.Sprintf("%s.DespatchDocumentReference[%d].UUID", c.root, i), ref.UUID),
+				IssueDate:      ref.IssueDate,
+			})
+	}
+
+	for i, ref := range doc.ContractDocumentReference {
+		if inv.ContractReferences == nil {
+			inv.ContractReferences = &schema.ContractReferences{}
+		}
+		inv.ContractReferences.ContractReference = append(
+			inv.ContractReferences.ContractReference, schema.ContractReference{
+				ContractID: ref.ID,
+				UUID:       c.uuid(fmt.Sprintf("%s.ContractDocumentReference[%d].UUID", c.root, i), ref.UUID),
+				IssueDate:  ref.IssueDate,
+			})
+	}
+}
+
+// uuid returns s as a UUID, reporting values that are not UUIDs.
+func (c *toISDOC) uuid(field, s string) types.UUID {
+	if s == "" {
+		return ""
+	}
+	u, err := types.NewUUID(s)
+	if err != nil {
+		c.warn(field, "%q is not a UUID", s)
+		return ""
+	}
+	return u
+}
+
+func (c *toISDOC) party(path string, p *Party) schema.Party {
+	var out schema.Party
+
+	for i, id := range p.PartyIdentification {
+		switch {
+		case id.ID.SchemeID == "0088" && out.PartyIdentification.CatalogFirmIdentification == "":
+			out.PartyIdentification.CatalogFirmIdentification = id.ID.Value
+		case out.PartyIdentification.ID == "":
+			out.PartyIdentification.ID = id.ID.Value
+			if id.ID.SchemeID != "" {
+				c.warn(fmt.Sprintf("%s.PartyIdentification[%d].ID", path, i), "identifier scheme %s is dropped", id.ID.SchemeID)
+			}
+		default:
+			c.warn(fmt.Sprintf("%s.PartyIdentification[%d]", path, i), "only one party identifier is converted")
+		}
+	}
+
+	if le := p.PartyLegalEntity; le != nil {
+		out.PartyName.Name = le.RegistrationName
+		if le.CompanyID != nil {
+			switch out.PartyIdentification.ID {
+			case "":
+				out.PartyIdentification.ID = le.CompanyID.Value
+			case le.CompanyID.Value:
+			default:
+				c.warn(path+".PartyLegalEntity.CompanyID", "the legal registration differs from the party identifier")
+			}
+		}
+		if le.CompanyLegalForm != "" {
+			out.RegisterIdentification = &schema.RegisterIdentification{Preformatted: le.CompanyLegalForm}
+		}
+	}
+	if p.PartyName != nil {
+		if out.PartyName.Name != "" && out.PartyName.Name != p.PartyName.Name {
+			c.warn(path+".PartyName.Name", "the trading name differs from the registration name")
+		}
+		out.PartyName.Name = p.PartyName.Name
+	}
+
+	if p.PostalAddress != nil {
+		out.PostalAddress = postalAddress(p.PostalAddress)
+	}
+
+	for _, ts := range p.PartyTaxScheme {
+		out.PartyTaxScheme = append(out.PartyTaxScheme, schema.PartyTaxScheme{
+			CompanyID: ts.CompanyID,
+			TaxScheme: ts.TaxScheme.ID,
+		})
+	}
+
+	if e := p.EndpointID; e != nil && !c.derivedEndpoint(e, &out) {
+		if e.SchemeID == "0088" && out.PartyIdentification.CatalogFirmIdentification == "" {
+			out.PartyIdentification.CatalogFirmIdentification = e.Value
+		} else {
+			c.warn(path+".EndpointID", "the electronic address %s:%s has no ISDOC counterpart", e.SchemeID, e.Value)
+		}
+	}
+
+	if ct := p.Contact; ct != nil {
+		out.Contact = &schema.Contact{Name: ct.Name, Telephone: ct.Telephone, ElectronicMail: ct.ElectronicMail}
+	}
+	return out
+}
+
+// derivedEndpoint reports whether e is the EndpointID FromISDOC writes for
+// the party.
+func (c *toISDOC) derivedEndpoint(e *Identifier, p *schema.Party) bool {
+	if e.SchemeID == "0088" {
+		return e.Value == p.PartyIdentification.CatalogFirmIdentification
+	}
+	for _, ts := range p.PartyTaxScheme {
+		if ts.CompanyID == e.Value && en16931.EndpointSchemes[en16931.Prefix(ts.CompanyID)] == e.SchemeID {
+			return true
+		}
+	}
+	return false
+}
+
+func postalAddress(a *Address) schema.PostalAddress {
+	return schema.PostalAddress{
+		StreetName:     a.StreetName,
+		BuildingNumber: a.BuildingNumber,
+		CityName:       a.CityName,
+		PostalZone:     a.PostalZone,
+		Country: schema.Country{
+			IdentificationCode: a.Country.IdentificationCode,
+			Name:               a.Country.Name,
+		},
+	}
+}
+
+func (c *toISDOC) delivery() {
+	d := c.doc.Delivery
+	if d == nil {
+		return
+	}
+	var party schema.Party
+	if d.DeliveryParty != nil {
+		party.PartyName.Name = d.DeliveryParty.PartyName.Name
+	}
+	if d.DeliveryLocation != nil && d.DeliveryLocation.Address != nil {
+		party.PostalAddress = postalAddress(d.DeliveryLocation.Address)
+	}
+	c.inv.Delivery = &schema.Delivery{Party: party}
+}
+
+// category returns the ISDOC tax category of a UBL tax category.
+func (c *toISDOC) category(path string, tc *TaxCategory) schema.ClassifiedTaxCategory {
+	out := schema.ClassifiedTaxCategory{Percent: tc.Percent}
+	if out.Percent == "" {
+		out.Percent = "0"
+	}
+	switch tc.ID {
+	case CategoryStandard, CategoryZero, CategoryExempt:
+	case CategoryReverseCharge:
+		out.LocalReverseCharge = &schema.LocalReverseCharge{}
+		c.warn(path, "the reverse charge code is not known")
+	case CategoryOutOfScope:
+		out.Percent = "0"
+	default:
+		c.warn(path, "VAT category %s is written as a %s%% rate", tc.ID, out.Percent)
+	}
+	if c.inv.VATApplicable.Bool() && out.LocalReverseCharge == nil && tc.ID != CategoryOutOfScope {
+		out.VATApplicable = true
+	}
+	return out
+}
+
+func (c *toISDOC) lines() {
+	for i := range c.doc.Lines {
+		l := &c.doc.Lines[i]
+		path := fmt.Sprintf("%s.%sLine[%d]", c.root, c.root, i)
+
+		line := schema.InvoiceLine{ID: l.ID, Note: l.Note}
+		if q := l.Quantity(); q != nil {
+			line.InvoicedQuantity = schema.Quantity{Value: q.Value, UnitCode: q.UnitCode}
+			if c.negate {
+				line.InvoicedQuantity.Value = en16931.Negate(q.Value)
+			}
+		}
+		c.amounts(l.LineExtensionAmount, &line.LineExtensionAmount, &line.LineExtensionAmountCurr)
+
+		price := en16931.ParseRat(l.Price.PriceAmount.Value)
+		if base := l.Price.BaseQuantity; base != nil {
+			if b := en16931.ParseRat(base.Value); b.Sign() != 0 {
+				price.Quo(price, b)
+			}
+		}
+		if c.rate != nil {
+			price.Mul(price, c.rate)
+		}
+		line.UnitPrice = en16931.FormatPrice(price)
+
+		line.ClassifiedTaxCategory = c.category(path+".Item.ClassifiedTaxCategory", &l.Item.ClassifiedTaxCategory)
+		en16931.ComputeLine(&line, c.rate != nil)
+
+		line.Item.Description = l.Item.Name
+		if l.Item.Description != "" && l.Item.Description != l.Item.Name {
+			c.warn(path+".Item.Description", "only the item name is converted")
+		}
+		if id := l.Item.SellersItemIdentification; id != nil {
+			line.Item.SellersItemIdentification = &schema.ItemIdentification{ID: id.ID.Value}
+		}
+		if id := l.Item.BuyersItemIdentification; id != nil {
+			line.Item.BuyersItemIdentification = &schema.ItemIdentification{ID: id.ID.Value}
+		}
+		if id := l.Item.StandardItemIdentification; id != nil {
+			line.Item.CatalogueItemIdentification = &schema.ItemIdentification{ID: id.ID.Value}
+		}
+		if ref := l.OrderLineReference; ref != nil {
+			line.OrderReference = &schema.OrderLineReference{LineID: ref.LineID}
+			if c.inv.OrderReferences != nil {
+				line.OrderReference.Ref = c.inv.OrderReferences.OrderReference[0].ID
+			}
+		}
+
+		c.inv.InvoiceLines.InvoiceLine = append(c.inv.InvoiceLines.InvoiceLine, line)
+	}
+}
+
+// allowanceCharges adds document level allowances and charges as lines.
+func (c *toISDOC) allowanceCharges() {
+	for i, ac := range c.doc.AllowanceCharge {
+		path := fmt.Sprintf("%s.AllowanceCharge[%d]", c.root, i)
+
+		amount := ac.Amount
+		if !ac.ChargeIndicator {
+			amount.Value = en16931.Negate(amount.Value)
+		}
+		line := schema.InvoiceLine{
+			ID:               fmt.Sprintf("AC%d", i+1),
+			InvoicedQuantity: schema.Quantity{Value: "1"},
+		}
+		c.amounts(amount, &line.LineExtensionAmount, &line.LineExtensionAmountCurr)
+		line.UnitPrice = line.LineExtensionAmount
+
+		line.Item.Description = en16931.JoinNonEmpty(" ", ac.ReasonCode, ac.Reason)
+		if line.Item.Description == "" {
+			line.Item.Description = "Allowance"
+			if ac.ChargeIndicator {
+				line.Item.Description = "Charge"
+			}
+		}
+		if ac.TaxCategory != nil {
+			line.ClassifiedTaxCategory = c.category(path+".TaxCategory", ac.TaxCategory)
+		}
+		en16931.ComputeLine(&line, c.rate != nil)
+
+		c.inv.InvoiceLines.InvoiceLine = append(c.inv.InvoiceLines.InvoiceLine, line)
+		c.warn(path, "the allowance or charge is written as line %s", line.ID)
+	}
+}
+
+func (c *toISDOC) taxTotal() {
+	vat := c.inv.VATApplicable
+	tt := &c.inv.TaxTotal
+
+	for _, total := range c.doc.TaxTotal {
+		if total.TaxAmount.CurrencyID != c.doc.DocumentCurrencyCode {
+			continue
+		}
+		c.amounts(total.TaxAmount, &tt.TaxAmount, &tt.TaxAmountCurr)
+
+		for _, st := range total.TaxSubtotal {
+			var out schema.TaxSubTotal
+			c.amounts(st.TaxableAmount, &out.TaxableAmount, &out.TaxableAmountCurr)
+			c.amounts(st.TaxAmount, &out.TaxAmount, &out.TaxAmountCurr)
+			out.TaxInclusiveAmount = en16931.Sum(out.TaxableAmount, out.TaxAmount)
+			out.AlreadyClaimedTaxableAmount = "0.00"
+			out.AlreadyClaimedTaxAmount = "0.00"
+			out.AlreadyClaimedTaxInclusiveAmount = "0.00"
+			out.DifferenceTaxableAmount = out.TaxableAmount
+			out.DifferenceTaxAmount = out.TaxAmount
+			out.DifferenceTaxInclusiveAmount = out.TaxInclusiveAmount
+			if c.rate != nil {
+				out.TaxInclusiveAmountCurr = en16931.Sum(out.TaxableAmountCurr, out.TaxAmountCurr)
+				out.AlreadyClaimedTaxableAmountCurr = "0.00"
+				out.AlreadyClaimedTaxAmountCurr = "0.00"
+				out.AlreadyClaimedTaxInclusiveAmountCurr = "0.00"
+				out.DifferenceTaxableAmountCurr = out.TaxableAmountCurr
+				out.DifferenceTaxAmountCurr = out.TaxAmountCurr
+				out.DifferenceTaxInclusiveAmountCurr = out.TaxInclusiveAmountCurr
+			}
+			out.TaxCategory = schema.TaxCategory{
+				Percent:                st.TaxCategory.Percent,
+				VATApplicable:          vat,
+				LocalReverseChargeFlag: st.TaxCategory.ID == CategoryReverseCharge,
+			}
+			if out.TaxCategory.Percent == "" {
+				out.TaxCategory.Percent = "0"
+			}
+			tt.TaxSubTotal = append(tt.TaxSubTotal, out)
+		}
+		return
+	}
+}
+
+func (c *toISDOC) monetaryTotal() {
+	src := &c.doc.LegalMonetaryTotal
+	lmt := &c.inv.LegalMonetaryTotal
+
+	c.amounts(src.TaxExclusiveAmount, &lmt.TaxExclusiveAmount, &lmt.TaxExclusiveAmountCurr)
+	c.amounts(src.TaxInclusiveAmount, &lmt.TaxInclusiveAmount, &lmt.TaxInclusiveAmountCurr)
+	lmt.AlreadyClaimedTaxExclusiveAmount = "0.00"
+	lmt.AlreadyClaimedTaxInclusiveAmount = "0.00"
+	lmt.DifferenceTaxExclusiveAmount = lmt.TaxExclusiveAmount
+	lmt.DifferenceTaxInclusiveAmount = lmt.TaxInclusiveAmount
+	if c.rate != nil {
+		lmt.AlreadyClaimedTaxExclusiveAmountCurr = "0.00"
+		lmt.AlreadyClaimedTaxInclusiveAmountCurr = "0.00"
+		lmt.DifferenceTaxExclusiveAmountCurr = lmt.TaxExclusiveAmountCurr
+		lmt.DifferenceTaxInclusiveAmountCurr = lmt.TaxInclusiveAmountCurr
+	}
+	if src.PrepaidAmount != nil {
+		c.amounts(*src.PrepaidAmount, &lmt.PaidDepositsAmount, &lmt.PaidDepositsAmountCurr)
+	}
+	if src.PayableRoundingAmount != nil {
+		c.amounts(*src.PayableRoundingAmount, &lmt.PayableRoundingAmount, &lmt.PayableRoundingAmountCurr)
+	}
+	c.amounts(src.PayableAmount, &lmt.PayableAmount, &lmt.PayableAmountCurr)
+}
+
+func (c *toISDOC) paymentMeans() {
+	doc := c.doc
+	if len(doc.PaymentMeans) == 0 {
+		if !doc.DueDate.IsZero() {
+			c.warn(c.root+".DueDate", "a due date without payment means has no ISDOC counterpart")
+		}
+		return
+	}
+
+	first := doc.PaymentMeans[0]
+	code, ok := en16931.PaymentMeansCode(first.PaymentMeansCode)
+	if !ok {
+		c.warn(c.root+".PaymentMeans[0].PaymentMeansCode", "UNCL4461 code %s is written as %d", first.PaymentMeansCode, code)
+	}
+	details := &schema.PaymentDetails{
+		PaymentDueDate: doc.DueDate,
+		VariableSymbol: first.PaymentID,
+	}
+	if details.PaymentDueDate.IsZero() {
+		details.PaymentDueDate = first.PaymentDueDate
+	}
+	if first.PayeeFinancialAccount != nil {
+		details.BankAccount = bankAccount(first.PayeeFinancialAccount)
+	}
+	payment := schema.Payment{
+		PaidAmount:       c.inv.LegalMonetaryTotal.PayableAmount,
+		PaymentMeansCode: code,
+		Details:          details,
+	}
+	c.inv.PaymentMeans = &schema.PaymentMeans{Payment: []schema.Payment{payment}}
+
+	// Further means of payment are alternative bank accounts
+	for i, m := range doc.PaymentMeans[1:] {
+		if m.PayeeFinancialAccount == nil {
+			c.warn(fmt.Sprintf("%s.PaymentMeans[%d]", c.root, i+1), "only the bank accounts of further payment means are converted")
+			continue
+		}
+		pm := c.inv.PaymentMeans
+		if pm.AlternateBankAccounts == nil {
+			pm.AlternateBankAccounts = &schema.AlternateBankAccounts{}
+		}
+		pm.AlternateBankAccounts.AlternateBankAccount = append(pm.AlternateBankAccounts.AlternateBankAccount,
+			*bankAccount(m.PayeeFinancialAccount))
+	}
+}
+
+// bankAccount splits an IBAN or an "account/bank code" number.
+func bankAccount(fa *FinancialAccount) *schema.BankAccount {
+	a := &schema.BankAccount{ID: fa.ID, Name: fa.Name}
+	if fa.FinancialInstitutionBranch != nil {
+		a.BIC = fa.FinancialInstitutionBranch.ID
+	}
+	if iban := strings.ReplaceAll(fa.ID, " ", ""); en16931.IsIBAN(iban) {
+		a.IBAN = iban
+		a.ID = iban
+		if id, code, ok := en16931.CzechAccount(iban); ok {
+			a.ID, a.BankCode = id, code
+		}
+	} else if id, code, ok := strings.Cut(fa.ID, "/"); ok {
+		a.ID, a.BankCode = id, code
+	}
+	return a
+}
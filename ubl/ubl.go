@@ -0,0 +1,118 @@
+// Package ubl converts ISDOC invoices to and from UBL 2.1 Invoice and
+// CreditNote documents following EN 16931 and Peppol BIS Billing 3.0.
+//
+// ISDOC and UBL share most of their structure, but not all ISDOC fields
+// have an EN 16931 counterpart and vice versa. Fields that cannot be carried
+// over are reported as warnings with code isdoc.ErrCodeLossyConversion:
+//
+//	data, warnings, err := ubl.Marshal(invoice)
+//	for _, w := range warnings {
+//	    log.Println(w)
+//	}
+//
+//	invoice, warnings, err := ubl.Unmarshal(data)
+//
+// Codes are mapped as follows:
+//   - DocumentType 1, 3, 4/5 and 7 become invoices with UNCL1001 type codes
+//     380, 383, 386 and 380; DocumentType 2 and 6 become credit notes (381)
+//   - VAT rates become UNCL5305 categories: S for positive rates, E for
+//     zero rates, AE for local reverse charge and O for invoices without VAT
+//   - ISDOC payment means codes are UNCL4461 codes and are kept as is
+//
+// ISDOC credit notes carry negative amounts, UBL credit notes positive
+// ones; signs are flipped when converting between them. An invoice in a
+// foreign currency becomes a UBL document in that currency with the VAT
+// total in the local currency as TaxCurrencyCode.
+//
+// Validate checks a converted document against the EN 16931 and Peppol BIS
+// Billing 3.0 business rules, reporting the official rule identifiers in
+// ValidationError.Rule:
+//
+//	doc, warnings := ubl.FromISDOC(invoice)
+//	errs := ubl.Validate(doc) // e.g. Rule "BR-CO-15", "PEPPOL-EN16931-R003"
+package ubl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/schema"
+)
+
+// ErrNotUBL is returned by Unmarshal for documents that are neither a UBL
+// Invoice nor a UBL CreditNote.
+var ErrNotUBL = errors.New("not a UBL invoice or credit note")
+
+// Marshal converts inv to UBL and encodes it as indented XML. The warnings
+// list the ISDOC fields that could not be converted.
+func Marshal(inv *schema.Invoice) ([]byte, isdoc.ValidationErrors, error) {
+	doc, warnings := FromISDOC(inv)
+	data, err := Encode(doc)
+	if err != nil {
+		return nil, warnings, err
+	}
+	return data, warnings, nil
+}
+
+// Encode encodes a UBL Invoice or CreditNote as indented XML.
+func Encode(doc *Document) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a UBL Invoice or CreditNote and converts it to ISDOC.
+// The warnings list the UBL fields that could not be converted.
+func Unmarshal(data []byte) (*schema.Invoice, isdoc.ValidationErrors, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	inv, warnings := ToISDOC(doc)
+	return inv, warnings, nil
+}
+
+// Decode decodes a UBL Invoice or CreditNote.
+func Decode(data []byte) (*Document, error) {
+	dec := xml.NewTokenDecoder(prefixReader{xml.NewDecoder(bytes.NewReader(data))})
+	var doc Document
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, ErrNotUBL) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("decoding UBL: %w", err)
+	}
+	return &doc, nil
+}
+
+// IsUBL reports whether data looks like a UBL Invoice or CreditNote.
+func IsUBL(data []byte) bool {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if el, ok := t.(xml.StartElement); ok {
+			return el.Name.Space == NamespaceInvoice || el.Name.Space == NamespaceCreditNote
+		}
+	}
+}
+
+func lossy(field, msg string) *isdoc.ValidationError {
+	return &isdoc.ValidationError{
+		Field:    field,
+		Code:     isdoc.ErrCodeLossyConversion,
+		Severity: isdoc.SeverityWarning,
+		Msg:      msg,
+	}
+}
@@ -0,0 +1,280 @@
+package ubl
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xseman/isdoc"
+	"github.com/xseman/isdoc/internal/en16931"
+	"github.com/xseman/isdoc/schema"
+	"github.com/xseman/isdoc/types"
+)
+
+func loadFixture(t *testing.T, name string) *schema.Invoice {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	inv, err := isdoc.DecodeBytes(data)
+	if err != nil {
+		t.Fatalf("DecodeBytes failed: %v", err)
+	}
+	return inv
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"test001.isdoc", "test002.isdoc", "sample.isdoc", "no-vat-applicable.isdoc"} {
+		t.Run(name, func(t *testing.T) {
+			want := loadFixture(t, name)
+
+			data, _, err := Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if !IsUBL(data) {
+				t.Fatal("IsUBL = false for marshaled document")
+			}
+			got, _, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			// The fixtures use "Ks" and other units outside UN/ECE Rec 20
+			for _, e := range isdoc.ValidateInvoice(got) {
+				if e.Code == isdoc.ErrCodeInvalidEnum && strings.HasSuffix(e.Field, ".@unitCode") {
+					continue
+				}
+				t.Errorf("converted invoice is invalid: %v", e)
+			}
+			for field, pair := range map[string][2]string{
+				"ID":                  {got.ID, want.ID},
+				"DocumentType":        {got.DocumentType.String(), want.DocumentType.String()},
+				"LocalCurrencyCode":   {got.LocalCurrencyCode, want.LocalCurrencyCode},
+				"ForeignCurrencyCode": {got.ForeignCurrencyCode, want.ForeignCurrencyCode},
+				"Seller":              {got.AccountingSupplierParty.Party.PartyIdentification.ID, want.AccountingSupplierParty.Party.PartyIdentification.ID},
+			} {
+				if pair[0] != pair[1] {
+					t.Errorf("%s = %q, want %q", field, pair[0], pair[1])
+				}
+			}
+			for field, pair := range map[string][2]string{
+				"TaxExclusiveAmount": {string(got.LegalMonetaryTotal.TaxExclusiveAmount), string(want.LegalMonetaryTotal.TaxExclusiveAmount)},
+				"TaxInclusiveAmount": {string(got.LegalMonetaryTotal.TaxInclusiveAmount), string(want.LegalMonetaryTotal.TaxInclusiveAmount)},
+				"PayableAmount":      {string(got.LegalMonetaryTotal.PayableAmount), string(want.LegalMonetaryTotal.PayableAmount)},
+				"TaxAmount":          {string(got.TaxTotal.TaxAmount), string(want.TaxTotal.TaxAmount)},
+			} {
+				if en16931.ParseRat(types.Decimal(pair[0])).Cmp(en16931.ParseRat(types.Decimal(pair[1]))) != 0 {
+					t.Errorf("%s = %s, want %s", field, pair[0], pair[1])
+				}
+			}
+			if len(got.InvoiceLines.InvoiceLine) != len(want.InvoiceLines.InvoiceLine) {
+				t.Errorf("got %d lines, want %d", len(got.InvoiceLines.InvoiceLine), len(want.InvoiceLines.InvoiceLine))
+			}
+		})
+	}
+}
+
+func TestFromISDOCForeignCurrency(t *testing.T) {
+	inv := loadFixture(t, "sample.isdoc")
+	if inv.ForeignCurrencyCode == "" {
+		t.Fatal("fixture has no foreign currency")
+	}
+	doc, _ := FromISDOC(inv)
+
+	if doc.DocumentCurrencyCode != inv.ForeignCurrencyCode {
+		t.Errorf("DocumentCurrencyCode = %s, want %s", doc.DocumentCurrencyCode, inv.ForeignCurrencyCode)
+	}
+	if doc.TaxCurrencyCode != inv.LocalCurrencyCode {
+		t.Errorf("TaxCurrencyCode = %s, want %s", doc.TaxCurrencyCode, inv.LocalCurrencyCode)
+	}
+	if len(doc.TaxTotal) != 2 {
+		t.Fatalf("got %d tax totals, want 2", len(doc.TaxTotal))
+	}
+	if local := doc.TaxTotal[1].TaxAmount; local.CurrencyID != inv.LocalCurrencyCode || !local.Value.Equal(inv.TaxTotal.TaxAmount) {
+		t.Errorf("local tax total = %s %s, want %s %s", local.Value, local.CurrencyID, inv.TaxTotal.TaxAmount, inv.LocalCurrencyCode)
+	}
+}
+
+func TestFromISDOCWarnings(t *testing.T) {
+	inv := loadFixture(t, "test001.isdoc")
+	_, warnings := FromISDOC(inv)
+	if len(warnings) == 0 {
+		t.Fatal("expected lossy conversion warnings")
+	}
+	var issuingSystem bool
+	for _, w := range warnings {
+		if w.Code != isdoc.ErrCodeLossyConversion || w.Severity != isdoc.SeverityWarning {
+			t.Errorf("unexpected warning %v", w)
+		}
+		if strings.Contains(w.Field, "CatalogFirmIdentification") {
+			t.Errorf("placeholder GLN reported: %v", w)
+		}
+		if w.Field == "Invoice.IssuingSystem" {
+			issuingSystem = true
+		}
+	}
+	if !issuingSystem {
+		t.Error("missing warning for IssuingSystem")
+	}
+}
+
+const creditNote = `<?xml version="1.0" encoding="UTF-8"?>
+<ubl:CreditNote xmlns:ubl="urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+    xmlns:a="urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+    xmlns:b="urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2">
+  <b:CustomizationID>urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0</b:CustomizationID>
+  <b:ID>CN-1</b:ID>
+  <b:IssueDate>2024-03-01</b:IssueDate>
+  <b:TaxPointDate>2024-03-01</b:TaxPointDate>
+  <b:CreditNoteTypeCode>381</b:CreditNoteTypeCode>
+  <b:DocumentCurrencyCode>CZK</b:DocumentCurrencyCode>
+  <a:BillingReference>
+    <a:InvoiceDocumentReference>
+      <b:ID>INV-1</b:ID>
+      <b:IssueDate>2024-02-01</b:IssueDate>
+    </a:InvoiceDocumentReference>
+  </a:BillingReference>
+  <a:AccountingSupplierParty>
+    <a:Party>
+      <a:PartyName><b:Name>Seller s.r.o.</b:Name></a:PartyName>
+      <a:PostalAddress>
+        <b:CityName>Praha</b:CityName>
+        <a:Country><b:IdentificationCode>CZ</b:IdentificationCode></a:Country>
+      </a:PostalAddress>
+      <a:PartyTaxScheme>
+        <b:CompanyID>CZ12345678</b:CompanyID>
+        <a:TaxScheme><b:ID>VAT</b:ID></a:TaxScheme>
+      </a:PartyTaxScheme>
+      <a:PartyLegalEntity>
+        <b:RegistrationName>Seller s.r.o.</b:RegistrationName>
+        <b:CompanyID>12345678</b:CompanyID>
+      </a:PartyLegalEntity>
+    </a:Party>
+  </a:AccountingSupplierParty>
+  <a:AccountingCustomerParty>
+    <a:Party>
+      <a:PartyName><b:Name>Buyer a.s.</b:Name></a:PartyName>
+      <a:PostalAddress>
+        <b:CityName>Brno</b:CityName>
+        <a:Country><b:IdentificationCode>CZ</b:IdentificationCode></a:Country>
+      </a:PostalAddress>
+      <a:PartyLegalEntity>
+        <b:RegistrationName>Buyer a.s.</b:RegistrationName>
+        <b:CompanyID>87654321</b:CompanyID>
+      </a:PartyLegalEntity>
+    </a:Party>
+  </a:AccountingCustomerParty>
+  <a:TaxTotal>
+    <b:TaxAmount currencyID="CZK">21.00</b:TaxAmount>
+    <a:TaxSubtotal>
+      <b:TaxableAmount currencyID="CZK">100.00</b:TaxableAmount>
+      <b:TaxAmount currencyID="CZK">21.00</b:TaxAmount>
+      <a:TaxCategory>
+        <b:ID>S</b:ID>
+        <b:Percent>21</b:Percent>
+        <a:TaxScheme><b:ID>VAT</b:ID></a:TaxScheme>
+      </a:TaxCategory>
+    </a:TaxSubtotal>
+  </a:TaxTotal>
+  <a:LegalMonetaryTotal>
+    <b:LineExtensionAmount currencyID="CZK">100.00</b:LineExtensionAmount>
+    <b:TaxExclusiveAmount currencyID="CZK">100.00</b:TaxExclusiveAmount>
+    <b:TaxInclusiveAmount currencyID="CZK">121.00</b:TaxInclusiveAmount>
+    <b:PayableAmount currencyID="CZK">121.00</b:PayableAmount>
+  </a:LegalMonetaryTotal>
+  <a:CreditNoteLine>
+    <b:ID>1</b:ID>
+    <b:CreditedQuantity unitCode="C62">2</b:CreditedQuantity>
+    <b:LineExtensionAmount currencyID="CZK">100.00</b:LineExtensionAmount>
+    <a:Item>
+      <b:Name>Returned goods</b:Name>
+      <a:ClassifiedTaxCategory>
+        <b:ID>S</b:ID>
+        <b:Percent>21</b:Percent>
+        <a:TaxScheme><b:ID>VAT</b:ID></a:TaxScheme>
+      </a:ClassifiedTaxCategory>
+    </a:Item>
+    <a:Price><b:PriceAmount currencyID="CZK">50.00</b:PriceAmount></a:Price>
+  </a:CreditNoteLine>
+</ubl:CreditNote>
+`
+
+func TestCreditNote(t *testing.T) {
+	data := []byte(creditNote)
+	if !IsUBL(data) {
+		t.Fatal("IsUBL = false")
+	}
+	inv, _, err := Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if inv.DocumentType != 2 {
+		t.Errorf("DocumentType = %d, want 2", inv.DocumentType)
+	}
+	if inv.OriginalDocumentReferences == nil || len(inv.OriginalDocumentReferences.OriginalDocumentReference) != 1 ||
+		inv.OriginalDocumentReferences.OriginalDocumentReference[0].OriginalDocumentID != "INV-1" {
+		t.Errorf("OriginalDocumentReferences = %+v", inv.OriginalDocumentReferences)
+	}
+	if got := inv.AccountingSupplierParty.Party.PartyIdentification.ID; got != "12345678" {
+		t.Errorf("seller ID = %q", got)
+	}
+	if got := inv.LegalMonetaryTotal.TaxInclusiveAmount; en16931.ParseRat(got).Cmp(en16931.ParseRat("-121")) != 0 {
+		t.Errorf("TaxInclusiveAmount = %s, want -121", got)
+	}
+	line := inv.InvoiceLines.InvoiceLine[0]
+	if got := line.LineExtensionAmount; en16931.ParseRat(got).Cmp(en16931.ParseRat("-100")) != 0 {
+		t.Errorf("line LineExtensionAmount = %s, want -100", got)
+	}
+	if errs := isdoc.ValidateInvoice(inv); len(errs) > 0 {
+		t.Errorf("converted credit note is invalid: %v", errs)
+	}
+
+	out, _, err := Marshal(inv)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Contains(out, []byte("<CreditNote ")) || !bytes.Contains(out, []byte("<cbc:CreditNoteTypeCode>381<")) {
+		t.Errorf("expected a credit note, got:\n%s", out)
+	}
+	if !bytes.Contains(out, []byte(`<cbc:PayableAmount currencyID="CZK">121.00<`)) {
+		t.Errorf("expected a positive payable amount, got:\n%s", out)
+	}
+}
+
+func TestUnmarshalNotUBL(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "fixtures", "test001.isdoc"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if IsUBL(data) {
+		t.Error("IsUBL = true for an ISDOC document")
+	}
+	if _, _, err := Unmarshal(data); !errors.Is(err, ErrNotUBL) {
+		t.Errorf("Unmarshal error = %v, want ErrNotUBL", err)
+	}
+}
+
+func TestTaxCategory(t *testing.T) {
+	tests := []struct {
+		vatApplicable bool
+		percent       string
+		reverseCharge bool
+		want          string
+	}{
+		{true, "21", false, CategoryStandard},
+		{true, "0", false, CategoryExempt},
+		{true, "21", true, CategoryReverseCharge},
+		{false, "0", false, CategoryOutOfScope},
+	}
+	for _, tt := range tests {
+		if got := taxCategory(tt.vatApplicable, types.Decimal(tt.percent), tt.reverseCharge).ID; got != tt.want {
+			t.Errorf("taxCategory(%v, %s, %v) = %s, want %s", tt.vatApplicable, tt.percent, tt.reverseCharge, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package isdoc
 
 import (
 	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
 
 	"github.com/xseman/isdoc/schema"
 	"github.com/xseman/isdoc/types"
@@ -17,6 +20,15 @@ type ValidateOptions struct {
 
 	// Tolerance is the maximum allowed difference for total mismatches. Default is 0.01.
 	Tolerance types.Decimal
+
+	// TaxPointWindow is the number of days a tax document may be issued
+	// after its TaxPointDate. Zero means DefaultTaxPointWindow; a negative
+	// value disables the check.
+	TaxPointWindow int
+
+	// SourceMap, if set, annotates errors with source positions. Use the
+	// SourceMap filled when decoding the document.
+	SourceMap *SourceMap
 }
 
 // DefaultValidateOptions returns sensible defaults for validation.
@@ -28,6 +40,15 @@ func DefaultValidateOptions() ValidateOptions {
 	}
 }
 
+// tolerance returns the largest difference accepted between an amount and
+// the value computed for it.
+func (o ValidateOptions) tolerance() *big.Rat {
+	if !o.AllowRoundingTolerance {
+		return new(big.Rat)
+	}
+	return new(big.Rat).Abs(parseAmount(o.Tolerance))
+}
+
 // ValidateInvoice validates an ISDOC Invoice with default options.
 //
 // Performs three-layer validation:
@@ -73,6 +94,10 @@ func ValidateInvoiceWithOptions(inv *schema.Invoice, opts ValidateOptions) Valid
 	// Semantic validation
 	errs = append(errs, validateSemantic(inv, opts)...)
 
+	if opts.SourceMap != nil {
+		opts.SourceMap.Annotate(errs)
+	}
+
 	return errs
 }
 
@@ -94,7 +119,7 @@ func validateStructural(inv *schema.Invoice, opts ValidateOptions) ValidationErr
 		})
 	}
 
-	if inv.DocumentType < 1 || inv.DocumentType > 7 {
+	if !inv.DocumentType.IsValid() {
 		errs = append(errs, &ValidationError{
 			Field:    "Invoice.DocumentType",
 			Code:     ErrCodeInvalidEnum,
@@ -164,6 +189,8 @@ func validateStructural(inv *schema.Invoice, opts ValidateOptions) ValidationErr
 		})
 	}
 
+	errs = append(errs, validateExtensions("Invoice.Extensions", inv.Extensions, opts)...)
+
 	// Supplier
 	errs = append(errs, validateParty("Invoice.AccountingSupplierParty.Party",
 		&inv.AccountingSupplierParty.Party, opts)...)
@@ -171,7 +198,7 @@ func validateStructural(inv *schema.Invoice, opts ValidateOptions) ValidationErr
 	// Customer (either AccountingCustomerParty or AnonymousCustomerParty for simplified docs)
 	if inv.AccountingCustomerParty == nil && inv.AnonymousCustomerParty == nil {
 		// For document type 7 (simplified), AnonymousCustomerParty is acceptable
-		if inv.DocumentType != 7 {
+		if inv.DocumentType != types.DocumentTypeSimplified {
 			errs = append(errs, &ValidationError{
 				Field:    "Invoice.AccountingCustomerParty",
 				Code:     ErrCodeRequiredField,
@@ -220,6 +247,21 @@ func validateStructural(inv *schema.Invoice, opts ValidateOptions) ValidationErr
 		})
 	}
 
+	if inv.PaymentMeans != nil {
+		for i, p := range inv.PaymentMeans.Payment {
+			if !p.PaymentMeansCode.IsValid() {
+				errs = append(errs, &ValidationError{
+					Field:    fmt.Sprintf("Invoice.PaymentMeans.Payment[%d].PaymentMeansCode", i),
+					Code:     ErrCodeInvalidEnum,
+					Severity: SeverityError,
+					Msg:      fmt.Sprintf("PaymentMeansCode %d is not in the ISDOC code list", p.PaymentMeansCode),
+				})
+			}
+		}
+	}
+
+	errs = append(errs, validateCodeLists(inv, opts)...)
+
 	return errs
 }
 
@@ -265,6 +307,8 @@ func validateParty(path string, party *schema.Party, opts ValidateOptions) Valid
 			Severity: severity,
 			Msg:      "Country.IdentificationCode is expected",
 		})
+	} else {
+		errs = append(errs, validateCountry(path, party, severity)...)
 	}
 
 	return errs
@@ -316,6 +360,90 @@ func validateInvoiceLine(path string, line *schema.InvoiceLine, opts ValidateOpt
 		})
 	}
 
+	if !line.ClassifiedTaxCategory.VATCalculationMethod.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:    path + ".ClassifiedTaxCategory.VATCalculationMethod",
+			Code:     ErrCodeInvalidEnum,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("VATCalculationMethod must be 0 or 1, got %d", line.ClassifiedTaxCategory.VATCalculationMethod),
+		})
+	}
+
+	if line.Item.StoreBatches != nil {
+		for j, batch := range line.Item.StoreBatches.StoreBatch {
+			if batch.BatchOrSerialNumber != "" && !batch.BatchOrSerialNumber.IsValid() {
+				errs = append(errs, &ValidationError{
+					Field:    fmt.Sprintf("%s.Item.StoreBatches.StoreBatch[%d].BatchOrSerialNumber", path, j),
+					Code:     ErrCodeInvalidEnum,
+					Severity: SeverityError,
+					Msg:      fmt.Sprintf("BatchOrSerialNumber must be \"B\" or \"S\", got %q", batch.BatchOrSerialNumber),
+				})
+			}
+		}
+	}
+
+	errs = append(errs, validateExtensions(path+".Extensions", line.Extensions, opts)...)
+
+	return errs
+}
+
+// validateExtensions checks that extension elements are in a foreign
+// namespace, as required by the XSD (##other), and that payloads of
+// registered extension types decode and pass their own validation.
+func validateExtensions(path string, ext *schema.Extensions, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	namespaces, err := ext.Namespaces()
+	if err != nil {
+		return append(errs, &ValidationError{
+			Field:    path,
+			Code:     ErrCodeInvalidXML,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("malformed extension content: %v", err),
+		})
+	}
+
+	for _, ns := range namespaces {
+		if ns == "" || ns == schema.Namespace {
+			errs = append(errs, &ValidationError{
+				Field:    path,
+				Code:     ErrCodeSchemaViolation,
+				Severity: severity,
+				Msg:      "extension elements must declare a namespace other than the ISDOC namespace",
+			})
+			continue
+		}
+
+		t, ok := schema.RegisteredExtension(ns)
+		if !ok {
+			continue
+		}
+		v := reflect.New(t).Interface()
+		if err := ext.Decode(v); err != nil {
+			errs = append(errs, &ValidationError{
+				Field:    path,
+				Code:     ErrCodeInvalidExtension,
+				Severity: SeverityError,
+				Msg:      fmt.Sprintf("extension %s: %v", ns, err),
+			})
+			continue
+		}
+		if validator, ok := v.(schema.ExtensionValidator); ok {
+			if err := validator.Validate(); err != nil {
+				errs = append(errs, &ValidationError{
+					Field:    path,
+					Code:     ErrCodeInvalidExtension,
+					Severity: SeverityError,
+					Msg:      fmt.Sprintf("extension %s: %v", ns, err),
+				})
+			}
+		}
+	}
+
 	return errs
 }
 
@@ -326,9 +454,14 @@ func validateSemantic(inv *schema.Invoice, opts ValidateOptions) ValidationError
 	// Schematron business rules from isdoc-6.0.2.sch
 	errs = append(errs, validateOriginalDocumentLink(inv, opts)...)
 	errs = append(errs, validateCurrencyConsistency(inv, opts)...)
+	errs = append(errs, validateCurrencyAmounts(inv, opts)...)
 	errs = append(errs, validateVATConsistency(inv, opts)...)
 	errs = append(errs, validateItemIdentificationHierarchy(inv, opts)...)
 	errs = append(errs, validateStoreBatches(inv, opts)...)
+	errs = append(errs, validateLocalReverseCharge(inv, opts)...)
+	errs = append(errs, validateDeposits(inv, opts)...)
+	errs = append(errs, validateDates(inv, opts)...)
+	errs = append(errs, validateDocumentContent("Invoice", inv.Note, inv.SupplementsList, opts)...)
 
 	return errs
 }
@@ -338,8 +471,7 @@ func validateSemantic(inv *schema.Invoice, opts ValidateOptions) ValidationError
 func validateOriginalDocumentLink(inv *schema.Invoice, opts ValidateOptions) ValidationErrors {
 	var errs ValidationErrors
 
-	// DocumentType 2=credit note, 3=debit note, 6=credit of advance invoice
-	if inv.DocumentType == 2 || inv.DocumentType == 3 || inv.DocumentType == 6 {
+	if inv.DocumentType.IsCorrective() {
 		if inv.OriginalDocumentReferences == nil || len(inv.OriginalDocumentReferences.OriginalDocumentReference) == 0 {
 			errs = append(errs, &ValidationError{
 				Field:    "Invoice.OriginalDocumentReferences",
@@ -1045,6 +1177,13 @@ func ValidateCommonDocumentWithOptions(doc *schema.CommonDocument, opts Validate
 	// Structural validation
 	errs = append(errs, validateCommonDocumentStructural(doc, opts)...)
 
+	// Semantic validation
+	errs = append(errs, validateCommonDocumentSemantic(doc, opts)...)
+
+	if opts.SourceMap != nil {
+		opts.SourceMap.Annotate(errs)
+	}
+
 	return errs
 }
 
@@ -1062,7 +1201,7 @@ func validateCommonDocumentStructural(doc *schema.CommonDocument, opts ValidateO
 		})
 	}
 
-	if doc.SubDocumentType == "" {
+	if strings.TrimSpace(doc.SubDocumentType) == "" {
 		errs = append(errs, &ValidationError{
 			Field:    "CommonDocument.SubDocumentType",
 			Code:     ErrCodeRequiredField,
@@ -1071,7 +1210,7 @@ func validateCommonDocumentStructural(doc *schema.CommonDocument, opts ValidateO
 		})
 	}
 
-	if doc.SubDocumentTypeOrigin == "" {
+	if strings.TrimSpace(doc.SubDocumentTypeOrigin) == "" {
 		errs = append(errs, &ValidationError{
 			Field:    "CommonDocument.SubDocumentTypeOrigin",
 			Code:     ErrCodeRequiredField,
@@ -1096,6 +1235,13 @@ func validateCommonDocumentStructural(doc *schema.CommonDocument, opts ValidateO
 			Severity: SeverityError,
 			Msg:      "UUID is required",
 		})
+	} else if !doc.UUID.IsValid() {
+		errs = append(errs, &ValidationError{
+			Field:    "CommonDocument.UUID",
+			Code:     ErrCodeInvalidUUID,
+			Severity: SeverityError,
+			Msg:      fmt.Sprintf("UUID %q is not in the format XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX", doc.UUID),
+		})
 	}
 
 	if doc.IssueDate.IsZero() {
@@ -1107,6 +1253,8 @@ func validateCommonDocumentStructural(doc *schema.CommonDocument, opts ValidateO
 		})
 	}
 
+	errs = append(errs, validateExtensions("CommonDocument.Extensions", doc.Extensions, opts)...)
+
 	// Supplier party
 	errs = append(errs, validateParty("CommonDocument.AccountingSupplierParty.Party",
 		&doc.AccountingSupplierParty.Party, opts)...)
@@ -1117,3 +1265,32 @@ func validateCommonDocumentStructural(doc *schema.CommonDocument, opts ValidateO
 
 	return errs
 }
+
+// validateCommonDocumentSemantic checks that LastValidDate is not before
+// IssueDate, the IČO and DIČ of the parties, and the note and supplements.
+// The Schematron rules of the ISDOC schema concern currencies, VAT and
+// invoice lines and do not apply to CommonDocument.
+func validateCommonDocumentSemantic(doc *schema.CommonDocument, opts ValidateOptions) ValidationErrors {
+	var errs ValidationErrors
+	severity := SeverityWarning
+	if opts.Strict {
+		severity = SeverityError
+	}
+
+	if !doc.LastValidDate.IsZero() && !doc.IssueDate.IsZero() && doc.LastValidDate.Before(doc.IssueDate.Time) {
+		errs = append(errs, &ValidationError{
+			Field:    "CommonDocument.LastValidDate",
+			Code:     ErrCodeInvalidDate,
+			Severity: severity,
+			Msg:      fmt.Sprintf("LastValidDate %s is before IssueDate %s", doc.LastValidDate, doc.IssueDate),
+		})
+	}
+
+	errs = append(errs, validatePartyIdentifiers("CommonDocument.AccountingSupplierParty.Party",
+		&doc.AccountingSupplierParty.Party, severity)...)
+	errs = append(errs, validatePartyIdentifiers("CommonDocument.AccountingCustomerParty.Party",
+		&doc.AccountingCustomerParty.Party, severity)...)
+	errs = append(errs, validateDocumentContent("CommonDocument", doc.Note, doc.SupplementsList, opts)...)
+
+	return errs
+}